@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/cli"
 	"github.com/docker/cagent/pkg/config"
+	"github.com/docker/cagent/pkg/paths"
 	"github.com/docker/cagent/pkg/server"
 	"github.com/docker/cagent/pkg/session"
 	"github.com/docker/cagent/pkg/telemetry"
@@ -21,6 +24,11 @@ type apiFlags struct {
 	pullIntervalMins int
 	fakeResponses    string
 	recordPath       string
+	autoApprove      bool
+	authTokensFile   string
+	authJWT          server.JWTConfig
+	webhooks         webhookFlags
+	webhookHosts     []string
 	runConfig        config.RuntimeConfig
 }
 
@@ -30,7 +38,7 @@ func newAPICmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "api <agent-file>|<agents-dir>",
 		Short:   "Start the cagent API server",
-		Long:    `Start the API server that exposes the agent via a cagent-specific HTTP API`,
+		Long:    `Start the API server that exposes the agent via a cagent-specific HTTP API and an OpenAI-compatible API`,
 		GroupID: "server",
 		Args:    cobra.ExactArgs(1),
 		RunE:    flags.runAPICommand,
@@ -41,6 +49,13 @@ func newAPICmd() *cobra.Command {
 	cmd.PersistentFlags().IntVar(&flags.pullIntervalMins, "pull-interval", 0, "Auto-pull OCI reference every N minutes (0 = disabled)")
 	cmd.PersistentFlags().StringVar(&flags.fakeResponses, "fake", "", "Replay AI responses from cassette file (for testing)")
 	cmd.PersistentFlags().StringVar(&flags.recordPath, "record", "", "Record AI API interactions to cassette file")
+	cmd.PersistentFlags().BoolVar(&flags.autoApprove, "yolo", false, "Automatically approve all the tool calls of OpenAI-compatible chat completions")
+	cmd.PersistentFlags().StringVar(&flags.authTokensFile, "auth-tokens-file", "", "Require bearer tokens listed in this YAML file (tokens can also be set with $"+server.TokensEnv+")")
+	cmd.PersistentFlags().StringVar(&flags.authJWT.JWKSURL, "auth-jwks-url", "", "Accept JSON Web Tokens signed by the keys of this JWKS URL")
+	cmd.PersistentFlags().StringVar(&flags.authJWT.Issuer, "auth-issuer", "", "Accept JSON Web Tokens from this OpenID Connect issuer")
+	cmd.PersistentFlags().StringVar(&flags.authJWT.Audience, "auth-audience", "", "Required audience of JSON Web Tokens (mandatory to accept them)")
+	addWebhookFlags(cmd, &flags.webhooks)
+	cmd.PersistentFlags().StringSliceVar(&flags.webhookHosts, "webhook-allowed-host", nil, "Let the webhooks of sessions target these hosts (session webhooks are refused by default)")
 	cmd.MarkFlagsMutuallyExclusive("fake", "record")
 	addRuntimeConfigFlags(cmd, &flags.runConfig)
 
@@ -102,10 +117,66 @@ func (f *apiFlags) runAPICommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to resolve agent sources: %w", err)
 	}
 
-	s, err := server.New(ctx, sessionStore, &f.runConfig, time.Duration(f.pullIntervalMins)*time.Minute, sources)
+	authenticators, err := f.authenticators()
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := f.webhooks.dispatcher()
+	if err != nil {
+		return err
+	}
+	defer flushWebhooks(webhooks)
+
+	s, err := server.New(ctx, sessionStore, &f.runConfig, time.Duration(f.pullIntervalMins)*time.Minute, sources,
+		server.WithCheckpoints(checkpoint.NewStore(filepath.Join(paths.GetDataDir(), "checkpoints"))),
+		server.WithCompletionToolsApproved(f.autoApprove),
+		server.WithAuthenticators(authenticators...),
+		server.WithWebhooks(webhooks),
+		server.WithSessionWebhookHosts(f.webhookHosts...))
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
 
 	return s.Serve(ctx, ln)
 }
+
+// authenticators returns the authenticators of API requests. Requests are not
+// authenticated if neither tokens nor JSON Web Tokens are configured.
+func (f *apiFlags) authenticators() ([]server.Authenticator, error) {
+	var authenticators []server.Authenticator
+
+	tokens := server.TokensFromEnv()
+	if f.authTokensFile != "" {
+		fileTokens, err := server.LoadTokensFile(f.authTokensFile)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, fileTokens...)
+	}
+	if len(tokens) > 0 {
+		tokenAuthenticator, err := server.NewTokenAuthenticator(tokens)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API tokens: %w", err)
+		}
+		authenticators = append(authenticators, tokenAuthenticator)
+	}
+
+	if f.authJWT.JWKSURL != "" || f.authJWT.Issuer != "" {
+		if f.authJWT.Audience == "" {
+			return nil, fmt.Errorf("--auth-jwks-url and --auth-issuer require --auth-audience")
+		}
+		jwtAuthenticator, err := server.NewJWTAuthenticator(f.authJWT)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	} else if f.authJWT.Audience != "" {
+		return nil, fmt.Errorf("--auth-audience requires --auth-jwks-url or --auth-issuer")
+	}
+
+	if len(authenticators) == 0 {
+		slog.Warn("The API is not authenticated: anyone who can reach it can run agents and read every session")
+	}
+	return authenticators, nil
+}
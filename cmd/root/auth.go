@@ -0,0 +1,142 @@
+package root
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/cagent/pkg/cli"
+	"github.com/docker/cagent/pkg/telemetry"
+	"github.com/docker/cagent/pkg/tools/mcp"
+)
+
+func newAuthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the credentials of remote MCP servers",
+		Long:  "Manage the OAuth tokens stored for remote MCP servers, in the OS keychain or in an encrypted file.",
+		Example: `  # List the servers cagent has credentials for
+  cagent auth list
+
+  # Forget the credentials of a server
+  cagent auth logout https://mcp.example.com/mcp`,
+		GroupID: "advanced",
+	}
+
+	cmd.AddCommand(newAuthListCmd())
+	cmd.AddCommand(newAuthLogoutCmd())
+
+	return cmd
+}
+
+func newAuthListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the servers with stored credentials",
+		Args:    cobra.NoArgs,
+		RunE:    runAuthListCommand,
+	}
+}
+
+func newAuthLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout <server>",
+		Short: "Remove the stored credentials of a server",
+		Long:  "Remove the stored credentials of a server, given by its URL or by its host name when it's unambiguous.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runAuthLogoutCommand,
+	}
+}
+
+func runAuthListCommand(cmd *cobra.Command, args []string) error {
+	telemetry.TrackCommand("auth", append([]string{"list"}, args...))
+
+	out := cli.NewPrinter(cmd.OutOrStdout())
+	store := mcp.NewPersistentTokenStore()
+
+	tokens, err := store.Tokens()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		out.Println("No stored credentials.")
+		return nil
+	}
+
+	servers := make([]string, 0, len(tokens))
+	for server := range tokens {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	out.Printf("Stored credentials (%s):\n\n", store.Location())
+	for _, server := range servers {
+		out.Printf("  %s (%s)\n", server, tokenStatus(tokens[server]))
+	}
+
+	return nil
+}
+
+func tokenStatus(token *mcp.OAuthToken) string {
+	switch {
+	case token.ExpiresAt.IsZero():
+		return "no expiry"
+	case !token.IsExpired():
+		return "expires " + token.ExpiresAt.Local().Format(time.DateTime)
+	case token.CanRefresh():
+		return "expired, will be refreshed"
+	default:
+		return "expired"
+	}
+}
+
+func runAuthLogoutCommand(cmd *cobra.Command, args []string) error {
+	telemetry.TrackCommand("auth", append([]string{"logout"}, args...))
+
+	out := cli.NewPrinter(cmd.OutOrStdout())
+	store := mcp.NewPersistentTokenStore()
+
+	tokens, err := store.Tokens()
+	if err != nil {
+		return err
+	}
+
+	server, err := findServer(tokens, args[0])
+	if err != nil {
+		return err
+	}
+	if err := store.RemoveToken(server); err != nil {
+		return err
+	}
+
+	out.Printf("Removed the credentials of %s\n", server)
+	return nil
+}
+
+// findServer finds the server matching a URL or a host name.
+func findServer(tokens map[string]*mcp.OAuthToken, name string) (string, error) {
+	if _, ok := tokens[name]; ok {
+		return name, nil
+	}
+
+	var matches []string
+	for server := range tokens {
+		if strings.Contains(server, "://"+name) {
+			matches = append(matches, server)
+		}
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no stored credentials for %s", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%s matches several servers: %s", name, strings.Join(matches, ", "))
+	}
+}
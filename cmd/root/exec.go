@@ -28,6 +28,7 @@ func newExecCmd() *cobra.Command {
 	addRuntimeConfigFlags(cmd, &flags.runConfig)
 	cmd.PersistentFlags().BoolVar(&flags.hideToolCalls, "hide-tool-calls", false, "Hide the tool calls in the output")
 	cmd.PersistentFlags().BoolVar(&flags.outputJSON, "json", false, "Output results in JSON format")
+	addWebhookFlags(cmd, &flags.webhooks)
 
 	return cmd
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/otel"
 
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/cli"
 	"github.com/docker/cagent/pkg/config"
 	"github.com/docker/cagent/pkg/paths"
@@ -37,6 +38,7 @@ type runExecFlags struct {
 	// Exec only
 	hideToolCalls bool
 	outputJSON    bool
+	webhooks      webhookFlags
 }
 
 func newRunCmd() *cobra.Command {
@@ -210,6 +212,7 @@ func (f *runExecFlags) createLocalRuntimeAndSession(ctx context.Context, t *team
 
 	localRt, err := runtime.New(t,
 		runtime.WithSessionStore(sessStore),
+		runtime.WithCheckpoints(checkpoint.NewStore(filepath.Join(paths.GetDataDir(), "checkpoints"))),
 		runtime.WithCurrentAgent(f.agentName),
 		runtime.WithTracer(otel.Tracer(AppName)),
 	)
@@ -238,12 +241,19 @@ func (f *runExecFlags) handleExecMode(ctx context.Context, out *cli.Printer, rt
 		execArgs = append(execArgs, "Follow the default instructions")
 	}
 
-	err := cli.Run(ctx, out, cli.Config{
+	webhooks, err := f.webhooks.dispatcher()
+	if err != nil {
+		return err
+	}
+	defer flushWebhooks(webhooks)
+
+	err = cli.Run(ctx, out, cli.Config{
 		AppName:        AppName,
 		AttachmentPath: f.attachmentPath,
 		HideToolCalls:  f.hideToolCalls,
 		OutputJSON:     f.outputJSON,
 		AutoApprove:    f.autoApprove,
+		Webhooks:       webhooks,
 	}, rt, sess, execArgs)
 	if cliErr, ok := err.(cli.RuntimeError); ok {
 		return RuntimeError{Err: cliErr.Err}
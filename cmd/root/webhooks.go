@@ -0,0 +1,62 @@
+package root
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/docker/cagent/pkg/paths"
+	"github.com/docker/cagent/pkg/webhook"
+)
+
+const (
+	envWebhookSecret = "CAGENT_WEBHOOK_SECRET"
+
+	// webhookFlushTimeout is how long pending webhook deliveries can take once the command is done.
+	webhookFlushTimeout = 30 * time.Second
+)
+
+type webhookFlags struct {
+	urls       []string
+	events     []string
+	secret     string
+	deadLetter string
+}
+
+func addWebhookFlags(cmd *cobra.Command, flags *webhookFlags) {
+	cmd.PersistentFlags().StringArrayVar(&flags.urls, "webhook", nil, "POST runtime events to this URL (repeatable)")
+	cmd.PersistentFlags().StringSliceVar(&flags.events, "webhook-event", nil, "Only POST these events to webhooks (default: all of them)")
+	cmd.PersistentFlags().StringVar(&flags.secret, "webhook-secret", "", "Sign webhook payloads with this secret (can also be set with $"+envWebhookSecret+")")
+	cmd.PersistentFlags().StringVar(&flags.deadLetter, "webhook-dead-letter", filepath.Join(paths.GetDataDir(), "webhooks-dead-letter.jsonl"), "Log the webhook payloads that couldn't be delivered to this file")
+}
+
+// dispatcher returns the dispatcher of the runtime events to the webhooks.
+func (f *webhookFlags) dispatcher() (*webhook.Dispatcher, error) {
+	secret := cmp.Or(f.secret, os.Getenv(envWebhookSecret))
+
+	var subscriptions []webhook.Subscription
+	for _, url := range f.urls {
+		subscriptions = append(subscriptions, webhook.Subscription{
+			URL:    url,
+			Secret: secret,
+			Events: f.events,
+		})
+	}
+
+	return webhook.New(subscriptions, webhook.WithDeadLetterLog(f.deadLetter))
+}
+
+// flushWebhooks waits for the pending webhook deliveries before the command exits.
+func flushWebhooks(dispatcher *webhook.Dispatcher) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookFlushTimeout)
+	defer cancel()
+
+	if err := dispatcher.Close(ctx); err != nil {
+		slog.Error("Some webhook deliveries were abandoned", "error", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/coder/acp-go-sdk"
 	"github.com/google/uuid"
@@ -306,6 +307,11 @@ func (a *Agent) runAgent(ctx context.Context, acpSess *Session) error {
 
 	eventsChan := acpSess.rt.RunStream(ctx, acpSess.sess)
 
+	// Tool call updates replace the content of the tool call, so the tail of the
+	// partial output is accumulated and sent at most every toolOutputInterval.
+	// The final update of the tool call holds its whole output anyway.
+	toolOutputs := map[string]*toolOutput{}
+
 	for event := range eventsChan {
 		if ctx.Err() != nil {
 			return ctx.Err()
@@ -342,7 +348,27 @@ func (a *Agent) runAgent(ctx context.Context, acpSess *Session) error {
 				return err
 			}
 
+		case *runtime.ToolCallOutputDeltaEvent:
+			output, ok := toolOutputs[e.ToolCallID]
+			if !ok {
+				output = &toolOutput{}
+				toolOutputs[e.ToolCallID] = output
+			}
+			output.text = tools.AppendOutputTail(output.text, e.Delta)
+			if time.Since(output.sentAt) < toolOutputInterval {
+				continue
+			}
+			output.sentAt = time.Now()
+
+			if err := a.conn.SessionUpdate(ctx, acp.SessionNotification{
+				SessionId: acp.SessionId(acpSess.id),
+				Update:    buildToolCallProgress(e.ToolCallID, output.text),
+			}); err != nil {
+				return err
+			}
+
 		case *runtime.ToolCallResponseEvent:
+			delete(toolOutputs, e.ToolCall.ID)
 			if err := a.conn.SessionUpdate(ctx, acp.SessionNotification{
 				SessionId: acp.SessionId(acpSess.id),
 				Update:    buildToolCallComplete(e.ToolCall, e.Response),
@@ -362,6 +388,18 @@ func (a *Agent) runAgent(ctx context.Context, acpSess *Session) error {
 				}
 			}
 
+		case *runtime.ModelFallbackEvent:
+			// Sent text can't be taken back: tell the user the response starts over.
+			if !e.Discard {
+				continue
+			}
+			if err := a.conn.SessionUpdate(ctx, acp.SessionNotification{
+				SessionId: acp.SessionId(acpSess.id),
+				Update:    acp.UpdateAgentMessageText(fmt.Sprintf("\n\n(%s was interrupted, restarting the response)\n\n", e.FailedModel)),
+			}); err != nil {
+				return err
+			}
+
 		case *runtime.ErrorEvent:
 			if err := a.conn.SessionUpdate(ctx, acp.SessionNotification{
 				SessionId: acp.SessionId(acpSess.id),
@@ -611,6 +649,24 @@ func buildToolCallComplete(toolCall tools.ToolCall, output string) acp.SessionUp
 	)
 }
 
+// toolOutputInterval is the minimum delay between two updates of the partial output of a tool call.
+const toolOutputInterval = 200 * time.Millisecond
+
+// toolOutput is the partial output of a running tool call.
+type toolOutput struct {
+	text   string
+	sentAt time.Time
+}
+
+// buildToolCallProgress creates a tool call update with the output produced so far
+func buildToolCallProgress(toolCallID, output string) acp.SessionUpdate {
+	return acp.UpdateToolCall(
+		acp.ToolCallId(toolCallID),
+		acp.WithUpdateStatus(acp.ToolCallStatusInProgress),
+		acp.WithUpdateContent([]acp.ToolCallContent{acp.ToolContent(acp.TextBlock(output))}),
+	)
+}
+
 // isFileEditTool returns true if the tool is a file editing operation
 func isFileEditTool(toolName string) bool {
 	return slices.Contains([]string{"edit_file", "write_file"}, toolName)
@@ -6,7 +6,9 @@ import (
 	"log/slog"
 	"math/rand"
 
+	"github.com/docker/cagent/pkg/hooks"
 	"github.com/docker/cagent/pkg/model/provider"
+	"github.com/docker/cagent/pkg/permissions"
 	"github.com/docker/cagent/pkg/tools"
 )
 
@@ -18,6 +20,8 @@ type Agent struct {
 	instruction        string
 	toolsets           []*StartableToolSet
 	models             []provider.Provider
+	fallbackModels     []provider.Provider
+	modelRetries       int
 	subAgents          []*Agent
 	handoffs           []*Agent
 	parents            []*Agent
@@ -25,6 +29,10 @@ type Agent struct {
 	addEnvironmentInfo bool
 	maxIterations      int
 	numHistoryItems    int
+	toolConcurrency    int
+	concurrentTools    []string
+	permissions        *permissions.Checker
+	hooks              *hooks.Runner
 	addPromptFiles     []string
 	tools              []tools.Tool
 	commands           map[string]string
@@ -71,6 +79,28 @@ func (a *Agent) NumHistoryItems() int {
 	return a.numHistoryItems
 }
 
+// ToolConcurrency returns the maximum number of tool calls that can run in
+// parallel within a single model turn. Values below 2 mean sequential execution.
+func (a *Agent) ToolConcurrency() int {
+	return a.toolConcurrency
+}
+
+// ConcurrentTools returns the names of tools, in addition to read-only ones,
+// that are declared safe to run in parallel.
+func (a *Agent) ConcurrentTools() []string {
+	return a.concurrentTools
+}
+
+// Permissions returns the agent's tool permission policy, or nil if it has none.
+func (a *Agent) Permissions() *permissions.Checker {
+	return a.permissions
+}
+
+// Hooks returns the commands run at the different points of the agent's lifecycle, or nil if it has none.
+func (a *Agent) Hooks() *hooks.Runner {
+	return a.hooks
+}
+
 func (a *Agent) AddPromptFiles() []string {
 	return a.addPromptFiles
 }
@@ -110,6 +140,17 @@ func (a *Agent) Model() provider.Provider {
 	return a.models[rand.Intn(len(a.models))]
 }
 
+// FallbackModels returns the ordered list of models to try when the agent's model fails.
+func (a *Agent) FallbackModels() []provider.Provider {
+	return a.fallbackModels
+}
+
+// ModelRetries returns how many times a model is retried on retryable errors
+// before moving to the next fallback model.
+func (a *Agent) ModelRetries() int {
+	return a.modelRetries
+}
+
 // Commands returns the named commands configured for this agent.
 func (a *Agent) Commands() map[string]string {
 	return a.commands
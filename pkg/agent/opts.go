@@ -3,7 +3,9 @@ package agent
 import (
 	"sync/atomic"
 
+	"github.com/docker/cagent/pkg/hooks"
 	"github.com/docker/cagent/pkg/model/provider"
+	"github.com/docker/cagent/pkg/permissions"
 	"github.com/docker/cagent/pkg/tools"
 )
 
@@ -58,6 +60,18 @@ func WithModel(model provider.Provider) Opt {
 	}
 }
 
+func WithFallbackModel(model provider.Provider) Opt {
+	return func(a *Agent) {
+		a.fallbackModels = append(a.fallbackModels, model)
+	}
+}
+
+func WithModelRetries(retries int) Opt {
+	return func(a *Agent) {
+		a.modelRetries = retries
+	}
+}
+
 func WithSubAgents(subAgents ...*Agent) Opt {
 	return func(a *Agent) {
 		a.subAgents = subAgents
@@ -103,6 +117,30 @@ func WithNumHistoryItems(numHistoryItems int) Opt {
 	}
 }
 
+func WithToolConcurrency(toolConcurrency int) Opt {
+	return func(a *Agent) {
+		a.toolConcurrency = toolConcurrency
+	}
+}
+
+func WithConcurrentTools(concurrentTools []string) Opt {
+	return func(a *Agent) {
+		a.concurrentTools = concurrentTools
+	}
+}
+
+func WithPermissions(checker *permissions.Checker) Opt {
+	return func(a *Agent) {
+		a.permissions = checker
+	}
+}
+
+func WithHooks(runner *hooks.Runner) Opt {
+	return func(a *Agent) {
+		a.hooks = runner
+	}
+}
+
 func WithCommands(commands map[string]string) Opt {
 	return func(a *Agent) {
 		a.commands = commands
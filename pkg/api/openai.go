@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/docker/cagent/pkg/chat"
+)
+
+// The types below implement the subset of the OpenAI chat completions API
+// (https://platform.openai.com/docs/api-reference/chat) served by `cagent api`.
+
+// ChatCompletionRequest represents a request to /v1/chat/completions
+type ChatCompletionRequest struct {
+	Model         string                       `json:"model"`
+	Messages      []ChatCompletionMessage      `json:"messages"`
+	Stream        bool                         `json:"stream,omitempty"`
+	StreamOptions *ChatCompletionStreamOptions `json:"stream_options,omitempty"`
+}
+
+// ChatCompletionStreamOptions represents the options of a streamed chat completion
+type ChatCompletionStreamOptions struct {
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+// ChatCompletionMessage represents a message of a chat completion.
+// Its content is either a string or an array of text and image parts.
+type ChatCompletionMessage struct {
+	Role         string             `json:"role"`
+	Content      string             `json:"content"`
+	MultiContent []chat.MessagePart `json:"-"`
+}
+
+func (m *ChatCompletionMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	m.Role = raw.Role
+	m.Content = ""
+	m.MultiContent = nil
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Content, &m.Content); err == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(raw.Content, &m.MultiContent); err != nil {
+		return errors.New("message content must be a string or an array of content parts")
+	}
+	var text []string
+	for _, part := range m.MultiContent {
+		if part.Type == chat.MessagePartTypeText {
+			text = append(text, part.Text)
+		}
+	}
+	m.Content = strings.Join(text, "\n")
+	return nil
+}
+
+// ChatCompletionResponse represents the response of a non-streamed chat completion
+type ChatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []ChatCompletionChoice `json:"choices"`
+	Usage   *ChatCompletionUsage   `json:"usage,omitempty"`
+}
+
+// ChatCompletionChoice represents a choice of a non-streamed chat completion
+type ChatCompletionChoice struct {
+	Index        int                   `json:"index"`
+	Message      ChatCompletionMessage `json:"message"`
+	FinishReason string                `json:"finish_reason"`
+}
+
+// ChatCompletionChunk represents a chunk of a streamed chat completion
+type ChatCompletionChunk struct {
+	ID      string                      `json:"id"`
+	Object  string                      `json:"object"`
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model"`
+	Choices []ChatCompletionChunkChoice `json:"choices"`
+	Usage   *ChatCompletionUsage        `json:"usage,omitempty"`
+}
+
+// ChatCompletionChunkChoice represents a choice of a streamed chat completion chunk
+type ChatCompletionChunkChoice struct {
+	Index        int                 `json:"index"`
+	Delta        ChatCompletionDelta `json:"delta"`
+	FinishReason *string             `json:"finish_reason"`
+}
+
+// ChatCompletionDelta represents the content added by a streamed chat completion chunk
+type ChatCompletionDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// ChatCompletionUsage represents the tokens used by a chat completion
+type ChatCompletionUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// Model represents an agent listed by /v1/models
+type Model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// ModelsResponse represents the response of /v1/models
+type ModelsResponse struct {
+	Object string  `json:"object"`
+	Data   []Model `json:"data"`
+}
+
+// OpenAIErrorResponse represents an error returned by the OpenAI-compatible endpoints
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
+
+// OpenAIError describes an error returned by the OpenAI-compatible endpoints
+type OpenAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
@@ -115,13 +115,16 @@ type DeleteAgentResponse struct {
 
 // SessionsResponse represents a session in the sessions list
 type SessionsResponse struct {
-	ID           string `json:"id"`
-	Title        string `json:"title"`
-	CreatedAt    string `json:"created_at"`
-	NumMessages  int    `json:"num_messages"`
-	InputTokens  int64  `json:"input_tokens"`
-	OutputTokens int64  `json:"output_tokens"`
-	WorkingDir   string `json:"working_dir,omitempty"`
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	CreatedAt    string        `json:"created_at"`
+	NumMessages  int           `json:"num_messages"`
+	InputTokens  int64         `json:"input_tokens"`
+	OutputTokens int64         `json:"output_tokens"`
+	WorkingDir   string        `json:"working_dir,omitempty"`
+	ParentID     string        `json:"parent_id,omitempty"`
+	ForkPoint    int           `json:"fork_point,omitempty"`
+	Status       SessionStatus `json:"status"`
 }
 
 // SessionResponse represents a detailed session
@@ -134,6 +137,53 @@ type SessionResponse struct {
 	InputTokens   int64             `json:"input_tokens"`
 	OutputTokens  int64             `json:"output_tokens"`
 	WorkingDir    string            `json:"working_dir,omitempty"`
+	ParentID      string            `json:"parent_id,omitempty"`
+	ForkPoint     int               `json:"fork_point,omitempty"`
+	Status        SessionStatus     `json:"status"`
+}
+
+// SessionStatus tells what the agent loop of a session is doing
+type SessionStatus string
+
+const (
+	// SessionStatusIdle means that the agent isn't running
+	SessionStatusIdle SessionStatus = "idle"
+	// SessionStatusRunning means that the agent is running
+	SessionStatusRunning SessionStatus = "running"
+	// SessionStatusWaitingForConfirmation means that the agent waits for a tool call, or more iterations, to be confirmed
+	SessionStatusWaitingForConfirmation SessionStatus = "waiting_for_confirmation"
+	// SessionStatusWaitingForElicitation means that the agent waits for an answer to an elicitation request
+	SessionStatusWaitingForElicitation SessionStatus = "waiting_for_elicitation"
+)
+
+// ForkSessionRequest represents a request to fork a session
+type ForkSessionRequest struct {
+	// MessageIndex is the index of the first message not copied to the new session.
+	// When omitted, all the messages are copied.
+	MessageIndex *int `json:"message_index,omitempty"`
+}
+
+// RewindSessionRequest represents a request to rewind a session to one of its user messages
+type RewindSessionRequest struct {
+	MessageIndex int `json:"message_index"`
+}
+
+// RewindSessionResponse represents the response from rewinding a session
+type RewindSessionResponse struct {
+	// Content is the content of the user message that was removed, so that it can be sent again
+	Content string `json:"content"`
+}
+
+// RestoreFilesRequest represents a request to restore the files modified by a session
+type RestoreFilesRequest struct {
+	// MessageIndex is the index of the first message whose file changes are reverted
+	MessageIndex int `json:"message_index"`
+}
+
+// RestoreFilesResponse represents the response from restoring files
+type RestoreFilesResponse struct {
+	MessageIndex int      `json:"message_index"`
+	Files        []string `json:"files"`
 }
 
 // ResumeSessionRequest represents a request to resume a session
@@ -10,6 +10,7 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/runtime"
 	"github.com/docker/cagent/pkg/session"
 	"github.com/docker/cagent/pkg/tools"
@@ -109,6 +110,23 @@ func (a *App) ExecuteMCPPrompt(ctx context.Context, promptName string, arguments
 	return "", fmt.Errorf("MCP prompt '%s' not found in any active toolset", promptName)
 }
 
+// CurrentMCPResources returns the resources exposed by the MCP servers of the active agent
+func (a *App) CurrentMCPResources(ctx context.Context) []mcptools.ResourceInfo {
+	if localRuntime, ok := a.runtime.(*runtime.LocalRuntime); ok {
+		return localRuntime.CurrentMCPResources(ctx)
+	}
+	return nil
+}
+
+// ReadMCPResource reads an MCP resource referenced as server:uri and returns its content
+func (a *App) ReadMCPResource(ctx context.Context, ref string) (string, error) {
+	localRuntime, ok := a.runtime.(*runtime.LocalRuntime)
+	if !ok {
+		return "", fmt.Errorf("MCP resources are only supported with local runtime")
+	}
+	return localRuntime.ReadMCPResource(ctx, ref)
+}
+
 // ResolveCommand converts /command to its prompt text
 func (a *App) ResolveCommand(ctx context.Context, userInput string) string {
 	return runtime.ResolveCommand(ctx, a.runtime, userInput)
@@ -181,11 +199,92 @@ func (a *App) ResumeElicitation(ctx context.Context, action tools.ElicitationAct
 }
 
 func (a *App) NewSession() {
+	a.stop()
+	a.session = session.New()
+}
+
+// ForkSession switches to a new session forked right before the n-th user message
+// of the current session (the last one when n is 0) and returns the content of
+// that message so that it can be edited and sent again. The current session is left untouched.
+func (a *App) ForkSession(ctx context.Context, n int) (string, error) {
+	brancher, ok := a.runtime.(runtime.SessionBrancher)
+	if !ok {
+		return "", fmt.Errorf("forking sessions is not supported by this runtime")
+	}
+
+	index, err := a.session.UserMessageIndex(n)
+	if err != nil {
+		return "", err
+	}
+	content := a.session.GetAllMessages()[index].Message.Content
+
+	fork, err := brancher.ForkSession(ctx, a.session, index)
+	if err != nil {
+		return "", err
+	}
+
+	a.stop()
+	a.session = fork
+	return content, nil
+}
+
+// RewindSession removes the n-th user message of the current session (the last one
+// when n is 0) and everything after it, and returns the content of that message.
+func (a *App) RewindSession(ctx context.Context, n int) (string, error) {
+	brancher, ok := a.runtime.(runtime.SessionBrancher)
+	if !ok {
+		return "", fmt.Errorf("rewinding sessions is not supported by this runtime")
+	}
+
+	index, err := a.session.UserMessageIndex(n)
+	if err != nil {
+		return "", err
+	}
+
+	a.stop()
+	return brancher.RewindSession(ctx, a.session, index)
+}
+
+// UndoFileChanges reverts the files modified during the last turn of the current
+// session that changed files. It returns the paths of the restored files.
+func (a *App) UndoFileChanges() ([]string, error) {
+	store, err := a.checkpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	_, files, err := store.Undo(a.session)
+	return files, err
+}
+
+// RestoreFiles reverts the files modified since the n-th user message of the
+// current session (the last one when n is 0). It returns the paths of the restored files.
+func (a *App) RestoreFiles(n int) ([]string, error) {
+	store, err := a.checkpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := a.session.UserMessageIndex(n)
+	if err != nil {
+		return nil, err
+	}
+	return store.Restore(a.session.ID, index)
+}
+
+func (a *App) checkpoints() (*checkpoint.Store, error) {
+	localRt, ok := a.runtime.(*runtime.LocalRuntime)
+	if !ok || localRt.Checkpoints() == nil {
+		return nil, fmt.Errorf("file checkpoints are not enabled")
+	}
+	return localRt.Checkpoints(), nil
+}
+
+func (a *App) stop() {
 	if a.cancel != nil {
 		a.cancel()
 		a.cancel = nil
 	}
-	a.session = session.New()
 }
 
 func (a *App) Session() *session.Session {
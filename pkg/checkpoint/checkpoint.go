@@ -0,0 +1,305 @@
+// Package checkpoint snapshots files before agents modify them so that their
+// edits can be undone, even outside of a git repository.
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/session"
+)
+
+// ErrNoCheckpoint is returned when there are no file changes to undo.
+var ErrNoCheckpoint = errors.New("no file changes to undo")
+
+// File is the state of a file before it was first modified for a message.
+type File struct {
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	Mode    fs.FileMode `json:"mode,omitempty"`
+	// Blob is the name of the file holding the original content
+	Blob string `json:"blob,omitempty"`
+}
+
+// Checkpoint groups the snapshots of the files modified because of a message of a session.
+type Checkpoint struct {
+	// MessageIndex is the index of the message, as numbered by session.GetAllMessages
+	MessageIndex int       `json:"message_index"`
+	CreatedAt    time.Time `json:"created_at"`
+	Files        []File    `json:"files"`
+}
+
+// Store keeps the checkpoints of each session on disk, one directory per session.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a checkpoint store that keeps its data in dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Snapshot records the current content of path, or the fact that it doesn't exist,
+// before it gets modified because of the message at messageIndex.
+// Only the first snapshot of a file for a given message is kept.
+func (s *Store) Snapshot(sessionID string, messageIndex int, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoints, err := s.load(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if n := len(checkpoints); n == 0 || checkpoints[n-1].MessageIndex != messageIndex {
+		checkpoints = append(checkpoints, Checkpoint{
+			MessageIndex: messageIndex,
+			CreatedAt:    time.Now(),
+		})
+	}
+	last := &checkpoints[len(checkpoints)-1]
+	if slices.ContainsFunc(last.Files, func(f File) bool { return f.Path == absPath }) {
+		return nil
+	}
+
+	file := File{Path: absPath}
+	info, err := os.Stat(absPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	case info.IsDir():
+		return fmt.Errorf("%s is a directory", absPath)
+	default:
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return err
+		}
+
+		file.Existed = true
+		file.Mode = info.Mode().Perm()
+		file.Blob = strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.Itoa(len(last.Files))
+		if err := os.WriteFile(filepath.Join(s.sessionDir(sessionID), file.Blob), content, 0o600); err != nil {
+			return err
+		}
+	}
+
+	last.Files = append(last.Files, file)
+	return s.save(sessionID, checkpoints)
+}
+
+// List returns the checkpoints of a session, oldest first.
+func (s *Store) List(sessionID string) ([]Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(sessionID)
+}
+
+// Restore puts back the files modified because of the message at messageIndex,
+// or any later message, in the state they were before. The matching checkpoints
+// are removed. It returns the paths of the restored files.
+func (s *Store) Restore(sessionID string, messageIndex int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoints, err := s.load(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var restored []string
+	var kept []Checkpoint
+	var removed []File
+	// Undo the most recent changes first so that each file ends up in its oldest recorded state.
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		checkpoint := checkpoints[i]
+		if checkpoint.MessageIndex < messageIndex {
+			kept = append(kept, checkpoint)
+			continue
+		}
+
+		for _, file := range checkpoint.Files {
+			if err := s.restoreFile(sessionID, file); err != nil {
+				return nil, fmt.Errorf("restoring %s: %w", file.Path, err)
+			}
+			removed = append(removed, file)
+			if !slices.Contains(restored, file.Path) {
+				restored = append(restored, file.Path)
+			}
+		}
+	}
+	slices.Reverse(kept)
+
+	// Only delete the snapshots once the index doesn't reference them anymore,
+	// so that a failure never leaves checkpoints pointing at missing blobs.
+	if err := s.save(sessionID, kept); err != nil {
+		return nil, err
+	}
+	for _, file := range removed {
+		s.removeBlob(sessionID, file)
+	}
+
+	slices.Sort(restored)
+	slog.Debug("Restored files", "session_id", sessionID, "message_index", messageIndex, "files", len(restored))
+	return restored, nil
+}
+
+// Undo reverts the file changes of the last turn of the session that modified files.
+// A turn starts with a user message. It returns the index of that user message and
+// the paths of the restored files.
+func (s *Store) Undo(sess *session.Session) (int, []string, error) {
+	checkpoints, err := s.List(sess.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(checkpoints) == 0 {
+		return 0, nil, ErrNoCheckpoint
+	}
+
+	last := checkpoints[len(checkpoints)-1].MessageIndex
+	turn := 0
+	for i, msg := range sess.GetAllMessages() {
+		if i > last {
+			break
+		}
+		if msg.Message.Role == chat.MessageRoleUser && !msg.Implicit {
+			turn = i
+		}
+	}
+
+	restored, err := s.Restore(sess.ID, turn)
+	return turn, restored, err
+}
+
+// Delete removes the checkpoints of a session, and the snapshots of its files.
+func (s *Store) Delete(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name := filepath.Base(sessionID); name == "." || name == ".." || name == string(filepath.Separator) {
+		return fmt.Errorf("invalid session id %q", sessionID)
+	}
+	return os.RemoveAll(s.sessionDir(sessionID))
+}
+
+func (s *Store) restoreFile(sessionID string, file File) error {
+	if !file.Existed {
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.sessionDir(sessionID), file.Blob))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file.Path, content, file.Mode)
+}
+
+func (s *Store) removeBlob(sessionID string, file File) {
+	if file.Blob != "" {
+		_ = os.Remove(filepath.Join(s.sessionDir(sessionID), file.Blob))
+	}
+}
+
+func (s *Store) sessionDir(sessionID string) string {
+	return filepath.Join(s.dir, filepath.Base(sessionID))
+}
+
+func (s *Store) load(sessionID string) ([]Checkpoint, error) {
+	buf, err := os.ReadFile(filepath.Join(s.sessionDir(sessionID), "checkpoints.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, os.MkdirAll(s.sessionDir(sessionID), 0o700)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []Checkpoint
+	if err := json.Unmarshal(buf, &checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+func (s *Store) save(sessionID string, checkpoints []Checkpoint) error {
+	buf, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so that a crash never leaves a half-written index.
+	tmp, err := os.CreateTemp(s.sessionDir(sessionID), ".checkpoints-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.sessionDir(sessionID), "checkpoints.json"))
+}
+
+type recorderKey struct{}
+
+// Recorder snapshots files for a given message of a session.
+type Recorder struct {
+	store        *Store
+	sessionID    string
+	messageIndex int
+}
+
+// NewRecorder returns a recorder for the message at messageIndex of a session.
+func (s *Store) NewRecorder(sessionID string, messageIndex int) *Recorder {
+	return &Recorder{
+		store:        s,
+		sessionID:    sessionID,
+		messageIndex: messageIndex,
+	}
+}
+
+// Snapshot records the current state of path. It's a no-op on a nil recorder.
+func (r *Recorder) Snapshot(path string) error {
+	if r == nil {
+		return nil
+	}
+	return r.store.Snapshot(r.sessionID, r.messageIndex, path)
+}
+
+// WithRecorder returns a context that carries a recorder for the tools to use.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// RecorderFromContext returns the recorder carried by the context, or nil.
+func RecorderFromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
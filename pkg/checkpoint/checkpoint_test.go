@@ -0,0 +1,144 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/agent"
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/session"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func TestRestore(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(t.TempDir())
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	created := filepath.Join(dir, "created.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("v1"), 0o640))
+
+	// Message 0 modifies the existing file, twice.
+	require.NoError(t, store.Snapshot("sess", 0, existing))
+	require.NoError(t, os.WriteFile(existing, []byte("v2"), 0o640))
+	require.NoError(t, store.Snapshot("sess", 0, existing))
+	require.NoError(t, os.WriteFile(existing, []byte("v3"), 0o640))
+
+	// Message 2 modifies it again and creates a new file.
+	require.NoError(t, store.Snapshot("sess", 2, existing))
+	require.NoError(t, os.WriteFile(existing, []byte("v4"), 0o640))
+	require.NoError(t, store.Snapshot("sess", 2, created))
+	require.NoError(t, os.WriteFile(created, []byte("new"), 0o600))
+
+	checkpoints, err := store.List("sess")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Len(t, checkpoints[0].Files, 1, "only the first snapshot of a file per message is kept")
+	assert.Len(t, checkpoints[1].Files, 2)
+
+	restored, err := store.Restore("sess", 2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{existing, created}, restored)
+	assert.Equal(t, "v3", readFile(t, existing))
+	assert.NoFileExists(t, created)
+
+	restored, err = store.Restore("sess", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{existing}, restored)
+	assert.Equal(t, "v1", readFile(t, existing))
+
+	info, err := os.Stat(existing)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	checkpoints, err = store.List("sess")
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+
+	entries, err := os.ReadDir(store.sessionDir("sess"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "the snapshots and temporary files are removed")
+	assert.Equal(t, "checkpoints.json", entries[0].Name())
+}
+
+func TestUndo(t *testing.T) {
+	t.Parallel()
+
+	a := agent.New("root", "instructions")
+	sess := session.New()
+	sess.AddMessage(session.UserMessage("first"))
+	sess.AddMessage(session.NewAgentMessage(a, &chat.Message{Role: chat.MessageRoleAssistant, Content: "calling write_file"}))
+	sess.AddMessage(session.UserMessage("second"))
+	sess.AddMessage(session.NewAgentMessage(a, &chat.Message{Role: chat.MessageRoleAssistant, Content: "calling write_file"}))
+	sess.AddMessage(session.NewAgentMessage(a, &chat.Message{Role: chat.MessageRoleAssistant, Content: "calling edit_file"}))
+
+	store := NewStore(t.TempDir())
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("v1"), 0o600))
+
+	require.NoError(t, store.Snapshot(sess.ID, 1, file))
+	require.NoError(t, os.WriteFile(file, []byte("v2"), 0o600))
+	require.NoError(t, store.Snapshot(sess.ID, 3, file))
+	require.NoError(t, os.WriteFile(file, []byte("v3"), 0o600))
+	require.NoError(t, store.Snapshot(sess.ID, 4, file))
+	require.NoError(t, os.WriteFile(file, []byte("v4"), 0o600))
+
+	turn, restored, err := store.Undo(sess)
+	require.NoError(t, err)
+	assert.Equal(t, 2, turn)
+	assert.Equal(t, []string{file}, restored)
+	assert.Equal(t, "v2", readFile(t, file), "all the changes of the last turn should be undone")
+
+	turn, _, err = store.Undo(sess)
+	require.NoError(t, err)
+	assert.Equal(t, 0, turn)
+	assert.Equal(t, "v1", readFile(t, file))
+
+	_, _, err = store.Undo(sess)
+	require.ErrorIs(t, err, ErrNoCheckpoint)
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := NewStore(dir)
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("v1"), 0o600))
+	require.NoError(t, store.Snapshot("sess", 1, file))
+	require.NoError(t, store.Snapshot("other", 1, file))
+
+	require.NoError(t, store.Delete("sess"))
+	assert.NoDirExists(t, filepath.Join(dir, "sess"))
+	checkpoints, err := store.List("sess")
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+
+	checkpoints, err = store.List("other")
+	require.NoError(t, err)
+	assert.Len(t, checkpoints, 1)
+
+	require.Error(t, store.Delete(""))
+	assert.DirExists(t, filepath.Join(dir, "other"))
+}
+
+func TestRecorderFromContext(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, RecorderFromContext(t.Context()))
+	require.NoError(t, RecorderFromContext(t.Context()).Snapshot("missing"), "a nil recorder should be a no-op")
+
+	recorder := NewStore(t.TempDir()).NewRecorder("sess", 1)
+	assert.Same(t, recorder, RecorderFromContext(WithRecorder(t.Context(), recorder)))
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/docker/cagent/pkg/runtime"
 	"github.com/docker/cagent/pkg/session"
 	"github.com/docker/cagent/pkg/telemetry"
+	"github.com/docker/cagent/pkg/webhook"
 )
 
 // RuntimeError wraps runtime errors to distinguish them from usage errors
@@ -39,6 +40,8 @@ type Config struct {
 	AutoApprove    bool
 	HideToolCalls  bool
 	OutputJSON     bool
+	// Webhooks, if not nil, is notified of the runtime events.
+	Webhooks *webhook.Dispatcher
 }
 
 // Run executes an agent in non-TUI mode, handling user input and runtime events
@@ -76,6 +79,7 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 
 		if cfg.OutputJSON {
 			for event := range rt.RunStream(ctx, sess) {
+				cfg.Webhooks.Notify(sess.ID, event)
 				switch e := event.(type) {
 				case *runtime.ToolCallConfirmationEvent:
 					if !cfg.AutoApprove {
@@ -99,6 +103,7 @@ func Run(ctx context.Context, out *Printer, cfg Config, rt runtime.Runtime, sess
 		lastAgent := rt.CurrentAgentName()
 		var lastConfirmedToolCallID string
 		for event := range rt.RunStream(ctx, sess) {
+			cfg.Webhooks.Notify(sess.ID, event)
 			agentName := event.GetAgentName()
 			if agentName != "" && (firstLoop || lastAgent != agentName) {
 				if !firstLoop {
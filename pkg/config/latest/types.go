@@ -2,6 +2,7 @@ package latest
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/goccy/go-yaml"
@@ -22,23 +23,108 @@ type Config struct {
 
 // AgentConfig represents a single agent configuration
 type AgentConfig struct {
-	Model              string            `json:"model,omitempty"`
-	Description        string            `json:"description,omitempty"`
-	WelcomeMessage     string            `json:"welcome_message,omitempty"`
-	Toolsets           []Toolset         `json:"toolsets,omitempty"`
-	Instruction        string            `json:"instruction,omitempty"`
-	SubAgents          []string          `json:"sub_agents,omitempty"`
-	Handoffs           []string          `json:"handoffs,omitempty"`
-	RAG                []string          `json:"rag,omitempty"`
-	AddDate            bool              `json:"add_date,omitempty"`
-	AddEnvironmentInfo bool              `json:"add_environment_info,omitempty"`
-	CodeModeTools      bool              `json:"code_mode_tools,omitempty"`
-	MaxIterations      int               `json:"max_iterations,omitempty"`
-	NumHistoryItems    int               `json:"num_history_items,omitempty"`
-	AddPromptFiles     []string          `json:"add_prompt_files,omitempty" yaml:"add_prompt_files,omitempty"`
-	Commands           types.Commands    `json:"commands,omitempty"`
-	StructuredOutput   *StructuredOutput `json:"structured_output,omitempty"`
-	Skills             *bool             `json:"skills,omitempty"`
+	Model              string             `json:"model,omitempty"`
+	Description        string             `json:"description,omitempty"`
+	WelcomeMessage     string             `json:"welcome_message,omitempty"`
+	Toolsets           []Toolset          `json:"toolsets,omitempty"`
+	Instruction        string             `json:"instruction,omitempty"`
+	SubAgents          []string           `json:"sub_agents,omitempty"`
+	Handoffs           []string           `json:"handoffs,omitempty"`
+	RAG                []string           `json:"rag,omitempty"`
+	AddDate            bool               `json:"add_date,omitempty"`
+	AddEnvironmentInfo bool               `json:"add_environment_info,omitempty"`
+	CodeModeTools      bool               `json:"code_mode_tools,omitempty"`
+	MaxIterations      int                `json:"max_iterations,omitempty"`
+	NumHistoryItems    int                `json:"num_history_items,omitempty"`
+	ToolConcurrency    int                `json:"tool_concurrency,omitempty"`
+	ConcurrentTools    []string           `json:"concurrent_tools,omitempty"`
+	AddPromptFiles     []string           `json:"add_prompt_files,omitempty" yaml:"add_prompt_files,omitempty"`
+	Commands           types.Commands     `json:"commands,omitempty"`
+	StructuredOutput   *StructuredOutput  `json:"structured_output,omitempty"`
+	Skills             *bool              `json:"skills,omitempty"`
+	Permissions        *PermissionsConfig `json:"permissions,omitempty"`
+	Fallback           *FallbackConfig    `json:"fallback,omitempty"`
+	Hooks              *HooksConfig       `json:"hooks,omitempty"`
+}
+
+// HooksConfig declares commands that run at given points of an agent's lifecycle.
+// Hooks receive a JSON description of the event on stdin. See pkg/hooks for the protocol.
+type HooksConfig struct {
+	SessionStart     []HookConfig `json:"session_start,omitempty"`
+	UserPromptSubmit []HookConfig `json:"user_prompt_submit,omitempty"`
+	PreToolUse       []HookConfig `json:"pre_tool_use,omitempty"`
+	PostToolUse      []HookConfig `json:"post_tool_use,omitempty"`
+	Stop             []HookConfig `json:"stop,omitempty"`
+}
+
+// HookConfig is a command run by a hook.
+type HookConfig struct {
+	// Matcher is a tool name pattern, where `*` matches any sequence of characters
+	// and `?` a single character. Only used by tool hooks. Defaults to all tools.
+	Matcher string `json:"matcher,omitempty"`
+	Command string `json:"command"`
+	// Timeout in seconds. Defaults to 60.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// FallbackConfig configures what happens when a request to the agent's model fails.
+// Retryable errors (rate limits, server errors, streams cut mid-response) are
+// retried with exponential backoff, then the next model in Models is tried.
+type FallbackConfig struct {
+	// Models is the ordered list of models to try after the agent's model fails.
+	Models []string `json:"models,omitempty"`
+	// Retries is the number of times each model is retried on retryable errors
+	// before moving to the next one. Defaults to 2.
+	Retries *int `json:"retries,omitempty"`
+}
+
+// GetRetries returns the number of retries per model, defaulting to 2
+func (f *FallbackConfig) GetRetries() int {
+	if f == nil || f.Retries == nil {
+		return 2
+	}
+	return *f.Retries
+}
+
+// PermissionsConfig declares which tool calls run without confirmation, which
+// always require confirmation and which are refused.
+// Deny rules take precedence over ask rules, which take precedence over allow rules.
+// Tool calls matching no rule go through the regular approval flow.
+type PermissionsConfig struct {
+	Allow []PermissionRule `json:"allow,omitempty"`
+	Ask   []PermissionRule `json:"ask,omitempty"`
+	Deny  []PermissionRule `json:"deny,omitempty"`
+}
+
+// PermissionRule matches tool calls by tool name and, optionally, by the value
+// of some of their arguments. Both are glob patterns where `*` matches any
+// sequence of characters and `?` matches a single character.
+// It can be written either as a plain tool name or as an object:
+//
+//	allow:
+//	  - read_file
+//	  - tool: shell
+//	    args:
+//	      cmd: "git status*"
+type PermissionRule struct {
+	Tool string            `json:"tool"`
+	Args map[string]string `json:"args,omitempty"`
+}
+
+func (r *PermissionRule) UnmarshalYAML(unmarshal func(any) error) error {
+	var tool string
+	if err := unmarshal(&tool); err == nil {
+		*r = PermissionRule{Tool: tool}
+		return nil
+	}
+
+	type alias PermissionRule
+	var tmp alias
+	if err := unmarshal(&tmp); err != nil {
+		return err
+	}
+	*r = PermissionRule(tmp)
+	return nil
 }
 
 // ModelConfig represents the configuration for a model
@@ -112,6 +198,20 @@ type APIToolConfig struct {
 	OutputSchema map[string]any `json:"output_schema,omitempty"`
 }
 
+// OpenAPIToolConfig describes an OpenAPI 3 document from which one tool per operation is generated
+type OpenAPIToolConfig struct {
+	// Spec is the path, relative to the agent's configuration file, or the http(s) URL of the document.
+	Spec string `json:"spec,omitempty"`
+	// BaseURL overrides the URL of the first server of the document.
+	BaseURL string `json:"base_url,omitempty"`
+	// Headers are sent with the requests to the base URL, which must be set with them.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Include only keeps the operations with one of these operationIds or tags.
+	Include []string `json:"include,omitempty"`
+	// Exclude removes the operations with one of these operationIds or tags.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
 // PostEditConfig represents a post-edit command configuration
 type PostEditConfig struct {
 	Path string `json:"path"`
@@ -133,6 +233,10 @@ type Toolset struct {
 	Ref     string   `json:"ref,omitempty"`
 	Remote  Remote   `json:"remote,omitempty"`
 	Config  any      `json:"config,omitempty"`
+	// ResourceTools adds tools to list and read the resources of the MCP server.
+	ResourceTools bool `json:"resource_tools,omitempty"`
+	// Sampling limits the completions the MCP server can request through the agent's model.
+	Sampling *SamplingConfig `json:"sampling,omitempty"`
 
 	// For the `a2a` tool
 	Name string `json:"name,omitempty"`
@@ -141,6 +245,9 @@ type Toolset struct {
 	// For `shell`, `script` or `mcp` tools
 	Env map[string]string `json:"env,omitempty"`
 
+	// For `shell` or `script` tools
+	Sandbox *SandboxConfig `json:"sandbox,omitempty"`
+
 	// For the `todo` tool
 	Shared bool `json:"shared,omitempty"`
 
@@ -155,6 +262,9 @@ type Toolset struct {
 
 	APIConfig APIToolConfig `json:"api_config"`
 
+	// For the `openapi` tool
+	OpenAPIConfig *OpenAPIToolConfig `json:"openapi_config,omitempty"`
+
 	// For the `filesystem` tool - VCS integration
 	IgnoreVCS *bool `json:"ignore_vcs,omitempty"`
 
@@ -172,6 +282,74 @@ func (t *Toolset) UnmarshalYAML(unmarshal func(any) error) error {
 	return t.validate()
 }
 
+// SamplingConfig lets an MCP server request completions through the agent's
+// model with sampling/createMessage, within limits. Servers without it can't.
+// It can be written either as `sampling: true` or as an object.
+type SamplingConfig struct {
+	// MaxTokens caps the number of tokens of each completion. Defaults to 4096.
+	MaxTokens int64 `json:"max_tokens,omitempty"`
+	// Models are the models, as provider/model, the server can use. The server's
+	// hints pick one of them. Defaults to the agent's model and its fallbacks.
+	Models []string `json:"models,omitempty"`
+	// RequireApproval asks the user to approve each request, like a tool call.
+	RequireApproval bool `json:"require_approval,omitempty"`
+}
+
+// SandboxConfig runs the commands of a shell or script toolset inside a
+// long-lived Docker container instead of on the host.
+// It can be written either as `sandbox: true` or as an object.
+type SandboxConfig struct {
+	// Image is the container image. Defaults to alpine:latest.
+	Image string `json:"image,omitempty"`
+	// Network gives the container network access. Defaults to false.
+	Network bool `json:"network,omitempty"`
+	// CPUs limits the number of CPUs the container can use.
+	CPUs float64 `json:"cpus,omitempty"`
+	// Memory limits the memory the container can use, e.g. "2g".
+	Memory string `json:"memory,omitempty"`
+	// Mounts are additional bind mounts, in the `host:container[:ro]` format.
+	// The working directory is always mounted.
+	Mounts []string `json:"mounts,omitempty"`
+}
+
+func (s *SandboxConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var enabled bool
+	if err := unmarshal(&enabled); err == nil {
+		if !enabled {
+			return errors.New("sandbox can't be set to false, remove it instead")
+		}
+		*s = SandboxConfig{}
+		return nil
+	}
+
+	type alias SandboxConfig
+	var tmp alias
+	if err := unmarshal(&tmp); err != nil {
+		return err
+	}
+	*s = SandboxConfig(tmp)
+	return nil
+}
+
+func (s *SamplingConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var enabled bool
+	if err := unmarshal(&enabled); err == nil {
+		if !enabled {
+			return errors.New("sampling can't be set to false, remove it instead")
+		}
+		*s = SamplingConfig{}
+		return nil
+	}
+
+	type alias SamplingConfig
+	var tmp alias
+	if err := unmarshal(&tmp); err != nil {
+		return err
+	}
+	*s = SamplingConfig(tmp)
+	return nil
+}
+
 type Remote struct {
 	URL           string            `json:"url"`
 	TransportType string            `json:"transport_type,omitempty"`
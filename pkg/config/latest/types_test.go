@@ -217,3 +217,128 @@ func toFloat64(v any) float64 {
 		return 0
 	}
 }
+
+func TestPermissionsConfig_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	input := []byte(`
+allow:
+  - read_file
+  - tool: shell
+    args:
+      cmd: "git status*"
+deny:
+  - tool: write_file
+    args:
+      path: "./secrets/*"
+`)
+	var permissions PermissionsConfig
+	err := yaml.Unmarshal(input, &permissions)
+	require.NoError(t, err)
+
+	require.Equal(t, []PermissionRule{
+		{Tool: "read_file"},
+		{Tool: "shell", Args: map[string]string{"cmd": "git status*"}},
+	}, permissions.Allow)
+	require.Empty(t, permissions.Ask)
+	require.Equal(t, []PermissionRule{
+		{Tool: "write_file", Args: map[string]string{"path": "./secrets/*"}},
+	}, permissions.Deny)
+}
+
+func TestHooksConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	var cfg Config
+	err := yaml.Unmarshal([]byte(`
+agents:
+  root:
+    hooks:
+      pre_tool_use:
+        - matcher: "shell"
+          command: ./check.sh
+      stop:
+        - command: ./notify.sh
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, []HookConfig{{Matcher: "shell", Command: "./check.sh"}}, cfg.Agents["root"].Hooks.PreToolUse)
+
+	err = yaml.Unmarshal([]byte(`
+agents:
+  root:
+    hooks:
+      stop:
+        - matcher: "shell"
+          command: ./notify.sh
+`), &cfg)
+	require.ErrorContains(t, err, "matcher can only be used")
+
+	err = yaml.Unmarshal([]byte(`
+agents:
+  root:
+    hooks:
+      session_start:
+        - command: ""
+`), &cfg)
+	require.ErrorContains(t, err, "hooks require a command")
+}
+
+func TestSandboxConfig_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	var toolset Toolset
+	require.NoError(t, yaml.Unmarshal([]byte("type: shell\nsandbox: true\n"), &toolset))
+	require.Equal(t, &SandboxConfig{}, toolset.Sandbox)
+
+	require.NoError(t, yaml.Unmarshal([]byte("type: script\nsandbox:\n  image: golang:1.25\n  network: true\n  memory: 2g\n"), &toolset))
+	require.Equal(t, &SandboxConfig{Image: "golang:1.25", Network: true, Memory: "2g"}, toolset.Sandbox)
+
+	err := yaml.Unmarshal([]byte("type: filesystem\nsandbox: true\n"), &toolset)
+	require.ErrorContains(t, err, "sandbox can only be used with type 'shell' or 'script'")
+}
+
+func TestOpenAPIToolConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	var toolset Toolset
+	require.NoError(t, yaml.Unmarshal([]byte("type: openapi\nopenapi_config:\n  spec: ./petstore.yaml\n  exclude: [admin]\n"), &toolset))
+	require.Equal(t, &OpenAPIToolConfig{Spec: "./petstore.yaml", Exclude: []string{"admin"}}, toolset.OpenAPIConfig)
+
+	err := yaml.Unmarshal([]byte("type: openapi\n"), &Toolset{})
+	require.ErrorContains(t, err, "openapi toolset requires a spec")
+
+	err = yaml.Unmarshal([]byte("type: api\nopenapi_config:\n  spec: ./petstore.yaml\n"), &Toolset{})
+	require.ErrorContains(t, err, "openapi_config can only be used with type 'openapi'")
+}
+
+func TestResourceTools_Validate(t *testing.T) {
+	t.Parallel()
+
+	var toolset Toolset
+	require.NoError(t, yaml.Unmarshal([]byte("type: mcp\ncommand: docs-server\nresource_tools: true\n"), &toolset))
+	require.True(t, toolset.ResourceTools)
+
+	err := yaml.Unmarshal([]byte("type: shell\nresource_tools: true\n"), &Toolset{})
+	require.ErrorContains(t, err, "resource_tools can only be used with type 'mcp'")
+}
+
+func TestSamplingConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	var toolset Toolset
+	require.NoError(t, yaml.Unmarshal([]byte("type: mcp\ncommand: research-server\nsampling:\n  max_tokens: 2048\n  require_approval: true\n"), &toolset))
+	require.Equal(t, &SamplingConfig{MaxTokens: 2048, RequireApproval: true}, toolset.Sampling)
+
+	toolset = Toolset{}
+	require.NoError(t, yaml.Unmarshal([]byte("type: mcp\ncommand: research-server\nsampling: true\n"), &toolset))
+	require.Equal(t, &SamplingConfig{}, toolset.Sampling)
+
+	err := yaml.Unmarshal([]byte("type: mcp\ncommand: research-server\nsampling: false\n"), &Toolset{})
+	require.ErrorContains(t, err, "sampling can't be set to false")
+
+	err = yaml.Unmarshal([]byte("type: shell\nsampling:\n  max_tokens: 10\n"), &Toolset{})
+	require.ErrorContains(t, err, "sampling can only be used with type 'mcp'")
+
+	err = yaml.Unmarshal([]byte("type: mcp\ncommand: research-server\nsampling:\n  max_tokens: -1\n"), &Toolset{})
+	require.ErrorContains(t, err, "sampling max_tokens must be positive")
+}
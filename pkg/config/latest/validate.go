@@ -23,6 +23,50 @@ func (t *Config) validate() error {
 				return err
 			}
 		}
+		if agent.Permissions != nil {
+			if err := agent.Permissions.validate(); err != nil {
+				return err
+			}
+		}
+		if agent.Hooks != nil {
+			if err := agent.Hooks.validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *PermissionsConfig) validate() error {
+	for _, rules := range [][]PermissionRule{p.Allow, p.Ask, p.Deny} {
+		for _, rule := range rules {
+			if rule.Tool == "" {
+				return errors.New("permission rules require a tool name")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *HooksConfig) validate() error {
+	for _, hooks := range [][]HookConfig{h.SessionStart, h.UserPromptSubmit, h.PreToolUse, h.PostToolUse, h.Stop} {
+		for _, hook := range hooks {
+			if strings.TrimSpace(hook.Command) == "" {
+				return errors.New("hooks require a command")
+			}
+			if hook.Timeout < 0 {
+				return errors.New("hook timeout must be positive")
+			}
+		}
+	}
+	for _, hooks := range [][]HookConfig{h.SessionStart, h.UserPromptSubmit, h.Stop} {
+		for _, hook := range hooks {
+			if hook.Matcher != "" {
+				return errors.New("matcher can only be used with pre_tool_use and post_tool_use hooks")
+			}
+		}
 	}
 
 	return nil
@@ -42,6 +86,12 @@ func (t *Toolset) validate() error {
 	if t.IgnoreVCS != nil && t.Type != "filesystem" {
 		return errors.New("ignore_vcs can only be used with type 'filesystem'")
 	}
+	if t.OpenAPIConfig != nil && t.Type != "openapi" {
+		return errors.New("openapi_config can only be used with type 'openapi'")
+	}
+	if t.Sandbox != nil && t.Type != "shell" && t.Type != "script" {
+		return errors.New("sandbox can only be used with type 'shell' or 'script'")
+	}
 	if len(t.Env) > 0 && (t.Type != "shell" && t.Type != "script" && t.Type != "mcp") {
 		return errors.New("env can only be used with type 'shell', 'script' or 'mcp'")
 	}
@@ -66,6 +116,15 @@ func (t *Toolset) validate() error {
 	if t.Config != nil && t.Type != "mcp" {
 		return errors.New("config can only be used with type 'mcp'")
 	}
+	if t.ResourceTools && t.Type != "mcp" {
+		return errors.New("resource_tools can only be used with type 'mcp'")
+	}
+	if t.Sampling != nil && t.Type != "mcp" {
+		return errors.New("sampling can only be used with type 'mcp'")
+	}
+	if t.Sampling != nil && t.Sampling.MaxTokens < 0 {
+		return errors.New("sampling max_tokens must be positive")
+	}
 	if t.URL != "" && t.Type != "a2a" {
 		return errors.New("url can only be used with type 'a2a'")
 	}
@@ -78,6 +137,10 @@ func (t *Toolset) validate() error {
 		if t.Path == "" {
 			return errors.New("memory toolset requires a path to be set")
 		}
+	case "openapi":
+		if t.OpenAPIConfig == nil || t.OpenAPIConfig.Spec == "" {
+			return errors.New("openapi toolset requires a spec in openapi_config")
+		}
 	case "mcp":
 		count := 0
 		if t.Command != "" {
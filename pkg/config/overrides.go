@@ -91,6 +91,14 @@ func ensureModelsExist(cfg *latest.Config) error {
 				return err
 			}
 		}
+
+		if agentConfig.Fallback != nil {
+			for _, modelName := range agentConfig.Fallback.Models {
+				if err := ensureSingleModelExists(cfg, modelName, fmt.Sprintf("fallback of agent '%s'", agentName)); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	// Ensure models referenced by RAG strategies exist
@@ -3,7 +3,9 @@ package environment
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os/exec"
 	"strings"
@@ -51,3 +53,46 @@ func (p *KeychainProvider) Get(ctx context.Context, name string) (string, bool)
 
 	return strings.TrimSpace(out.String()), true
 }
+
+// Set stores a secret in the macOS keychain, replacing any existing value.
+// The command is sent on the standard input of `security -i`, with the value
+// hex-encoded, so that the secret never shows up in the process list.
+func (p *KeychainProvider) Set(ctx context.Context, name, value string) error {
+	cmd := exec.CommandContext(ctx, "security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -a cagent -s %s -X %s\n", quoteKeychainArg(name), hex.EncodeToString([]byte(value))))
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err == nil && stderr.Len() > 0 {
+		// Interactive mode doesn't always exit with an error when a command fails.
+		err = errors.New("security command failed")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store secret in keychain: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// quoteKeychainArg quotes an argument of a command run by `security -i`.
+func quoteKeychainArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// Delete removes a secret from the macOS keychain. Deleting a missing secret is not an error.
+func (p *KeychainProvider) Delete(ctx context.Context, name string) error {
+	if _, found := p.Get(ctx, name); !found {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "security", "delete-generic-password", "-s", name)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete secret from keychain: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
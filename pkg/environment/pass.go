@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os/exec"
 	"strings"
@@ -50,3 +51,34 @@ func (p *PassProvider) Get(ctx context.Context, name string) (string, bool) {
 
 	return strings.TrimSpace(out.String()), true
 }
+
+// Set stores a secret in the `pass` store, replacing any existing value.
+func (p *PassProvider) Set(ctx context.Context, name, value string) error {
+	cmd := exec.CommandContext(ctx, "pass", "insert", "--multiline", "--force", name)
+	cmd.Stdin = strings.NewReader(value)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to store secret in pass: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Delete removes a secret from the `pass` store. Deleting a missing secret is not an error.
+func (p *PassProvider) Delete(ctx context.Context, name string) error {
+	if _, found := p.Get(ctx, name); !found {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "pass", "rm", "--force", name)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete secret from pass: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
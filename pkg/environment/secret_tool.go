@@ -0,0 +1,84 @@
+package environment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SecretToolProvider is a provider that retrieves secrets from the Secret Service
+// keyring (GNOME Keyring, KWallet...) via the `secret-tool` command-line tool.
+type SecretToolProvider struct{}
+
+type ErrSecretToolNotAvailable struct{}
+
+func (ErrSecretToolNotAvailable) Error() string {
+	return "secret-tool is not installed or there's no D-Bus session (Secret Service keyring access)"
+}
+
+// NewSecretToolProvider creates a new SecretToolProvider instance.
+// It verifies that `secret-tool` is available and that there's a D-Bus session
+// to reach the keyring through.
+func NewSecretToolProvider() (*SecretToolProvider, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil && !errors.Is(err, exec.ErrNotFound) {
+		slog.Warn("failed to lookup `secret-tool` binary", "error", err)
+	}
+	if path == "" || os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return nil, ErrSecretToolNotAvailable{}
+	}
+	return &SecretToolProvider{}, nil
+}
+
+// Get retrieves the value of a secret by its name from the keyring.
+// Secrets are looked up with the `service=cagent name=<name>` attributes.
+func (p *SecretToolProvider) Get(ctx context.Context, name string) (string, bool) {
+	cmd := exec.CommandContext(ctx, "secret-tool", "lookup", "service", "cagent", "name", name)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		// Ignore error
+		slog.Debug("Failed to find secret in keyring", "error", err)
+		return "", false
+	}
+
+	return out.String(), true
+}
+
+// Set stores a secret in the keyring, replacing any existing value.
+// The value is sent on the standard input so that it never shows up in the process list.
+func (p *SecretToolProvider) Set(ctx context.Context, name, value string) error {
+	cmd := exec.CommandContext(ctx, "secret-tool", "store", "--label", name, "service", "cagent", "name", name)
+	cmd.Stdin = strings.NewReader(value)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to store secret in keyring: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Delete removes a secret from the keyring. Deleting a missing secret is not an error.
+func (p *SecretToolProvider) Delete(ctx context.Context, name string) error {
+	cmd := exec.CommandContext(ctx, "secret-tool", "clear", "service", "cagent", "name", name)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete secret from keyring: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// Package hooks runs user commands at given points of an agent's lifecycle:
+// when a session starts, when the user submits a prompt, before and after each
+// tool call and when the agent stops.
+//
+// A hook receives a JSON description of the event on stdin (see Input). It can:
+//   - exit with code 0 and print nothing: the agent carries on.
+//   - exit with code 0 and print a JSON object (see Output) to block the event,
+//     replace the arguments of a tool call or add context for the model.
+//   - exit with code 0 and print plain text: the text is added as context.
+//   - exit with code 2: the event is blocked and stderr is given as the reason.
+//
+// Any other failure is logged and ignored so that a broken hook doesn't break the agent,
+// except for pre_tool_use hooks: a tool call is blocked if one of them fails.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/cagent/pkg/config/latest"
+)
+
+// Event is a point of the agent's lifecycle where hooks run.
+type Event string
+
+const (
+	// SessionStart runs before the first model request of a session.
+	SessionStart Event = "session_start"
+	// UserPromptSubmit runs when the user submits a prompt, before the model sees it.
+	UserPromptSubmit Event = "user_prompt_submit"
+	// PreToolUse runs before a tool call, before asking for confirmation.
+	PreToolUse Event = "pre_tool_use"
+	// PostToolUse runs after a tool call, before its result is given to the model.
+	PostToolUse Event = "post_tool_use"
+	// Stop runs when the agent is done answering.
+	Stop Event = "stop"
+)
+
+const (
+	defaultTimeout = 60 * time.Second
+	// blockExitCode is the exit code a hook uses to block an event.
+	blockExitCode = 2
+)
+
+// Input is the JSON document hooks receive on stdin.
+type Input struct {
+	HookEvent Event  `json:"hook_event"`
+	SessionID string `json:"session_id"`
+	Agent     string `json:"agent"`
+	Cwd       string `json:"cwd,omitempty"`
+	// Prompt is set for user_prompt_submit hooks.
+	Prompt string `json:"prompt,omitempty"`
+	// Tool fields are set for pre_tool_use and post_tool_use hooks.
+	ToolName     string          `json:"tool_name,omitempty"`
+	ToolCallID   string          `json:"tool_call_id,omitempty"`
+	ToolInput    json.RawMessage `json:"tool_input,omitempty"`
+	ToolResponse string          `json:"tool_response,omitempty"`
+	ToolError    bool            `json:"tool_error,omitempty"`
+	// StopHookActive is true when the agent is already running because a stop hook blocked it.
+	StopHookActive bool `json:"stop_hook_active,omitempty"`
+}
+
+// Output is the JSON document hooks can print on stdout.
+type Output struct {
+	// Decision is "block" to refuse the tool call, the prompt, or the stop.
+	Decision string `json:"decision,omitempty"`
+	// Reason is given to the model, or shown to the user for blocked prompts.
+	Reason string `json:"reason,omitempty"`
+	// ToolInput replaces the arguments of the tool call. Only used by pre_tool_use hooks.
+	ToolInput json.RawMessage `json:"tool_input,omitempty"`
+	// AdditionalContext is added to the conversation for the model to see.
+	// It's ignored by pre_tool_use hooks.
+	AdditionalContext string `json:"additional_context,omitempty"`
+}
+
+// Result is the combined outcome of the hooks run for an event.
+type Result struct {
+	Blocked bool
+	Reason  string
+	// ToolInput holds the replaced tool call arguments, or is empty if they didn't change.
+	ToolInput         string
+	AdditionalContext []string
+}
+
+// Context returns the additional context of all the hooks as a single string.
+func (r *Result) Context() string {
+	return strings.Join(r.AdditionalContext, "\n\n")
+}
+
+// Runner runs an agent's hooks. A nil Runner has no hooks.
+type Runner struct {
+	hooks map[Event][]hook
+}
+
+type hook struct {
+	matcher string
+	command string
+	timeout time.Duration
+}
+
+// NewRunner creates a runner for the hooks of an agent.
+func NewRunner(cfg *latest.HooksConfig) (*Runner, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	r := &Runner{hooks: map[Event][]hook{}}
+	for event, hooks := range map[Event][]latest.HookConfig{
+		SessionStart:     cfg.SessionStart,
+		UserPromptSubmit: cfg.UserPromptSubmit,
+		PreToolUse:       cfg.PreToolUse,
+		PostToolUse:      cfg.PostToolUse,
+		Stop:             cfg.Stop,
+	} {
+		for _, h := range hooks {
+			if _, err := path.Match(h.Matcher, ""); err != nil {
+				return nil, fmt.Errorf("invalid matcher %q for %s hook: %w", h.Matcher, event, err)
+			}
+
+			timeout := defaultTimeout
+			if h.Timeout > 0 {
+				timeout = time.Duration(h.Timeout) * time.Second
+			}
+			r.hooks[event] = append(r.hooks[event], hook{
+				matcher: h.Matcher,
+				command: h.Command,
+				timeout: timeout,
+			})
+		}
+	}
+
+	return r, nil
+}
+
+// Has returns true if there are hooks for the given event.
+func (r *Runner) Has(event Event) bool {
+	return r != nil && len(r.hooks[event]) > 0
+}
+
+// Run runs the hooks matching the input, in order. It stops at the first hook
+// that blocks. Replaced tool arguments are passed on to the following hooks.
+func (r *Runner) Run(ctx context.Context, input Input) Result {
+	var result Result
+	if r == nil {
+		return result
+	}
+
+	for _, h := range r.hooks[input.HookEvent] {
+		if !h.matches(input.ToolName) {
+			continue
+		}
+
+		out, err := h.run(ctx, input)
+		if err != nil {
+			slog.Warn("Hook failed", "event", input.HookEvent, "command", h.command, "error", err)
+			if input.HookEvent != PreToolUse {
+				continue
+			}
+			// Hooks guarding tool calls fail closed: a broken policy mustn't let the call through.
+			result.Blocked = true
+			result.Reason = fmt.Sprintf("the hook failed: %v", err)
+			return result
+		}
+
+		if out.AdditionalContext != "" {
+			result.AdditionalContext = append(result.AdditionalContext, out.AdditionalContext)
+		}
+		if len(out.ToolInput) > 0 && input.HookEvent == PreToolUse {
+			input.ToolInput = out.ToolInput
+			result.ToolInput = string(out.ToolInput)
+		}
+		if out.Decision == "block" {
+			slog.Debug("Hook blocked event", "event", input.HookEvent, "command", h.command, "reason", out.Reason)
+			result.Blocked = true
+			result.Reason = out.Reason
+			return result
+		}
+	}
+
+	return result
+}
+
+func (h *hook) matches(toolName string) bool {
+	if h.matcher == "" || toolName == "" {
+		return true
+	}
+	matched, _ := path.Match(h.matcher, toolName)
+	return matched
+}
+
+func (h *hook) run(ctx context.Context, input Input) (*Output, error) {
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", h.command)
+	cmd.Dir = input.Cwd
+	cmd.Env = append(cmd.Environ(), "CAGENT_HOOK_EVENT="+string(input.HookEvent), "CAGENT_SESSION_ID="+input.SessionID)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait forever for background processes that keep the pipes open.
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == blockExitCode {
+			return &Output{Decision: "block", Reason: strings.TrimSpace(stderr.String())}, nil
+		}
+		if parent.Err() != nil {
+			return nil, parent.Err()
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %s", h.timeout)
+		}
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseOutput(stdout.Bytes()), nil
+}
+
+// parseOutput reads a hook's stdout. JSON objects are decoded as an Output,
+// anything else is considered additional context.
+func parseOutput(stdout []byte) *Output {
+	stdout = bytes.TrimSpace(stdout)
+	if len(stdout) == 0 {
+		return &Output{}
+	}
+
+	if stdout[0] == '{' {
+		var out Output
+		if err := json.Unmarshal(stdout, &out); err == nil {
+			return &out
+		}
+	}
+
+	return &Output{AdditionalContext: string(stdout)}
+}
@@ -0,0 +1,132 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/config/latest"
+)
+
+func TestNilRunner(t *testing.T) {
+	runner, err := NewRunner(nil)
+	require.NoError(t, err)
+
+	assert.False(t, runner.Has(PreToolUse))
+	assert.Equal(t, Result{}, runner.Run(t.Context(), Input{HookEvent: PreToolUse}))
+}
+
+func TestRun_ReceivesInputOnStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "input.json")
+	runner, err := NewRunner(&latest.HooksConfig{
+		PostToolUse: []latest.HookConfig{{Command: "cat > " + out}},
+	})
+	require.NoError(t, err)
+
+	res := runner.Run(t.Context(), Input{
+		HookEvent:    PostToolUse,
+		SessionID:    "sess",
+		ToolName:     "shell",
+		ToolInput:    json.RawMessage(`{"cmd":"ls"}`),
+		ToolResponse: "file.txt",
+	})
+	assert.False(t, res.Blocked)
+
+	buf, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var input Input
+	require.NoError(t, json.Unmarshal(buf, &input))
+	assert.Equal(t, PostToolUse, input.HookEvent)
+	assert.Equal(t, "sess", input.SessionID)
+	assert.JSONEq(t, `{"cmd":"ls"}`, string(input.ToolInput))
+	assert.Equal(t, "file.txt", input.ToolResponse)
+}
+
+func TestRun_BlockWithExitCode(t *testing.T) {
+	runner, err := NewRunner(&latest.HooksConfig{
+		PreToolUse: []latest.HookConfig{
+			{Matcher: "shell", Command: "echo 'not allowed' >&2; exit 2"},
+			{Command: "echo never"},
+		},
+	})
+	require.NoError(t, err)
+
+	res := runner.Run(t.Context(), Input{HookEvent: PreToolUse, ToolName: "shell"})
+	assert.True(t, res.Blocked)
+	assert.Equal(t, "not allowed", res.Reason)
+	assert.Empty(t, res.AdditionalContext, "hooks after a blocking hook shouldn't run")
+
+	res = runner.Run(t.Context(), Input{HookEvent: PreToolUse, ToolName: "read_file"})
+	assert.False(t, res.Blocked, "the matcher should only match the shell tool")
+	assert.Equal(t, []string{"never"}, res.AdditionalContext)
+}
+
+func TestRun_JSONOutput(t *testing.T) {
+	runner, err := NewRunner(&latest.HooksConfig{
+		PreToolUse: []latest.HookConfig{
+			{Command: `echo '{"tool_input": {"cmd": "ls -la"}, "additional_context": "rewritten"}'`},
+			{Command: `grep -q '"ls -la"' && echo '{"decision": "block", "reason": "saw the new input"}'`},
+		},
+	})
+	require.NoError(t, err)
+
+	res := runner.Run(t.Context(), Input{HookEvent: PreToolUse, ToolName: "shell", ToolInput: json.RawMessage(`{"cmd":"ls"}`)})
+	assert.JSONEq(t, `{"cmd": "ls -la"}`, res.ToolInput)
+	assert.Equal(t, "rewritten", res.Context())
+	assert.True(t, res.Blocked)
+	assert.Equal(t, "saw the new input", res.Reason)
+}
+
+func TestRun_FailingHooksAreIgnored(t *testing.T) {
+	runner, err := NewRunner(&latest.HooksConfig{
+		Stop: []latest.HookConfig{
+			{Command: "exit 1"},
+			{Command: "sleep 5", Timeout: 1},
+			{Command: "echo done"},
+		},
+	})
+	require.NoError(t, err)
+
+	res := runner.Run(t.Context(), Input{HookEvent: Stop})
+	assert.False(t, res.Blocked)
+	assert.Equal(t, []string{"done"}, res.AdditionalContext)
+}
+
+func TestRun_FailingPreToolUseHooksBlock(t *testing.T) {
+	runner, err := NewRunner(&latest.HooksConfig{
+		PreToolUse: []latest.HookConfig{
+			{Command: "echo broken >&2; exit 1"},
+			{Command: "echo never"},
+		},
+	})
+	require.NoError(t, err)
+
+	res := runner.Run(t.Context(), Input{HookEvent: PreToolUse, ToolName: "shell"})
+	assert.True(t, res.Blocked)
+	assert.Contains(t, res.Reason, "broken")
+	assert.Empty(t, res.AdditionalContext)
+}
+
+func TestRun_CancelledIsNotATimeout(t *testing.T) {
+	h := hook{command: "sleep 5", timeout: time.Minute}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	_, err := h.run(ctx, Input{HookEvent: Stop})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NotContains(t, err.Error(), "timed out")
+}
+
+func TestNewRunner_InvalidMatcher(t *testing.T) {
+	_, err := NewRunner(&latest.HooksConfig{
+		PreToolUse: []latest.HookConfig{{Matcher: "[", Command: "true"}},
+	})
+	require.Error(t, err)
+}
@@ -142,28 +142,12 @@ func convertBetaMessages(messages []chat.Message) []anthropic.BetaMessageParam {
 			// Collect consecutive tool messages and merge them into a single user message
 			// This is required by Anthropic API: all tool_result blocks for tool_use blocks
 			// from the same assistant message must be in the same user message
-			toolResultBlocks := []anthropic.BetaContentBlockParamUnion{
-				{
-					OfToolResult: &anthropic.BetaToolResultBlockParam{
-						ToolUseID: msg.ToolCallID,
-						Content: []anthropic.BetaToolResultBlockParamContentUnion{
-							{OfText: &anthropic.BetaTextBlockParam{Text: strings.TrimSpace(msg.Content)}},
-						},
-					},
-				},
-			}
+			toolResultBlocks := []anthropic.BetaContentBlockParamUnion{betaToolResult(msg)}
 
 			// Look ahead for consecutive tool messages and merge them
 			j := i + 1
 			for j < len(messages) && messages[j].Role == chat.MessageRoleTool {
-				toolResultBlocks = append(toolResultBlocks, anthropic.BetaContentBlockParamUnion{
-					OfToolResult: &anthropic.BetaToolResultBlockParam{
-						ToolUseID: messages[j].ToolCallID,
-						Content: []anthropic.BetaToolResultBlockParamContentUnion{
-							{OfText: &anthropic.BetaTextBlockParam{Text: strings.TrimSpace(messages[j].Content)}},
-						},
-					},
-				})
+				toolResultBlocks = append(toolResultBlocks, betaToolResult(&messages[j]))
 				j++
 			}
 
@@ -219,3 +203,29 @@ func convertBetaTools(t []tools.Tool) ([]anthropic.BetaToolUnionParam, error) {
 
 	return betaTools, nil
 }
+
+// betaToolResult converts a tool message, and its images, into a tool_result block.
+func betaToolResult(msg *chat.Message) anthropic.BetaContentBlockParamUnion {
+	content := []anthropic.BetaToolResultBlockParamContentUnion{
+		{OfText: &anthropic.BetaTextBlockParam{Text: strings.TrimSpace(msg.Content)}},
+	}
+	for _, image := range toolResultImages(msg) {
+		content = append(content, anthropic.BetaToolResultBlockParamContentUnion{
+			OfImage: &anthropic.BetaImageBlockParam{
+				Source: anthropic.BetaImageBlockParamSourceUnion{
+					OfBase64: &anthropic.BetaBase64ImageSourceParam{
+						Data:      image.data,
+						MediaType: anthropic.BetaBase64ImageSourceMediaType(image.mediaType),
+					},
+				},
+			},
+		})
+	}
+
+	return anthropic.BetaContentBlockParamUnion{
+		OfToolResult: &anthropic.BetaToolResultBlockParam{
+			ToolUseID: msg.ToolCallID,
+			Content:   content,
+		},
+	}
+}
@@ -462,6 +462,11 @@ func convertMessages(messages []chat.Message) []anthropic.MessageParam {
 			j := i
 			for j < len(messages) && messages[j].Role == chat.MessageRoleTool {
 				tr := anthropic.NewToolResultBlock(messages[j].ToolCallID, strings.TrimSpace(messages[j].Content), false)
+				for _, image := range toolResultImages(&messages[j]) {
+					tr.OfToolResult.Content = append(tr.OfToolResult.Content, anthropic.ToolResultBlockParamContentUnion{
+						OfImage: anthropic.NewImageBlockBase64(image.mediaType, image.data).OfImage,
+					})
+				}
 				blocks = append(blocks, tr)
 				j++
 			}
@@ -754,3 +759,25 @@ func countAnthropicTokens(
 	}
 	return result.InputTokens, nil
 }
+
+// base64Image is an image of a tool result, as sent to Anthropic.
+type base64Image struct {
+	mediaType string
+	data      string
+}
+
+// toolResultImages extracts the images, stored as base64 data URLs, of a tool result.
+func toolResultImages(msg *chat.Message) []base64Image {
+	var images []base64Image
+	for _, part := range msg.MultiContent {
+		if part.Type != chat.MessagePartTypeImageURL || part.ImageURL == nil {
+			continue
+		}
+		mediaType, data, ok := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ";base64,")
+		if !ok {
+			continue
+		}
+		images = append(images, base64Image{mediaType: mediaType, data: data})
+	}
+	return images
+}
@@ -100,6 +100,39 @@ func TestConvertMessages_AssistantToolCalls_NoText_IncludesToolUse(t *testing.T)
 	assert.Equal(t, "tool_use", cb["type"])
 }
 
+func TestConvertMessages_ToolResultWithImage(t *testing.T) {
+	msgs := []chat.Message{
+		{
+			Role:      chat.MessageRoleAssistant,
+			ToolCalls: []tools.ToolCall{{ID: "tool-1", Function: tools.FunctionCall{Name: "screenshot", Arguments: "{}"}}},
+		},
+		{
+			Role:         chat.MessageRoleTool,
+			ToolCallID:   "tool-1",
+			Content:      "Here it is",
+			MultiContent: []chat.MessagePart{{Type: chat.MessagePartTypeImageURL, ImageURL: &chat.MessageImageURL{URL: "data:image/png;base64,AAAA"}}},
+		},
+	}
+
+	out := convertMessages(msgs)
+	require.Len(t, out, 2)
+
+	b, err := json.Marshal(out[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"role": "user",
+		"content": [{
+			"type": "tool_result",
+			"tool_use_id": "tool-1",
+			"is_error": false,
+			"content": [
+				{"type": "text", "text": "Here it is"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAAA"}}
+			]
+		}]
+	}`, string(b))
+}
+
 func TestSystemMessages_AreExtractedAndNotInMessageList(t *testing.T) {
 	msgs := []chat.Message{
 		{Role: chat.MessageRoleSystem, Content: "  system rules here  "},
@@ -25,6 +25,9 @@ func CloneWithOptions(ctx context.Context, base Provider, opts ...options.Opt) P
 		opt(tempOpts)
 		mt := tempOpts.MaxTokens()
 		modelConfig.MaxTokens = &mt
+		if temperature := tempOpts.Temperature(); temperature != nil {
+			modelConfig.Temperature = temperature
+		}
 	}
 
 	clone, err := New(ctx, &modelConfig, config.Env, mergedOpts...)
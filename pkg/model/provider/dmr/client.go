@@ -306,8 +306,27 @@ func mergeRuntimeFlagsPreferUser(derived, user []string) (out, warnings []string
 	return out, warnings
 }
 
+// toolImageParts returns the parts showing the model the images returned by a tool.
+func toolImageParts(msg *chat.Message) []openai.ChatCompletionContentPartUnionParam {
+	var parts []openai.ChatCompletionContentPartUnionParam
+	for _, part := range msg.MultiContent {
+		if part.Type != chat.MessagePartTypeImageURL || part.ImageURL == nil {
+			continue
+		}
+		if len(parts) == 0 {
+			parts = append(parts, openai.TextContentPart(fmt.Sprintf("Images returned by the tool call %s:", msg.ToolCallID)))
+		}
+		parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+			URL:    part.ImageURL.URL,
+			Detail: string(part.ImageURL.Detail),
+		}))
+	}
+	return parts
+}
+
 func convertMessages(messages []chat.Message) []openai.ChatCompletionMessageParamUnion {
 	openaiMessages := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	var toolImages []openai.ChatCompletionContentPartUnionParam
 	for i := range messages {
 		msg := &messages[i]
 
@@ -394,25 +413,33 @@ func convertMessages(messages []chat.Message) []openai.ChatCompletionMessagePara
 				ToolCallID: msg.ToolCallID,
 			}
 
-			if len(msg.MultiContent) == 0 {
+			// Convert multi-content for tool messages. Their images are sent separately.
+			textParts := make([]openai.ChatCompletionContentPartTextParam, 0)
+			for _, part := range msg.MultiContent {
+				if part.Type == chat.MessagePartTypeText {
+					textParts = append(textParts, openai.ChatCompletionContentPartTextParam{
+						Text: part.Text,
+					})
+				}
+			}
+			if len(textParts) == 0 {
 				toolParam.Content.OfString = param.NewOpt(msg.Content)
 			} else {
-				// Convert multi-content for tool messages
-				textParts := make([]openai.ChatCompletionContentPartTextParam, 0)
-				for _, part := range msg.MultiContent {
-					if part.Type == chat.MessagePartTypeText {
-						textParts = append(textParts, openai.ChatCompletionContentPartTextParam{
-							Text: part.Text,
-						})
-					}
-				}
 				toolParam.Content.OfArrayOfContentParts = textParts
 			}
 
 			openaiMessage.OfTool = &toolParam
+			toolImages = append(toolImages, toolImageParts(msg)...)
 		}
 
 		openaiMessages = append(openaiMessages, openaiMessage)
+
+		// Tool messages only accept text: the images of consecutive tool results
+		// are shown to the model in a user message that follows them.
+		if len(toolImages) > 0 && (i+1 == len(messages) || messages[i+1].Role != chat.MessageRoleTool) {
+			openaiMessages = append(openaiMessages, openai.UserMessage(toolImages))
+			toolImages = nil
+		}
 	}
 
 	var mergedMessages []openai.ChatCompletionMessageParamUnion
@@ -162,6 +162,24 @@ func NewClient(ctx context.Context, cfg *latest.ModelConfig, env environment.Pro
 	}, nil
 }
 
+// toolResultImages converts the images of a tool result, stored as base64 data URLs, into parts.
+func toolResultImages(msg *chat.Message) []*genai.Part {
+	var parts []*genai.Part
+	for _, part := range msg.MultiContent {
+		if part.Type != chat.MessagePartTypeImageURL || part.ImageURL == nil {
+			continue
+		}
+		mimeType, base64Data, ok := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ";base64,")
+		if !ok {
+			continue
+		}
+		if imageData, err := base64.StdEncoding.DecodeString(base64Data); err == nil {
+			parts = append(parts, genai.NewPartFromBytes(imageData, mimeType))
+		}
+	}
+	return parts
+}
+
 // convertMessagesToGemini converts chat.Messages into Gemini Contents
 func convertMessagesToGemini(messages []chat.Message) []*genai.Content {
 	contents := make([]*genai.Content, 0, len(messages))
@@ -187,7 +205,9 @@ func convertMessagesToGemini(messages []chat.Message) []*genai.Content {
 			part := genai.NewPartFromFunctionResponse(msg.ToolCallID, map[string]any{
 				"result": msg.Content,
 			})
-			contents = append(contents, genai.NewContentFromParts([]*genai.Part{part}, role))
+			// Images returned by the tool follow its response
+			parts := append([]*genai.Part{part}, toolResultImages(msg)...)
+			contents = append(contents, genai.NewContentFromParts(parts, role))
 			continue
 		}
 
@@ -156,9 +156,28 @@ func convertMultiContent(multiContent []chat.MessagePart) []openai.ChatCompletio
 	return parts
 }
 
+// toolImageParts returns the parts showing the model the images returned by a tool.
+func toolImageParts(msg *chat.Message) []openai.ChatCompletionContentPartUnionParam {
+	var parts []openai.ChatCompletionContentPartUnionParam
+	for _, part := range msg.MultiContent {
+		if part.Type != chat.MessagePartTypeImageURL || part.ImageURL == nil {
+			continue
+		}
+		if len(parts) == 0 {
+			parts = append(parts, openai.TextContentPart(fmt.Sprintf("Images returned by the tool call %s:", msg.ToolCallID)))
+		}
+		parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+			URL:    part.ImageURL.URL,
+			Detail: string(part.ImageURL.Detail),
+		}))
+	}
+	return parts
+}
+
 // convertMessages converts chat.ChatCompletionMessage to openai.ChatCompletionMessageParamUnion
 func convertMessages(messages []chat.Message) []openai.ChatCompletionMessageParamUnion {
 	openaiMessages := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	var toolImages []openai.ChatCompletionContentPartUnionParam
 	for i := range messages {
 		msg := &messages[i]
 
@@ -245,25 +264,33 @@ func convertMessages(messages []chat.Message) []openai.ChatCompletionMessagePara
 				ToolCallID: msg.ToolCallID,
 			}
 
-			if len(msg.MultiContent) == 0 {
+			// Convert multi-content for tool messages. Their images are sent separately.
+			textParts := make([]openai.ChatCompletionContentPartTextParam, 0)
+			for _, part := range msg.MultiContent {
+				if part.Type == chat.MessagePartTypeText {
+					textParts = append(textParts, openai.ChatCompletionContentPartTextParam{
+						Text: part.Text,
+					})
+				}
+			}
+			if len(textParts) == 0 {
 				toolParam.Content.OfString = param.NewOpt(msg.Content)
 			} else {
-				// Convert multi-content for tool messages
-				textParts := make([]openai.ChatCompletionContentPartTextParam, 0)
-				for _, part := range msg.MultiContent {
-					if part.Type == chat.MessagePartTypeText {
-						textParts = append(textParts, openai.ChatCompletionContentPartTextParam{
-							Text: part.Text,
-						})
-					}
-				}
 				toolParam.Content.OfArrayOfContentParts = textParts
 			}
 
 			openaiMessage.OfTool = &toolParam
+			toolImages = append(toolImages, toolImageParts(msg)...)
 		}
 
 		openaiMessages = append(openaiMessages, openaiMessage)
+
+		// Tool messages only accept text: the images of consecutive tool results
+		// are shown to the model in a user message that follows them.
+		if len(toolImages) > 0 && (i+1 == len(messages) || messages[i+1].Role != chat.MessageRoleTool) {
+			openaiMessages = append(openaiMessages, openai.UserMessage(toolImages))
+			toolImages = nil
+		}
 	}
 	return openaiMessages
 }
@@ -608,6 +635,11 @@ func convertMessagesToResponseInput(messages []chat.Message) []responses.Respons
 					OfString: param.NewOpt(msg.Content),
 				},
 			}
+			if outputItems := functionCallOutputItems(&msg); outputItems != nil {
+				item.OfFunctionCallOutput.Output = responses.ResponseInputItemFunctionCallOutputOutputUnionParam{
+					OfResponseFunctionCallOutputItemArray: outputItems,
+				}
+			}
 		}
 
 		if item.OfMessage != nil || item.OfInputMessage != nil || item.OfFunctionCall != nil || item.OfFunctionCallOutput != nil {
@@ -617,6 +649,29 @@ func convertMessagesToResponseInput(messages []chat.Message) []responses.Respons
 	return input
 }
 
+// functionCallOutputItems converts a tool result with images into a list of
+// text and image items. It returns nil for text only results.
+func functionCallOutputItems(msg *chat.Message) responses.ResponseFunctionCallOutputItemListParam {
+	var items responses.ResponseFunctionCallOutputItemListParam
+	for _, part := range msg.MultiContent {
+		if part.Type == chat.MessagePartTypeImageURL && part.ImageURL != nil {
+			items = append(items, responses.ResponseFunctionCallOutputItemUnionParam{
+				OfInputImage: &responses.ResponseInputImageContentParam{
+					ImageURL: param.NewOpt(part.ImageURL.URL),
+					Detail:   responses.ResponseInputImageContentDetail(part.ImageURL.Detail),
+				},
+			})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	return append(responses.ResponseFunctionCallOutputItemListParam{{
+		OfInputText: &responses.ResponseInputTextContentParam{Text: msg.Content},
+	}}, items...)
+}
+
 // CreateEmbedding generates an embedding vector for the given text
 func (c *Client) CreateEmbedding(ctx context.Context, text string) (*base.EmbeddingResult, error) {
 	slog.Debug("Creating OpenAI embedding", "model", c.ModelConfig.Model, "text_length", len(text))
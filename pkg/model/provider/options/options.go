@@ -9,6 +9,7 @@ type ModelOptions struct {
 	structuredOutput *latest.StructuredOutput
 	generatingTitle  bool
 	maxTokens        int64
+	temperature      *float64
 }
 
 func (c *ModelOptions) Gateway() string {
@@ -27,6 +28,10 @@ func (c *ModelOptions) MaxTokens() int64 {
 	return c.maxTokens
 }
 
+func (c *ModelOptions) Temperature() *float64 {
+	return c.temperature
+}
+
 type Opt func(*ModelOptions)
 
 func WithGateway(gateway string) Opt {
@@ -53,6 +58,12 @@ func WithMaxTokens(maxTokens int64) Opt {
 	}
 }
 
+func WithTemperature(temperature float64) Opt {
+	return func(cfg *ModelOptions) {
+		cfg.temperature = &temperature
+	}
+}
+
 // FromModelOptions converts a concrete ModelOptions value into a slice of
 // Opt configuration functions. Later Opts override earlier ones when applied.
 func FromModelOptions(m ModelOptions) []Opt {
@@ -66,6 +77,9 @@ func FromModelOptions(m ModelOptions) []Opt {
 	if m.generatingTitle {
 		out = append(out, WithGeneratingTitle())
 	}
+	if m.temperature != nil {
+		out = append(out, WithTemperature(*m.temperature))
+	}
 	if m.maxTokens != 0 {
 		out = append(out, WithMaxTokens(m.maxTokens))
 	}
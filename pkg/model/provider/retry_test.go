@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"unexpected EOF", fmt.Errorf("error receiving from stream: %w", io.ErrUnexpectedEOF), true},
+		{"network error", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, true},
+		{"rate limited", fmt.Errorf("creating chat completion: %w", genai.APIError{Code: 429}), true},
+		{"server error", genai.APIError{Code: 503}, true},
+		{"bad request", genai.APIError{Code: 400}, false},
+		{"unauthorized", genai.APIError{Code: 401}, false},
+		{"other", errors.New("invalid tool schema"), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsRetryableError(tt.err), tt.name)
+	}
+}
@@ -0,0 +1,286 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/docker/cagent/pkg/config/latest"
+)
+
+// Decision is the outcome of checking a tool call against a permission policy.
+type Decision int
+
+const (
+	// Default means no rule matched and the regular approval flow applies.
+	Default Decision = iota
+	// Allow means the tool call runs without asking for confirmation.
+	Allow
+	// Ask means the tool call always requires confirmation, even in YOLO mode.
+	Ask
+	// Deny means the tool call is refused without being executed.
+	Deny
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Allow:
+		return "allow"
+	case Ask:
+		return "ask"
+	case Deny:
+		return "deny"
+	default:
+		return "default"
+	}
+}
+
+// Checker evaluates tool calls against an agent's permission policy.
+// A nil Checker has no rules and always returns Default.
+type Checker struct {
+	allow []rule
+	ask   []rule
+	deny  []rule
+}
+
+type rule struct {
+	tool *regexp.Regexp
+	args map[string]argPattern
+}
+
+type argPattern struct {
+	re *regexp.Regexp
+	// wildcard is true if the pattern has a `*` or a `?`.
+	wildcard bool
+}
+
+// NewChecker compiles the rules of a permissions configuration.
+func NewChecker(cfg *latest.PermissionsConfig) (*Checker, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	allow, err := compileRules(cfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	ask, err := compileRules(cfg.Ask)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := compileRules(cfg.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checker{
+		allow: allow,
+		ask:   ask,
+		deny:  deny,
+	}, nil
+}
+
+// Check returns the decision for a call to the given tool with the given JSON arguments.
+// Deny rules are evaluated first, then ask rules, then allow rules.
+func (c *Checker) Check(toolName, arguments string) Decision {
+	if c == nil {
+		return Default
+	}
+
+	var args map[string]any
+	if arguments != "" {
+		// Arguments that can't be parsed only match rules without argument patterns.
+		_ = json.Unmarshal([]byte(arguments), &args)
+	}
+
+	switch {
+	case matchAny(c.deny, toolName, args, false):
+		return Deny
+	case matchAny(c.ask, toolName, args, false):
+		return Ask
+	case matchAny(c.allow, toolName, args, true):
+		return Allow
+	default:
+		return Default
+	}
+}
+
+func compileRules(rules []latest.PermissionRule) ([]rule, error) {
+	compiled := make([]rule, 0, len(rules))
+	for _, r := range rules {
+		tool, err := compilePattern(r.Tool)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", r.Tool, err)
+		}
+
+		args := make(map[string]argPattern, len(r.Args))
+		for name, pattern := range r.Args {
+			var (
+				re  *regexp.Regexp
+				err error
+			)
+			if isPathArg(name) {
+				re, err = compilePathPattern(cleanPath(pattern))
+			} else {
+				re, err = compilePattern(pattern)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q for argument %q of tool %q: %w", pattern, name, r.Tool, err)
+			}
+			args[name] = argPattern{re: re, wildcard: strings.ContainsAny(pattern, "*?")}
+		}
+
+		compiled = append(compiled, rule{tool: tool, args: args})
+	}
+	return compiled, nil
+}
+
+// compilePattern turns a glob pattern into an anchored regular expression.
+// `*` matches any sequence of characters, including `/`, and `?` matches a single character.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// compilePathPattern turns a glob pattern for paths into an anchored regular expression.
+// `*` and `?` don't match `/`, `**` matches any sequence of characters and `**/` any
+// number of directories, including none.
+func compilePathPattern(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+func matchAny(rules []rule, toolName string, args map[string]any, allow bool) bool {
+	for _, r := range rules {
+		if r.matches(toolName, args, allow) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a tool call matches the rule. Array arguments match when
+// all of their elements match (allow rules) or when any of them does. Paths that
+// escape their base directory never match allow rules, and neither do commands
+// chaining other commands, unless the rule spells them out without wildcards.
+func (r rule) matches(toolName string, args map[string]any, allow bool) bool {
+	if !r.tool.MatchString(toolName) {
+		return false
+	}
+
+	for name, pattern := range r.args {
+		value, ok := args[name]
+		if !ok {
+			return false
+		}
+
+		values, isList := value.([]any)
+		if !isList {
+			values = []any{value}
+		}
+		if len(values) == 0 {
+			return false
+		}
+
+		pathArg := isPathArg(name)
+		commandArg := isCommandArg(name)
+		matched := allow
+		for _, v := range values {
+			value := stringify(v)
+			if pathArg {
+				value = cleanPath(value)
+			}
+			m := pattern.re.MatchString(value)
+			if allow {
+				m = m && !(pathArg && escapes(value)) && !(commandArg && pattern.wildcard && hasShellOperators(value))
+			}
+			if allow {
+				matched = matched && m
+			} else {
+				matched = matched || m
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stringify(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// isPathArg reports whether an argument holds file paths, based on its name.
+func isPathArg(name string) bool {
+	switch name {
+	case "path", "paths", "file", "files", "dir", "dirs", "filename", "directory":
+		return true
+	}
+	for _, suffix := range []string{"_path", "_paths", "_file", "_files", "_dir", "_dirs"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommandArg reports whether an argument holds a shell command, based on its name.
+func isCommandArg(name string) bool {
+	return name == "cmd" || name == "command" || strings.HasSuffix(name, "_cmd") || strings.HasSuffix(name, "_command")
+}
+
+// hasShellOperators reports whether a command could run other commands than the
+// one it starts with: `git status; rm -rf ~`, `git status && curl ...`, `$(...)`, etc.
+func hasShellOperators(cmd string) bool {
+	return strings.ContainsAny(cmd, ";&|`$<>()\n\r")
+}
+
+// cleanPath makes `./docs/x`, `docs//x` and `docs/y/../x` equivalent to `docs/x`.
+func cleanPath(p string) string {
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+// escapes reports whether a cleaned path still has a `..` segment, i.e. points
+// outside of the directory it's relative to.
+func escapes(p string) bool {
+	segments := strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' })
+	return slices.Contains(segments, "..")
+}
@@ -0,0 +1,438 @@
+// Package ann implements an approximate nearest neighbour index of embeddings,
+// used by the vector strategies to avoid comparing queries with every chunk.
+package ann
+
+import (
+	"cmp"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Key identifies the chunk of a document an embedding was computed for.
+type Key struct {
+	SourcePath string
+	ChunkIndex int
+}
+
+// Result is a chunk found by Search, with its cosine similarity to the query.
+type Result struct {
+	Key        Key
+	Similarity float64
+}
+
+// Options configures an HNSW index.
+type Options struct {
+	// Dimensions of the embeddings
+	Dimensions int
+	// M is the number of neighbours of each node (default: 16)
+	M int
+	// EfConstruction is the size of the candidate list when inserting (default: 200)
+	EfConstruction int
+	// EfSearch is the size of the candidate list when searching (default: 64).
+	// Higher values improve recall at the cost of speed.
+	EfSearch int
+}
+
+// HNSW is a Hierarchical Navigable Small World graph of normalized embeddings,
+// searched by cosine similarity. It's safe for concurrent use.
+//
+// Deleted chunks are only marked as such, and skipped in results, until they
+// make up half of the graph, which is then rebuilt.
+type HNSW struct {
+	mu sync.RWMutex
+
+	opts      Options
+	levelMult float64
+	rng       *rand.Rand
+
+	nodes    []*node
+	entry    int32
+	maxLevel int
+	bySource map[string][]int32
+	deleted  int
+}
+
+type node struct {
+	Key       Key
+	Vector    []float32
+	Neighbors [][]int32 // by level
+	Deleted   bool
+}
+
+// New creates an empty index.
+func New(opts Options) *HNSW {
+	opts.M = cmp.Or(opts.M, 16)
+	opts.EfConstruction = cmp.Or(opts.EfConstruction, 200)
+	opts.EfSearch = cmp.Or(opts.EfSearch, 64)
+
+	return &HNSW{
+		opts:      opts,
+		levelMult: 1 / math.Log(float64(opts.M)),
+		rng:       rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		entry:     -1,
+		bySource:  map[string][]int32{},
+	}
+}
+
+// Len returns the number of chunks in the index, deleted ones excluded.
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes) - h.deleted
+}
+
+// Add inserts the embedding of a chunk. Adding a chunk twice replaces its embedding.
+func (h *HNSW) Add(key Key, embedding []float64) error {
+	if len(embedding) != h.opts.Dimensions {
+		return fmt.Errorf("embedding dimension mismatch: got %d, expected %d", len(embedding), h.opts.Dimensions)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range h.bySource[key.SourcePath] {
+		if h.nodes[id].Key == key {
+			h.delete(id)
+			break
+		}
+	}
+	h.insert(&node{Key: key, Vector: normalize(embedding)})
+	h.compactIfNeeded()
+	return nil
+}
+
+// DeleteSource removes all the chunks of a document.
+func (h *HNSW) DeleteSource(sourcePath string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range slices.Clone(h.bySource[sourcePath]) {
+		h.delete(id)
+	}
+	h.compactIfNeeded()
+}
+
+// Search returns the k chunks most similar to the query, most similar first.
+func (h *HNSW) Search(query []float64, k int) []Result {
+	if len(query) != h.opts.Dimensions || k <= 0 {
+		return nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry < 0 {
+		return nil
+	}
+
+	q := normalize(query)
+	ep := h.entry
+	for level := h.maxLevel; level > 0; level-- {
+		ep = h.greedy(q, ep, level)
+	}
+
+	// Deleted nodes are traversed but not returned: look a bit further for them.
+	ef := max(h.opts.EfSearch, k) + min(h.deleted, k)
+	candidates := h.searchLayer(q, ep, ef, 0)
+
+	var results []Result
+	for _, c := range candidates {
+		n := h.nodes[c.id]
+		if n.Deleted {
+			continue
+		}
+		results = append(results, Result{Key: n.Key, Similarity: float64(c.sim)})
+		if len(results) == k {
+			break
+		}
+	}
+	return results
+}
+
+func (h *HNSW) insert(n *node) {
+	id := int32(len(h.nodes))
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+	n.Neighbors = make([][]int32, level+1)
+	h.nodes = append(h.nodes, n)
+	h.bySource[n.Key.SourcePath] = append(h.bySource[n.Key.SourcePath], id)
+
+	if h.entry < 0 {
+		h.entry = id
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(n.Vector, ep, l)
+	}
+
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(n.Vector, ep, h.opts.EfConstruction, l)
+		ep = candidates[0].id
+
+		maxConn := h.maxConnections(l)
+		for _, c := range candidates[:min(len(candidates), h.opts.M)] {
+			n.Neighbors[l] = append(n.Neighbors[l], c.id)
+
+			neighbour := h.nodes[c.id]
+			neighbour.Neighbors[l] = append(neighbour.Neighbors[l], id)
+			if len(neighbour.Neighbors[l]) > maxConn {
+				neighbour.Neighbors[l] = h.closest(neighbour.Vector, neighbour.Neighbors[l], maxConn)
+			}
+		}
+	}
+
+	if level > h.maxLevel {
+		h.entry = id
+		h.maxLevel = level
+	}
+}
+
+func (h *HNSW) maxConnections(level int) int {
+	if level == 0 {
+		return 2 * h.opts.M
+	}
+	return h.opts.M
+}
+
+// greedy walks a layer towards the node the most similar to q.
+func (h *HNSW) greedy(q []float32, ep int32, level int) int32 {
+	best := dot(q, h.nodes[ep].Vector)
+	for changed := true; changed; {
+		changed = false
+		for _, id := range h.nodes[ep].Neighbors[level] {
+			if sim := dot(q, h.nodes[id].Vector); sim > best {
+				best, ep, changed = sim, id, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns the ef nodes of a layer the most similar to q, most similar first.
+func (h *HNSW) searchLayer(q []float32, ep int32, ef, level int) []candidate {
+	visited := map[int32]bool{ep: true}
+	first := candidate{id: ep, sim: dot(q, h.nodes[ep].Vector)}
+	toVisit := &maxHeap{first}
+	found := &minHeap{first}
+
+	for toVisit.Len() > 0 {
+		c := heap.Pop(toVisit).(candidate)
+		if found.Len() >= ef && c.sim < (*found)[0].sim {
+			break
+		}
+
+		for _, id := range h.nodes[c.id].Neighbors[level] {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+
+			sim := dot(q, h.nodes[id].Vector)
+			if found.Len() < ef || sim > (*found)[0].sim {
+				heap.Push(toVisit, candidate{id: id, sim: sim})
+				heap.Push(found, candidate{id: id, sim: sim})
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	results := []candidate(*found)
+	slices.SortFunc(results, func(a, b candidate) int { return cmp.Compare(b.sim, a.sim) })
+	return results
+}
+
+// closest keeps the n ids the most similar to v.
+func (h *HNSW) closest(v []float32, ids []int32, n int) []int32 {
+	candidates := make([]candidate, len(ids))
+	for i, id := range ids {
+		candidates[i] = candidate{id: id, sim: dot(v, h.nodes[id].Vector)}
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int { return cmp.Compare(b.sim, a.sim) })
+
+	kept := make([]int32, n)
+	for i := range kept {
+		kept[i] = candidates[i].id
+	}
+	return kept
+}
+
+func (h *HNSW) delete(id int32) {
+	n := h.nodes[id]
+	if n.Deleted {
+		return
+	}
+	n.Deleted = true
+	h.deleted++
+
+	ids := slices.DeleteFunc(h.bySource[n.Key.SourcePath], func(other int32) bool { return other == id })
+	if len(ids) == 0 {
+		delete(h.bySource, n.Key.SourcePath)
+	} else {
+		h.bySource[n.Key.SourcePath] = ids
+	}
+}
+
+// compactIfNeeded rebuilds the graph without the deleted nodes once they make up half of it.
+func (h *HNSW) compactIfNeeded() {
+	if h.deleted == 0 || h.deleted*2 < len(h.nodes) {
+		return
+	}
+
+	nodes := h.nodes
+	h.nodes = nil
+	h.entry = -1
+	h.maxLevel = 0
+	h.bySource = map[string][]int32{}
+	h.deleted = 0
+
+	for _, n := range nodes {
+		if !n.Deleted {
+			h.insert(&node{Key: n.Key, Vector: n.Vector})
+		}
+	}
+}
+
+// indexFile is the on-disk representation of an index.
+type indexFile struct {
+	Options  Options
+	Nodes    []*node
+	Entry    int32
+	MaxLevel int
+}
+
+// Save writes the index to a file.
+func (h *HNSW) Save(path string) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write then rename so that a crash never leaves a half-written index.
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(f).Encode(indexFile{
+		Options:  h.opts,
+		Nodes:    h.nodes,
+		Entry:    h.entry,
+		MaxLevel: h.maxLevel,
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// ErrIncompatible is returned by Load when the saved index was built with other options.
+var ErrIncompatible = errors.New("index built with different options")
+
+// Load reads an index saved with Save. The options of the saved index must match opts.
+func Load(path string, opts Options) (*HNSW, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var saved indexFile
+	if err := gob.NewDecoder(f).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	h := New(opts)
+	if saved.Options.Dimensions != h.opts.Dimensions || saved.Options.M != h.opts.M {
+		return nil, ErrIncompatible
+	}
+
+	h.nodes = saved.Nodes
+	h.entry = saved.Entry
+	h.maxLevel = saved.MaxLevel
+	for id, n := range h.nodes {
+		if n.Deleted {
+			h.deleted++
+			continue
+		}
+		h.bySource[n.Key.SourcePath] = append(h.bySource[n.Key.SourcePath], int32(id))
+	}
+	return h, nil
+}
+
+func normalize(v []float64) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+
+	normalized := make([]float32, len(v))
+	if norm == 0 {
+		return normalized
+	}
+	for i, x := range v {
+		normalized[i] = float32(x / norm)
+	}
+	return normalized
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+type candidate struct {
+	id  int32
+	sim float32
+}
+
+// maxHeap pops the most similar candidate first.
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].sim > h[j].sim }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// minHeap pops the least similar candidate first.
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].sim < h[j].sim }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
@@ -0,0 +1,140 @@
+package ann
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/rag/database"
+)
+
+func randomVectors(n, dims int) [][]float64 {
+	rng := rand.New(rand.NewPCG(1, 2))
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		vectors[i] = make([]float64, dims)
+		for j := range vectors[i] {
+			vectors[i][j] = rng.NormFloat64()
+		}
+	}
+	return vectors
+}
+
+func key(i int) Key {
+	return Key{SourcePath: fmt.Sprintf("file%d.md", i/10), ChunkIndex: i % 10}
+}
+
+func exactSearch(vectors [][]float64, query []float64, k int) []Key {
+	type scored struct {
+		key Key
+		sim float64
+	}
+	var all []scored
+	for i, v := range vectors {
+		all = append(all, scored{key(i), database.CosineSimilarity(query, v)})
+	}
+	slices.SortFunc(all, func(a, b scored) int { return cmp.Compare(b.sim, a.sim) })
+
+	var keys []Key
+	for _, s := range all[:k] {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+func TestHNSW_Recall(t *testing.T) {
+	t.Parallel()
+
+	const dims = 32
+	vectors := randomVectors(2000, dims)
+	index := New(Options{Dimensions: dims})
+	for i, v := range vectors {
+		require.NoError(t, index.Add(key(i), v))
+	}
+	assert.Equal(t, len(vectors), index.Len())
+
+	var found, total int
+	for _, query := range randomVectors(50, dims) {
+		expected := exactSearch(vectors, query, 10)
+		results := index.Search(query, 10)
+		require.Len(t, results, 10)
+		assert.InDelta(t, database.CosineSimilarity(query, vectors[slices.Index(keysOf(vectors), results[0].Key)]), results[0].Similarity, 1e-5)
+
+		for _, r := range results {
+			if slices.Contains(expected, r.Key) {
+				found++
+			}
+		}
+		total += len(expected)
+	}
+
+	recall := float64(found) / float64(total)
+	assert.Greater(t, recall, 0.9, "recall too low: %f", recall)
+}
+
+func keysOf(vectors [][]float64) []Key {
+	keys := make([]Key, len(vectors))
+	for i := range vectors {
+		keys[i] = key(i)
+	}
+	return keys
+}
+
+func TestHNSW_DeleteSource(t *testing.T) {
+	t.Parallel()
+
+	vectors := randomVectors(100, 8)
+	index := New(Options{Dimensions: 8})
+	for i, v := range vectors {
+		require.NoError(t, index.Add(key(i), v))
+	}
+
+	index.DeleteSource("file0.md")
+	assert.Equal(t, 90, index.Len())
+	for _, r := range index.Search(vectors[0], 20) {
+		assert.NotEqual(t, "file0.md", r.Key.SourcePath)
+	}
+
+	// Re-adding a chunk replaces it.
+	require.NoError(t, index.Add(key(10), vectors[0]))
+	assert.Equal(t, 90, index.Len())
+	assert.Equal(t, key(10), index.Search(vectors[0], 1)[0].Key)
+
+	// The graph is rebuilt once half of it is deleted.
+	for i := range 6 {
+		index.DeleteSource(fmt.Sprintf("file%d.md", i+1))
+	}
+	assert.Equal(t, 30, index.Len())
+	assert.Less(t, len(index.nodes), 100)
+	assert.Len(t, index.Search(vectors[99], 50), 30)
+
+	require.Error(t, index.Add(key(0), []float64{1, 2}))
+}
+
+func TestHNSW_SaveLoad(t *testing.T) {
+	t.Parallel()
+
+	vectors := randomVectors(200, 16)
+	index := New(Options{Dimensions: 16})
+	for i, v := range vectors {
+		require.NoError(t, index.Add(key(i), v))
+	}
+	index.DeleteSource("file3.md")
+
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	require.NoError(t, index.Save(path))
+
+	loaded, err := Load(path, Options{Dimensions: 16})
+	require.NoError(t, err)
+	assert.Equal(t, index.Len(), loaded.Len())
+	assert.Equal(t, index.Search(vectors[42], 5), loaded.Search(vectors[42], 5))
+
+	_, err = Load(path, Options{Dimensions: 32})
+	require.ErrorIs(t, err, ErrIncompatible)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 
 	"github.com/docker/cagent/pkg/config/latest"
 	"github.com/docker/cagent/pkg/environment"
 	"github.com/docker/cagent/pkg/model/provider"
 	"github.com/docker/cagent/pkg/rag/rerank"
+	"github.com/docker/cagent/pkg/rag/source"
 	"github.com/docker/cagent/pkg/rag/strategy"
 	"github.com/docker/cagent/pkg/rag/types"
 )
@@ -19,6 +21,7 @@ type ManagersBuildConfig struct {
 	ModelsGateway string
 	Env           environment.Provider
 	Models        map[string]latest.ModelConfig // Model configurations from config
+	CacheDir      string                        // Directory caching remote documents (defaults to source.DefaultCacheDir())
 }
 
 // NewManagers constructs all RAG managers defined in the config.
@@ -39,6 +42,12 @@ func NewManagers(
 			return nil, fmt.Errorf("no strategies configured for RAG %q", ragName)
 		}
 
+		// Remote documents are indexed from their local cache
+		ragCfg, sources, err := resolveRemoteDocs(ragCfg, buildCfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid docs for RAG %q: %w", ragName, err)
+		}
+
 		// Build context for strategy builders
 		strategyBuildCtx := strategy.BuildContext{
 			RAGName:       ragName,
@@ -59,6 +68,7 @@ func NewManagers(
 		if err != nil {
 			return nil, fmt.Errorf("failed to build manager config for RAG %q: %w", ragName, err)
 		}
+		managerCfg.Sources = sources
 
 		// The strategyEvents channel is so the manager can convert strategy events to RAG events.
 		manager, err := New(ctx, ragName, managerCfg, strategyEvents)
@@ -81,6 +91,55 @@ func NewManagers(
 	return managers, nil
 }
 
+// resolveRemoteDocs replaces the remote documents (URLs, sitemaps and git repositories)
+// of a RAG config, shared or strategy-specific, with the directories caching them.
+func resolveRemoteDocs(ragCfg latest.RAGConfig, buildCfg ManagersBuildConfig) (latest.RAGConfig, []*source.Source, error) {
+	cacheDir := buildCfg.CacheDir
+	if cacheDir == "" {
+		cacheDir = source.DefaultCacheDir()
+	}
+
+	var sources []*source.Source
+	resolve := func(docs []string) ([]string, error) {
+		var resolved []string
+		for _, doc := range docs {
+			if !source.IsRemote(doc) {
+				resolved = append(resolved, doc)
+				continue
+			}
+
+			idx := slices.IndexFunc(sources, func(s *source.Source) bool { return s.String() == doc })
+			if idx < 0 {
+				src, err := source.New(doc, cacheDir)
+				if err != nil {
+					return nil, err
+				}
+				sources = append(sources, src)
+				idx = len(sources) - 1
+			}
+			resolved = append(resolved, sources[idx].Path())
+		}
+		return resolved, nil
+	}
+
+	docs, err := resolve(ragCfg.Docs)
+	if err != nil {
+		return ragCfg, nil, err
+	}
+	ragCfg.Docs = docs
+
+	strategies := make([]latest.RAGStrategyConfig, len(ragCfg.Strategies))
+	for i, strategyCfg := range ragCfg.Strategies {
+		if strategyCfg.Docs, err = resolve(strategyCfg.Docs); err != nil {
+			return ragCfg, nil, err
+		}
+		strategies[i] = strategyCfg
+	}
+	ragCfg.Strategies = strategies
+
+	return ragCfg, sources, nil
+}
+
 // buildManagerConfig constructs a rag.Manager Config from the configuration and strategies.
 func buildManagerConfig(
 	ctx context.Context,
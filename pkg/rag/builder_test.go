@@ -0,0 +1,78 @@
+package rag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/config/latest"
+)
+
+func TestNewManagers_RemoteDocs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		content atomic.Value
+		fetches atomic.Int32
+	)
+	content.Store("The alpha release notes.")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := content.Load().(string)
+		if r.Header.Get("If-None-Match") == body {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "text/markdown")
+		w.Header().Set("ETag", body)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var ragCfg latest.RAGConfig
+	require.NoError(t, yaml.Unmarshal([]byte(`
+docs:
+  - `+server.URL+`/notes.md
+  - `+server.URL+`/notes.md
+strategies:
+  - type: bm25
+    database: `+filepath.Join(dir, "bm25.db")+`
+    threshold: 0
+`), &ragCfg))
+
+	managers, err := NewManagers(t.Context(), &latest.Config{RAG: map[string]latest.RAGConfig{"notes": ragCfg}}, ManagersBuildConfig{
+		ParentDir: dir,
+		CacheDir:  filepath.Join(dir, "cache"),
+	})
+	require.NoError(t, err)
+	manager := managers["notes"]
+	t.Cleanup(func() { _ = manager.Close() })
+	require.Len(t, manager.config.Sources, 1)
+
+	require.NoError(t, manager.Initialize(t.Context()))
+	results, err := manager.Query(t.Context(), "alpha")
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Contains(t, results[0].Document.Content, "alpha")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// Unchanged documents are revalidated but not downloaded again.
+	require.NoError(t, manager.CheckAndReindexChangedFiles(t.Context()))
+	assert.Equal(t, int32(1), fetches.Load())
+
+	content.Store("The beta release notes.")
+	require.NoError(t, manager.CheckAndReindexChangedFiles(t.Context()))
+	assert.Equal(t, int32(2), fetches.Load())
+
+	results, err = manager.Query(t.Context(), "beta")
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Contains(t, results[0].Document.Content, "beta")
+}
@@ -0,0 +1,163 @@
+package chunk
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MarkdownDocumentProcessor chunks Markdown documents along their sections, so
+// that chunks don't mix unrelated sections. Each chunk has the path of headings
+// leading to its section as "heading_path" metadata (e.g. "Setup > Linux").
+// Sections larger than the chunk size are split with the text chunker.
+type MarkdownDocumentProcessor struct {
+	size int
+	text *TextDocumentProcessor
+}
+
+// NewMarkdownDocumentProcessor creates a heading-aware Markdown document processor
+func NewMarkdownDocumentProcessor(size, overlap int, respectWordBoundaries bool) *MarkdownDocumentProcessor {
+	text := NewTextDocumentProcessor(size, overlap, respectWordBoundaries)
+	return &MarkdownDocumentProcessor{
+		size: text.size,
+		text: text,
+	}
+}
+
+type markdownSection struct {
+	headings []string
+	content  strings.Builder
+}
+
+// Process implements DocumentProcessor
+func (m *MarkdownDocumentProcessor) Process(_ string, content []byte) ([]Chunk, error) {
+	var chunks []Chunk
+	add := func(headings []string, text string) {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return
+		}
+
+		var metadata map[string]string
+		if len(headings) > 0 {
+			metadata = map[string]string{"heading_path": strings.Join(headings, " > ")}
+		}
+
+		if utf8.RuneCountInString(text) <= m.size {
+			chunks = append(chunks, Chunk{Index: len(chunks), Content: text, Metadata: metadata})
+			return
+		}
+		for _, ch := range m.text.chunkText(text) {
+			chunks = append(chunks, Chunk{Index: len(chunks), Content: ch.Content, Metadata: metadata})
+		}
+	}
+
+	// Small sections are merged with their subsections, as long as they fit in a chunk.
+	var pending *markdownSection
+	for _, section := range splitMarkdownSections(string(content)) {
+		if pending != nil && isSubsection(section.headings, pending.headings) &&
+			utf8.RuneCountInString(pending.content.String())+utf8.RuneCountInString(section.content.String()) <= m.size {
+			pending.content.WriteString(section.content.String())
+			continue
+		}
+		if pending != nil {
+			add(pending.headings, pending.content.String())
+		}
+		pending = section
+	}
+	if pending != nil {
+		add(pending.headings, pending.content.String())
+	}
+
+	return chunks, nil
+}
+
+// splitMarkdownSections splits a document at each ATX heading (# Title), ignoring
+// lines that only look like headings inside fenced code blocks.
+func splitMarkdownSections(text string) []*markdownSection {
+	var sections []*markdownSection
+	var path []string // headings by level - 1
+	current := &markdownSection{}
+	var fence string
+
+	for line := range strings.Lines(text) {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			current.content.WriteString(line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			current.content.WriteString(line)
+			continue
+		}
+
+		level, title := markdownHeading(line)
+		if level == 0 {
+			current.content.WriteString(line)
+			continue
+		}
+
+		sections = append(sections, current)
+
+		if len(path) >= level {
+			path = path[:level-1]
+		}
+		for len(path) < level-1 {
+			// Skipped levels, e.g. a ### right under a #.
+			path = append(path, "")
+		}
+		path = append(path, title)
+
+		current = &markdownSection{headings: compactHeadings(path)}
+		current.content.WriteString(line)
+	}
+
+	return append(sections, current)
+}
+
+// markdownHeading returns the level and title of an ATX heading, or 0 if the line isn't one.
+func markdownHeading(line string) (int, string) {
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return 0, ""
+	}
+	trimmed := strings.TrimSpace(line)
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(trimmed) && trimmed[level] != ' ' && trimmed[level] != '\t') {
+		return 0, ""
+	}
+
+	title := strings.TrimSpace(trimmed[level:])
+	// Closing sequence, e.g. "## Title ##"
+	title = strings.TrimSpace(strings.TrimRight(title, "#"))
+	return level, title
+}
+
+func compactHeadings(path []string) []string {
+	headings := make([]string, 0, len(path))
+	for _, heading := range path {
+		if heading != "" {
+			headings = append(headings, heading)
+		}
+	}
+	return headings
+}
+
+func isSubsection(headings, parent []string) bool {
+	if len(parent) == 0 || len(headings) <= len(parent) {
+		return false
+	}
+	for i := range parent {
+		if headings[i] != parent[i] {
+			return false
+		}
+	}
+	return true
+}
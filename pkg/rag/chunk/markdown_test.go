@@ -0,0 +1,95 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownDocumentProcessor_HeadingPath(t *testing.T) {
+	t.Parallel()
+
+	doc := `Intro paragraph.
+
+# Setup
+
+` + strings.Repeat("Setup instructions. ", 5) + `
+
+## Linux
+
+Install the package.
+
+## macOS
+
+Use brew.
+
+` + "```sh\n# not a heading\nbrew install cagent\n```" + `
+
+# Usage
+
+Run it.
+`
+
+	processor := NewMarkdownDocumentProcessor(120, 0, true)
+	chunks, err := processor.Process("doc.md", []byte(doc))
+	require.NoError(t, err)
+
+	var paths []string
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		paths = append(paths, chunk.Metadata["heading_path"])
+	}
+	assert.Equal(t, []string{"", "Setup", "Setup > Linux", "Setup > macOS", "Usage"}, paths)
+
+	assert.Equal(t, "Intro paragraph.", chunks[0].Content)
+	assert.Contains(t, chunks[3].Content, "# not a heading")
+	assert.Equal(t, "# Usage\n\nRun it.", chunks[4].Content)
+}
+
+func TestMarkdownDocumentProcessor_MergesSmallSubsections(t *testing.T) {
+	t.Parallel()
+
+	doc := "# API\n\nOverview.\n\n## GET\n\nReads.\n\n## POST\n\nWrites.\n"
+
+	chunks, err := NewMarkdownDocumentProcessor(1000, 0, true).Process("api.md", []byte(doc))
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "API", chunks[0].Metadata["heading_path"])
+	assert.Contains(t, chunks[0].Content, "## POST")
+}
+
+func TestMarkdownDocumentProcessor_SplitsLargeSections(t *testing.T) {
+	t.Parallel()
+
+	doc := "## Big\n\n" + strings.Repeat("word ", 100)
+
+	chunks, err := NewMarkdownDocumentProcessor(100, 0, true).Process("big.md", []byte(doc))
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.Equal(t, "Big", chunk.Metadata["heading_path"])
+		assert.LessOrEqual(t, len(chunk.Content), 100)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	text := NewTextDocumentProcessor(1000, 0, false)
+	markdown := NewMarkdownDocumentProcessor(1000, 0, false)
+
+	registry := NewRegistry(text)
+	registry.Register(markdown, ".md", ".MDX")
+
+	assert.Same(t, markdown, registry.Processor("docs/README.MD"))
+	assert.Same(t, markdown, registry.Processor("page.mdx"))
+	assert.Same(t, text, registry.Processor("main.go"))
+	assert.Same(t, text, registry.Processor("Makefile"))
+
+	chunks, err := registry.Process("README.md", []byte("# Title\n\nBody"))
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "Title", chunks[0].Metadata["heading_path"])
+}
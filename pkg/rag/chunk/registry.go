@@ -0,0 +1,41 @@
+package chunk
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Registry is a DocumentProcessor that delegates to the processor registered
+// for the extension of each file, or to a fallback processor.
+type Registry struct {
+	processors map[string]DocumentProcessor
+	fallback   DocumentProcessor
+}
+
+// NewRegistry creates a registry processing the files of unregistered types with fallback.
+func NewRegistry(fallback DocumentProcessor) *Registry {
+	return &Registry{
+		processors: make(map[string]DocumentProcessor),
+		fallback:   fallback,
+	}
+}
+
+// Register sets the processor of the files with the given extensions (e.g. ".pdf").
+func (r *Registry) Register(processor DocumentProcessor, extensions ...string) {
+	for _, ext := range extensions {
+		r.processors[strings.ToLower(ext)] = processor
+	}
+}
+
+// Processor returns the processor of a file.
+func (r *Registry) Processor(path string) DocumentProcessor {
+	if processor, ok := r.processors[strings.ToLower(filepath.Ext(path))]; ok {
+		return processor
+	}
+	return r.fallback
+}
+
+// Process implements DocumentProcessor.
+func (r *Registry) Process(path string, content []byte) ([]Chunk, error) {
+	return r.Processor(path).Process(path, content)
+}
@@ -14,6 +14,10 @@ type Document struct {
 	Content    string `json:"content"`
 	FileHash   string `json:"file_hash"`
 	CreatedAt  string `json:"created_at"`
+	// StartLine and EndLine are the lines of the source document covered by the chunk,
+	// when known (code-aware chunking).
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
 }
 
 // SearchResult represents a document with its relevance score.
@@ -0,0 +1,150 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxDOCXDocumentSize limits the size of the uncompressed document, against zip bombs.
+const maxDOCXDocumentSize = 64 << 20
+
+// DOCX extracts the text of a Word document as Markdown: headings are kept, and
+// table cells are separated with pipes.
+func DOCX(content []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX archive: %w", err)
+	}
+
+	file, err := archive.Open("word/document.xml")
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX archive: %w", err)
+	}
+	defer file.Close()
+
+	document, err := io.ReadAll(io.LimitReader(file, maxDOCXDocumentSize+1))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX archive: %w", err)
+	}
+	if len(document) > maxDOCXDocumentSize {
+		return "", fmt.Errorf("DOCX document larger than %d bytes once uncompressed", maxDOCXDocumentSize)
+	}
+
+	return docxToMarkdown(bytes.NewReader(document))
+}
+
+func docxToMarkdown(r io.Reader) (string, error) {
+	const ns = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+	var (
+		out       strings.Builder
+		paragraph strings.Builder
+		heading   int
+		inText    bool
+		cells     []string
+		inCell    int
+	)
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid DOCX document: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space != ns {
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				heading = 0
+			case "pStyle":
+				heading = docxHeadingLevel(docxAttr(t, "val"))
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteByte('\t')
+			case "br", "cr":
+				paragraph.WriteByte('\n')
+			case "tr":
+				if inCell == 0 {
+					// Nested tables are flattened in the cells of the outer table.
+					cells = nil
+				}
+			case "tc":
+				inCell++
+				cells = append(cells, "")
+			}
+		case xml.EndElement:
+			if t.Name.Space != ns {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				switch {
+				case inCell > 0:
+					last := len(cells) - 1
+					cells[last] = strings.TrimSpace(cells[last] + " " + text)
+				case text == "":
+				case heading > 0:
+					out.WriteString(strings.Repeat("#", heading) + " " + text + "\n\n")
+				default:
+					out.WriteString(text + "\n\n")
+				}
+			case "tc":
+				inCell--
+			case "tr":
+				if inCell == 0 && len(cells) > 0 {
+					out.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+				}
+			case "tbl":
+				if inCell == 0 {
+					out.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		}
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
+func docxAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// docxHeadingLevel returns the level of the built-in heading styles (Title, Heading1..6).
+func docxHeadingLevel(style string) int {
+	style = strings.ToLower(style)
+	if style == "title" {
+		return 1
+	}
+	level, err := strconv.Atoi(strings.TrimPrefix(style, "heading"))
+	if err != nil || !strings.HasPrefix(style, "heading") || level < 1 || level > 6 {
+		return 0
+	}
+	return level
+}
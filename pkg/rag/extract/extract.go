@@ -0,0 +1,43 @@
+// Package extract converts documents that aren't plain text (PDF, HTML, DOCX)
+// to text, or Markdown, before they are chunked.
+package extract
+
+import (
+	"fmt"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+
+	"github.com/docker/cagent/pkg/rag/chunk"
+)
+
+// Extractor converts the content of a document to text.
+type Extractor func(content []byte) (string, error)
+
+// Processor is a chunk.DocumentProcessor extracting the text of documents
+// before chunking it with another processor.
+type Processor struct {
+	extract Extractor
+	next    chunk.DocumentProcessor
+}
+
+// NewProcessor creates a processor chunking the text extracted from documents with next.
+func NewProcessor(extract Extractor, next chunk.DocumentProcessor) *Processor {
+	return &Processor{
+		extract: extract,
+		next:    next,
+	}
+}
+
+// Process implements chunk.DocumentProcessor.
+func (p *Processor) Process(path string, content []byte) ([]chunk.Chunk, error) {
+	text, err := p.extract(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text from %s: %w", path, err)
+	}
+	return p.next.Process(path, []byte(text))
+}
+
+// HTML converts an HTML document to Markdown, keeping headings, lists, links and tables.
+func HTML(content []byte) (string, error) {
+	return htmltomarkdown.ConvertString(string(content))
+}
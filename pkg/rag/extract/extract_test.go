@@ -0,0 +1,74 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/rag/chunk"
+)
+
+func buildDOCX(t *testing.T, body string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, err := archive.Create("word/document.xml")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body + `</w:body></w:document>`))
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+func TestDOCX(t *testing.T) {
+	t.Parallel()
+
+	content := buildDOCX(t, `
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">First </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>paragraph</w:t></w:r><w:r><w:tab/><w:t>tabbed</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Results</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Name</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Score</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Alice</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>42</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+<w:p><w:r><w:t>Line</w:t><w:br/><w:t>break</w:t></w:r></w:p>`)
+
+	text, err := DOCX(content)
+	require.NoError(t, err)
+	assert.Equal(t, "# Report\n\nFirst paragraph\ttabbed\n\n## Results\n\n| Name | Score |\n| Alice | 42 |\n\nLine\nbreak", text)
+
+	_, err = DOCX([]byte("not a zip"))
+	require.ErrorContains(t, err, "invalid DOCX archive")
+
+	_, err = DOCX(buildDOCX(t, strings.Repeat(" ", maxDOCXDocumentSize)))
+	require.ErrorContains(t, err, "larger than")
+}
+
+func TestHTML(t *testing.T) {
+	t.Parallel()
+
+	text, err := HTML([]byte("<html><body><h1>Title</h1><p>Some <b>bold</b> text.</p><ul><li>One</li></ul></body></html>"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Title\n\nSome **bold** text.\n\n- One", text)
+}
+
+func TestProcessor(t *testing.T) {
+	t.Parallel()
+
+	processor := NewProcessor(HTML, chunk.NewMarkdownDocumentProcessor(1000, 0, true))
+
+	chunks, err := processor.Process("page.html", []byte("<h2>Install</h2><p>Run the installer.</p>"))
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "Install", chunks[0].Metadata["heading_path"])
+	assert.Equal(t, "## Install\n\nRun the installer.", chunks[0].Content)
+
+	_, err = NewProcessor(PDF, chunk.NewTextDocumentProcessor(1000, 0, false)).Process("doc.pdf", []byte("oops"))
+	require.ErrorContains(t, err, "failed to extract text from doc.pdf")
+}
@@ -0,0 +1,519 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxFormDepth limits how deep form XObjects drawing other forms are followed.
+	maxFormDepth = 8
+	// maxDecodedStreamSize limits the size of decoded streams, against zip bombs.
+	maxDecodedStreamSize = 64 << 20
+	// maxDecodedSize limits the total size of the streams decoded for a document.
+	maxDecodedSize = 256 << 20
+)
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// PDF extracts the text of a PDF document, page by page. Only text drawn with
+// fonts is extracted: there's no OCR of scanned pages, and encrypted documents
+// aren't supported.
+func PDF(content []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(content, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return "", errors.New("not a PDF document")
+	}
+
+	doc, err := parsePDF(content)
+	if err != nil {
+		return "", err
+	}
+
+	var pages []string
+	for _, page := range doc.pages() {
+		if text := doc.pageText(page); text != "" {
+			pages = append(pages, text)
+		}
+	}
+	if len(pages) == 0 {
+		return "", errors.New("no text found in PDF document")
+	}
+	return strings.Join(pages, "\n\n"), nil
+}
+
+type pdfDocument struct {
+	objects  map[int]any
+	trailers []pdfDict
+	fonts    map[any]*pdfFont
+	// decoded is the number of bytes decoded so far, see maxDecodedSize.
+	decoded int
+}
+
+// parsePDF reads every indirect object of a document, including those in object
+// streams. The cross-reference table is ignored: objects are found by scanning
+// the file, the last definition of an object winning as with incremental updates.
+func parsePDF(content []byte) (*pdfDocument, error) {
+	doc := &pdfDocument{
+		objects: map[int]any{},
+		fonts:   map[any]*pdfFont{},
+	}
+
+	for pos := 0; pos < len(content); {
+		loc := pdfObjectHeader.FindSubmatchIndex(content[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		if start > 0 && !isPDFSpace(content[start-1]) && !isPDFDelimiter(content[start-1]) {
+			pos += loc[1]
+			continue
+		}
+
+		var num int
+		_, _ = fmt.Sscan(string(content[pos+loc[2]:pos+loc[3]]), &num)
+
+		lexer := &pdfLexer{data: content, pos: pos + loc[1]}
+		obj, err := lexer.object()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if dict, ok := obj.(pdfDict); ok {
+			if stream, ok := doc.readStream(lexer, dict); ok {
+				obj = stream
+			}
+		}
+		doc.objects[num] = obj
+		pos = lexer.pos
+	}
+
+	doc.trailers = findPDFTrailers(content)
+	for _, obj := range doc.objects {
+		if stream, ok := obj.(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") {
+			doc.trailers = append(doc.trailers, stream.dict)
+		}
+	}
+	for _, trailer := range doc.trailers {
+		if _, ok := trailer["Encrypt"]; ok {
+			return nil, errors.New("encrypted PDF documents are not supported")
+		}
+	}
+
+	doc.readObjectStreams()
+	return doc, nil
+}
+
+func findPDFTrailers(content []byte) []pdfDict {
+	var trailers []pdfDict
+	for pos := 0; ; {
+		i := bytes.Index(content[pos:], []byte("trailer"))
+		if i < 0 {
+			return trailers
+		}
+		lexer := &pdfLexer{data: content, pos: pos + i + len("trailer")}
+		if obj, err := lexer.object(); err == nil {
+			if dict, ok := obj.(pdfDict); ok {
+				trailers = append(trailers, dict)
+			}
+		}
+		pos += i + len("trailer")
+	}
+}
+
+// readStream reads the data of a stream object, right after its dictionary.
+func (d *pdfDocument) readStream(lexer *pdfLexer, dict pdfDict) (*pdfStream, bool) {
+	save := lexer.pos
+	if tok, err := lexer.token(); err != nil || tok != pdfKeyword("stream") {
+		lexer.pos = save
+		return nil, false
+	}
+
+	// The stream keyword is followed by CRLF or LF.
+	data := lexer.data
+	start := lexer.pos
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	end := -1
+	if length, ok := d.resolve(dict["Length"]).(float64); ok {
+		if e := start + int(length); e >= start && e <= len(data) {
+			rest := bytes.TrimLeft(data[e:], "\x00\t\n\f\r ")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				end = e
+			}
+		}
+	}
+	if end < 0 {
+		i := bytes.Index(data[start:], []byte("endstream"))
+		if i < 0 {
+			lexer.pos = len(data)
+			return &pdfStream{dict: dict, data: data[start:]}, true
+		}
+		end = start + i
+		// The end of line before endstream isn't part of the data.
+		if end > start && data[end-1] == '\n' {
+			end--
+		}
+		if end > start && data[end-1] == '\r' {
+			end--
+		}
+	}
+
+	lexer.pos = end
+	if i := bytes.Index(data[end:], []byte("endstream")); i >= 0 {
+		lexer.pos = end + i + len("endstream")
+	}
+	return &pdfStream{dict: dict, data: data[start:end]}, true
+}
+
+// readObjectStreams adds the objects compressed in object streams, unless they
+// are also defined directly.
+func (d *pdfDocument) readObjectStreams() {
+	var streams []*pdfStream
+	for _, obj := range d.objects {
+		if stream, ok := obj.(*pdfStream); ok && stream.dict["Type"] == pdfName("ObjStm") {
+			streams = append(streams, stream)
+		}
+	}
+
+	for _, stream := range streams {
+		data, err := d.decode(stream)
+		if err != nil {
+			continue
+		}
+		n, _ := d.resolve(stream.dict["N"]).(float64)
+		first, _ := d.resolve(stream.dict["First"]).(float64)
+		if int(first) > len(data) {
+			continue
+		}
+
+		header := &pdfLexer{data: data[:int(first)]}
+		for range int(n) {
+			num, err1 := header.token()
+			offset, err2 := header.token()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			objNum, ok1 := num.(float64)
+			objOffset, ok2 := offset.(float64)
+			if !ok1 || !ok2 || int(first+objOffset) >= len(data) {
+				break
+			}
+			if _, exists := d.objects[int(objNum)]; exists {
+				continue
+			}
+			lexer := &pdfLexer{data: data, pos: int(first + objOffset)}
+			if obj, err := lexer.object(); err == nil {
+				d.objects[int(objNum)] = obj
+			}
+		}
+	}
+}
+
+// resolve follows indirect references.
+func (d *pdfDocument) resolve(obj any) any {
+	for range 32 {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(obj any) pdfDict {
+	switch obj := d.resolve(obj).(type) {
+	case pdfDict:
+		return obj
+	case *pdfStream:
+		return obj.dict
+	}
+	return nil
+}
+
+// decode returns the decoded data of a stream. Only the Flate filter is supported.
+func (d *pdfDocument) decode(stream *pdfStream) ([]byte, error) {
+	var filters []any
+	switch filter := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{filter}
+	case pdfArray:
+		filters = filter
+	}
+
+	data := stream.data
+	for _, filter := range filters {
+		switch filter := d.resolve(filter); filter {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			reader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Flate stream: %w", err)
+			}
+			limit := min(maxDecodedStreamSize, maxDecodedSize-d.decoded)
+			decoded, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+			if err != nil && len(decoded) == 0 {
+				return nil, fmt.Errorf("invalid Flate stream: %w", err)
+			}
+			if len(decoded) > limit {
+				if limit < maxDecodedStreamSize {
+					// Don't decode anything else once the budget is spent.
+					d.decoded = maxDecodedSize
+					return nil, fmt.Errorf("PDF document larger than %d bytes once decoded", maxDecodedSize)
+				}
+				return nil, fmt.Errorf("Flate stream larger than %d bytes once decoded", maxDecodedStreamSize)
+			}
+			d.decoded += len(decoded)
+			data = decoded
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %v", filter)
+		}
+	}
+	return data, nil
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages lists the pages of the document, in order, from its page tree. If the
+// document has no valid page tree, every page object is listed.
+func (d *pdfDocument) pages() []pdfPage {
+	var pages []pdfPage
+	visited := map[int]bool{}
+
+	var walk func(node any, resources pdfDict)
+	walk = func(node any, resources pdfDict) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		dict := d.dict(node)
+		if dict == nil {
+			return
+		}
+		if r := d.dict(dict["Resources"]); r != nil {
+			resources = r
+		}
+		if dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+			return
+		}
+		if kids, ok := d.resolve(dict["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(kid, resources)
+			}
+		}
+	}
+
+	for _, trailer := range d.trailers {
+		if root := d.dict(trailer["Root"]); root != nil {
+			walk(root["Pages"], nil)
+			if len(pages) > 0 {
+				return pages
+			}
+		}
+	}
+
+	var nums []int
+	for num, obj := range d.objects {
+		if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	slices.Sort(nums)
+	for _, num := range nums {
+		page := d.objects[num].(pdfDict)
+		resources := d.dict(page["Resources"])
+		// Resources inherited from the parent page tree node
+		for parent, depth := d.dict(page["Parent"]), 0; resources == nil && parent != nil && depth < 32; parent, depth = d.dict(parent["Parent"]), depth+1 {
+			resources = d.dict(parent["Resources"])
+		}
+		pages = append(pages, pdfPage{dict: page, resources: resources})
+	}
+	return pages
+}
+
+func (d *pdfDocument) pageText(page pdfPage) string {
+	var content []byte
+	contents := d.resolve(page.dict["Contents"])
+	streams, ok := contents.(pdfArray)
+	if !ok {
+		streams = pdfArray{contents}
+	}
+	for _, obj := range streams {
+		stream, ok := d.resolve(obj).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := d.decode(stream)
+		if err != nil {
+			continue
+		}
+		content = append(content, data...)
+		content = append(content, '\n')
+	}
+
+	var text textWriter
+	d.runContent(content, page.resources, &text, 0)
+	return text.String()
+}
+
+// runContent interprets the text operators of a content stream.
+func (d *pdfDocument) runContent(content []byte, resources pdfDict, text *textWriter, depth int) {
+	lexer := &pdfLexer{data: content}
+	var (
+		operands []any
+		font     *pdfFont
+		lineY    float64
+	)
+
+	for {
+		obj, err := lexer.object()
+		if err != nil {
+			if errors.Is(err, errPDFEOF) {
+				return
+			}
+			// Skip what can't be parsed.
+			operands = nil
+			lexer.pos++
+			continue
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			lineY = 0
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = d.font(d.dict(resources["Font"])[name])
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				text.write(font.decode(operands[len(operands)-1]))
+			}
+		case "'", "\"":
+			text.newLine()
+			if len(operands) >= 1 {
+				text.write(font.decode(operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				array, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range array {
+					if adjust, ok := item.(float64); ok {
+						// Large negative adjustments separate words.
+						if adjust < -250 {
+							text.space()
+						}
+						continue
+					}
+					text.write(font.decode(item))
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[len(operands)-2].(float64)
+				ty, _ := operands[len(operands)-1].(float64)
+				switch {
+				case ty != 0:
+					text.newLine()
+				case tx != 0:
+					text.space()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[len(operands)-1].(float64)
+				if y != lineY {
+					text.newLine()
+				} else {
+					text.space()
+				}
+				lineY = y
+			}
+		case "T*":
+			text.newLine()
+		case "ET":
+			text.space()
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				name, _ := operands[len(operands)-1].(pdfName)
+				form, ok := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream)
+				if ok && form.dict["Subtype"] == pdfName("Form") {
+					if data, err := d.decode(form); err == nil {
+						formResources := d.dict(form.dict["Resources"])
+						if formResources == nil {
+							formResources = resources
+						}
+						d.runContent(data, formResources, text, depth+1)
+					}
+				}
+			}
+		case "BI":
+			// Inline image: skip its parameters and data.
+			for {
+				obj, err := lexer.object()
+				if err != nil || obj == pdfKeyword("ID") {
+					break
+				}
+			}
+			lexer.skipInlineImage()
+		}
+		operands = nil
+	}
+}
+
+// textWriter collects the text of a page, normalizing its whitespace.
+type textWriter struct {
+	lines []string
+	line  strings.Builder
+}
+
+func (w *textWriter) write(s string) {
+	w.line.WriteString(s)
+}
+
+func (w *textWriter) space() {
+	if line := w.line.String(); line != "" && !strings.HasSuffix(line, " ") {
+		w.line.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) newLine() {
+	w.lines = append(w.lines, strings.Join(strings.Fields(w.line.String()), " "))
+	w.line.Reset()
+}
+
+func (w *textWriter) String() string {
+	w.newLine()
+
+	// Collapse blank lines
+	var out []string
+	for _, line := range w.lines {
+		if line == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,220 @@
+package extract
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxCMapRange limits the size of the ranges of ToUnicode CMaps.
+const maxCMapRange = 1 << 16
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	// toUnicode maps character codes to text, from the ToUnicode CMap of the font.
+	toUnicode map[string]string
+	// codeLengths are the lengths, in bytes, of the character codes, shortest first.
+	codeLengths []int
+	// composite fonts (Type0) have multi-byte codes that can't be decoded without a CMap.
+	composite bool
+}
+
+// winAnsiSpecials are the characters of WinAnsiEncoding that differ from Latin-1.
+var winAnsiSpecials = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘',
+	0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜',
+	0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+func (d *pdfDocument) font(obj any) *pdfFont {
+	key := obj
+	if _, ok := obj.(pdfRef); !ok {
+		// Direct font dictionaries can't be cached.
+		key = nil
+	}
+	if font, ok := d.fonts[key]; ok && key != nil {
+		return font
+	}
+
+	dict := d.dict(obj)
+	if dict == nil {
+		return nil
+	}
+	font := &pdfFont{
+		composite: dict["Subtype"] == pdfName("Type0"),
+	}
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decode(stream); err == nil {
+			font.toUnicode, font.codeLengths = parseToUnicode(data)
+		}
+	}
+
+	if key != nil {
+		d.fonts[key] = font
+	}
+	return font
+}
+
+// decode converts a string drawn with the font to text.
+func (f *pdfFont) decode(obj any) string {
+	s, ok := obj.(pdfString)
+	if !ok {
+		return ""
+	}
+	if f == nil || len(f.toUnicode) == 0 {
+		if f != nil && f.composite {
+			return ""
+		}
+		return decodeWinAnsi(s)
+	}
+
+	var text strings.Builder
+	for len(s) > 0 {
+		decoded := false
+		for _, n := range f.codeLengths {
+			if n > len(s) {
+				break
+			}
+			if unicode, ok := f.toUnicode[string(s[:n])]; ok {
+				text.WriteString(unicode)
+				s = s[n:]
+				decoded = true
+				break
+			}
+		}
+		if decoded {
+			continue
+		}
+		// Unmapped code
+		if !f.composite {
+			text.WriteString(decodeWinAnsi(s[:1]))
+		}
+		s = s[min(f.codeLengths[0], len(s)):]
+	}
+	return text.String()
+}
+
+func decodeWinAnsi(s []byte) string {
+	runes := make([]rune, 0, len(s))
+	for _, c := range s {
+		if r, ok := winAnsiSpecials[c]; ok {
+			runes = append(runes, r)
+		} else {
+			runes = append(runes, rune(c))
+		}
+	}
+	return string(runes)
+}
+
+// parseToUnicode reads the bfchar and bfrange mappings of a ToUnicode CMap.
+func parseToUnicode(data []byte) (map[string]string, []int) {
+	mapping := map[string]string{}
+	var lengths []int
+	addLength := func(n int) {
+		if n > 0 && !slices.Contains(lengths, n) {
+			lengths = append(lengths, n)
+		}
+	}
+
+	lexer := &pdfLexer{data: data}
+	var operands []any
+	section := ""
+	for {
+		obj, err := lexer.object()
+		if err != nil {
+			break
+		}
+		keyword, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch keyword {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = string(keyword)
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if low, ok := operands[i].(pdfString); ok {
+					addLength(len(low))
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					mapping[string(src)] = decodeUTF16(dst)
+					addLength(len(src))
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, ok1 := operands[i].(pdfString)
+				high, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || len(low) != len(high) || len(low) == 0 || len(low) > 4 {
+					continue
+				}
+				addLength(len(low))
+				start, end := codeValue(low), codeValue(high)
+				if end < start || end-start >= maxCMapRange {
+					continue
+				}
+				for code := start; code <= end; code++ {
+					var unicode string
+					switch dst := operands[i+2].(type) {
+					case pdfString:
+						// The last byte of the destination is incremented.
+						next := slices.Clone([]byte(dst))
+						if len(next) > 0 {
+							next[len(next)-1] += byte(code - start)
+						}
+						unicode = decodeUTF16(next)
+					case pdfArray:
+						if int(code-start) < len(dst) {
+							if s, ok := dst[code-start].(pdfString); ok {
+								unicode = decodeUTF16(s)
+							}
+						}
+					}
+					mapping[string(codeBytes(code, len(low)))] = unicode
+				}
+			}
+			section = ""
+		}
+		if section == "" || keyword == pdfKeyword(section) {
+			operands = nil
+		}
+	}
+
+	slices.Sort(lengths)
+	return mapping, lengths
+}
+
+func codeValue(code []byte) uint32 {
+	var value uint32
+	for _, b := range code {
+		value = value<<8 | uint32(b)
+	}
+	return value
+}
+
+func codeBytes(value uint32, n int) []byte {
+	code := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		code[i] = byte(value)
+		value >>= 8
+	}
+	return code
+}
+
+func decodeUTF16(s []byte) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
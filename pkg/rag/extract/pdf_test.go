@@ -0,0 +1,200 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF writes a PDF document made of the given objects, numbered from 1,
+// with object 1 as its catalog.
+func buildPDF(t testing.TB, objects ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\n%%%%EOF\n", len(objects)+1)
+	return buf.Bytes()
+}
+
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func flateStream(t testing.TB, data string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return stream("/Filter /FlateDecode", buf.String())
+}
+
+func TestPDF(t *testing.T) {
+	t.Parallel()
+
+	content := buildPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [7 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		stream("", "BT /F1 12 Tf 72 720 Td (Hello, \\(PDF\\) world!) Tj 0 -14 Td [(Second) -300 (line)] TJ ET"),
+		flateStream(t, "BT /F1 12 Tf 72 720 Td (Compressed page) Tj T* (\\223quoted\\224) Tj ET"),
+	)
+
+	text, err := PDF(content)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, (PDF) world!\nSecond line\n\nCompressed page\n“quoted”", text)
+}
+
+func TestPDF_ToUnicode(t *testing.T) {
+	t.Parallel()
+
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0048>
+<0002> <0069>
+endbfchar
+1 beginbfrange
+<0010> <0012> <00E9>
+endbfrange
+endcmap
+end end`
+
+	content := buildPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> /XObject << /X1 7 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		stream("", "BT /F1 12 Tf 1 0 0 1 72 720 Tm <00010002> Tj 1 0 0 1 72 700 Tm <001000110012> Tj ET /X1 Do"),
+		flateStream(t, cmap),
+		stream("/Type /XObject /Subtype /Form /BBox [0 0 100 100]", "BT /F1 10 Tf 0 0 Td <0001> Tj ET"),
+	)
+
+	text, err := PDF(content)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi\néêë H", text)
+}
+
+func TestPDF_ObjectStream(t *testing.T) {
+	t.Parallel()
+
+	pages := "<< /Type /Pages /Kids [3 0 R] /Count 1 >> "
+	page := "<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 << /Subtype /Type1 >> >> >> /Contents 5 0 R >>"
+	objects := pages + page
+	header := fmt.Sprintf("2 0 3 %d ", len(pages))
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write([]byte(header + objects))
+	_ = w.Close()
+
+	content := buildPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"null",
+		"null",
+		fmt.Sprintf("<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", len(header), buf.Len(), buf.String()),
+		stream("", "BT /F1 12 Tf (In an object stream) Tj ET"),
+	)
+	// Objects 2 and 3 are only defined in the object stream.
+	content = bytes.Replace(content, []byte("2 0 obj\nnull\nendobj\n3 0 obj\nnull\nendobj\n"), nil, 1)
+
+	text, err := PDF(content)
+	require.NoError(t, err)
+	assert.Equal(t, "In an object stream", text)
+}
+
+func TestPDF_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := PDF([]byte("plain text"))
+	require.ErrorContains(t, err, "not a PDF document")
+
+	encrypted := buildPDF(t, "<< /Type /Catalog >>")
+	encrypted = bytes.Replace(encrypted, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 2 0 R"), 1)
+	_, err = PDF(encrypted)
+	require.ErrorContains(t, err, "encrypted")
+
+	scanned := buildPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		stream("", "q 100 0 0 100 0 0 cm BI /W 1 /H 1 /CS /G /BPC 8 ID \x00 EI Q"),
+	)
+	_, err = PDF(scanned)
+	require.ErrorContains(t, err, "no text found")
+}
+
+func TestPDF_Limits(t *testing.T) {
+	t.Parallel()
+
+	nested := buildPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 << /Subtype /Type1 >> >> >> /Contents 4 0 R >>",
+		stream("", "BT /F1 12 Tf "+strings.Repeat("[", 100_000)+" (Too deep) Tj ET"),
+	)
+	_, err := PDF(nested)
+	require.ErrorContains(t, err, "no text found")
+
+	_, err = (&pdfLexer{data: []byte(strings.Repeat("<< /A ", 100) + ">>")}).object()
+	require.ErrorContains(t, err, "nested more than")
+
+	var bomb bytes.Buffer
+	w := zlib.NewWriter(&bomb)
+	_, err = w.Write(make([]byte, maxDecodedStreamSize+1))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	_, err = (&pdfDocument{}).decode(&pdfStream{dict: pdfDict{"Filter": pdfName("FlateDecode")}, data: bomb.Bytes()})
+	require.ErrorContains(t, err, "larger than")
+
+	// Streams under the per-stream limit still count towards the document's limit.
+	var small bytes.Buffer
+	w = zlib.NewWriter(&small)
+	_, err = w.Write([]byte("BT ET"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	_, err = (&pdfDocument{decoded: maxDecodedSize - 2}).decode(&pdfStream{dict: pdfDict{"Filter": pdfName("FlateDecode")}, data: small.Bytes()})
+	require.ErrorContains(t, err, "PDF document larger than")
+}
+
+func FuzzPDF(f *testing.F) {
+	f.Add(buildPDF(f,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		flateStream(f, "BT /F1 12 Tf 72 720 Td (Hello) Tj T* [(PDF) -300 (world)] TJ ET"),
+	))
+	f.Add([]byte("%PDF-1.4\n1 0 obj << /A [1 2 0 R (x) <41>] >> endobj trailer << /Root 1 0 R >>"))
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		_, _ = PDF(content)
+	})
+}
+
+func TestParseToUnicode_Array(t *testing.T) {
+	t.Parallel()
+
+	mapping, lengths := parseToUnicode([]byte("1 beginbfrange <01> <02> [<0066006C> <0041>] endbfrange"))
+	assert.Equal(t, []int{1}, lengths)
+	assert.Equal(t, map[string]string{"\x01": "fl", "\x02": "A"}, mapping)
+
+	font := &pdfFont{toUnicode: mapping, codeLengths: lengths}
+	assert.Equal(t, "flAé", font.decode(pdfString("\x01\x02\xe9")))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/docker/cagent/pkg/rag/database"
 	"github.com/docker/cagent/pkg/rag/fusion"
 	"github.com/docker/cagent/pkg/rag/rerank"
+	"github.com/docker/cagent/pkg/rag/source"
 	"github.com/docker/cagent/pkg/rag/strategy"
 	"github.com/docker/cagent/pkg/rag/types"
 )
@@ -27,6 +28,7 @@ type ToolConfig struct {
 type Config struct {
 	Tool            ToolConfig
 	Docs            []string
+	Sources         []*source.Source // Remote documents, fetched into a local cache before indexing
 	Results         ResultsConfig
 	FusionConfig    *FusionConfig
 	StrategyConfigs []strategy.Config
@@ -145,6 +147,8 @@ func (m *Manager) Initialize(ctx context.Context) error {
 		"rag_name", m.name,
 		"num_strategies", len(m.strategies))
 
+	m.syncSources(ctx)
+
 	// Initialize strategies in parallel to avoid blocking
 	type result struct {
 		strategyName string
@@ -431,8 +435,11 @@ func getStrategyNames(stratMap map[string]strategy.Strategy) []string {
 	return names
 }
 
-// CheckAndReindexChangedFiles checks for file changes and re-indexes if needed
+// CheckAndReindexChangedFiles checks for file changes and re-indexes if needed.
+// Remote documents are revalidated first so that only the ones that changed get re-indexed.
 func (m *Manager) CheckAndReindexChangedFiles(ctx context.Context) error {
+	m.syncSources(ctx)
+
 	for strategyName, strategyImpl := range m.strategies {
 		strategyCfg := m.strategyConfigs[strategyName]
 		if err := strategyImpl.CheckAndReindexChangedFiles(ctx, strategyCfg.Docs, strategyCfg.Chunking); err != nil {
@@ -442,6 +449,25 @@ func (m *Manager) CheckAndReindexChangedFiles(ctx context.Context) error {
 	return nil
 }
 
+// syncSources fetches the remote documents into their local cache. Failures are only logged:
+// the previously cached copies of the documents, if any, are indexed instead.
+func (m *Manager) syncSources(ctx context.Context) {
+	for _, src := range m.config.Sources {
+		start := time.Now()
+		if err := src.Sync(ctx); err != nil {
+			slog.Warn("[RAG Manager] Failed to sync remote documents",
+				"rag_name", m.name,
+				"source", src,
+				"error", err)
+			continue
+		}
+		slog.Debug("[RAG Manager] Synced remote documents",
+			"rag_name", m.name,
+			"source", src,
+			"duration", time.Since(start))
+	}
+}
+
 // StartFileWatcher starts monitoring files and directories for changes
 func (m *Manager) StartFileWatcher(ctx context.Context) error {
 	for strategyName, strategyImpl := range m.strategies {
@@ -0,0 +1,37 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// syncGit fetches the ref of a git repository and checks it out. The git directory
+// is kept outside of the checked out files so that it doesn't get indexed, and
+// only the files changed since the previous checkout are rewritten.
+func (s *Source) syncGit(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(s.dir, "git")); os.IsNotExist(err) {
+		if err := s.git(ctx, "init", "--quiet"); err != nil {
+			return err
+		}
+	}
+
+	if err := s.git(ctx, "fetch", "--quiet", "--depth", "1", "--", s.url, s.ref); err != nil {
+		return err
+	}
+	return s.git(ctx, "checkout", "--quiet", "--force", "FETCH_HEAD")
+}
+
+func (s *Source) git(ctx context.Context, args ...string) error {
+	args = append([]string{"--git-dir", filepath.Join(s.dir, "git"), "--work-tree", s.Path()}, args...)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed for %s: %w: %s", args[4], s.url, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/docker/cagent/pkg/useragent"
+)
+
+const (
+	maxPageSize     = 50 << 20
+	maxPages        = 1000
+	maxSitemapDepth = 3
+	fetchWorkers    = 4
+)
+
+// extensions maps content types to the file extensions their documents are stored
+// with, so that they get chunked by the right document processor.
+var extensions = map[string][]string{
+	"text/html":             {".html", ".htm"},
+	"application/xhtml+xml": {".html", ".htm", ".xhtml"},
+	"text/markdown":         {".md", ".markdown", ".mdx"},
+	"text/x-markdown":       {".md", ".markdown", ".mdx"},
+	"application/pdf":       {".pdf"},
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": {".docx"},
+	"application/json": {".json"},
+}
+
+// manifest records the validators of the cached pages.
+type manifest struct {
+	Pages map[string]page `json:"pages"`
+}
+
+type page struct {
+	File         string `json:"file"` // Relative to the cached files
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func (s *Source) manifestPath() string {
+	return filepath.Join(s.dir, "manifest.json")
+}
+
+func (s *Source) loadManifest() manifest {
+	m := manifest{Pages: map[string]page{}}
+
+	buf, err := os.ReadFile(s.manifestPath())
+	if err != nil {
+		return m
+	}
+	if err := json.Unmarshal(buf, &m); err != nil || m.Pages == nil {
+		slog.Warn("Ignoring invalid RAG cache manifest", "path", s.manifestPath(), "error", err)
+		return manifest{Pages: map[string]page{}}
+	}
+	return m
+}
+
+func (s *Source) saveManifest(m manifest) error {
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(s.manifestPath(), buf)
+}
+
+// syncPages fetches the given pages and removes the cached pages that are not
+// listed anymore.
+func (s *Source) syncPages(ctx context.Context, urls []string) error {
+	m := s.loadManifest()
+
+	var (
+		mu      sync.Mutex
+		errs    []error
+		wg      sync.WaitGroup
+		fetched = map[string]page{}
+		listed  = map[string]bool{}
+	)
+	workers := make(chan struct{}, fetchWorkers)
+	for _, u := range urls {
+		listed[u] = true
+		cached, isCached := m.Pages[u]
+		wg.Go(func() {
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			p, err := s.fetchPage(ctx, u, cached, isCached)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			fetched[u] = p
+		})
+	}
+	wg.Wait()
+
+	for u, p := range m.Pages {
+		if !listed[u] {
+			slog.Debug("Removing page no longer listed", "url", u)
+			if err := os.Remove(filepath.Join(s.Path(), p.File)); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			delete(m.Pages, u)
+		}
+	}
+	maps.Copy(m.Pages, fetched)
+
+	if err := s.saveManifest(m); err != nil {
+		errs = append(errs, fmt.Errorf("failed to save cache manifest: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// fetchPage downloads a page, revalidating its cached copy if any.
+// Unchanged pages are not rewritten on disk.
+func (s *Source) fetchPage(ctx context.Context, rawURL string, cached page, isCached bool) (page, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return page{}, fmt.Errorf("invalid URL %s: %w", rawURL, err)
+	}
+	req.Header.Set("User-Agent", useragent.Header)
+
+	if isCached {
+		if _, err := os.Stat(filepath.Join(s.Path(), cached.File)); err != nil {
+			isCached = false
+		}
+	}
+	if isCached {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return page{}, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return page{}, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return page{}, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+
+	fetched := page{
+		File:         fileName(rawURL, resp.Header.Get("Content-Type")),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if err := writeFileIfChanged(filepath.Join(s.Path(), fetched.File), body); err != nil {
+		return page{}, fmt.Errorf("failed to cache %s: %w", rawURL, err)
+	}
+	if isCached && cached.File != fetched.File {
+		_ = os.Remove(filepath.Join(s.Path(), cached.File))
+	}
+	return fetched, nil
+}
+
+// fileName returns the path, relative to the cached files, a page is stored at.
+// Pages are stored by host and path, with an extension matching their content type.
+func fileName(rawURL, contentType string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return hash(rawURL)
+	}
+
+	name := path.Clean("/" + u.Path)
+	if name == "/" || strings.HasSuffix(u.Path, "/") {
+		name = path.Join(name, "index")
+	}
+	if u.RawQuery != "" {
+		name += "-" + hash(u.RawQuery)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case mediaType == "text/plain":
+		// Source files are often served as plain text: keep their extension.
+		if ext == "" {
+			name += ".txt"
+		}
+	case len(extensions[mediaType]) > 0 && !slices.Contains(extensions[mediaType], ext):
+		name += extensions[mediaType][0]
+	}
+
+	return filepath.Join(u.Hostname(), filepath.FromSlash(name))
+}
+
+func writeFileIfChanged(filename string, content []byte) error {
+	if existing, err := os.ReadFile(filename); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	return writeFile(filename, content)
+}
+
+// writeFile atomically writes a file, creating its parent directories.
+func writeFile(filename string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// sitemap is either a <urlset> listing pages or a <sitemapindex> listing other sitemaps.
+type sitemap struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// syncSitemap fetches the pages listed in a sitemap, limited to the sitemap's domain.
+func (s *Source) syncSitemap(ctx context.Context) error {
+	root, err := url.Parse(s.url)
+	if err != nil {
+		return fmt.Errorf("invalid sitemap URL %s: %w", s.url, err)
+	}
+
+	var urls []string
+	seen := map[string]bool{}
+	if err := s.collectSitemap(ctx, s.url, root.Hostname(), 0, seen, &urls); err != nil {
+		// Keep the cached pages rather than removing all of them.
+		return err
+	}
+
+	if len(urls) > maxPages {
+		slog.Warn("Sitemap lists too many pages, only fetching the first ones", "sitemap", s.url, "pages", len(urls), "limit", maxPages)
+		urls = urls[:maxPages]
+	}
+
+	return s.syncPages(ctx, urls)
+}
+
+func (s *Source) collectSitemap(ctx context.Context, sitemapURL, host string, depth int, seen map[string]bool, urls *[]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("invalid sitemap URL %s: %w", sitemapURL, err)
+	}
+	req.Header.Set("User-Agent", useragent.Header)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch sitemap %s: %s", sitemapURL, resp.Status)
+	}
+
+	var sm sitemap
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxPageSize)).Decode(&sm); err != nil {
+		return fmt.Errorf("invalid sitemap %s: %w", sitemapURL, err)
+	}
+
+	for _, entry := range sm.URLs {
+		loc := strings.TrimSpace(entry.Loc)
+		if !seen[loc] && sameHost(loc, host) {
+			seen[loc] = true
+			*urls = append(*urls, loc)
+		}
+	}
+
+	for _, entry := range sm.Sitemaps {
+		loc := strings.TrimSpace(entry.Loc)
+		if seen[loc] || !sameHost(loc, host) {
+			continue
+		}
+		seen[loc] = true
+
+		if depth+1 >= maxSitemapDepth {
+			slog.Warn("Ignoring deeply nested sitemap", "sitemap", loc)
+			continue
+		}
+		if err := s.collectSitemap(ctx, loc, host, depth+1, seen, urls); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sameHost(rawURL, host string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return strings.EqualFold(u.Hostname(), host)
+}
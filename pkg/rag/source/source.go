@@ -0,0 +1,142 @@
+// Package source fetches the remote documents of RAG configurations (web pages,
+// sitemaps and git repositories) into a local cache, so that strategies can
+// index them like local files.
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/cagent/pkg/paths"
+)
+
+// Kind is the kind of a remote document.
+type Kind string
+
+const (
+	// KindURL is a single web page or file.
+	KindURL Kind = "url"
+	// KindSitemap is a sitemap.xml listing the pages to fetch. Only the pages
+	// hosted on the same domain as the sitemap are fetched.
+	KindSitemap Kind = "sitemap"
+	// KindGit is a git repository, checked out at a given ref.
+	KindGit Kind = "git"
+)
+
+// DefaultCacheDir returns the directory caching remote documents.
+func DefaultCacheDir() string {
+	return filepath.Join(paths.GetDataDir(), "rag-cache")
+}
+
+// IsRemote returns true if doc is a remote document rather than a local path.
+func IsRemote(doc string) bool {
+	for _, prefix := range []string{"http://", "https://", "git+", "git@"} {
+		if strings.HasPrefix(doc, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Source is a remote document cached in a local directory.
+type Source struct {
+	doc    string
+	kind   Kind
+	url    string // URL of the page, the sitemap or the git repository
+	ref    string // git ref to check out
+	dir    string
+	client *http.Client
+}
+
+// New returns the source of a remote document, cached under cacheDir.
+//
+// Supported documents are:
+//   - web pages and files: https://docs.example.com/guide.html
+//   - sitemaps: https://docs.example.com/sitemap.xml
+//   - git repositories: git+https://github.com/org/repo.git#main,
+//     https://github.com/org/repo.git#v1.2.0 or git@github.com:org/repo.git.
+//     The ref defaults to the remote HEAD.
+func New(doc, cacheDir string) (*Source, error) {
+	location, fragment, _ := strings.Cut(doc, "#")
+
+	s := &Source{
+		doc:    doc,
+		url:    location,
+		dir:    filepath.Join(cacheDir, hash(doc)),
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+
+	switch {
+	case strings.HasPrefix(location, "git+"), strings.HasPrefix(location, "git@"), strings.HasSuffix(location, ".git"):
+		s.kind = KindGit
+		s.url = strings.TrimPrefix(location, "git+")
+		s.ref = fragment
+		if s.ref == "" {
+			s.ref = "HEAD"
+		}
+		// Neither can be taken for an option of git.
+		if s.url == "" || strings.HasPrefix(s.url, "-") || strings.HasPrefix(s.ref, "-") {
+			return nil, fmt.Errorf("invalid git repository %q", doc)
+		}
+		return s, nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", doc)
+	}
+
+	s.kind = KindURL
+	if base := path.Base(u.Path); strings.HasPrefix(base, "sitemap") && strings.HasSuffix(base, ".xml") {
+		s.kind = KindSitemap
+	}
+	return s, nil
+}
+
+// Kind returns the kind of the remote document.
+func (s *Source) Kind() Kind {
+	return s.kind
+}
+
+// Path returns the local directory holding the fetched documents.
+func (s *Source) Path() string {
+	return filepath.Join(s.dir, "files")
+}
+
+func (s *Source) String() string {
+	return s.doc
+}
+
+// Sync fetches the remote documents into the local cache. Web pages are revalidated
+// with their ETag and Last-Modified headers and git repositories are fetched
+// incrementally, so that unchanged documents are left untouched on disk.
+//
+// When a document can't be fetched, its previously cached copy is kept.
+func (s *Source) Sync(ctx context.Context) error {
+	if err := os.MkdirAll(s.Path(), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	switch s.kind {
+	case KindGit:
+		return s.syncGit(ctx)
+	case KindSitemap:
+		return s.syncSitemap(ctx)
+	default:
+		return s.syncPages(ctx, []string{s.url})
+	}
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,220 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		doc  string
+		kind Kind
+		url  string
+		ref  string
+	}{
+		{doc: "https://docs.example.com/guide", kind: KindURL, url: "https://docs.example.com/guide"},
+		{doc: "https://docs.example.com/sitemap.xml", kind: KindSitemap, url: "https://docs.example.com/sitemap.xml"},
+		{doc: "https://docs.example.com/sitemap_index.xml", kind: KindSitemap, url: "https://docs.example.com/sitemap_index.xml"},
+		{doc: "git+https://github.com/docker/cagent.git#main", kind: KindGit, url: "https://github.com/docker/cagent.git", ref: "main"},
+		{doc: "https://github.com/docker/cagent.git#v1.0.0", kind: KindGit, url: "https://github.com/docker/cagent.git", ref: "v1.0.0"},
+		{doc: "git@github.com:docker/cagent.git", kind: KindGit, url: "git@github.com:docker/cagent.git", ref: "HEAD"},
+	}
+	for _, tt := range tests {
+		require.True(t, IsRemote(tt.doc), tt.doc)
+
+		s, err := New(tt.doc, "/cache")
+		require.NoError(t, err, tt.doc)
+		assert.Equal(t, tt.kind, s.Kind(), tt.doc)
+		assert.Equal(t, tt.url, s.url, tt.doc)
+		assert.Equal(t, tt.ref, s.ref, tt.doc)
+	}
+
+	assert.False(t, IsRemote("./docs"))
+	assert.False(t, IsRemote("/abs/docs/*.md"))
+
+	_, err := New("https://", "/cache")
+	require.Error(t, err)
+
+	// Git options can't be injected.
+	_, err = New("--upload-pack=touch /tmp/pwned.git", "/cache")
+	require.Error(t, err)
+	_, err = New("https://github.com/docker/cagent.git#--upload-pack=touch /tmp/pwned", "/cache")
+	require.Error(t, err)
+}
+
+func TestFileName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, filepath.Join("example.com", "index.html"), fileName("https://example.com", "text/html; charset=utf-8"))
+	assert.Equal(t, filepath.Join("example.com", "docs", "index.html"), fileName("https://example.com/docs/", "text/html"))
+	assert.Equal(t, filepath.Join("example.com", "docs", "page.htm"), fileName("https://example.com/docs/page.htm", "text/html"))
+	assert.Equal(t, filepath.Join("example.com", "page.php.html"), fileName("https://example.com/page.php", "text/html"))
+	assert.Equal(t, filepath.Join("example.com", "manual.pdf"), fileName("https://example.com/manual", "application/pdf"))
+	assert.Equal(t, filepath.Join("example.com", "main.go"), fileName("https://example.com/main.go", "text/plain"))
+	assert.Equal(t, filepath.Join("example.com", "notes.txt"), fileName("https://example.com/notes", "text/plain"))
+	assert.Equal(t, filepath.Join("example.com", "etc", "passwd"), fileName("https://example.com/../../etc/passwd", ""))
+	assert.NotEqual(t, fileName("https://example.com/search?q=a", "text/html"), fileName("https://example.com/search?q=b", "text/html"))
+}
+
+func TestSync_RevalidatesWithETag(t *testing.T) {
+	t.Parallel()
+
+	var (
+		content  atomic.Value
+		requests atomic.Int32
+		notMod   atomic.Int32
+	)
+	content.Store("<h1>Version 1</h1>")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body := content.Load().(string)
+		etag := `"` + hash(body) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notMod.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL+"/guide", t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Sync(t.Context()))
+	file := filepath.Join(s.Path(), "127.0.0.1", "guide.html")
+	assert.FileExists(t, file)
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Sync(t.Context()))
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, int32(1), notMod.Load())
+	unchanged, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), unchanged.ModTime())
+
+	content.Store("<h1>Version 2</h1>")
+	require.NoError(t, s.Sync(t.Context()))
+	buf, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>Version 2</h1>", string(buf))
+}
+
+func TestSync_KeepsCachedPageOnError(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/markdown")
+		_, _ = w.Write([]byte("# Guide"))
+	}))
+	defer server.Close()
+
+	s, err := New(server.URL+"/guide.md", t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, s.Sync(t.Context()))
+
+	failing.Store(true)
+	require.ErrorContains(t, s.Sync(t.Context()), "503")
+	assert.FileExists(t, filepath.Join(s.Path(), "127.0.0.1", "guide.md"))
+}
+
+func TestSync_Sitemap(t *testing.T) {
+	t.Parallel()
+
+	var pages atomic.Value
+	pages.Store([]string{"/a", "/b"})
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + server.URL + `/sitemap-pages.xml</loc></sitemap>
+  <sitemap><loc>https://other.example.com/sitemap.xml</loc></sitemap>
+</sitemapindex>`))
+	})
+	mux.HandleFunc("/sitemap-pages.xml", func(w http.ResponseWriter, _ *http.Request) {
+		body := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+		for _, p := range pages.Load().([]string) {
+			body += "<url><loc>" + server.URL + p + "</loc></url>"
+		}
+		body += "<url><loc>https://other.example.com/c</loc></url></urlset>"
+		_, _ = w.Write([]byte(body))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<p>" + r.URL.Path + "</p>"))
+	})
+
+	s, err := New(server.URL+"/sitemap.xml", t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, s.Sync(t.Context()))
+
+	dir := filepath.Join(s.Path(), "127.0.0.1")
+	assert.FileExists(t, filepath.Join(dir, "a.html"))
+	assert.FileExists(t, filepath.Join(dir, "b.html"))
+	assert.NoDirExists(t, filepath.Join(s.Path(), "other.example.com"))
+
+	// Pages removed from the sitemap are removed from the cache.
+	pages.Store([]string{"/a"})
+	require.NoError(t, s.Sync(t.Context()))
+	assert.FileExists(t, filepath.Join(dir, "a.html"))
+	assert.NoFileExists(t, filepath.Join(dir, "b.html"))
+}
+
+func TestSync_Git(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	run("init", "--quiet", "--initial-branch", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "README.md"), []byte("# Repo"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "old.md"), []byte("old"), 0o644))
+	run("add", ".")
+	run("commit", "--quiet", "-m", "first")
+
+	s, err := New("git+file://"+filepath.ToSlash(repo)+"#main", t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, s.Sync(t.Context()))
+	assert.FileExists(t, filepath.Join(s.Path(), "README.md"))
+	assert.FileExists(t, filepath.Join(s.Path(), "old.md"))
+	assert.NoDirExists(t, filepath.Join(s.Path(), ".git"))
+
+	run("rm", "--quiet", "old.md")
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "new.md"), []byte("new"), 0o644))
+	run("add", ".")
+	run("commit", "--quiet", "-m", "second")
+
+	require.NoError(t, s.Sync(t.Context()))
+	assert.FileExists(t, filepath.Join(s.Path(), "new.md"))
+	assert.NoFileExists(t, filepath.Join(s.Path(), "old.md"))
+}
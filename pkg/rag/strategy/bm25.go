@@ -18,7 +18,6 @@ import (
 	"github.com/docker/cagent/pkg/fsx"
 	"github.com/docker/cagent/pkg/rag/chunk"
 	"github.com/docker/cagent/pkg/rag/database"
-	"github.com/docker/cagent/pkg/rag/treesitter"
 	"github.com/docker/cagent/pkg/rag/types"
 )
 
@@ -102,18 +101,10 @@ type BM25Strategy struct {
 
 // newBM25Strategy creates a new BM25-based retrieval strategy
 func newBM25Strategy(name string, db *bm25DB, events chan<- types.Event, k1, b float64, chunking ChunkingConfig, shouldIgnore func(string) bool) *BM25Strategy {
-	// Create the appropriate document processor based on config
-	var dp chunk.DocumentProcessor
-	if chunking.CodeAware {
-		dp = treesitter.NewDocumentProcessor(chunking.Size, chunking.Overlap, chunking.RespectWordBoundaries)
-	} else {
-		dp = chunk.NewTextDocumentProcessor(chunking.Size, chunking.Overlap, chunking.RespectWordBoundaries)
-	}
-
 	return &BM25Strategy{
 		name:         name,
 		db:           db,
-		docProcessor: dp,
+		docProcessor: NewDocumentProcessor(chunking),
 		fileHashes:   make(map[string]string),
 		events:       events,
 		shouldIgnore: shouldIgnore,
@@ -539,12 +530,15 @@ func (s *BM25Strategy) indexFile(ctx context.Context, filePath string) error {
 			continue
 		}
 
+		startLine, endLine := chunkLines(chunk)
 		doc := database.Document{
 			ID:         fmt.Sprintf("%s_%d_%d", filePath, chunk.Index, time.Now().UnixNano()),
 			SourcePath: filePath,
 			ChunkIndex: chunk.Index,
 			Content:    chunk.Content,
 			FileHash:   fileHash,
+			StartLine:  startLine,
+			EndLine:    endLine,
 		}
 
 		if err := s.db.AddDocument(ctx, doc); err != nil {
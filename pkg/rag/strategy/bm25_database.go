@@ -65,6 +65,8 @@ func (d *bm25DB) createSchema() error {
 		content TEXT NOT NULL,
 		file_hash TEXT NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		start_line INTEGER NOT NULL DEFAULT 0,
+		end_line INTEGER NOT NULL DEFAULT 0,
 		UNIQUE(source_path, chunk_index)
 	);
 	CREATE INDEX IF NOT EXISTS idx_%s_source_path ON %s(source_path);
@@ -85,8 +87,15 @@ func (d *bm25DB) createSchema() error {
 		d.metadataTable,
 		d.tablePrefix, d.metadataTable)
 
-	_, err := d.db.Exec(schema)
-	return err
+	if _, err := d.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Migration for existing databases that don't have the line columns
+	_, _ = d.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN start_line INTEGER NOT NULL DEFAULT 0`, d.docsTable))
+	_, _ = d.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN end_line INTEGER NOT NULL DEFAULT 0`, d.docsTable))
+
+	return nil
 }
 
 func (d *bm25DB) AddDocument(ctx context.Context, doc database.Document) error {
@@ -107,17 +116,17 @@ func (d *bm25DB) AddDocument(ctx context.Context, doc database.Document) error {
 
 	if exists {
 		_, err = tx.ExecContext(ctx,
-			fmt.Sprintf(`UPDATE %s SET content = ?, file_hash = ?, created_at = CURRENT_TIMESTAMP 
+			fmt.Sprintf(`UPDATE %s SET content = ?, file_hash = ?, start_line = ?, end_line = ?, created_at = CURRENT_TIMESTAMP 
 			 WHERE source_path = ? AND chunk_index = ?`, d.docsTable),
-			doc.Content, doc.FileHash, doc.SourcePath, doc.ChunkIndex)
+			doc.Content, doc.FileHash, doc.StartLine, doc.EndLine, doc.SourcePath, doc.ChunkIndex)
 		if err != nil {
 			return fmt.Errorf("failed to update document: %w", err)
 		}
 	} else {
 		_, err = tx.ExecContext(ctx,
-			fmt.Sprintf(`INSERT INTO %s (id, source_path, chunk_index, content, file_hash)
-			 VALUES (?, ?, ?, ?, ?)`, d.docsTable),
-			doc.ID, doc.SourcePath, doc.ChunkIndex, doc.Content, doc.FileHash)
+			fmt.Sprintf(`INSERT INTO %s (id, source_path, chunk_index, content, file_hash, start_line, end_line)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`, d.docsTable),
+			doc.ID, doc.SourcePath, doc.ChunkIndex, doc.Content, doc.FileHash, doc.StartLine, doc.EndLine)
 		if err != nil {
 			return fmt.Errorf("failed to insert document: %w", err)
 		}
@@ -133,7 +142,7 @@ func (d *bm25DB) DeleteDocumentsByPath(ctx context.Context, sourcePath string) e
 
 func (d *bm25DB) GetAllDocuments(ctx context.Context) ([]database.Document, error) {
 	query := fmt.Sprintf(`
-	SELECT id, source_path, chunk_index, content, file_hash, created_at
+	SELECT id, source_path, chunk_index, content, file_hash, created_at, start_line, end_line
 	FROM %s
 	`, d.docsTable)
 
@@ -147,7 +156,7 @@ func (d *bm25DB) GetAllDocuments(ctx context.Context) ([]database.Document, erro
 	for rows.Next() {
 		var doc database.Document
 		if err := rows.Scan(&doc.ID, &doc.SourcePath, &doc.ChunkIndex, &doc.Content,
-			&doc.FileHash, &doc.CreatedAt); err != nil {
+			&doc.FileHash, &doc.CreatedAt, &doc.StartLine, &doc.EndLine); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 		docs = append(docs, doc)
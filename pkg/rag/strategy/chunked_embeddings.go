@@ -63,6 +63,12 @@ func NewChunkedEmbeddingsFromConfig(ctx context.Context, cfg latest.RAGStrategyC
 		return nil, fmt.Errorf("failed to create database: %w", err)
 	}
 
+	indexCfg, err := ParseIndexConfig(cfg.Params, dbPath, vectorDimensions)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	// Create embedder
 	embedder := CreateEmbedder(embeddingCfg.Provider, batchSize, maxConcurrency)
 
@@ -85,6 +91,7 @@ func NewChunkedEmbeddingsFromConfig(ctx context.Context, cfg latest.RAGStrategyC
 		FileIndexConcurrency: fileIndexConcurrency,
 		Chunking:             chunkingCfg,
 		ShouldIgnore:         BuildShouldIgnore(buildCtx, cfg.Params),
+		Index:                indexCfg,
 	})
 
 	return &Config{
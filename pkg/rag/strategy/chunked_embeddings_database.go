@@ -16,11 +16,9 @@ import (
 // chunkedVectorDB implements vectorStoreDB for the chunked-embeddings strategy.
 // It stores document chunks with their embedding vectors (no semantic summaries).
 type chunkedVectorDB struct {
-	db               *sql.DB
+	embeddingChunksDB
 	vectorDimensions int
 	tablePrefix      string
-	filesTable       string
-	chunksTable      string
 }
 
 // newChunkedVectorDB creates a new SQLite database for chunked vector embeddings.
@@ -41,11 +39,13 @@ func newChunkedVectorDB(dbPath string, vectorDimensions int, strategyName string
 	tablePrefix := sanitizeTableName(strategyName)
 
 	cdb := &chunkedVectorDB{
-		db:               db,
+		embeddingChunksDB: embeddingChunksDB{
+			db:          db,
+			filesTable:  tablePrefix + "_files",
+			chunksTable: tablePrefix + "_chunks",
+		},
 		vectorDimensions: vectorDimensions,
 		tablePrefix:      tablePrefix,
-		filesTable:       tablePrefix + "_files",
-		chunksTable:      tablePrefix + "_chunks",
 	}
 
 	if err := cdb.createSchema(); err != nil {
@@ -75,13 +75,22 @@ func (d *chunkedVectorDB) createSchema() error {
 		chunk_index INTEGER NOT NULL,
 		content TEXT NOT NULL,
 		embedding BLOB NOT NULL,
+		start_line INTEGER NOT NULL DEFAULT 0,
+		end_line INTEGER NOT NULL DEFAULT 0,
 		PRIMARY KEY (source_path, chunk_index),
 		FOREIGN KEY (source_path) REFERENCES %s(source_path) ON DELETE CASCADE
 	);
 	`, d.filesTable, d.tablePrefix, d.filesTable, d.chunksTable, d.filesTable)
 
-	_, err := d.db.Exec(schema)
-	return err
+	if _, err := d.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Migration for existing databases that don't have the line columns
+	_, _ = d.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN start_line INTEGER NOT NULL DEFAULT 0`, d.chunksTable))
+	_, _ = d.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN end_line INTEGER NOT NULL DEFAULT 0`, d.chunksTable))
+
+	return nil
 }
 
 // AddDocumentWithEmbedding implements vectorStoreDB.
@@ -116,11 +125,11 @@ func (d *chunkedVectorDB) AddDocumentWithEmbedding(ctx context.Context, doc data
 	}
 
 	_, err = tx.ExecContext(ctx,
-		fmt.Sprintf(`INSERT INTO %s (source_path, chunk_index, content, embedding)
-		 VALUES (?, ?, ?, ?)
+		fmt.Sprintf(`INSERT INTO %s (source_path, chunk_index, content, embedding, start_line, end_line)
+		 VALUES (?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(source_path, chunk_index) 
-		 DO UPDATE SET content = excluded.content, embedding = excluded.embedding`, d.chunksTable),
-		doc.SourcePath, doc.ChunkIndex, doc.Content, embJSON)
+		 DO UPDATE SET content = excluded.content, embedding = excluded.embedding, start_line = excluded.start_line, end_line = excluded.end_line`, d.chunksTable),
+		doc.SourcePath, doc.ChunkIndex, doc.Content, embJSON, doc.StartLine, doc.EndLine)
 	if err != nil {
 		return fmt.Errorf("failed to upsert chunk: %w", err)
 	}
@@ -131,7 +140,7 @@ func (d *chunkedVectorDB) AddDocumentWithEmbedding(ctx context.Context, doc data
 // SearchSimilarVectors implements vectorStoreDB.
 func (d *chunkedVectorDB) SearchSimilarVectors(ctx context.Context, queryEmbedding []float64, limit int) ([]VectorSearchResultData, error) {
 	query := fmt.Sprintf(`
-	SELECT c.source_path, c.chunk_index, c.content, c.embedding, f.file_hash, f.indexed_at
+	SELECT c.source_path, c.chunk_index, c.content, c.start_line, c.end_line, c.embedding, f.file_hash, f.indexed_at
 	FROM %s c
 	JOIN %s f ON c.source_path = f.source_path
 	`, d.chunksTable, d.filesTable)
@@ -147,7 +156,7 @@ func (d *chunkedVectorDB) SearchSimilarVectors(ctx context.Context, queryEmbeddi
 		var doc database.Document
 		var embJSON []byte
 
-		if err := rows.Scan(&doc.SourcePath, &doc.ChunkIndex, &doc.Content,
+		if err := rows.Scan(&doc.SourcePath, &doc.ChunkIndex, &doc.Content, &doc.StartLine, &doc.EndLine,
 			&embJSON, &doc.FileHash, &doc.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
@@ -183,6 +192,10 @@ func (d *chunkedVectorDB) SearchSimilarVectors(ctx context.Context, queryEmbeddi
 }
 
 func (d *chunkedVectorDB) DeleteDocumentsByPath(ctx context.Context, sourcePath string) error {
+	// SQLite doesn't enforce foreign keys by default: the chunks aren't deleted in cascade.
+	if _, err := d.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE source_path = ?", d.chunksTable), sourcePath); err != nil {
+		return err
+	}
 	_, err := d.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE source_path = ?", d.filesTable), sourcePath)
 	return err
 }
@@ -0,0 +1,74 @@
+package strategy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/cagent/pkg/rag/ann"
+	"github.com/docker/cagent/pkg/rag/database"
+)
+
+// embeddingChunksDB reads the chunks and embeddings stored by the vector strategies'
+// databases, which share the layout of their files and chunks tables.
+type embeddingChunksDB struct {
+	db          *sql.DB
+	filesTable  string
+	chunksTable string
+}
+
+// GetChunk implements vectorStoreDB.
+func (d *embeddingChunksDB) GetChunk(ctx context.Context, key ann.Key) (*database.Document, error) {
+	var doc database.Document
+	err := d.db.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT c.source_path, c.chunk_index, c.content, c.start_line, c.end_line, f.file_hash, f.indexed_at
+		 FROM %s c
+		 JOIN %s f ON c.source_path = f.source_path
+		 WHERE c.source_path = ? AND c.chunk_index = ?`, d.chunksTable, d.filesTable),
+		key.SourcePath, key.ChunkIndex).Scan(&doc.SourcePath, &doc.ChunkIndex, &doc.Content, &doc.StartLine, &doc.EndLine, &doc.FileHash, &doc.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunk: %w", err)
+	}
+
+	doc.ID = fmt.Sprintf("%s_%d", doc.SourcePath, doc.ChunkIndex)
+	return &doc, nil
+}
+
+// ForEachEmbedding implements vectorStoreDB.
+func (d *embeddingChunksDB) ForEachEmbedding(ctx context.Context, fn func(key ann.Key, embedding []float64) error) error {
+	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(`SELECT source_path, chunk_index, embedding FROM %s`, d.chunksTable))
+	if err != nil {
+		return fmt.Errorf("failed to query embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key ann.Key
+		var embJSON []byte
+		if err := rows.Scan(&key.SourcePath, &key.ChunkIndex, &embJSON); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		var embedding []float64
+		if err := json.Unmarshal(embJSON, &embedding); err != nil {
+			return fmt.Errorf("failed to unmarshal embedding: %w", err)
+		}
+		if err := fn(key, embedding); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountChunks implements vectorStoreDB.
+func (d *embeddingChunksDB) CountChunks(ctx context.Context) (int, error) {
+	var count int
+	err := d.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", d.chunksTable)).Scan(&count)
+	return count, err
+}
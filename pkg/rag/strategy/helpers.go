@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/docker/cagent/pkg/config/latest"
 	"github.com/docker/cagent/pkg/fsx"
 	"github.com/docker/cagent/pkg/paths"
+	"github.com/docker/cagent/pkg/rag/chunk"
+	"github.com/docker/cagent/pkg/rag/extract"
+	"github.com/docker/cagent/pkg/rag/treesitter"
 	"github.com/docker/cagent/pkg/rag/types"
 )
 
@@ -232,6 +236,40 @@ func ParseChunkingConfig(cfg latest.RAGStrategyConfig) ChunkingConfig {
 	}
 }
 
+// NewDocumentProcessor creates the document processor of a strategy, picked by file type:
+// Markdown is chunked by section, the text of PDF, HTML and DOCX documents is extracted
+// before chunking, and other files are chunked as code (if code-aware) or plain text.
+func NewDocumentProcessor(chunking ChunkingConfig) chunk.DocumentProcessor {
+	text := chunk.NewTextDocumentProcessor(chunking.Size, chunking.Overlap, chunking.RespectWordBoundaries)
+	markdown := chunk.NewMarkdownDocumentProcessor(chunking.Size, chunking.Overlap, chunking.RespectWordBoundaries)
+
+	var fallback chunk.DocumentProcessor = text
+	if chunking.CodeAware {
+		fallback = treesitter.NewDocumentProcessor(chunking.Size, chunking.Overlap, chunking.RespectWordBoundaries)
+	}
+
+	registry := chunk.NewRegistry(fallback)
+	registry.Register(markdown, ".md", ".markdown", ".mdx")
+	registry.Register(extract.NewProcessor(extract.HTML, markdown), ".html", ".htm")
+	registry.Register(extract.NewProcessor(extract.DOCX, markdown), ".docx")
+	registry.Register(extract.NewProcessor(extract.PDF, text), ".pdf")
+	return registry
+}
+
+// chunkLines returns the lines of the source document covered by a chunk, as recorded
+// in its metadata by code-aware chunking, or zeros if they're unknown.
+func chunkLines(ch chunk.Chunk) (int, int) {
+	startLine, err := strconv.Atoi(ch.Metadata["start_line"])
+	if err != nil {
+		return 0, 0
+	}
+	endLine, err := strconv.Atoi(ch.Metadata["end_line"])
+	if err != nil {
+		return 0, 0
+	}
+	return startLine, endLine
+}
+
 // BuildShouldIgnore creates a filter function based on BuildContext and optional strategy-level override.
 // Strategy params can override the RAG-level respect_vcs setting.
 // Returns nil if no filtering should be applied.
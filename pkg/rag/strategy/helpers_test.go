@@ -0,0 +1,42 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/rag/chunk"
+	"github.com/docker/cagent/pkg/rag/extract"
+	"github.com/docker/cagent/pkg/rag/treesitter"
+)
+
+func TestNewDocumentProcessor(t *testing.T) {
+	t.Parallel()
+
+	registry, ok := NewDocumentProcessor(ChunkingConfig{Size: 1000}).(*chunk.Registry)
+	require.True(t, ok)
+
+	assert.IsType(t, &chunk.MarkdownDocumentProcessor{}, registry.Processor("README.md"))
+	assert.IsType(t, &extract.Processor{}, registry.Processor("manual.PDF"))
+	assert.IsType(t, &extract.Processor{}, registry.Processor("index.html"))
+	assert.IsType(t, &extract.Processor{}, registry.Processor("spec.docx"))
+	assert.IsType(t, &chunk.TextDocumentProcessor{}, registry.Processor("main.go"))
+
+	codeAware, ok := NewDocumentProcessor(ChunkingConfig{Size: 1000, CodeAware: true}).(*chunk.Registry)
+	require.True(t, ok)
+	assert.IsType(t, &treesitter.DocumentProcessor{}, codeAware.Processor("main.go"))
+	assert.IsType(t, &chunk.MarkdownDocumentProcessor{}, codeAware.Processor("README.md"))
+}
+
+func TestChunkLines(t *testing.T) {
+	t.Parallel()
+
+	startLine, endLine := chunkLines(chunk.Chunk{Metadata: map[string]string{"start_line": "12", "end_line": "40"}})
+	assert.Equal(t, 12, startLine)
+	assert.Equal(t, 40, endLine)
+
+	startLine, endLine = chunkLines(chunk.Chunk{})
+	assert.Zero(t, startLine)
+	assert.Zero(t, endLine)
+}
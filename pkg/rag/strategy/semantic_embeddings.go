@@ -132,6 +132,12 @@ func NewSemanticEmbeddingsFromConfig(ctx context.Context, cfg latest.RAGStrategy
 		return nil, fmt.Errorf("failed to create database: %w", err)
 	}
 
+	indexCfg, err := ParseIndexConfig(cfg.Params, dbPath, vectorDimensions)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	// Create embedder
 	embedder := CreateEmbedder(embeddingCfg.Provider, batchSize, maxConcurrency)
 
@@ -154,6 +160,7 @@ func NewSemanticEmbeddingsFromConfig(ctx context.Context, cfg latest.RAGStrategy
 		FileIndexConcurrency: fileIndexConcurrency,
 		Chunking:             chunkingCfg,
 		ShouldIgnore:         BuildShouldIgnore(buildCtx, cfg.Params),
+		Index:                indexCfg,
 	})
 
 	// Create usage tracker for chat LLM calls
@@ -373,6 +380,13 @@ func buildEmbeddingInputWithMetadata(sourcePath string, metadata map[string]stri
 		sb.WriteString("\n")
 	}
 
+	// Add the class, impl... of methods
+	if parent := metadata["parent"]; parent != "" {
+		sb.WriteString("Parent: ")
+		sb.WriteString(parent)
+		sb.WriteString("\n")
+	}
+
 	// Add receiver for methods
 	if receiver := metadata["receiver"]; receiver != "" {
 		sb.WriteString("Receiver: ")
@@ -422,6 +436,7 @@ func formatASTContext(metadata map[string]string) string {
 	keyOrder := []keyLabel{
 		{key: "symbol_name", label: "Symbol"},
 		{key: "symbol_kind", label: "Kind"},
+		{key: "parent", label: "Parent"},
 		{key: "receiver", label: "Receiver"},
 		{key: "signature", label: "Signature"},
 		{key: "doc", label: "Doc"},
@@ -17,11 +17,9 @@ import (
 // It stores document chunks with their embedding vectors AND the LLM-generated
 // summary (embedding_input) for debugging what text produced the embedding.
 type semanticVectorDB struct {
-	db               *sql.DB
+	embeddingChunksDB
 	vectorDimensions int
 	tablePrefix      string
-	filesTable       string
-	chunksTable      string
 }
 
 // newSemanticVectorDB creates a new SQLite database for semantic embeddings.
@@ -42,11 +40,13 @@ func newSemanticVectorDB(dbPath string, vectorDimensions int, strategyName strin
 	tablePrefix := sanitizeTableName(strategyName)
 
 	sdb := &semanticVectorDB{
-		db:               db,
+		embeddingChunksDB: embeddingChunksDB{
+			db:          db,
+			filesTable:  tablePrefix + "_files",
+			chunksTable: tablePrefix + "_chunks",
+		},
 		vectorDimensions: vectorDimensions,
 		tablePrefix:      tablePrefix,
-		filesTable:       tablePrefix + "_files",
-		chunksTable:      tablePrefix + "_chunks",
 	}
 
 	if err := sdb.createSchema(); err != nil {
@@ -77,6 +77,8 @@ func (d *semanticVectorDB) createSchema() error {
 		content TEXT NOT NULL,
 		embedding BLOB NOT NULL,
 		embedding_input TEXT,
+		start_line INTEGER NOT NULL DEFAULT 0,
+		end_line INTEGER NOT NULL DEFAULT 0,
 		PRIMARY KEY (source_path, chunk_index),
 		FOREIGN KEY (source_path) REFERENCES %s(source_path) ON DELETE CASCADE
 	);
@@ -89,6 +91,10 @@ func (d *semanticVectorDB) createSchema() error {
 	// Migration for existing databases that don't have embedding_input column
 	_, _ = d.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN embedding_input TEXT`, d.chunksTable))
 
+	// Migration for existing databases that don't have the line columns
+	_, _ = d.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN start_line INTEGER NOT NULL DEFAULT 0`, d.chunksTable))
+	_, _ = d.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN end_line INTEGER NOT NULL DEFAULT 0`, d.chunksTable))
+
 	return nil
 }
 
@@ -124,11 +130,12 @@ func (d *semanticVectorDB) AddDocumentWithEmbedding(ctx context.Context, doc dat
 	}
 
 	_, err = tx.ExecContext(ctx,
-		fmt.Sprintf(`INSERT INTO %s (source_path, chunk_index, content, embedding, embedding_input)
-		 VALUES (?, ?, ?, ?, ?)
+		fmt.Sprintf(`INSERT INTO %s (source_path, chunk_index, content, embedding, embedding_input, start_line, end_line)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(source_path, chunk_index) 
-		 DO UPDATE SET content = excluded.content, embedding = excluded.embedding, embedding_input = excluded.embedding_input`, d.chunksTable),
-		doc.SourcePath, doc.ChunkIndex, doc.Content, embJSON, embeddingInput)
+		 DO UPDATE SET content = excluded.content, embedding = excluded.embedding, embedding_input = excluded.embedding_input,
+		  start_line = excluded.start_line, end_line = excluded.end_line`, d.chunksTable),
+		doc.SourcePath, doc.ChunkIndex, doc.Content, embJSON, embeddingInput, doc.StartLine, doc.EndLine)
 	if err != nil {
 		return fmt.Errorf("failed to upsert chunk: %w", err)
 	}
@@ -139,7 +146,7 @@ func (d *semanticVectorDB) AddDocumentWithEmbedding(ctx context.Context, doc dat
 // SearchSimilarVectors implements vectorStoreDB.
 func (d *semanticVectorDB) SearchSimilarVectors(ctx context.Context, queryEmbedding []float64, limit int) ([]VectorSearchResultData, error) {
 	query := fmt.Sprintf(`
-	SELECT c.source_path, c.chunk_index, c.content, c.embedding, c.embedding_input, f.file_hash, f.indexed_at
+	SELECT c.source_path, c.chunk_index, c.content, c.start_line, c.end_line, c.embedding, c.embedding_input, f.file_hash, f.indexed_at
 	FROM %s c
 	JOIN %s f ON c.source_path = f.source_path
 	`, d.chunksTable, d.filesTable)
@@ -156,7 +163,7 @@ func (d *semanticVectorDB) SearchSimilarVectors(ctx context.Context, queryEmbedd
 		var embJSON []byte
 		var embeddingInput sql.NullString
 
-		if err := rows.Scan(&doc.SourcePath, &doc.ChunkIndex, &doc.Content,
+		if err := rows.Scan(&doc.SourcePath, &doc.ChunkIndex, &doc.Content, &doc.StartLine, &doc.EndLine,
 			&embJSON, &embeddingInput, &doc.FileHash, &doc.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
@@ -193,6 +200,10 @@ func (d *semanticVectorDB) SearchSimilarVectors(ctx context.Context, queryEmbedd
 }
 
 func (d *semanticVectorDB) DeleteDocumentsByPath(ctx context.Context, sourcePath string) error {
+	// SQLite doesn't enforce foreign keys by default: the chunks aren't deleted in cascade.
+	if _, err := d.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE source_path = ?", d.chunksTable), sourcePath); err != nil {
+		return err
+	}
 	_, err := d.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE source_path = ?", d.filesTable), sourcePath)
 	return err
 }
@@ -0,0 +1,154 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"github.com/docker/cagent/pkg/rag/ann"
+	"github.com/docker/cagent/pkg/rag/database"
+)
+
+// IndexConfig configures how a VectorStore finds the chunks most similar to a query.
+type IndexConfig struct {
+	// Type is "exact" (default), to compare the query with every chunk, or
+	// "hnsw", to search an approximate nearest neighbour index.
+	Type string
+	// Path is where the index is persisted. It's kept in memory if empty.
+	Path           string
+	Dimensions     int
+	M              int
+	EfConstruction int
+	EfSearch       int
+}
+
+// ParseIndexConfig reads the vector_index, hnsw_m, hnsw_ef_construction and
+// hnsw_ef_search params. The index is persisted next to the database.
+func ParseIndexConfig(params map[string]any, dbPath string, dimensions int) (IndexConfig, error) {
+	cfg := IndexConfig{
+		Type:           GetParam(params, "vector_index", "exact"),
+		Dimensions:     dimensions,
+		M:              GetParam(params, "hnsw_m", 16),
+		EfConstruction: GetParam(params, "hnsw_ef_construction", 200),
+		EfSearch:       GetParam(params, "hnsw_ef_search", 64),
+	}
+	if cfg.Type != "exact" && cfg.Type != "hnsw" {
+		return IndexConfig{}, fmt.Errorf("unsupported vector_index %q, must be 'exact' or 'hnsw'", cfg.Type)
+	}
+	if !strings.Contains(dbPath, "://") {
+		cfg.Path = dbPath + ".hnsw"
+	}
+	return cfg, nil
+}
+
+func (c IndexConfig) options() ann.Options {
+	return ann.Options{
+		Dimensions:     c.Dimensions,
+		M:              c.M,
+		EfConstruction: c.EfConstruction,
+		EfSearch:       c.EfSearch,
+	}
+}
+
+// loadIndex loads the persisted index or, if it's missing or out of sync with
+// the database, builds it from the embeddings stored in the database.
+func (s *VectorStore) loadIndex(ctx context.Context) error {
+	count, err := s.db.CountChunks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count chunks: %w", err)
+	}
+
+	if s.indexConfig.Path != "" {
+		index, err := ann.Load(s.indexConfig.Path, s.indexConfig.options())
+		switch {
+		case err == nil && index.Len() == count:
+			slog.Debug("Loaded vector index", "strategy", s.name, "path", s.indexConfig.Path, "chunks", count)
+			s.setIndex(index)
+			return nil
+		case err == nil:
+			slog.Info("Vector index out of sync with the database, rebuilding it", "strategy", s.name, "indexed", index.Len(), "chunks", count)
+		case !errors.Is(err, fs.ErrNotExist):
+			slog.Warn("Failed to load vector index, rebuilding it", "strategy", s.name, "path", s.indexConfig.Path, "error", err)
+		}
+	}
+
+	index := ann.New(s.indexConfig.options())
+	if err := s.db.ForEachEmbedding(ctx, index.Add); err != nil {
+		return fmt.Errorf("failed to build vector index: %w", err)
+	}
+	s.setIndex(index)
+	s.indexDirty.Store(true)
+
+	slog.Info("Built vector index", "strategy", s.name, "chunks", index.Len())
+	return nil
+}
+
+func (s *VectorStore) setIndex(index *ann.HNSW) {
+	s.indexMu.Lock()
+	s.index = index
+	s.indexMu.Unlock()
+}
+
+func (s *VectorStore) getIndex() *ann.HNSW {
+	s.indexMu.RLock()
+	defer s.indexMu.RUnlock()
+	return s.index
+}
+
+// saveIndex persists the index if it changed since it was last saved.
+func (s *VectorStore) saveIndex() {
+	index := s.getIndex()
+	if index == nil || s.indexConfig.Path == "" || !s.indexDirty.Swap(false) {
+		return
+	}
+
+	if err := index.Save(s.indexConfig.Path); err != nil {
+		slog.Warn("Failed to save vector index", "strategy", s.name, "path", s.indexConfig.Path, "error", err)
+		s.indexDirty.Store(true)
+	}
+}
+
+func (s *VectorStore) indexAdd(doc database.Document, embedding []float64) {
+	if index := s.getIndex(); index != nil {
+		if err := index.Add(ann.Key{SourcePath: doc.SourcePath, ChunkIndex: doc.ChunkIndex}, embedding); err != nil {
+			slog.Warn("Failed to add chunk to the vector index", "strategy", s.name, "path", doc.SourcePath, "error", err)
+		}
+		s.indexDirty.Store(true)
+	}
+}
+
+func (s *VectorStore) indexDelete(sourcePath string) {
+	if index := s.getIndex(); index != nil {
+		index.DeleteSource(sourcePath)
+		s.indexDirty.Store(true)
+	}
+}
+
+// searchIndex finds the chunks most similar to the query with the index. It returns
+// false if there's no index to search, in which case every chunk has to be compared.
+func (s *VectorStore) searchIndex(ctx context.Context, queryEmbedding []float64, limit int) ([]VectorSearchResultData, bool, error) {
+	index := s.getIndex()
+	if index == nil {
+		return nil, false, nil
+	}
+
+	var results []VectorSearchResultData
+	for _, found := range index.Search(queryEmbedding, limit) {
+		doc, err := s.db.GetChunk(ctx, found.Key)
+		if err != nil {
+			return nil, true, err
+		}
+		if doc == nil {
+			// Deleted from the database since it was indexed.
+			continue
+		}
+		results = append(results, VectorSearchResultData{
+			Document:   *doc,
+			Similarity: found.Similarity,
+		})
+	}
+	return results, true, nil
+}
@@ -0,0 +1,119 @@
+package strategy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/rag/database"
+)
+
+func newIndexedStore(t *testing.T, dbPath string) *VectorStore {
+	t.Helper()
+
+	db, err := newChunkedVectorDB(dbPath, 3, "chunked-embeddings")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	indexCfg, err := ParseIndexConfig(map[string]any{"vector_index": "hnsw"}, dbPath, 3)
+	require.NoError(t, err)
+
+	store := &VectorStore{name: "chunked-embeddings", db: db, indexConfig: indexCfg}
+	require.NoError(t, store.loadIndex(t.Context()))
+	return store
+}
+
+func addChunk(t *testing.T, store *VectorStore, path string, index int, content string, embedding []float64) {
+	t.Helper()
+
+	doc := database.Document{SourcePath: path, ChunkIndex: index, Content: content, FileHash: "hash"}
+	require.NoError(t, store.db.AddDocumentWithEmbedding(t.Context(), doc, embedding, ""))
+	store.indexAdd(doc, embedding)
+}
+
+func TestVectorStore_HNSWIndex(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	store := newIndexedStore(t, dbPath)
+
+	addChunk(t, store, "a.md", 0, "about cats", []float64{1, 0, 0})
+	addChunk(t, store, "a.md", 1, "about dogs", []float64{0, 1, 0})
+	addChunk(t, store, "b.md", 0, "about birds", []float64{0, 0, 1})
+
+	results, indexed, err := store.searchIndex(t.Context(), []float64{0.9, 0.1, 0}, 2)
+	require.NoError(t, err)
+	assert.True(t, indexed)
+	require.Len(t, results, 2)
+	assert.Equal(t, "about cats", results[0].Content)
+	assert.Equal(t, "a.md_0", results[0].ID)
+	assert.Equal(t, "about dogs", results[1].Content)
+
+	// Deleted documents are removed from both the database and the index.
+	require.NoError(t, store.db.DeleteDocumentsByPath(t.Context(), "a.md"))
+	store.indexDelete("a.md")
+	results, _, err = store.searchIndex(t.Context(), []float64{0.9, 0.1, 0}, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "about birds", results[0].Content)
+
+	store.saveIndex()
+	assert.FileExists(t, dbPath+".hnsw")
+}
+
+func TestVectorStore_ChunkLines(t *testing.T) {
+	t.Parallel()
+
+	store := newIndexedStore(t, filepath.Join(t.TempDir(), "rag.db"))
+
+	doc := database.Document{SourcePath: "calc.py", ChunkIndex: 0, Content: "def add(a, b): ...", FileHash: "hash", StartLine: 12, EndLine: 14}
+	require.NoError(t, store.db.AddDocumentWithEmbedding(t.Context(), doc, []float64{1, 0, 0}, ""))
+
+	results, err := store.db.SearchSimilarVectors(t.Context(), []float64{1, 0, 0}, 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 12, results[0].StartLine)
+	assert.Equal(t, 14, results[0].EndLine)
+}
+
+func TestVectorStore_HNSWIndexRebuild(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "rag.db")
+
+	store := newIndexedStore(t, dbPath)
+	addChunk(t, store, "a.md", 0, "about cats", []float64{1, 0, 0})
+	store.saveIndex()
+
+	// A chunk indexed without updating the persisted index.
+	doc := database.Document{SourcePath: "b.md", ChunkIndex: 0, Content: "about birds", FileHash: "hash"}
+	require.NoError(t, store.db.AddDocumentWithEmbedding(t.Context(), doc, []float64{0, 0, 1}, ""))
+	require.NoError(t, store.db.Close())
+
+	// The index is out of sync with the database: it's rebuilt.
+	reopened := newIndexedStore(t, dbPath)
+	results, _, err := reopened.searchIndex(t.Context(), []float64{0, 0, 1}, 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "about birds", results[0].Content)
+}
+
+func TestParseIndexConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseIndexConfig(nil, "/data/rag.db", 1536)
+	require.NoError(t, err)
+	assert.Equal(t, IndexConfig{Type: "exact", Path: "/data/rag.db.hnsw", Dimensions: 1536, M: 16, EfConstruction: 200, EfSearch: 64}, cfg)
+
+	cfg, err = ParseIndexConfig(map[string]any{"vector_index": "hnsw", "hnsw_ef_search": 128}, "postgres://db", 1536)
+	require.NoError(t, err)
+	assert.Equal(t, "hnsw", cfg.Type)
+	assert.Equal(t, 128, cfg.EfSearch)
+	assert.Empty(t, cfg.Path)
+
+	_, err = ParseIndexConfig(map[string]any{"vector_index": "ivf"}, "/data/rag.db", 1536)
+	require.ErrorContains(t, err, "unsupported vector_index")
+}
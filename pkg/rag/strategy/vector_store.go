@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -15,10 +16,10 @@ import (
 
 	"github.com/docker/cagent/pkg/fsx"
 	"github.com/docker/cagent/pkg/modelsdev"
+	"github.com/docker/cagent/pkg/rag/ann"
 	"github.com/docker/cagent/pkg/rag/chunk"
 	"github.com/docker/cagent/pkg/rag/database"
 	"github.com/docker/cagent/pkg/rag/embed"
-	"github.com/docker/cagent/pkg/rag/treesitter"
 	"github.com/docker/cagent/pkg/rag/types"
 )
 
@@ -31,6 +32,9 @@ type vectorStoreDB interface {
 	SetFileMetadata(ctx context.Context, metadata database.FileMetadata) error
 	GetAllFileMetadata(ctx context.Context) ([]database.FileMetadata, error)
 	DeleteFileMetadata(ctx context.Context, sourcePath string) error
+	GetChunk(ctx context.Context, key ann.Key) (*database.Document, error)
+	ForEachEmbedding(ctx context.Context, fn func(key ann.Key, embedding []float64) error) error
+	CountChunks(ctx context.Context) (int, error)
 	Close() error
 }
 
@@ -84,6 +88,13 @@ type VectorStore struct {
 	// reindexMu prevents concurrent reindexing operations from the file watcher
 	// to avoid overwhelming the system and causing event channel saturation.
 	reindexMu sync.Mutex
+
+	// index, if enabled, finds the chunks most similar to a query without
+	// comparing it with all of them. It's updated as files are (re)indexed.
+	indexConfig IndexConfig
+	index       *ann.HNSW
+	indexMu     sync.RWMutex
+	indexDirty  atomic.Bool
 }
 
 type modelStore interface {
@@ -119,23 +130,16 @@ type VectorStoreConfig struct {
 	FileIndexConcurrency int
 	Chunking             ChunkingConfig
 	ShouldIgnore         func(path string) bool // Optional filter for gitignore support
+	Index                IndexConfig
 }
 
 // NewVectorStore creates a new vector store with the given configuration.
 func NewVectorStore(cfg VectorStoreConfig) *VectorStore {
-	// Create the appropriate document processor based on config
-	var dp chunk.DocumentProcessor
-	if cfg.Chunking.CodeAware {
-		dp = treesitter.NewDocumentProcessor(cfg.Chunking.Size, cfg.Chunking.Overlap, cfg.Chunking.RespectWordBoundaries)
-	} else {
-		dp = chunk.NewTextDocumentProcessor(cfg.Chunking.Size, cfg.Chunking.Overlap, cfg.Chunking.RespectWordBoundaries)
-	}
-
 	s := &VectorStore{
 		name:                  cfg.Name,
 		db:                    cfg.Database,
 		embedder:              cfg.Embedder,
-		docProcessor:          dp,
+		docProcessor:          NewDocumentProcessor(cfg.Chunking),
 		fileHashes:            make(map[string]string),
 		events:                cfg.Events,
 		shouldIgnore:          cfg.ShouldIgnore,
@@ -145,6 +149,7 @@ func NewVectorStore(cfg VectorStoreConfig) *VectorStore {
 		embeddingInputBuilder: DefaultEmbeddingInputBuilder{},
 		embeddingConcurrency:  cfg.EmbeddingConcurrency,
 		fileIndexConcurrency:  cfg.FileIndexConcurrency,
+		indexConfig:           cfg.Index,
 	}
 
 	// Set usage handler to calculate cost from models.dev and emit events with CUMULATIVE totals
@@ -229,6 +234,13 @@ func (s *VectorStore) Initialize(ctx context.Context, docPaths []string, chunkin
 		slog.Warn("Failed to load existing file hashes", "strategy", s.name, "error", err)
 	}
 
+	if s.indexConfig.Type == "hnsw" {
+		if err := s.loadIndex(ctx); err != nil {
+			slog.Warn("Failed to load vector index, falling back to exact search", "strategy", s.name, "error", err)
+		}
+		defer s.saveIndex()
+	}
+
 	// Collect all files
 	slog.Debug("Collecting files", "strategy", s.name, "paths", docPaths)
 	files, err := fsx.CollectFiles(docPaths, s.shouldIgnore)
@@ -368,9 +380,15 @@ func (s *VectorStore) Query(ctx context.Context, query string, numResults int, t
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
-	results, err := s.db.SearchSimilarVectors(ctx, queryEmbedding, numResults)
+	results, indexed, err := s.searchIndex(ctx, queryEmbedding, numResults)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search: %w", err)
+		slog.Warn("Failed to search the vector index, falling back to exact search", "strategy", s.name, "error", err)
+	}
+	if !indexed || err != nil {
+		results, err = s.db.SearchSimilarVectors(ctx, queryEmbedding, numResults)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search: %w", err)
+		}
 	}
 
 	// Convert internal result type to public SearchResult type
@@ -417,6 +435,7 @@ func (s *VectorStore) CheckAndReindexChangedFiles(ctx context.Context, docPaths
 		slog.Error("Failed to cleanup orphaned documents during file watch", "error", err)
 	}
 
+	s.saveIndex()
 	return nil
 }
 
@@ -461,6 +480,8 @@ func (s *VectorStore) Close() error {
 		s.watcher = nil
 	}
 
+	s.saveIndex()
+
 	// Close database connection
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
@@ -536,6 +557,7 @@ func (s *VectorStore) indexFile(ctx context.Context, filePath string) error {
 	if err := s.db.DeleteDocumentsByPath(ctx, filePath); err != nil {
 		return fmt.Errorf("failed to delete old documents: %w", err)
 	}
+	s.indexDelete(filePath)
 
 	chunks, err := chunk.ProcessFile(s.docProcessor, filePath)
 	if err != nil {
@@ -579,12 +601,15 @@ func (s *VectorStore) indexFile(ctx context.Context, filePath string) error {
 	// Store all documents
 	storedChunks := 0
 	for i, ch := range validChunks {
+		startLine, endLine := chunkLines(ch)
 		doc := database.Document{
 			ID:         fmt.Sprintf("%s_%d_%d", filePath, ch.Index, time.Now().UnixNano()),
 			SourcePath: filePath,
 			ChunkIndex: ch.Index,
 			Content:    ch.Content,
 			FileHash:   fileHash,
+			StartLine:  startLine,
+			EndLine:    endLine,
 		}
 
 		// Pass embedding and embedding input separately - the database implementation
@@ -592,6 +617,7 @@ func (s *VectorStore) indexFile(ctx context.Context, filePath string) error {
 		if err := s.db.AddDocumentWithEmbedding(ctx, doc, embeddings[i], chunkContents[i]); err != nil {
 			return fmt.Errorf("failed to add document: %w", err)
 		}
+		s.indexAdd(doc, embeddings[i])
 
 		storedChunks++
 	}
@@ -679,6 +705,7 @@ func (s *VectorStore) cleanupOrphanedDocuments(ctx context.Context, seenFiles ma
 				"path", meta.SourcePath, "error", err)
 			continue
 		}
+		s.indexDelete(meta.SourcePath)
 
 		if err := s.db.DeleteFileMetadata(ctx, meta.SourcePath); err != nil {
 			slog.Error("Failed to delete orphaned metadata",
@@ -834,6 +861,7 @@ func (s *VectorStore) watchLoop(ctx context.Context, docPaths []string) {
 			if err := s.cleanupOrphanedDocumentsFromDisk(ctx, docPaths); err != nil {
 				slog.Error("Failed to cleanup orphaned documents", "error", err)
 			}
+			s.saveIndex()
 
 			s.emitEvent(types.Event{
 				Type:    "indexing_completed",
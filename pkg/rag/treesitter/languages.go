@@ -0,0 +1,321 @@
+package treesitter
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+	"github.com/smacker/go-tree-sitter/yaml"
+)
+
+// language describes how to find the symbols (functions, methods, classes...)
+// in the syntax trees of a language.
+type language struct {
+	grammar *sitter.Language
+	// symbols maps the node types of symbols to their kind.
+	symbols map[string]string
+	// wrappers are node types wrapping a symbol together with its decorators,
+	// annotations or export keyword. They are chunked with the symbol they wrap.
+	wrappers map[string]bool
+	// containers are the kinds of symbols holding other symbols, e.g. classes
+	// holding methods. Containers larger than a chunk are split into their members.
+	containers map[string]bool
+	// name returns the name of a symbol. By default, it's its "name" field.
+	name func(n *sitter.Node, content []byte) string
+	// packageName returns the package declared by a file, if the language has packages.
+	packageName func(root *sitter.Node, content []byte) string
+}
+
+func set(values ...string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
+
+var (
+	goLanguage = &language{
+		grammar: golang.GetLanguage(),
+		symbols: map[string]string{
+			"function_declaration": "function",
+			"method_declaration":   "method",
+		},
+		packageName: extractPackageName,
+	}
+
+	pythonLanguage = &language{
+		grammar: python.GetLanguage(),
+		symbols: map[string]string{
+			"function_definition": "function",
+			"class_definition":    "class",
+		},
+		wrappers:   set("decorated_definition"),
+		containers: set("class"),
+	}
+
+	javascriptSymbols = map[string]string{
+		"function_declaration":           "function",
+		"generator_function_declaration": "function",
+		"class_declaration":              "class",
+		"method_definition":              "method",
+		"lexical_declaration":            "function",
+		"variable_declaration":           "function",
+	}
+
+	typescriptSymbols = merge(javascriptSymbols, map[string]string{
+		"abstract_class_declaration": "class",
+		"interface_declaration":      "interface",
+		"type_alias_declaration":     "type",
+		"enum_declaration":           "enum",
+	})
+
+	javascriptLanguage = &language{
+		grammar:    javascript.GetLanguage(),
+		symbols:    javascriptSymbols,
+		wrappers:   set("export_statement"),
+		containers: set("class"),
+		name:       javascriptName,
+	}
+
+	typescriptLanguage = &language{
+		grammar:    typescript.GetLanguage(),
+		symbols:    typescriptSymbols,
+		wrappers:   set("export_statement"),
+		containers: set("class"),
+		name:       javascriptName,
+	}
+
+	tsxLanguage = &language{
+		grammar:    tsx.GetLanguage(),
+		symbols:    typescriptSymbols,
+		wrappers:   set("export_statement"),
+		containers: set("class"),
+		name:       javascriptName,
+	}
+
+	javaLanguage = &language{
+		grammar: java.GetLanguage(),
+		symbols: map[string]string{
+			"class_declaration":       "class",
+			"interface_declaration":   "interface",
+			"enum_declaration":        "enum",
+			"record_declaration":      "record",
+			"method_declaration":      "method",
+			"constructor_declaration": "constructor",
+		},
+		containers:  set("class", "interface", "enum", "record"),
+		packageName: javaPackageName,
+	}
+
+	rustLanguage = &language{
+		grammar: rust.GetLanguage(),
+		symbols: map[string]string{
+			"function_item":    "function",
+			"impl_item":        "impl",
+			"trait_item":       "trait",
+			"struct_item":      "struct",
+			"enum_item":        "enum",
+			"mod_item":         "module",
+			"macro_definition": "macro",
+		},
+		containers: set("impl", "trait", "module"),
+		name:       rustName,
+	}
+
+	cLanguage = &language{
+		grammar: c.GetLanguage(),
+		symbols: map[string]string{
+			"function_definition": "function",
+			"struct_specifier":    "struct",
+			"type_definition":     "type",
+		},
+		name: cName,
+	}
+
+	cppLanguage = &language{
+		grammar: cpp.GetLanguage(),
+		symbols: map[string]string{
+			"function_definition":  "function",
+			"class_specifier":      "class",
+			"struct_specifier":     "struct",
+			"namespace_definition": "namespace",
+		},
+		wrappers:   set("template_declaration"),
+		containers: set("class", "struct", "namespace"),
+		name:       cName,
+	}
+
+	yamlLanguage = &language{
+		grammar: yaml.GetLanguage(),
+		symbols: map[string]string{
+			"block_mapping_pair":  "key",
+			"block_sequence_item": "item",
+		},
+		containers: set("key", "item"),
+		name: func(n *sitter.Node, content []byte) string {
+			return strings.TrimSpace(nodeText(content, n.ChildByFieldName("key")))
+		},
+	}
+)
+
+// languagesByExt maps file extensions to the languages chunked by syntax tree.
+var languagesByExt = map[string]*language{
+	".go":   goLanguage,
+	".py":   pythonLanguage,
+	".pyi":  pythonLanguage,
+	".js":   javascriptLanguage,
+	".jsx":  javascriptLanguage,
+	".mjs":  javascriptLanguage,
+	".cjs":  javascriptLanguage,
+	".ts":   typescriptLanguage,
+	".mts":  typescriptLanguage,
+	".cts":  typescriptLanguage,
+	".tsx":  tsxLanguage,
+	".java": javaLanguage,
+	".rs":   rustLanguage,
+	".c":    cLanguage,
+	".h":    cLanguage,
+	".cc":   cppLanguage,
+	".cpp":  cppLanguage,
+	".cxx":  cppLanguage,
+	".hh":   cppLanguage,
+	".hpp":  cppLanguage,
+	".hxx":  cppLanguage,
+	".yaml": yamlLanguage,
+	".yml":  yamlLanguage,
+}
+
+func merge(maps ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// symbolKind returns the kind of symbol a node is, or "" if it isn't one.
+func (l *language) symbolKind(n *sitter.Node) string {
+	kind := l.symbols[n.Type()]
+	switch n.Type() {
+	case "lexical_declaration", "variable_declaration":
+		// Only `const f = () => {}` and `const f = function() {}` are symbols.
+		declarator := n.NamedChild(0)
+		if n.NamedChildCount() != 1 || declarator == nil || declarator.Type() != "variable_declarator" {
+			return ""
+		}
+		value := declarator.ChildByFieldName("value")
+		if value == nil || (value.Type() != "arrow_function" && value.Type() != "function_expression" && value.Type() != "function") {
+			return ""
+		}
+	case "struct_specifier", "class_specifier":
+		// Only struct and class definitions, not their uses in declarations.
+		if n.ChildByFieldName("body") == nil {
+			return ""
+		}
+	}
+	return kind
+}
+
+// symbol returns the node defining the symbol of n, looking inside wrappers.
+func (l *language) symbol(n *sitter.Node) (*sitter.Node, string) {
+	if kind := l.symbolKind(n); kind != "" {
+		return n, kind
+	}
+	if !l.wrappers[n.Type()] {
+		return nil, ""
+	}
+	for i := range int(n.NamedChildCount()) {
+		if child := n.NamedChild(i); child != nil {
+			if symbol, kind := l.symbol(child); symbol != nil {
+				return symbol, kind
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (l *language) symbolName(n *sitter.Node, content []byte) string {
+	if l.name != nil {
+		return l.name(n, content)
+	}
+	return strings.TrimSpace(nodeText(content, n.ChildByFieldName("name")))
+}
+
+func javascriptName(n *sitter.Node, content []byte) string {
+	switch n.Type() {
+	case "lexical_declaration", "variable_declaration":
+		if declarator := n.NamedChild(0); declarator != nil {
+			return strings.TrimSpace(nodeText(content, declarator.ChildByFieldName("name")))
+		}
+		return ""
+	}
+	return strings.TrimSpace(nodeText(content, n.ChildByFieldName("name")))
+}
+
+func rustName(n *sitter.Node, content []byte) string {
+	if n.Type() == "impl_item" {
+		name := strings.TrimSpace(nodeText(content, n.ChildByFieldName("type")))
+		if trait := n.ChildByFieldName("trait"); trait != nil {
+			name = strings.TrimSpace(nodeText(content, trait)) + " for " + name
+		}
+		return name
+	}
+	return strings.TrimSpace(nodeText(content, n.ChildByFieldName("name")))
+}
+
+// cName finds the name of C and C++ symbols. The name of functions is nested in their declarator,
+// e.g. `int *(*name)(void)`.
+func cName(n *sitter.Node, content []byte) string {
+	switch n.Type() {
+	case "function_definition", "type_definition":
+		declarator := n.ChildByFieldName("declarator")
+		for declarator != nil {
+			switch declarator.Type() {
+			case "identifier", "field_identifier", "type_identifier", "qualified_identifier",
+				"destructor_name", "operator_name":
+				return strings.TrimSpace(nodeText(content, declarator))
+			}
+			next := declarator.ChildByFieldName("declarator")
+			if next == nil && declarator.NamedChildCount() > 0 {
+				// e.g. a reference_declarator has no declarator field.
+				next = declarator.NamedChild(int(declarator.NamedChildCount()) - 1)
+			}
+			declarator = next
+		}
+		return ""
+	}
+	return strings.TrimSpace(nodeText(content, n.ChildByFieldName("name")))
+}
+
+func javaPackageName(root *sitter.Node, content []byte) string {
+	for i := range int(root.NamedChildCount()) {
+		child := root.NamedChild(i)
+		if child == nil || child.Type() != "package_declaration" {
+			continue
+		}
+		if name := child.NamedChild(0); name != nil {
+			return strings.TrimSpace(nodeText(content, name))
+		}
+	}
+	return ""
+}
+
+// isComment returns true for comments and for the attributes preceding Rust items.
+func isComment(n *sitter.Node) bool {
+	switch n.Type() {
+	case "comment", "line_comment", "block_comment", "attribute_item":
+		return true
+	}
+	return false
+}
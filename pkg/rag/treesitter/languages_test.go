@@ -0,0 +1,233 @@
+package treesitter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type symbolChunk struct {
+	name, kind, parent, startLine, endLine string
+}
+
+func chunkSymbols(t *testing.T, processor *DocumentProcessor, path, content string) []symbolChunk {
+	t.Helper()
+
+	chunks, err := processor.Process(path, []byte(content))
+	require.NoError(t, err)
+
+	var symbols []symbolChunk
+	for _, c := range chunks {
+		require.NotNil(t, c.Metadata, "chunk %d of %s should be code-aware", c.Index, path)
+		symbols = append(symbols, symbolChunk{
+			name:      c.Metadata["symbol_name"],
+			kind:      c.Metadata["symbol_kind"],
+			parent:    c.Metadata["parent"],
+			startLine: c.Metadata["start_line"],
+			endLine:   c.Metadata["end_line"],
+		})
+	}
+	return symbols
+}
+
+func TestLanguages_Python(t *testing.T) {
+	t.Parallel()
+
+	content := `import os
+
+# Greets.
+@decorator
+def hello(name):
+    return f"hi {name}"
+
+
+class Greeter(Base):
+    """A greeter."""
+
+    greeting = 'hi'
+
+    def __init__(self, name):
+        self.name = name
+
+    # Greets someone.
+    def greet(self):
+        return self.greeting + ' ' + self.name
+`
+
+	// The class doesn't fit in a chunk: it's split into its methods.
+	processor := NewDocumentProcessor(120, 0, false)
+	assert.Equal(t, []symbolChunk{
+		{"hello", "function", "", "3", "6"},
+		{"Greeter", "class", "", "9", "15"},
+		{"greet", "method", "Greeter", "17", "19"},
+	}, chunkSymbols(t, processor, "greeter.py", content))
+
+	chunks, err := processor.Process("greeter.py", []byte(content))
+	require.NoError(t, err)
+	assert.Equal(t, "# Greets.\n@decorator\ndef hello(name):\n    return f\"hi {name}\"", chunks[0].Content)
+	assert.Equal(t, "__init__", chunks[1].Metadata["additional_symbols"])
+	// Methods are dedented.
+	assert.Equal(t, "# Greets someone.\ndef greet(self):\n    return self.greeting + ' ' + self.name", chunks[2].Content)
+
+	// The class fits in a chunk: it's kept whole.
+	assert.Equal(t, []symbolChunk{
+		{"hello", "function", "", "3", "19"},
+	}, chunkSymbols(t, NewDocumentProcessor(1000, 0, false), "greeter.py", content))
+}
+
+func TestLanguages_TypeScript(t *testing.T) {
+	t.Parallel()
+
+	content := `import x from 'y';
+
+/** Adds. */
+export function add(a: number, b: number): number {
+  return a + b;
+}
+
+export const sub = (a: number, b: number) => a - b;
+
+const notAFunction = 42;
+
+interface Shape {
+  area(): number;
+}
+
+export class Circle implements Shape {
+  constructor(private r: number) {}
+
+  area(): number {
+    return Math.PI * this.r * this.r;
+  }
+}
+`
+
+	symbols := chunkSymbols(t, NewDocumentProcessor(80, 0, false), "shapes.ts", content)
+	assert.Equal(t, []symbolChunk{
+		{"add", "function", "", "3", "6"},
+		{"sub", "function", "", "8", "8"},
+		{"Shape", "interface", "", "12", "16"},
+		{"constructor", "method", "Circle", "17", "17"},
+		{"area", "method", "Circle", "19", "21"},
+	}, symbols)
+
+	// JavaScript shares the same symbols.
+	assert.Equal(t, []symbolChunk{
+		{"add", "function", "", "1", "3"},
+	}, chunkSymbols(t, NewDocumentProcessor(1000, 0, false), "add.js", "function add(a, b) {\n  return a + b;\n}\n"))
+}
+
+func TestLanguages_Java(t *testing.T) {
+	t.Parallel()
+
+	content := `package com.example.shapes;
+
+/** A circle. */
+public class Circle {
+  private final double r;
+
+  public Circle(double r) {
+    this.r = r;
+  }
+
+  /** Area. */
+  @Override
+  public double area() {
+    return Math.PI * r * r;
+  }
+}
+`
+
+	processor := NewDocumentProcessor(80, 0, false)
+	assert.Equal(t, []symbolChunk{
+		{"Circle", "class", "", "3", "5"},
+		{"Circle", "constructor", "Circle", "7", "9"},
+		{"area", "method", "Circle", "11", "15"},
+	}, chunkSymbols(t, processor, "Circle.java", content))
+
+	chunks, err := processor.Process("Circle.java", []byte(content))
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.shapes", chunks[2].Metadata["package"])
+	assert.Equal(t, "public double area()", chunks[2].Metadata["signature"])
+}
+
+func TestLanguages_Rust(t *testing.T) {
+	t.Parallel()
+
+	content := `use std::fmt;
+
+/// A point.
+#[derive(Debug)]
+pub struct Point {
+    x: i32,
+}
+
+impl fmt::Display for Point {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        write!(f, "{}", self.x)
+    }
+}
+`
+
+	assert.Equal(t, []symbolChunk{
+		{"Point", "struct", "", "3", "7"},
+		{"fmt::Display for Point", "impl", "", "9", "9"},
+		{"fmt", "method", "fmt::Display for Point", "10", "12"},
+	}, chunkSymbols(t, NewDocumentProcessor(70, 0, false), "point.rs", content))
+}
+
+func TestLanguages_CAndCpp(t *testing.T) {
+	t.Parallel()
+
+	c := `#include <stdio.h>
+
+/* Adds. */
+static int *add(int a, int b) {
+  return 0;
+}
+`
+	assert.Equal(t, []symbolChunk{
+		{"add", "function", "", "3", "6"},
+	}, chunkSymbols(t, NewDocumentProcessor(1000, 0, false), "add.c", c))
+
+	cpp := `namespace geo {
+
+class Circle {
+ public:
+  double area() const {
+    return 3.14 * r * r;
+  }
+
+ private:
+  double r;
+};
+
+}  // namespace geo
+
+int Circle::perimeter() { return 0; }
+`
+	assert.Equal(t, []symbolChunk{
+		{"geo", "namespace", "", "1", "4"},
+		{"area", "method", "geo.Circle", "5", "7"},
+		{"Circle::perimeter", "function", "", "15", "15"},
+	}, chunkSymbols(t, NewDocumentProcessor(60, 0, false), "circle.cpp", cpp))
+}
+
+func TestLanguages_YAML(t *testing.T) {
+	t.Parallel()
+
+	content := `name: app
+services:
+  web:
+    image: nginx
+  db:
+    image: postgres
+`
+
+	assert.Equal(t, []symbolChunk{
+		{"name", "key", "", "1", "2"},
+		{"web", "key", "services", "3", "4"},
+		{"db", "key", "services", "5", "6"},
+	}, chunkSymbols(t, NewDocumentProcessor(25, 0, false), "compose.yaml", content))
+}
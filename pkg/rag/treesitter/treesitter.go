@@ -11,7 +11,6 @@ import (
 	"unicode/utf8"
 
 	sitter "github.com/smacker/go-tree-sitter"
-	"github.com/smacker/go-tree-sitter/golang"
 
 	"github.com/docker/cagent/pkg/rag/chunk"
 )
@@ -20,38 +19,26 @@ import (
 // files and produce semantically aligned chunks (e.g., whole functions) while
 // still respecting a maximum chunk size where possible.
 //
-// NOTE: To keep the initial implementation minimal, this currently supports
-// Go source files via the golang grammar. The design is intentionally generic
-// so we can add more languages incrementally.
+// Go, Python, JavaScript, TypeScript, Java, Rust, C, C++ and YAML files are
+// split along their functions, methods and classes (or top-level keys, for
+// YAML). Classes larger than a chunk are split into their methods.
 //
 // The processor is thread-safe: it creates a new parser for each Process()
 // call since the underlying tree-sitter C library is not thread-safe.
 type DocumentProcessor struct {
 	chunkSize    int
 	chunkOverlap int
-	langByExt    map[string]*sitter.Language
-	functionNode map[string]func(*sitter.Node) bool
+	languages    map[string]*language
 	textFallback *chunk.TextDocumentProcessor
 }
 
-// NewDocumentProcessor creates a new document processor instance with a
-// language mapping that can be expanded over time. Falls back to text chunking
-// for unsupported file types.
+// NewDocumentProcessor creates a new document processor instance. Falls back
+// to text chunking for unsupported file types.
 func NewDocumentProcessor(chunkSize, chunkOverlap int, respectWordBoundaries bool) *DocumentProcessor {
-	// Currently only Go is wired; more languages can be added later.
-	langByExt := map[string]*sitter.Language{
-		".go": golang.GetLanguage(),
-	}
-
-	functionNode := map[string]func(*sitter.Node) bool{
-		".go": isGoFunctionLike,
-	}
-
 	return &DocumentProcessor{
 		chunkSize:    chunkSize,
 		chunkOverlap: chunkOverlap,
-		langByExt:    langByExt,
-		functionNode: functionNode,
+		languages:    languagesByExt,
 		textFallback: chunk.NewTextDocumentProcessor(chunkSize, chunkOverlap, respectWordBoundaries),
 	}
 }
@@ -65,7 +52,7 @@ func (p *DocumentProcessor) Process(path string, content []byte) ([]chunk.Chunk,
 		"chunk_overlap", p.chunkOverlap)
 
 	ext := strings.ToLower(filepath.Ext(path))
-	lang, ok := p.langByExt[ext]
+	lang, ok := p.languages[ext]
 	if !ok {
 		slog.Debug("[TreeSitter] Unsupported file extension, falling back to text chunking",
 			"path", path,
@@ -79,7 +66,7 @@ func (p *DocumentProcessor) Process(path string, content []byte) ([]chunk.Chunk,
 
 	// Create a new parser for each call to ensure thread-safety
 	parser := sitter.NewParser()
-	parser.SetLanguage(lang)
+	parser.SetLanguage(lang.grammar)
 
 	slog.Debug("[TreeSitter] Parsing source code with tree-sitter",
 		"path", path)
@@ -97,53 +84,33 @@ func (p *DocumentProcessor) Process(path string, content []byte) ([]chunk.Chunk,
 		"path", path)
 
 	root := tree.RootNode()
-	packageName := extractPackageName(root, content)
-	fnFilter, ok := p.functionNode[ext]
-	if !ok {
-		slog.Debug("[TreeSitter] No function filter defined for extension, falling back to text chunking",
-			"path", path,
-			"extension", ext)
-		return p.textFallback.Process(path, content)
+	packageName := ""
+	if lang.packageName != nil {
+		packageName = lang.packageName(root, content)
 	}
 
-	// Extract function-like nodes.
-	var funcNodes []*sitter.Node
-	var walk func(*sitter.Node)
-	walk = func(n *sitter.Node) {
-		if fnFilter(n) {
-			funcNodes = append(funcNodes, n)
-			return
-		}
-		for i := range int(n.ChildCount()) {
-			child := n.Child(i)
-			if child == nil {
-				continue
-			}
-			walk(child)
-		}
-	}
-	walk(root)
+	// Extract the symbols: functions, methods, classes...
+	symbols := p.collectSymbols(lang, root, content, packageName, "", "")
 
-	slog.Debug("[TreeSitter] Extracted function nodes from syntax tree",
+	slog.Debug("[TreeSitter] Extracted symbols from syntax tree",
 		"path", path,
-		"function_count", len(funcNodes))
+		"symbol_count", len(symbols))
 
-	// If we didn't find any function-like nodes, fall back to text chunking.
-	if len(funcNodes) == 0 {
-		slog.Debug("[TreeSitter] No function nodes found, falling back to text chunking",
+	// If we didn't find any symbol, fall back to text chunking.
+	if len(symbols) == 0 {
+		slog.Debug("[TreeSitter] No symbols found, falling back to text chunking",
 			"path", path)
 		return p.textFallback.Process(path, content)
 	}
 
-	// Group functions into chunks under the size budget where possible, without
-	// ever splitting a single function across chunks.
-	text := string(content)
+	// Group symbols into chunks under the size budget where possible, without
+	// ever splitting a single symbol across chunks.
 	var chunksOut []chunk.Chunk
 	index := 0
 
 	var buf strings.Builder
 	currentLen := 0
-	var chunkFunctions []functionMetadata
+	var chunkSymbols []codeSymbol
 
 	flush := func() {
 		if buf.Len() == 0 {
@@ -153,12 +120,13 @@ func (p *DocumentProcessor) Process(path string, content []byte) ([]chunk.Chunk,
 		if c == "" {
 			buf.Reset()
 			currentLen = 0
+			chunkSymbols = nil
 			return
 		}
 		chunksOut = append(chunksOut, chunk.Chunk{
 			Index:    index,
 			Content:  c,
-			Metadata: buildChunkMetadata(chunkFunctions),
+			Metadata: buildChunkMetadata(chunkSymbols),
 		})
 		slog.Debug("[TreeSitter] Created code-aware chunk",
 			"chunk_index", index,
@@ -166,95 +134,67 @@ func (p *DocumentProcessor) Process(path string, content []byte) ([]chunk.Chunk,
 		index++
 		buf.Reset()
 		currentLen = 0
-		chunkFunctions = nil
+		chunkSymbols = nil
 	}
 
-	for funcIdx, fn := range funcNodes {
-		// Find any comments that precede this function
-		start := int(findPrecedingComments(fn, content))
-		end := int(fn.EndByte())
-		if start < 0 || end <= start || end > len(text) {
-			slog.Debug("[TreeSitter] Skipping function node with invalid byte range",
-				"path", path,
-				"function_index", funcIdx,
-				"start_byte", start,
-				"end_byte", end)
-			continue
-		}
-
-		fnText := strings.TrimSpace(text[start:end])
-		if fnText == "" {
-			slog.Debug("[TreeSitter] Skipping empty function node",
-				"path", path,
-				"function_index", funcIdx)
-			continue
-		}
-
-		fnLen := utf8.RuneCountInString(fnText)
-		fnType := fn.Type()
-
-		docText := ""
-		funcStart := int(fn.StartByte())
-		if start >= 0 && funcStart <= len(content) && start < funcStart {
-			docText = string(content[start:funcStart])
-		}
+	for symbolIdx, symbol := range symbols {
+		symbolLen := utf8.RuneCountInString(symbol.text)
 
-		slog.Debug("[TreeSitter] Processing function node",
+		slog.Debug("[TreeSitter] Processing symbol",
 			"path", path,
-			"function_index", funcIdx,
-			"function_type", fnType,
-			"function_length", fnLen,
+			"symbol_index", symbolIdx,
+			"symbol_kind", symbol.meta.Kind,
+			"symbol_length", symbolLen,
 			"current_chunk_length", currentLen,
 			"chunk_size_limit", p.chunkSize)
 
-		// If the function alone is larger than chunkSize, emit it as its own
+		// If the symbol alone is larger than chunkSize, emit it as its own
 		// chunk to avoid splitting function bodies.
-		if p.chunkSize > 0 && fnLen > p.chunkSize {
-			slog.Debug("[TreeSitter] Function exceeds chunk size, creating dedicated chunk",
+		if p.chunkSize > 0 && symbolLen > p.chunkSize {
+			slog.Debug("[TreeSitter] Symbol exceeds chunk size, creating dedicated chunk",
 				"path", path,
-				"function_index", funcIdx,
-				"function_length", fnLen,
+				"symbol_index", symbolIdx,
+				"symbol_length", symbolLen,
 				"chunk_size_limit", p.chunkSize,
 				"chunk_index", index)
 			flush()
-			meta := buildFunctionMetadata(fn, content, packageName, docText)
 			chunksOut = append(chunksOut, chunk.Chunk{
 				Index:    index,
-				Content:  fnText,
-				Metadata: buildChunkMetadata([]functionMetadata{meta}),
+				Content:  symbol.text,
+				Metadata: buildChunkMetadata([]codeSymbol{symbol}),
 			})
-			slog.Debug("[TreeSitter] Created code-aware chunk for large function",
+			slog.Debug("[TreeSitter] Created code-aware chunk for large symbol",
 				"chunk_index", index,
-				"chunk_content", fnText)
+				"chunk_content", symbol.text)
 			index++
 			continue
 		}
 
-		// If adding this function would exceed the budget, flush and start new.
-		if p.chunkSize > 0 && currentLen > 0 && currentLen+fnLen > p.chunkSize {
-			slog.Debug("[TreeSitter] Adding function would exceed chunk size, flushing current chunk",
+		// If adding this symbol would exceed the budget, flush and start new.
+		if p.chunkSize > 0 && currentLen > 0 && currentLen+symbolLen > p.chunkSize {
+			slog.Debug("[TreeSitter] Adding symbol would exceed chunk size, flushing current chunk",
 				"path", path,
-				"function_index", funcIdx,
+				"symbol_index", symbolIdx,
 				"current_chunk_length", currentLen,
-				"function_length", fnLen,
-				"total_would_be", currentLen+fnLen,
+				"symbol_length", symbolLen,
+				"total_would_be", currentLen+symbolLen,
 				"chunk_size_limit", p.chunkSize,
 				"chunk_index", index)
 			flush()
 		}
 
-		slog.Debug("[TreeSitter] Adding function to current chunk",
+		slog.Debug("[TreeSitter] Adding symbol to current chunk",
 			"path", path,
-			"function_index", funcIdx,
-			"function_length", fnLen,
-			"new_chunk_length", currentLen+fnLen)
+			"symbol_index", symbolIdx,
+			"symbol_length", symbolLen,
+			"new_chunk_length", currentLen+symbolLen)
 
 		if buf.Len() > 0 {
 			buf.WriteString("\n\n")
 		}
-		buf.WriteString(fnText)
-		currentLen += fnLen
-		chunkFunctions = append(chunkFunctions, buildFunctionMetadata(fn, content, packageName, docText))
+		buf.WriteString(symbol.text)
+		currentLen += symbolLen
+		chunkSymbols = append(chunkSymbols, symbol)
 	}
 
 	flush()
@@ -286,7 +226,7 @@ func (p *DocumentProcessor) Process(path string, content []byte) ([]chunk.Chunk,
 
 	slog.Debug("[TreeSitter] Successfully chunked file using syntax tree",
 		"path", path,
-		"total_functions", len(funcNodes),
+		"total_symbols", len(symbols),
 		"total_chunks", len(chunksOut),
 		"avg_chunk_size", avgChunkSize,
 		"min_chunk_size", minChunkSize,
@@ -296,15 +236,154 @@ func (p *DocumentProcessor) Process(path string, content []byte) ([]chunk.Chunk,
 	return chunksOut, nil
 }
 
-// isGoFunctionLike returns true for nodes that represent top-level functions
-// or methods in Go. The exact node types are determined by the golang grammar.
-func isGoFunctionLike(n *sitter.Node) bool {
-	switch n.Type() {
-	case "function_declaration", "method_declaration":
-		return true
+const spaces = "\t\n\v\f\r "
+
+// codeSymbol is a piece of source code holding a symbol, with its preceding comments.
+type codeSymbol struct {
+	text      string
+	startLine int
+	endLine   int
+	meta      functionMetadata
+}
+
+// collectSymbols walks a syntax tree and returns its symbols, in order. Containers
+// (e.g. classes) larger than a chunk are returned as their header, followed by
+// their members.
+func (p *DocumentProcessor) collectSymbols(lang *language, n *sitter.Node, content []byte, packageName, parent, parentKind string) []codeSymbol {
+	node, kind := lang.symbol(n)
+	if node == nil {
+		var symbols []codeSymbol
+		for i := range int(n.ChildCount()) {
+			if child := n.Child(i); child != nil {
+				symbols = append(symbols, p.collectSymbols(lang, child, content, packageName, parent, parentKind)...)
+			}
+		}
+		return symbols
+	}
+
+	switch parentKind {
+	case "", "namespace", "module", "key", "item":
 	default:
-		return false
+		if kind == "function" {
+			kind = "method"
+		}
+	}
+	name := lang.symbolName(node, content)
+
+	// n is either the symbol or the wrapper holding its decorators, annotations...
+	start := int(findPrecedingComments(n, content))
+	end := int(n.EndByte())
+	if start < 0 || end <= start || end > len(content) {
+		return nil
+	}
+
+	docText := ""
+	if start < int(n.StartByte()) {
+		docText = string(content[start:n.StartByte()])
+	}
+
+	meta := buildFunctionMetadata(node, content, packageName, docText)
+	meta.Name = name
+	meta.Kind = kind
+	meta.Parent = parent
+	if kind == "key" || kind == "item" {
+		meta.Signature = ""
+	}
+
+	newSymbol := func(start, end int) []codeSymbol {
+		raw := string(content[start:end])
+		text := strings.TrimSpace(raw)
+		if text == "" {
+			return nil
+		}
+		// Lines are those of the code, not of the surrounding blank lines.
+		start += len(raw) - len(strings.TrimLeft(raw, spaces))
+		end -= len(raw) - len(strings.TrimRight(raw, spaces))
+		return []codeSymbol{{
+			text:      dedent(content, start, end),
+			startLine: lineAt(content, start),
+			endLine:   lineAt(content, end-1),
+			meta:      meta,
+		}}
+	}
+
+	if !lang.containers[kind] || p.chunkSize <= 0 || utf8.RuneCount(content[start:end]) <= p.chunkSize {
+		return newSymbol(start, end)
+	}
+
+	qualified := name
+	if parent != "" && name != "" {
+		qualified = parent + "." + name
+	}
+	var members []codeSymbol
+	for i := range int(node.ChildCount()) {
+		if child := node.Child(i); child != nil {
+			members = append(members, p.collectSymbols(lang, child, content, packageName, qualified, kind)...)
+		}
+	}
+	if len(members) == 0 {
+		return newSymbol(start, end)
+	}
+
+	// The header of the container: its declaration, fields...
+	headerEnd := end
+	for i := range int(node.ChildCount()) {
+		if firstMember := firstSymbolStart(lang, node.Child(i), content); firstMember >= 0 {
+			headerEnd = firstMember
+			break
+		}
+	}
+	return append(newSymbol(start, max(start, headerEnd)), members...)
+}
+
+// firstSymbolStart returns the offset of the first symbol of a subtree, with its comments, or -1.
+func firstSymbolStart(lang *language, n *sitter.Node, content []byte) int {
+	if n == nil {
+		return -1
+	}
+	if node, _ := lang.symbol(n); node != nil {
+		return int(findPrecedingComments(n, content))
+	}
+	for i := range int(n.ChildCount()) {
+		if start := firstSymbolStart(lang, n.Child(i), content); start >= 0 {
+			return start
+		}
+	}
+	return -1
+}
+
+// dedent returns the code between two offsets without the indentation common
+// to all its lines, including the indentation of its first line.
+func dedent(content []byte, start, end int) string {
+	lineStart := bytes.LastIndexByte(content[:start], '\n') + 1
+	if strings.TrimSpace(string(content[lineStart:start])) != "" {
+		return strings.TrimSpace(string(content[start:end]))
+	}
+
+	lines := strings.Split(string(content[lineStart:end]), "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
 	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// lineAt returns the 1-based line of a byte offset.
+func lineAt(content []byte, offset int) int {
+	return bytes.Count(content[:max(0, min(offset, len(content)))], []byte("\n")) + 1
 }
 
 // findPrecedingComments finds all comment nodes that immediately precede a function
@@ -340,7 +419,12 @@ func findPrecedingComments(fn *sitter.Node, content []byte) (startByte uint32) {
 		}
 
 		// Check if this is a comment node
-		if sibling.Type() == "comment" {
+		if isComment(sibling) {
+			lineStart := bytes.LastIndexByte(content[:sibling.StartByte()], '\n') + 1
+			if strings.TrimSpace(string(content[lineStart:sibling.StartByte()])) != "" {
+				// A comment at the end of a line of code isn't documentation.
+				break
+			}
 			commentNodes = append([]*sitter.Node{sibling}, commentNodes...)
 			continue
 		}
@@ -387,29 +471,33 @@ func findPrecedingComments(fn *sitter.Node, content []byte) (startByte uint32) {
 type functionMetadata struct {
 	Name      string
 	Kind      string
+	Parent    string
 	Receiver  string
 	Signature string
 	Doc       string
 	Package   string
-	StartLine int
-	EndLine   int
 }
 
-func buildChunkMetadata(functions []functionMetadata) map[string]string {
-	if len(functions) == 0 {
+// buildChunkMetadata describes the first symbol of a chunk and lists the others.
+// The start and end lines are those of the whole chunk, so that it can be cited.
+func buildChunkMetadata(symbols []codeSymbol) map[string]string {
+	if len(symbols) == 0 {
 		return nil
 	}
 
-	meta := make(map[string]string, 10)
-	meta["symbol_count"] = strconv.Itoa(len(functions))
+	meta := make(map[string]string, 11)
+	meta["symbol_count"] = strconv.Itoa(len(symbols))
 
-	primary := functions[0]
+	primary := symbols[0].meta
 	if primary.Name != "" {
 		meta["symbol_name"] = primary.Name
 	}
 	if primary.Kind != "" {
 		meta["symbol_kind"] = primary.Kind
 	}
+	if primary.Parent != "" {
+		meta["parent"] = primary.Parent
+	}
 	if primary.Receiver != "" {
 		meta["receiver"] = primary.Receiver
 	}
@@ -422,18 +510,14 @@ func buildChunkMetadata(functions []functionMetadata) map[string]string {
 	if primary.Package != "" {
 		meta["package"] = primary.Package
 	}
-	if primary.StartLine > 0 {
-		meta["start_line"] = strconv.Itoa(primary.StartLine)
-	}
-	if primary.EndLine > 0 {
-		meta["end_line"] = strconv.Itoa(primary.EndLine)
-	}
+	meta["start_line"] = strconv.Itoa(symbols[0].startLine)
+	meta["end_line"] = strconv.Itoa(symbols[len(symbols)-1].endLine)
 
-	if len(functions) > 1 {
-		names := make([]string, 0, len(functions)-1)
-		for _, fn := range functions[1:] {
-			if fn.Name != "" {
-				names = append(names, fn.Name)
+	if len(symbols) > 1 {
+		names := make([]string, 0, len(symbols)-1)
+		for _, symbol := range symbols[1:] {
+			if symbol.meta.Name != "" {
+				names = append(names, symbol.meta.Name)
 			}
 		}
 		if len(names) > 0 {
@@ -446,32 +530,30 @@ func buildChunkMetadata(functions []functionMetadata) map[string]string {
 
 func buildFunctionMetadata(fn *sitter.Node, content []byte, pkgName, docText string) functionMetadata {
 	meta := functionMetadata{
-		Name:      strings.TrimSpace(nodeText(content, fn.ChildByFieldName("name"))),
-		Kind:      mapFunctionKind(fn.Type()),
 		Receiver:  strings.TrimSpace(nodeText(content, fn.ChildByFieldName("receiver"))),
-		Signature: buildGoSignature(content, fn),
+		Signature: buildSignature(content, fn),
 		Doc:       truncateMetadataValue(strings.TrimSpace(docText), 400),
 		Package:   pkgName,
-		StartLine: int(fn.StartPoint().Row) + 1,
-		EndLine:   int(fn.EndPoint().Row) + 1,
 	}
 
 	return meta
 }
 
-func mapFunctionKind(nodeType string) string {
-	if nodeType == "method_declaration" {
-		return "method"
-	}
-	return "function"
-}
-
-func buildGoSignature(content []byte, fn *sitter.Node) string {
+// buildSignature returns the first line of a symbol's declaration, up to its body.
+func buildSignature(content []byte, fn *sitter.Node) string {
 	if fn == nil {
 		return ""
 	}
 
 	text := strings.TrimSpace(string(content[fn.StartByte():fn.EndByte()]))
+	// Skip annotations, e.g. Java's @Override
+	for strings.HasPrefix(text, "@") {
+		newlineIdx := strings.Index(text, "\n")
+		if newlineIdx == -1 {
+			break
+		}
+		text = strings.TrimSpace(text[newlineIdx:])
+	}
 	if text == "" {
 		return ""
 	}
@@ -284,7 +284,7 @@ func TestTreeSitterPreProcessor_UnsupportedExtension(t *testing.T) {
 	content := []byte(`console.log("hello");`)
 
 	// For unsupported extensions, it falls back to text chunking
-	chunks, err := processor.Process("test.js", content)
+	chunks, err := processor.Process("test.txt", content)
 	require.NoError(t, err)
 	// Text fallback should produce chunks
 	require.NotNil(t, chunks)
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/config/latest"
 	"github.com/docker/cagent/pkg/session"
 	"github.com/docker/cagent/pkg/tools"
@@ -63,6 +64,7 @@ func NewClient(baseURL string, opts ...ClientOption) (*Client, error) {
 			"tool_call":              func() Event { return &ToolCallEvent{} },
 			"tool_call_response":     func() Event { return &ToolCallResponseEvent{} },
 			"tool_call_confirmation": func() Event { return &ToolCallConfirmationEvent{} },
+			"tool_call_output_delta": func() Event { return &ToolCallOutputDeltaEvent{} },
 			"token_usage":            func() Event { return &TokenUsageEvent{} },
 			"stream_stopped":         func() Event { return &StreamStoppedEvent{} },
 			"stream_started":         func() Event { return &StreamStartedEvent{} },
@@ -73,12 +75,15 @@ func NewClient(baseURL string, opts ...ClientOption) (*Client, error) {
 			"partial_tool_call":      func() Event { return &PartialToolCallEvent{} },
 			"max_iterations_reached": func() Event { return &MaxIterationsReachedEvent{} },
 			"error":                  func() Event { return &ErrorEvent{} },
+			"model_fallback":         func() Event { return &ModelFallbackEvent{} },
+			"model_answered":         func() Event { return &ModelAnsweredEvent{} },
 			"elicitation_request":    func() Event { return &ElicitationRequestEvent{} },
 			"authorization_event":    func() Event { return &AuthorizationEvent{} },
 			"agent_choice":           func() Event { return &AgentChoiceEvent{} },
 			"agent_choice_reasoning": func() Event { return &AgentChoiceReasoningEvent{} },
 			"mcp_init_started":       func() Event { return &MCPInitStartedEvent{} },
 			"mcp_init_finished":      func() Event { return &MCPInitFinishedEvent{} },
+			"mcp_server_status":      func() Event { return &MCPServerStatusEvent{} },
 		},
 	}
 
@@ -251,12 +256,62 @@ func (c *Client) CreateSession(ctx context.Context, sessTemplate *session.Sessio
 	return &sess, err
 }
 
+// ForkSession forks a session into a new session. When messageIndex is nil, all the messages are copied.
+func (c *Client) ForkSession(ctx context.Context, id string, messageIndex *int) (*api.SessionResponse, error) {
+	req := api.ForkSessionRequest{MessageIndex: messageIndex}
+	var sess api.SessionResponse
+	err := c.doRequest(ctx, http.MethodPost, "/api/sessions/"+id+"/fork", req, &sess)
+	return &sess, err
+}
+
+// GetSessionForks retrieves the sessions forked from a session
+func (c *Client) GetSessionForks(ctx context.Context, id string) ([]api.SessionsResponse, error) {
+	var sessions []api.SessionsResponse
+	err := c.doRequest(ctx, http.MethodGet, "/api/sessions/"+id+"/forks", nil, &sessions)
+	return sessions, err
+}
+
+// RewindSession rewinds a session to one of its user messages and returns the content of that message
+func (c *Client) RewindSession(ctx context.Context, id string, messageIndex int) (string, error) {
+	req := api.RewindSessionRequest{MessageIndex: messageIndex}
+	var resp api.RewindSessionResponse
+	err := c.doRequest(ctx, http.MethodPost, "/api/sessions/"+id+"/rewind", req, &resp)
+	return resp.Content, err
+}
+
+// GetCheckpoints retrieves the file checkpoints of a session
+func (c *Client) GetCheckpoints(ctx context.Context, id string) ([]checkpoint.Checkpoint, error) {
+	var checkpoints []checkpoint.Checkpoint
+	err := c.doRequest(ctx, http.MethodGet, "/api/sessions/"+id+"/checkpoints", nil, &checkpoints)
+	return checkpoints, err
+}
+
+// RestoreFiles reverts the file changes made by a session from a given message onwards
+func (c *Client) RestoreFiles(ctx context.Context, id string, messageIndex int) ([]string, error) {
+	req := api.RestoreFilesRequest{MessageIndex: messageIndex}
+	var resp api.RestoreFilesResponse
+	err := c.doRequest(ctx, http.MethodPost, "/api/sessions/"+id+"/checkpoints/restore", req, &resp)
+	return resp.Files, err
+}
+
+// UndoFileChanges reverts the file changes of the last turn of a session
+func (c *Client) UndoFileChanges(ctx context.Context, id string) (*api.RestoreFilesResponse, error) {
+	var resp api.RestoreFilesResponse
+	err := c.doRequest(ctx, http.MethodPost, "/api/sessions/"+id+"/undo", nil, &resp)
+	return &resp, err
+}
+
 // ResumeSession resumes a session by ID
 func (c *Client) ResumeSession(ctx context.Context, id, confirmation string) error {
 	req := api.ResumeSessionRequest{Confirmation: confirmation}
 	return c.doRequest(ctx, http.MethodPost, "/api/sessions/"+id+"/resume", req, nil)
 }
 
+// CancelSession stops the agent loop of a session
+func (c *Client) CancelSession(ctx context.Context, id string) error {
+	return c.doRequest(ctx, http.MethodPost, "/api/sessions/"+id+"/cancel", nil, nil)
+}
+
 // DeleteSession deletes a session by ID
 func (c *Client) DeleteSession(ctx context.Context, id string) error {
 	return c.doRequest(ctx, "DELETE", "/api/sessions/"+id, nil, nil)
@@ -299,6 +354,27 @@ func (c *Client) runAgentWithAgentName(ctx context.Context, sessionID, agent, ag
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+
+	return c.streamEvents(req)
+}
+
+// AttachSession streams the events of the latest run of a session, from its start,
+// and follows the run until it stops. The channel is closed right away if the
+// session wasn't run since the server started.
+func (c *Client) AttachSession(ctx context.Context, sessionID string) (<-chan Event, error) {
+	u := *c.baseURL
+	u.Path = path.Join(u.Path, "/api/sessions/"+sessionID+"/events")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	return c.streamEvents(req)
+}
+
+// streamEvents sends a request answered with server-sent events and returns a channel of the decoded events.
+func (c *Client) streamEvents(req *http.Request) (<-chan Event, error) {
 	req.Header.Set("Accept", "text/event-stream")
 	req.Header.Set("Cache-Control", "no-cache")
 
@@ -101,6 +101,24 @@ func ToolCallResponse(toolCall tools.ToolCall, toolDefinition tools.Tool, result
 	}
 }
 
+// ToolCallOutputDeltaEvent is sent while a tool runs, with the output it produced since the last one.
+// The final result of the tool call is still sent with a ToolCallResponseEvent.
+type ToolCallOutputDeltaEvent struct {
+	Type       string `json:"type"`
+	ToolCallID string `json:"tool_call_id"`
+	Delta      string `json:"delta"`
+	AgentContext
+}
+
+func ToolCallOutputDelta(toolCallID, delta, agentName string) Event {
+	return &ToolCallOutputDeltaEvent{
+		Type:         "tool_call_output_delta",
+		ToolCallID:   toolCallID,
+		Delta:        delta,
+		AgentContext: AgentContext{AgentName: agentName},
+	}
+}
+
 type StreamStartedEvent struct {
 	Type      string `json:"type"`
 	SessionID string `json:"session_id,omitempty"`
@@ -156,6 +174,49 @@ func Error(msg string) Event {
 	}
 }
 
+// ModelFallbackEvent is sent when a model request failed and is retried,
+// either with the same model or with the next fallback model.
+type ModelFallbackEvent struct {
+	Type        string `json:"type"`
+	FailedModel string `json:"failed_model"`
+	Model       string `json:"model"`
+	Attempt     int    `json:"attempt"`
+	Reason      string `json:"reason"`
+	// Discard is set when the failed model had already streamed part of its
+	// response: clients should drop that partial output before the retry.
+	Discard bool `json:"discard,omitempty"`
+	AgentContext
+}
+
+func ModelFallback(failedModel, model string, attempt int, reason string, discard bool, agentName string) Event {
+	return &ModelFallbackEvent{
+		Type:         "model_fallback",
+		FailedModel:  failedModel,
+		Model:        model,
+		Attempt:      attempt,
+		Reason:       reason,
+		Discard:      discard,
+		AgentContext: AgentContext{AgentName: agentName},
+	}
+}
+
+// ModelAnsweredEvent is sent when a model answered after a retry or a fallback.
+type ModelAnsweredEvent struct {
+	Type    string `json:"type"`
+	Model   string `json:"model"`
+	Attempt int    `json:"attempt"`
+	AgentContext
+}
+
+func ModelAnswered(model string, attempt int, agentName string) Event {
+	return &ModelAnsweredEvent{
+		Type:         "model_answered",
+		Model:        model,
+		Attempt:      attempt,
+		AgentContext: AgentContext{AgentName: agentName},
+	}
+}
+
 type ShellOutputEvent struct {
 	Type   string `json:"type"`
 	Output string `json:"error"`
@@ -346,6 +407,26 @@ func MCPInitFinished(agentName string) Event {
 	}
 }
 
+// MCPServerStatusEvent is sent when the connection to an MCP server changes state:
+// connected, reconnecting or failed.
+type MCPServerStatusEvent struct {
+	Type   string `json:"type"`
+	Server string `json:"server"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	AgentContext
+}
+
+func MCPServerStatus(server, status, errMsg, agentName string) Event {
+	return &MCPServerStatusEvent{
+		Type:         "mcp_server_status",
+		Server:       server,
+		Status:       status,
+		Error:        errMsg,
+		AgentContext: AgentContext{AgentName: agentName},
+	}
+}
+
 // AgentInfoEvent is sent when agent information is available or changes
 type AgentInfoEvent struct {
 	Type           string `json:"type"`
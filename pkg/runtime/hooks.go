@@ -0,0 +1,187 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/docker/cagent/pkg/agent"
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/hooks"
+	"github.com/docker/cagent/pkg/session"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+// maxStopHookContinuations is how many times stop hooks can make the agent carry on
+// in a run, so that a hook that always blocks can't keep the agent running forever.
+const maxStopHookContinuations = 5
+
+func hookInput(sess *session.Session, a *agent.Agent, event hooks.Event) hooks.Input {
+	return hooks.Input{
+		HookEvent: event,
+		SessionID: sess.ID,
+		Agent:     a.Name(),
+		Cwd:       sess.WorkingDir,
+	}
+}
+
+// runPromptHooks runs the session_start hooks, on the first turn of a session,
+// and the user_prompt_submit hooks. It returns false if a hook blocked the prompt,
+// in which case the prompt is removed from the session.
+func (r *LocalRuntime) runPromptHooks(ctx context.Context, sess *session.Session, a *agent.Agent, events chan Event) bool {
+	h := a.Hooks()
+	if !h.Has(hooks.SessionStart) && !h.Has(hooks.UserPromptSubmit) {
+		return true
+	}
+
+	// Sub-agents run with an implicit prompt. Only prompts typed by the user trigger hooks.
+	messages := sess.GetAllMessages()
+	if len(messages) == 0 {
+		return true
+	}
+	last := messages[len(messages)-1]
+	if last.Message.Role != chat.MessageRoleUser || last.Implicit {
+		return true
+	}
+
+	if h.Has(hooks.SessionStart) && !hasAssistantMessages(messages) {
+		res := h.Run(ctx, hookInput(sess, a, hooks.SessionStart))
+		r.addHookContext(ctx, sess, &res)
+	}
+
+	if h.Has(hooks.UserPromptSubmit) {
+		input := hookInput(sess, a, hooks.UserPromptSubmit)
+		input.Prompt = last.Message.Content
+
+		res := h.Run(ctx, input)
+		if res.Blocked {
+			slog.Debug("Prompt blocked by hook", "agent", a.Name(), "session_id", sess.ID)
+			if index, err := sess.UserMessageIndex(0); err == nil {
+				_, _ = sess.Rewind(index)
+				_ = r.sessionStore.UpdateSession(ctx, sess)
+			}
+			events <- Error(blockedMessage("The prompt was blocked by a hook", res.Reason))
+			return false
+		}
+		r.addHookContext(ctx, sess, &res)
+	}
+
+	return true
+}
+
+// runStopHooks runs the stop hooks. It returns true if a hook asked the agent to carry on,
+// in which case its reason was added to the session for the model to act upon.
+func (r *LocalRuntime) runStopHooks(ctx context.Context, sess *session.Session, a *agent.Agent, stopHookActive bool) bool {
+	h := a.Hooks()
+	if !h.Has(hooks.Stop) {
+		return false
+	}
+
+	input := hookInput(sess, a, hooks.Stop)
+	input.StopHookActive = stopHookActive
+
+	res := h.Run(ctx, input)
+	if res.Blocked && res.Reason != "" {
+		slog.Debug("Stop blocked by hook", "agent", a.Name(), "session_id", sess.ID)
+		res.AdditionalContext = append(res.AdditionalContext, res.Reason)
+		r.addHookContext(ctx, sess, &res)
+		return true
+	}
+
+	r.addHookContext(ctx, sess, &res)
+	return false
+}
+
+// runPreToolHooks runs the pre_tool_use hooks. It returns the tool call, with its
+// arguments replaced if a hook asked for it, and false if a hook blocked the call,
+// in which case an error response was already added to the session.
+func (r *LocalRuntime) runPreToolHooks(ctx context.Context, sess *session.Session, toolCall tools.ToolCall, tool tools.Tool, events chan Event, a *agent.Agent) (tools.ToolCall, bool) {
+	h := a.Hooks()
+	if !h.Has(hooks.PreToolUse) {
+		return toolCall, true
+	}
+
+	input := hookInput(sess, a, hooks.PreToolUse)
+	input.ToolName = toolCall.Function.Name
+	input.ToolCallID = toolCall.ID
+	input.ToolInput = toolArguments(toolCall)
+
+	res := h.Run(ctx, input)
+	if res.Blocked {
+		slog.Debug("Tool call blocked by hook", "tool", toolCall.Function.Name, "session_id", sess.ID)
+		r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, blockedMessage(fmt.Sprintf("The tool call to '%s' was blocked by a hook", toolCall.Function.Name), res.Reason))
+		return toolCall, false
+	}
+	if res.ToolInput != "" {
+		slog.Debug("Tool call arguments replaced by hook", "tool", toolCall.Function.Name, "session_id", sess.ID)
+		toolCall.Function.Arguments = res.ToolInput
+	}
+
+	return toolCall, true
+}
+
+// runPostToolHooks runs the post_tool_use hooks. Their feedback is appended to
+// the tool result so that the model sees it along with the output.
+func (r *LocalRuntime) runPostToolHooks(ctx context.Context, sess *session.Session, toolCall tools.ToolCall, a *agent.Agent, res *tools.ToolCallResult) *tools.ToolCallResult {
+	h := a.Hooks()
+	if !h.Has(hooks.PostToolUse) {
+		return res
+	}
+
+	input := hookInput(sess, a, hooks.PostToolUse)
+	input.ToolName = toolCall.Function.Name
+	input.ToolCallID = toolCall.ID
+	input.ToolInput = toolArguments(toolCall)
+	input.ToolResponse = res.Output
+	input.ToolError = res.IsError
+
+	hookRes := h.Run(ctx, input)
+	feedback := hookRes.AdditionalContext
+	if hookRes.Blocked && hookRes.Reason != "" {
+		feedback = append(feedback, hookRes.Reason)
+	}
+	if len(feedback) == 0 {
+		return res
+	}
+
+	out := *res
+	out.Output = res.Output + "\n\n" + strings.Join(feedback, "\n\n")
+	out.IsError = res.IsError || hookRes.Blocked
+	return &out
+}
+
+// addHookContext adds the additional context returned by hooks to the session.
+func (r *LocalRuntime) addHookContext(ctx context.Context, sess *session.Session, res *hooks.Result) {
+	if len(res.AdditionalContext) == 0 {
+		return
+	}
+
+	sess.AddMessage(session.ImplicitUserMessage(res.Context()))
+	_ = r.sessionStore.UpdateSession(ctx, sess)
+}
+
+func hasAssistantMessages(messages []session.Message) bool {
+	for _, msg := range messages {
+		if msg.Message.Role == chat.MessageRoleAssistant {
+			return true
+		}
+	}
+	return false
+}
+
+// toolArguments returns the arguments of a tool call as raw JSON, or nil if they aren't valid JSON.
+func toolArguments(toolCall tools.ToolCall) json.RawMessage {
+	if !json.Valid([]byte(toolCall.Function.Arguments)) {
+		return nil
+	}
+	return json.RawMessage(toolCall.Function.Arguments)
+}
+
+func blockedMessage(msg, reason string) string {
+	if reason == "" {
+		return msg + "."
+	}
+	return msg + ": " + reason
+}
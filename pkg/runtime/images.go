@@ -0,0 +1,69 @@
+package runtime
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/modelsdev"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+// imageParts converts the images returned by a tool into message parts.
+// The text output of the tool stays in the message's Content.
+func imageParts(images []tools.Image) []chat.MessagePart {
+	parts := make([]chat.MessagePart, 0, len(images))
+	for _, image := range images {
+		parts = append(parts, chat.MessagePart{
+			Type: chat.MessagePartTypeImageURL,
+			ImageURL: &chat.MessageImageURL{
+				URL:    image.DataURL(),
+				Detail: chat.ImageURLDetailAuto,
+			},
+		})
+	}
+	return parts
+}
+
+// supportsImages reports whether a model accepts images as input.
+// Models we know nothing about are given the benefit of the doubt.
+func supportsImages(m *modelsdev.Model) bool {
+	return m == nil || len(m.Modalities.Input) == 0 || slices.Contains(m.Modalities.Input, "image")
+}
+
+// withoutToolImages replaces the images of tool results with text placeholders,
+// for models that can't look at them.
+func withoutToolImages(messages []chat.Message) []chat.Message {
+	var result []chat.Message
+	for i, msg := range messages {
+		if msg.Role != chat.MessageRoleTool || len(msg.MultiContent) == 0 {
+			continue
+		}
+		if result == nil {
+			result = slices.Clone(messages)
+		}
+
+		var placeholders []string
+		for _, part := range msg.MultiContent {
+			if part.Type == chat.MessagePartTypeImageURL && part.ImageURL != nil {
+				placeholders = append(placeholders, imagePlaceholder(part.ImageURL.URL))
+			}
+		}
+		result[i].MultiContent = nil
+		result[i].Content = strings.Join(append([]string{msg.Content}, placeholders...), "\n")
+	}
+	if result == nil {
+		return messages
+	}
+	return result
+}
+
+// imagePlaceholder describes an image the model can't see.
+func imagePlaceholder(url string) string {
+	image, ok := tools.ImageFromDataURL(url)
+	if !ok {
+		return "[image omitted: this model doesn't support images]"
+	}
+	return fmt.Sprintf("[image omitted: %s, %d bytes, this model doesn't support images]", image.MIMEType, len(image.Data))
+}
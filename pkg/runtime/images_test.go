@@ -0,0 +1,40 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/modelsdev"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+func TestSupportsImages(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, supportsImages(nil))
+	assert.True(t, supportsImages(&modelsdev.Model{Modalities: modelsdev.Modalities{Input: []string{"text", "image"}}}))
+	assert.False(t, supportsImages(&modelsdev.Model{Modalities: modelsdev.Modalities{Input: []string{"text"}}}))
+}
+
+func TestWithoutToolImages(t *testing.T) {
+	t.Parallel()
+
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: "Take a screenshot"},
+		{
+			Role:         chat.MessageRoleTool,
+			Content:      "Screenshot taken",
+			MultiContent: imageParts([]tools.Image{{MIMEType: "image/png", Data: []byte{1, 2, 3}}}),
+		},
+	}
+
+	result := withoutToolImages(messages)
+	assert.Equal(t, messages[0], result[0])
+	assert.Empty(t, result[1].MultiContent)
+	assert.Equal(t, "Screenshot taken\n[image omitted: image/png, 3 bytes, this model doesn't support images]", result[1].Content)
+
+	// The session's messages are left untouched.
+	assert.Len(t, messages[1].MultiContent, 1)
+}
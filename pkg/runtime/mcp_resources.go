@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/docker/cagent/pkg/agent"
+	"github.com/docker/cagent/pkg/session"
+	mcptools "github.com/docker/cagent/pkg/tools/mcp"
+)
+
+// CurrentMCPResources returns the resources and resource templates of all the
+// MCP toolsets of the current agent.
+func (r *LocalRuntime) CurrentMCPResources(ctx context.Context) []mcptools.ResourceInfo {
+	currentAgent := r.CurrentAgent()
+	if currentAgent == nil {
+		return nil
+	}
+
+	var resources []mcptools.ResourceInfo
+	for _, toolset := range currentAgent.ToolSets() {
+		mcpToolset := UnwrapMCPToolset(toolset)
+		if mcpToolset == nil {
+			continue
+		}
+
+		mcpResources, err := mcpToolset.ListResources(ctx)
+		if err != nil {
+			slog.Warn("Failed to list MCP resources from toolset", "error", err)
+			continue
+		}
+		resources = append(resources, mcpResources...)
+	}
+
+	slog.Debug("Discovered MCP resources", "agent", currentAgent.Name(), "resource_count", len(resources))
+	return resources
+}
+
+// ReadMCPResource reads a resource referenced as server:uri from the MCP toolsets of
+// the current agent. The runtime subscribes to updates of the resource, if the server
+// supports it, so that the agent is told when it changes.
+func (r *LocalRuntime) ReadMCPResource(ctx context.Context, ref string) (string, error) {
+	server, uri, ok := mcptools.ParseResourceRef(ref)
+	if !ok {
+		return "", fmt.Errorf("invalid resource reference %q, expected server:uri", ref)
+	}
+
+	currentAgent := r.CurrentAgent()
+	if currentAgent == nil {
+		return "", fmt.Errorf("no current agent")
+	}
+
+	for _, toolset := range currentAgent.ToolSets() {
+		mcpToolset := UnwrapMCPToolset(toolset)
+		if mcpToolset == nil || mcpToolset.ServerName() != server {
+			continue
+		}
+
+		content, err := mcpToolset.ReadResource(ctx, uri)
+		if err != nil {
+			return "", err
+		}
+		if err := mcpToolset.Subscribe(ctx, uri); err != nil {
+			slog.Warn("Failed to subscribe to MCP resource", "server", server, "uri", uri, "error", err)
+		}
+		return content, nil
+	}
+
+	return "", fmt.Errorf("MCP server %q not found for agent %q", server, currentAgent.Name())
+}
+
+// addUpdatedMCPResources gives the model the new content of the subscribed
+// resources that were updated since the last model call.
+func (r *LocalRuntime) addUpdatedMCPResources(ctx context.Context, sess *session.Session, a *agent.Agent) {
+	for _, toolset := range a.ToolSets() {
+		mcpToolset := UnwrapMCPToolset(toolset)
+		if mcpToolset == nil {
+			continue
+		}
+
+		for _, uri := range mcpToolset.UpdatedResources() {
+			ref := mcptools.ResourceInfo{Server: mcpToolset.ServerName(), URI: uri}.Ref()
+
+			content, err := mcpToolset.ReadResource(ctx, uri)
+			if err != nil {
+				slog.Warn("Failed to read updated MCP resource", "resource", ref, "error", err)
+				continue
+			}
+
+			slog.Debug("Adding updated MCP resource to the session", "resource", ref)
+			sess.AddMessage(session.ImplicitUserMessage(fmt.Sprintf("The resource @%s was updated. Its new content is:\n\n%s", ref, content)))
+		}
+	}
+}
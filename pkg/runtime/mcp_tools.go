@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/docker/cagent/pkg/agent"
+	mcptools "github.com/docker/cagent/pkg/tools/mcp"
+)
+
+// setToolsChangedHandlers tells the client about the new number of tools when
+// one of the MCP servers of an agent changes its list of tools.
+func (r *LocalRuntime) setToolsChangedHandlers(a *agent.Agent) {
+	for _, toolset := range a.ToolSets() {
+		if mcpToolset := UnwrapMCPToolset(toolset); mcpToolset != nil {
+			mcpToolset.SetToolsChangedHandler(func() {
+				// The tools can't be listed from the notification handler: the
+				// server's answer would only be read once the handler returns.
+				go r.emitToolsetInfo()
+			})
+		}
+	}
+}
+
+// emitToolsetInfo sends the number of tools of the current agent to the connected client, if any.
+func (r *LocalRuntime) emitToolsetInfo() {
+	a := r.CurrentAgent()
+	agentTools, err := a.Tools(context.Background())
+	if err != nil {
+		slog.Debug("Failed to list tools after a change", "agent", a.Name(), "error", err)
+		return
+	}
+
+	r.sendEvent(ToolsetInfo(len(agentTools), a.Name()))
+}
+
+// setConnectionStateHandlers tells the client when the connection to one of
+// the MCP servers of an agent is lost, restored or given up on.
+func (r *LocalRuntime) setConnectionStateHandlers(a *agent.Agent) {
+	for _, toolset := range a.ToolSets() {
+		if mcpToolset := UnwrapMCPToolset(toolset); mcpToolset != nil {
+			mcpToolset.SetConnectionStateHandler(func(state mcptools.ConnectionState, err error) {
+				var errMsg string
+				if err != nil {
+					errMsg = err.Error()
+				}
+				r.sendEvent(MCPServerStatus(mcpToolset.ServerName(), string(state), errMsg, a.Name()))
+			})
+		}
+	}
+}
+
+// emitMCPServerStatuses sends the state of the connection to each MCP server of an agent.
+func (r *LocalRuntime) emitMCPServerStatuses(a *agent.Agent, events chan Event) {
+	for _, toolset := range a.ToolSets() {
+		if mcpToolset := UnwrapMCPToolset(toolset); mcpToolset != nil {
+			if state := mcpToolset.ConnectionState(); state != "" {
+				events <- MCPServerStatus(mcpToolset.ServerName(), string(state), "", a.Name())
+			}
+		}
+	}
+}
+
+// sendEvent sends an event, emitted outside of the agent loop, to the connected client, if any.
+// The event is dropped rather than blocking the caller if the client is too slow.
+func (r *LocalRuntime) sendEvent(event Event) {
+	r.elicitationEventsChannelMux.RLock()
+	defer r.elicitationEventsChannelMux.RUnlock()
+
+	if r.elicitationEventsChannel == nil {
+		return
+	}
+	select {
+	case r.elicitationEventsChannel <- event:
+	default:
+		slog.Debug("Dropped event: the events channel is full", "event", fmt.Sprintf("%T", event))
+	}
+}
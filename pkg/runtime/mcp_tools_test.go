@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmitToolsetInfo(t *testing.T) {
+	t.Parallel()
+
+	rt := newSamplingRuntime(t, &mockProvider{id: "openai/gpt-4o", stream: newStreamBuilder().Build()})
+
+	// Without a client, there's no one to tell.
+	rt.emitToolsetInfo()
+
+	events := make(chan Event, 1)
+	rt.setElicitationEventsChannel(events)
+	rt.emitToolsetInfo()
+
+	require.Len(t, events, 1)
+	info, ok := (<-events).(*ToolsetInfoEvent)
+	require.True(t, ok)
+	assert.Equal(t, "root", info.AgentName)
+	assert.Equal(t, 0, info.AvailableTools)
+}
+
+func TestSendEvent_DropsWhenFull(t *testing.T) {
+	t.Parallel()
+
+	rt := newSamplingRuntime(t, &mockProvider{id: "openai/gpt-4o", stream: newStreamBuilder().Build()})
+
+	events := make(chan Event, 1)
+	rt.setElicitationEventsChannel(events)
+	rt.sendEvent(MCPServerStatus("docs", "reconnecting", "connection closed", "root"))
+	rt.sendEvent(MCPServerStatus("docs", "connected", "", "root"))
+
+	require.Len(t, events, 1)
+	status, ok := (<-events).(*MCPServerStatusEvent)
+	require.True(t, ok)
+	assert.Equal(t, "docs", status.Server)
+	assert.Equal(t, "reconnecting", status.Status)
+	assert.Equal(t, "connection closed", status.Error)
+}
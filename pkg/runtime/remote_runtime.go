@@ -167,6 +167,15 @@ func (r *RemoteRuntime) RunStream(ctx context.Context, sess *session.Session) <-
 			}
 			events <- streamEvent
 		}
+
+		// Remote runs outlive their streams, so they have to be stopped explicitly.
+		if ctx.Err() != nil {
+			cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			if err := r.client.CancelSession(cancelCtx, r.sessionID); err != nil {
+				slog.Debug("Failed to cancel remote session", "session_id", r.sessionID, "error", err)
+			}
+		}
 	}()
 
 	return events
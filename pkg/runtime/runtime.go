@@ -21,9 +21,12 @@ import (
 
 	"github.com/docker/cagent/pkg/agent"
 	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/checkpoint"
+	"github.com/docker/cagent/pkg/hooks"
 	"github.com/docker/cagent/pkg/model/provider"
 	"github.com/docker/cagent/pkg/model/provider/options"
 	"github.com/docker/cagent/pkg/modelsdev"
+	"github.com/docker/cagent/pkg/permissions"
 	"github.com/docker/cagent/pkg/rag"
 	ragtypes "github.com/docker/cagent/pkg/rag/types"
 	"github.com/docker/cagent/pkg/session"
@@ -35,6 +38,7 @@ import (
 )
 
 type SessionStore interface {
+	AddSession(ctx context.Context, sess *session.Session) error
 	UpdateSession(ctx context.Context, sess *session.Session) error
 }
 
@@ -51,6 +55,9 @@ func UnwrapMCPToolset(toolset tools.ToolSet) *mcptools.Toolset {
 	if mcpToolset, ok := innerToolset.(*mcptools.Toolset); ok {
 		return mcpToolset
 	}
+	if gatewayToolset, ok := innerToolset.(*mcptools.GatewayToolset); ok {
+		return gatewayToolset.Toolset
+	}
 
 	return nil
 }
@@ -123,6 +130,15 @@ type RAGInitializer interface {
 	StartBackgroundRAGInit(ctx context.Context, sendEvent func(Event))
 }
 
+// SessionBrancher is implemented by runtimes that can fork and rewind the sessions they run.
+type SessionBrancher interface {
+	// ForkSession forks a session before the given message and stores the new session
+	ForkSession(ctx context.Context, sess *session.Session, messageIndex int) (*session.Session, error)
+	// RewindSession removes the user message at the given index and everything after it
+	// and returns the content of that message
+	RewindSession(ctx context.Context, sess *session.Session, messageIndex int) (string, error)
+}
+
 // LocalRuntime manages the execution of agents
 type LocalRuntime struct {
 	toolMap                     map[string]ToolHandler
@@ -140,6 +156,8 @@ type LocalRuntime struct {
 	ragInitialized              atomic.Bool
 	titleGen                    *titleGenerator
 	sessionStore                SessionStore
+	checkpoints                 *checkpoint.Store
+	samplingApproved            sync.Map // MCP servers whose sampling requests were approved for the session
 }
 
 type streamResult struct {
@@ -149,6 +167,9 @@ type streamResult struct {
 	ThinkingSignature string // Used with Anthropic's extended thinking feature
 	ThoughtSignature  []byte
 	Stopped           bool
+	Usage             *chat.Usage // Last usage reported by the provider, if any
+	// Streamed is set when a stream failed after some of the response was sent as events.
+	Streamed bool
 }
 
 type Opt func(*LocalRuntime)
@@ -184,6 +205,14 @@ func WithModelStore(store ModelStore) Opt {
 	}
 }
 
+// WithCheckpoints makes the runtime snapshot files before the filesystem tools
+// modify them, so that the changes can be undone.
+func WithCheckpoints(store *checkpoint.Store) Opt {
+	return func(r *LocalRuntime) {
+		r.checkpoints = store
+	}
+}
+
 func WithSessionStore(store SessionStore) Opt {
 	return func(r *LocalRuntime) {
 		r.sessionStore = store
@@ -511,6 +540,10 @@ func (r *LocalRuntime) finalizeEventChannel(ctx context.Context, sess *session.S
 	telemetry.RecordSessionEnd(ctx)
 
 	r.titleGen.Wait()
+
+	// Handlers called asynchronously, like the MCP notification handlers,
+	// must not send events once the channel is closed.
+	r.clearElicitationEventsChannel()
 }
 
 // RunStream starts the agent's interaction loop and returns a channel of events
@@ -552,6 +585,9 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 			})
 			toolset.SetManagedOAuth(r.managedOAuth)
 		}
+		r.setSamplingHandlers(a)
+		r.setToolsChangedHandlers(a)
+		r.setConnectionStateHandlers(a)
 
 		agentTools, err := r.getTools(ctx, a, sessionSpan, events)
 		if err != nil {
@@ -560,6 +596,7 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 		}
 
 		events <- ToolsetInfo(len(agentTools), r.currentAgent)
+		r.emitMCPServerStatuses(a, events)
 
 		messages := sess.GetMessages(a)
 		if sess.SendUserMessage {
@@ -572,11 +609,17 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 
 		r.registerDefaultTools()
 
+		if !r.runPromptHooks(ctx, sess, a, events) {
+			return
+		}
+
 		if sess.Title == "" {
 			r.titleGen.Generate(ctx, sess, events)
 		}
 
 		iteration := 0
+		stopHookActive := false
+		stopHookContinuations := 0
 		// Use a runtime copy of maxIterations so we don't modify the session's persistent config
 		runtimeMaxIterations := sess.MaxIterations
 
@@ -592,6 +635,9 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 					events <- Authorization("confirmed", r.currentAgent)
 				})
 			}
+			r.setSamplingHandlers(a)
+			r.setToolsChangedHandlers(a)
+			r.setConnectionStateHandlers(a)
 
 			agentTools, err := r.getTools(ctx, a, sessionSpan, events)
 			if err != nil {
@@ -662,24 +708,12 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 				}
 			}
 
-			messages := sess.GetMessages(a)
-			slog.Debug("Retrieved messages for processing", "agent", a.Name(), "message_count", len(messages))
+			r.addUpdatedMCPResources(ctx, sess, a)
 
-			slog.Debug("Creating chat completion stream", "agent", a.Name())
-			stream, err := model.CreateChatCompletionStream(streamCtx, messages, agentTools)
-			if err != nil {
-				streamSpan.RecordError(err)
-				streamSpan.SetStatus(codes.Error, "creating chat completion")
-				slog.Error("Failed to create chat completion stream", "agent", a.Name(), "error", err)
-				// Track error in telemetry
-				telemetry.RecordError(ctx, err.Error())
-				events <- Error(fmt.Sprintf("creating chat completion: %v", err))
-				streamSpan.End()
-				return
-			}
+			messages := r.getMessages(sess, a, model, contextLimit, agentTools)
+			slog.Debug("Retrieved messages for processing", "agent", a.Name(), "message_count", len(messages))
 
-			slog.Debug("Processing stream", "agent", a.Name())
-			res, err := r.handleStream(ctx, stream, a, agentTools, sess, m, events)
+			res, err := r.streamWithFallback(ctx, streamCtx, a, model, m, messages, agentTools, sess, events)
 			if err != nil {
 				// Treat context cancellation as a graceful stop
 				if errors.Is(err, context.Canceled) {
@@ -744,6 +778,13 @@ func (r *LocalRuntime) RunStream(ctx context.Context, sess *session.Session) <-c
 			r.processToolCalls(ctx, sess, res.Calls, agentTools, events)
 
 			if res.Stopped {
+				if stopHookContinuations >= maxStopHookContinuations {
+					slog.Warn("Stop hooks blocked too many times, stopping anyway", "agent", a.Name(), "session_id", sess.ID, "continuations", stopHookContinuations)
+				} else if r.runStopHooks(ctx, sess, a, stopHookActive) {
+					stopHookActive = true
+					stopHookContinuations++
+					continue
+				}
 				slog.Debug("Conversation stopped", "agent", a.Name())
 				break
 			}
@@ -850,6 +891,125 @@ func (r *LocalRuntime) Run(ctx context.Context, sess *session.Session) ([]sessio
 	return sess.GetAllMessages(), nil
 }
 
+// modelRetryBackoff is the delay before the first retry of a failed model
+// request. It doubles with every retry, up to maxModelRetryBackoff.
+var (
+	modelRetryBackoff    = time.Second
+	maxModelRetryBackoff = 30 * time.Second
+)
+
+// streamWithFallback sends the conversation to the agent's model and processes
+// the response stream. Retryable errors, including streams cut mid-response, are
+// retried with exponential backoff, then the agent's fallback models are tried in
+// order, each with the history trimmed to fit its own context window.
+func (r *LocalRuntime) streamWithFallback(ctx, streamCtx context.Context, a *agent.Agent, primary provider.Provider, primaryDef *modelsdev.Model, messages []chat.Message, agentTools []tools.Tool, sess *session.Session, events chan Event) (streamResult, error) {
+	models := append([]provider.Provider{primary}, a.FallbackModels()...)
+
+	var (
+		lastErr     error
+		failedModel string
+		discard     bool
+	)
+	for i, model := range models {
+		m := primaryDef
+		if i > 0 {
+			m, _ = r.modelsStore.GetModel(ctx, model.ID())
+			var contextLimit int64
+			if m != nil {
+				contextLimit = int64(m.Limit.Context)
+			}
+			messages = r.getMessages(sess, a, model, contextLimit, agentTools)
+		}
+
+		backoff := modelRetryBackoff
+		for attempt := 0; attempt <= a.ModelRetries(); attempt++ {
+			if lastErr != nil {
+				events <- ModelFallback(failedModel, model.ID(), attempt+1, lastErr.Error(), discard, a.Name())
+				discard = false
+			}
+			if attempt > 0 {
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return streamResult{Stopped: true}, ctx.Err()
+				}
+				backoff = min(backoff*2, maxModelRetryBackoff)
+			}
+
+			slog.Debug("Creating chat completion stream", "agent", a.Name(), "model", model.ID(), "attempt", attempt+1)
+			res, err := r.streamModel(ctx, streamCtx, model, m, a, messages, agentTools, sess, events)
+			if err == nil {
+				if lastErr != nil {
+					events <- ModelAnswered(model.ID(), attempt+1, a.Name())
+				}
+				return res, nil
+			}
+			if errors.Is(err, context.Canceled) {
+				return res, err
+			}
+			// Clients already got part of the response: the next model_fallback
+			// event tells them to drop it, the response is restarted from scratch.
+			if res.Streamed {
+				slog.Warn("Model stream failed mid-response", "agent", a.Name(), "model", model.ID(), "error", err)
+				discard = true
+			}
+
+			lastErr = err
+			failedModel = model.ID()
+			if !provider.IsRetryableError(err) {
+				slog.Warn("Model request failed", "agent", a.Name(), "model", model.ID(), "error", err)
+				break
+			}
+			slog.Warn("Model request failed with a retryable error", "agent", a.Name(), "model", model.ID(), "attempt", attempt+1, "error", err)
+		}
+	}
+
+	return streamResult{Stopped: true}, lastErr
+}
+
+// getMessages returns the session's messages that fit in the model's context window,
+// leaving room for the tool definitions and the model's output.
+// When the context size is unknown, the history is trimmed by message count.
+func (r *LocalRuntime) getMessages(sess *session.Session, a *agent.Agent, model provider.Provider, contextLimit int64, agentTools []tools.Tool) []chat.Message {
+	if contextLimit <= 0 {
+		return sess.GetMessages(a)
+	}
+
+	reserved := contextLimit / 5
+	if maxTokens := model.BaseConfig().ModelConfig.MaxTokens; maxTokens != nil && *maxTokens > 0 && *maxTokens < reserved {
+		reserved = *maxTokens
+	}
+
+	budget := contextLimit - reserved - session.EstimateToolsTokens(agentTools)
+	if budget <= 0 {
+		return sess.GetMessages(a)
+	}
+
+	return sess.GetMessagesWithinBudget(a, budget)
+}
+
+// streamModel runs a single chat completion request against a model.
+func (r *LocalRuntime) streamModel(ctx, streamCtx context.Context, model provider.Provider, m *modelsdev.Model, a *agent.Agent, messages []chat.Message, agentTools []tools.Tool, sess *session.Session, events chan Event) (streamResult, error) {
+	if !supportsImages(m) {
+		messages = withoutToolImages(messages)
+	}
+
+	stream, err := model.CreateChatCompletionStream(streamCtx, messages, agentTools)
+	if err != nil {
+		return streamResult{Stopped: true}, fmt.Errorf("creating chat completion: %w", err)
+	}
+
+	slog.Debug("Processing stream", "agent", a.Name())
+	res, err := r.handleStream(ctx, stream, a, agentTools, sess, m, events)
+	if res.Usage != nil {
+		sess.CalibrateTokenEstimate(
+			session.EstimateMessagesTokens(messages)+session.EstimateToolsTokens(agentTools),
+			res.Usage.InputTokens+res.Usage.CachedInputTokens+res.Usage.CacheWriteTokens,
+		)
+	}
+	return res, err
+}
+
 func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStream, a *agent.Agent, agentTools []tools.Tool, sess *session.Session, m *modelsdev.Model, events chan Event) (streamResult, error) {
 	defer stream.Close()
 
@@ -858,6 +1018,7 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 	var thinkingSignature string
 	var thoughtSignature []byte
 	var toolCalls []tools.ToolCall
+	var usage *chat.Usage
 	// Track which tool call indices we've already emitted partial events for
 	emittedPartialEvents := make(map[string]bool)
 
@@ -867,10 +1028,12 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 			break
 		}
 		if err != nil {
-			return streamResult{Stopped: true}, fmt.Errorf("error receiving from stream: %w", err)
+			streamed := fullContent.Len() > 0 || fullReasoningContent.Len() > 0 || len(emittedPartialEvents) > 0
+			return streamResult{Stopped: true, Streamed: streamed}, fmt.Errorf("error receiving from stream: %w", err)
 		}
 
 		if response.Usage != nil {
+			usage = response.Usage
 			if m != nil && m.Cost != nil {
 				cost := float64(response.Usage.InputTokens)*m.Cost.Input +
 					float64(response.Usage.OutputTokens)*m.Cost.Output +
@@ -906,6 +1069,7 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 				ThinkingSignature: thinkingSignature,
 				ThoughtSignature:  thoughtSignature,
 				Stopped:           true,
+				Usage:             usage,
 			}, nil
 		}
 
@@ -1000,6 +1164,7 @@ func (r *LocalRuntime) handleStream(ctx context.Context, stream chat.MessageStre
 		ThinkingSignature: thinkingSignature,
 		ThoughtSignature:  thoughtSignature,
 		Stopped:           stoppedDueToNoOutput,
+		Usage:             usage,
 	}, nil
 }
 
@@ -1014,7 +1179,21 @@ func (r *LocalRuntime) processToolCalls(ctx context.Context, sess *session.Sessi
 		agentToolMap[t.Name] = t
 	}
 
-	for i, toolCall := range calls {
+	// Record file snapshots against the assistant message that made the calls.
+	// Sub-agents inherit the recorder so that their changes are attributed to the top-level session.
+	if r.checkpoints != nil && checkpoint.RecorderFromContext(ctx) == nil {
+		ctx = checkpoint.WithRecorder(ctx, r.checkpoints.NewRecorder(sess.ID, len(sess.GetAllMessages())-1))
+	}
+
+	for i := 0; i < len(calls); i++ {
+		// Independent, approval-free calls are executed together.
+		if n := r.concurrentBatchSize(sess, a, calls[i:], agentToolMap); n > 1 {
+			r.runToolsConcurrently(ctx, sess, calls[i:i+n], agentToolMap, events, a)
+			i += n - 1
+			continue
+		}
+
+		toolCall := calls[i]
 		callCtx, callSpan := r.startSpan(ctx, "runtime.tool.call", trace.WithAttributes(
 			attribute.String("tool.name", toolCall.Function.Name),
 			attribute.String("tool.type", string(toolCall.Type)),
@@ -1027,7 +1206,7 @@ func (r *LocalRuntime) processToolCalls(ctx context.Context, sess *session.Sessi
 
 		// Find the tool - first check runtime tools, then agent tools
 		var tool tools.Tool
-		var runTool func()
+		var runTool func(tools.ToolCall)
 
 		if def, exists := r.toolMap[toolCall.Function.Name]; exists {
 			// Validate that the tool is actually available to this agent
@@ -1039,10 +1218,12 @@ func (r *LocalRuntime) processToolCalls(ctx context.Context, sess *session.Sessi
 				continue
 			}
 			tool = def.tool
-			runTool = func() { r.runAgentTool(callCtx, def.handler, sess, toolCall, def.tool, events, a) }
+			runTool = func(toolCall tools.ToolCall) {
+				r.runAgentTool(callCtx, def.handler, sess, toolCall, def.tool, events, a)
+			}
 		} else if t, exists := agentToolMap[toolCall.Function.Name]; exists {
 			tool = t
-			runTool = func() { r.runTool(callCtx, t, toolCall, events, sess, a) }
+			runTool = func(toolCall tools.ToolCall) { r.runTool(callCtx, t, toolCall, events, sess, a) }
 		} else {
 			// Tool not found - skip
 			callSpan.SetStatus(codes.Ok, "tool not found")
@@ -1063,6 +1244,76 @@ func (r *LocalRuntime) processToolCalls(ctx context.Context, sess *session.Sessi
 	}
 }
 
+// concurrentBatchSize returns how many of the leading calls can be executed in
+// parallel. Only agent tools that never prompt for approval qualify: read-only
+// tools, and tools the agent declares safe once they are approved for the
+// session or allowed by the permission policy.
+func (r *LocalRuntime) concurrentBatchSize(sess *session.Session, a *agent.Agent, calls []tools.ToolCall, agentToolMap map[string]tools.Tool) int {
+	// Calls must go through the approval flow one by one for pre_tool_use hooks to run.
+	if a.ToolConcurrency() < 2 || a.Hooks().Has(hooks.PreToolUse) {
+		return 0
+	}
+
+	n := 0
+	for _, call := range calls {
+		if _, isRuntimeTool := r.toolMap[call.Function.Name]; isRuntimeTool {
+			break
+		}
+		t, exists := agentToolMap[call.Function.Name]
+		if !exists {
+			break
+		}
+		decision := a.Permissions().Check(call.Function.Name, call.Function.Arguments)
+		if decision == permissions.Deny || decision == permissions.Ask {
+			break
+		}
+		approved := sess.ToolsApproved || decision == permissions.Allow
+		if !t.Annotations.ReadOnlyHint && !(approved && slices.Contains(a.ConcurrentTools(), t.Name)) {
+			break
+		}
+		n++
+	}
+
+	return n
+}
+
+// runToolsConcurrently executes a batch of independent tool calls with at most
+// ToolConcurrency of them in flight. Responses are added to the session in the
+// original call order.
+func (r *LocalRuntime) runToolsConcurrently(ctx context.Context, sess *session.Session, calls []tools.ToolCall, agentToolMap map[string]tools.Tool, events chan Event, a *agent.Agent) {
+	slog.Debug("Running tool calls concurrently", "agent", a.Name(), "call_count", len(calls), "concurrency", a.ToolConcurrency())
+
+	results := make([]*tools.ToolCallResult, len(calls))
+	sem := make(chan struct{}, a.ToolConcurrency())
+
+	var wg sync.WaitGroup
+	for i, toolCall := range calls {
+		tool := agentToolMap[toolCall.Function.Name]
+
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			callCtx, callSpan := r.startSpan(ctx, "runtime.tool.call", trace.WithAttributes(
+				attribute.String("tool.name", toolCall.Function.Name),
+				attribute.String("tool.type", string(toolCall.Type)),
+				attribute.String("agent", a.Name()),
+				attribute.String("session.id", sess.ID),
+				attribute.String("tool.call_id", toolCall.ID),
+			))
+			defer callSpan.End()
+
+			results[i] = r.callToolWithHandler(callCtx, toolCall, tool, events, sess, a, "runtime.tool.handler", toolSetHandler(tool, toolCall))
+			callSpan.SetStatus(codes.Ok, "tool call processed")
+		})
+	}
+	wg.Wait()
+
+	for i, toolCall := range calls {
+		r.addToolResponse(ctx, sess, toolCall, agentToolMap[toolCall.Function.Name], results[i], events, a)
+	}
+}
+
 // executeWithApproval handles the tool approval flow and executes the tool.
 // Returns true if the operation was canceled and processing should stop.
 func (r *LocalRuntime) executeWithApproval(
@@ -1072,14 +1323,31 @@ func (r *LocalRuntime) executeWithApproval(
 	tool tools.Tool,
 	events chan Event,
 	a *agent.Agent,
-	runTool func(),
+	runTool func(tools.ToolCall),
 	remainingCalls []tools.ToolCall,
 ) (canceled bool) {
-	if sess.ToolsApproved || tool.Annotations.ReadOnlyHint {
-		runTool()
+	toolCall, ok := r.runPreToolHooks(ctx, sess, toolCall, tool, events, a)
+	if !ok {
 		return false
 	}
 
+	switch a.Permissions().Check(toolCall.Function.Name, toolCall.Function.Arguments) {
+	case permissions.Deny:
+		slog.Debug("Tool call denied by permission policy", "tool", toolCall.Function.Name, "session_id", sess.ID)
+		r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, fmt.Sprintf("The tool call to '%s' was denied by the agent's permission policy. Do not retry it with the same arguments.", toolCall.Function.Name))
+		return false
+	case permissions.Allow:
+		runTool(toolCall)
+		return false
+	case permissions.Ask:
+		// Always ask for confirmation, even if tools were approved for the session.
+	default:
+		if sess.ToolsApproved || tool.Annotations.ReadOnlyHint {
+			runTool(toolCall)
+			return false
+		}
+	}
+
 	slog.Debug("Tools not approved, waiting for resume", "tool", toolCall.Function.Name, "session_id", sess.ID)
 	events <- ToolCallConfirmation(toolCall, tool, a.Name())
 
@@ -1088,11 +1356,11 @@ func (r *LocalRuntime) executeWithApproval(
 		switch cType {
 		case ResumeTypeApprove:
 			slog.Debug("Resume signal received, approving tool", "tool", toolCall.Function.Name, "session_id", sess.ID)
-			runTool()
+			runTool(toolCall)
 		case ResumeTypeApproveSession:
 			slog.Debug("Resume signal received, approving session", "tool", toolCall.Function.Name, "session_id", sess.ID)
 			sess.ToolsApproved = true
-			runTool()
+			runTool(toolCall)
 		case ResumeTypeReject:
 			slog.Debug("Resume signal received, rejecting tool", "tool", toolCall.Function.Name, "session_id", sess.ID)
 			r.addToolErrorResponse(ctx, sess, toolCall, tool, events, a, "The user rejected the tool call.")
@@ -1120,6 +1388,22 @@ func (r *LocalRuntime) executeToolWithHandler(
 	spanName string,
 	execute func(ctx context.Context) (*tools.ToolCallResult, time.Duration, error),
 ) {
+	res := r.callToolWithHandler(ctx, toolCall, tool, events, sess, a, spanName, execute)
+	r.addToolResponse(ctx, sess, toolCall, tool, res, events, a)
+}
+
+// callToolWithHandler runs a tool handler and turns handler errors and
+// cancellations into error results. It does not touch the session.
+func (r *LocalRuntime) callToolWithHandler(
+	ctx context.Context,
+	toolCall tools.ToolCall,
+	tool tools.Tool,
+	events chan Event,
+	sess *session.Session,
+	a *agent.Agent,
+	spanName string,
+	execute func(ctx context.Context) (*tools.ToolCallResult, time.Duration, error),
+) *tools.ToolCallResult {
 	ctx, span := r.startSpan(ctx, spanName, trace.WithAttributes(
 		attribute.String("tool.name", toolCall.Function.Name),
 		attribute.String("agent", a.Name()),
@@ -1130,6 +1414,12 @@ func (r *LocalRuntime) executeToolWithHandler(
 
 	events <- ToolCall(toolCall, tool, a.Name())
 
+	ctx = tools.WithOutput(ctx, func(delta string) {
+		select {
+		case events <- ToolCallOutputDelta(toolCall.ID, delta, a.Name()):
+		case <-ctx.Done():
+		}
+	})
 	res, duration, err := execute(ctx)
 
 	telemetry.RecordToolCall(ctx, toolCall.Function.Name, sess.ID, a.Name(), duration, err)
@@ -1150,6 +1440,11 @@ func (r *LocalRuntime) executeToolWithHandler(
 		slog.Debug("Tool call completed", "tool", toolCall.Function.Name, "output_length", len(res.Output))
 	}
 
+	return r.runPostToolHooks(ctx, sess, toolCall, a, res)
+}
+
+// addToolResponse emits the tool response event and records the result in the session.
+func (r *LocalRuntime) addToolResponse(ctx context.Context, sess *session.Session, toolCall tools.ToolCall, tool tools.Tool, res *tools.ToolCallResult, events chan Event, a *agent.Agent) {
 	events <- ToolCallResponse(toolCall, tool, res, res.Output, a.Name())
 
 	// Ensure tool response content is not empty for API compatibility
@@ -1164,17 +1459,24 @@ func (r *LocalRuntime) executeToolWithHandler(
 		ToolCallID: toolCall.ID,
 		CreatedAt:  time.Now().Format(time.RFC3339),
 	}
+	if len(res.Images) > 0 {
+		toolResponseMsg.MultiContent = imageParts(res.Images)
+	}
 	sess.AddMessage(session.NewAgentMessage(a, &toolResponseMsg))
 	_ = r.sessionStore.UpdateSession(ctx, sess)
 }
 
 // runTool executes agent tools from toolsets (MCP, filesystem, etc.).
 func (r *LocalRuntime) runTool(ctx context.Context, tool tools.Tool, toolCall tools.ToolCall, events chan Event, sess *session.Session, a *agent.Agent) {
-	r.executeToolWithHandler(ctx, toolCall, tool, events, sess, a, "runtime.tool.handler",
-		func(ctx context.Context) (*tools.ToolCallResult, time.Duration, error) {
-			res, err := tool.Handler(ctx, toolCall)
-			return res, 0, err
-		})
+	r.executeToolWithHandler(ctx, toolCall, tool, events, sess, a, "runtime.tool.handler", toolSetHandler(tool, toolCall))
+}
+
+// toolSetHandler adapts a toolset tool's handler to executeToolWithHandler.
+func toolSetHandler(tool tools.Tool, toolCall tools.ToolCall) func(ctx context.Context) (*tools.ToolCallResult, time.Duration, error) {
+	return func(ctx context.Context) (*tools.ToolCallResult, time.Duration, error) {
+		res, err := tool.Handler(ctx, toolCall)
+		return res, 0, err
+	}
 }
 
 func (r *LocalRuntime) runAgentTool(ctx context.Context, handler ToolHandlerFunc, sess *session.Session, toolCall tools.ToolCall, tool tools.Tool, events chan Event, a *agent.Agent) {
@@ -1341,6 +1643,36 @@ func (r *LocalRuntime) handleHandoff(_ context.Context, _ *session.Session, tool
 	return tools.ResultSuccess(handoffMessage), nil
 }
 
+// ForkSession forks a session before the given message and stores the new session
+func (r *LocalRuntime) ForkSession(ctx context.Context, sess *session.Session, messageIndex int) (*session.Session, error) {
+	fork, err := sess.Fork(messageIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.sessionStore.AddSession(ctx, fork); err != nil {
+		return nil, fmt.Errorf("storing forked session: %w", err)
+	}
+	return fork, nil
+}
+
+// RewindSession removes the user message at the given index and everything after it
+// and returns the content of that message
+func (r *LocalRuntime) RewindSession(ctx context.Context, sess *session.Session, messageIndex int) (string, error) {
+	content, err := sess.Rewind(messageIndex)
+	if err != nil {
+		return "", err
+	}
+
+	_ = r.sessionStore.UpdateSession(ctx, sess)
+	return content, nil
+}
+
+// Checkpoints returns the store holding the file snapshots, or nil if checkpoints are disabled
+func (r *LocalRuntime) Checkpoints() *checkpoint.Store {
+	return r.checkpoints
+}
+
 // Summarize generates a summary for the session based on the conversation history
 func (r *LocalRuntime) Summarize(ctx context.Context, sess *session.Session, events chan Event) {
 	slog.Debug("Generating summary for session", "session_id", sess.ID)
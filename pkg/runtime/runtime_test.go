@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,8 +17,11 @@ import (
 
 	"github.com/docker/cagent/pkg/agent"
 	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/hooks"
 	"github.com/docker/cagent/pkg/model/provider/base"
 	"github.com/docker/cagent/pkg/modelsdev"
+	"github.com/docker/cagent/pkg/permissions"
 	"github.com/docker/cagent/pkg/rag"
 	"github.com/docker/cagent/pkg/rag/database"
 	"github.com/docker/cagent/pkg/rag/strategy"
@@ -856,3 +862,491 @@ func TestEmitStartupInfo(t *testing.T) {
 	// Should be empty due to deduplication
 	require.Empty(t, collectedEvents2, "EmitStartupInfo should not emit duplicate events")
 }
+
+func TestProcessToolCalls_ReadOnlyToolsRunConcurrently(t *testing.T) {
+	// Both handlers block until the other one has started, so this only
+	// completes if the calls are executed in parallel.
+	var started sync.WaitGroup
+	started.Add(2)
+	handler := func(output string) tools.ToolHandler {
+		return func(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+			started.Done()
+			started.Wait()
+			return tools.ResultSuccess(output), nil
+		}
+	}
+	readOnly := tools.ToolAnnotations{ReadOnlyHint: true}
+	agentTools := []tools.Tool{
+		{Name: "slow_read", Annotations: readOnly, Handler: handler("slow")},
+		{Name: "fast_read", Annotations: readOnly, Handler: handler("fast")},
+	}
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{}), agent.WithToolConcurrency(4))
+	tm := team.New(team.WithAgents(root))
+
+	rt, err := New(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Start"))
+	calls := []tools.ToolCall{
+		{ID: "call-1", Type: "function", Function: tools.FunctionCall{Name: "slow_read", Arguments: "{}"}},
+		{ID: "call-2", Type: "function", Function: tools.FunctionCall{Name: "fast_read", Arguments: "{}"}},
+	}
+
+	events := make(chan Event, 10)
+	done := make(chan struct{})
+	go func() {
+		rt.processToolCalls(t.Context(), sess, calls, agentTools, events)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("read-only tool calls were not executed concurrently")
+	}
+
+	var toolMessages []chat.Message
+	for _, it := range sess.Messages {
+		if it.IsMessage() && it.Message.Message.Role == chat.MessageRoleTool {
+			toolMessages = append(toolMessages, it.Message.Message)
+		}
+	}
+	require.Len(t, toolMessages, 2)
+	require.Equal(t, "call-1", toolMessages[0].ToolCallID)
+	require.Equal(t, "slow", toolMessages[0].Content)
+	require.Equal(t, "call-2", toolMessages[1].ToolCallID)
+	require.Equal(t, "fast", toolMessages[1].Content)
+}
+
+func TestConcurrentBatchSize(t *testing.T) {
+	readOnly := tools.ToolAnnotations{ReadOnlyHint: true}
+	agentToolMap := map[string]tools.Tool{
+		"read_file":  {Name: "read_file", Annotations: readOnly},
+		"fetch":      {Name: "fetch"},
+		"write_file": {Name: "write_file"},
+	}
+	call := func(name string) tools.ToolCall {
+		return tools.ToolCall{Function: tools.FunctionCall{Name: name}}
+	}
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{}), agent.WithToolConcurrency(4), agent.WithConcurrentTools([]string{"fetch"}))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+	rt.registerDefaultTools()
+
+	calls := []tools.ToolCall{call("read_file"), call("fetch"), call("read_file"), call("write_file"), call("read_file")}
+
+	sess := session.New()
+	require.Equal(t, 1, rt.concurrentBatchSize(sess, root, calls, agentToolMap), "declared tools need an approved session")
+
+	sess.ToolsApproved = true
+	require.Equal(t, 3, rt.concurrentBatchSize(sess, root, calls, agentToolMap))
+
+	require.Equal(t, 0, rt.concurrentBatchSize(sess, root, []tools.ToolCall{call("transfer_task"), call("read_file")}, agentToolMap))
+
+	sequential := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{}))
+	require.Equal(t, 0, rt.concurrentBatchSize(sess, sequential, calls, agentToolMap))
+}
+
+func TestExecuteWithApproval_DeniedByPermissions(t *testing.T) {
+	checker, err := permissions.NewChecker(&latest.PermissionsConfig{
+		Deny: []latest.PermissionRule{{Tool: "shell", Args: map[string]string{"cmd": "rm *"}}},
+	})
+	require.NoError(t, err)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{}), agent.WithPermissions(checker))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	// Denied calls are refused even when tools are approved for the session.
+	sess := session.New(session.WithUserMessage("Start"), session.WithToolsApproved(true))
+	toolCall := tools.ToolCall{ID: "call-1", Type: "function", Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd": "rm -rf /"}`}}
+
+	events := make(chan Event, 10)
+	ran := false
+	canceled := rt.executeWithApproval(t.Context(), sess, toolCall, tools.Tool{Name: "shell"}, events, root, func(tools.ToolCall) { ran = true }, nil)
+	close(events)
+
+	require.False(t, canceled)
+	require.False(t, ran)
+
+	var response *ToolCallResponseEvent
+	for ev := range events {
+		if e, ok := ev.(*ToolCallResponseEvent); ok {
+			response = e
+		}
+	}
+	require.NotNil(t, response)
+	require.True(t, response.Result.IsError)
+	require.Contains(t, response.Response, "denied")
+}
+
+func TestExecuteWithApproval_PreToolHooks(t *testing.T) {
+	runner, err := hooks.NewRunner(&latest.HooksConfig{
+		PreToolUse: []latest.HookConfig{
+			{Matcher: "shell", Command: `grep -q 'rm ' && { echo 'destructive command' >&2; exit 2; }; echo '{"tool_input": {"cmd": "ls -la"}}'`},
+		},
+		PostToolUse: []latest.HookConfig{
+			{Command: "echo 'formatted by hook'"},
+		},
+	})
+	require.NoError(t, err)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{}), agent.WithHooks(runner))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Start"), session.WithToolsApproved(true))
+	shellTool := tools.Tool{
+		Name: "shell",
+		Handler: func(_ context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+			return &tools.ToolCallResult{Output: "ran " + toolCall.Function.Arguments}, nil
+		},
+	}
+
+	// Blocked calls are never executed.
+	events := make(chan Event, 10)
+	blocked := tools.ToolCall{ID: "call-1", Type: "function", Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd": "rm -rf /"}`}}
+	ran := false
+	rt.executeWithApproval(t.Context(), sess, blocked, shellTool, events, root, func(tools.ToolCall) { ran = true }, nil)
+	require.False(t, ran)
+
+	// Other calls run with the arguments rewritten by the hook.
+	allowed := tools.ToolCall{ID: "call-2", Type: "function", Function: tools.FunctionCall{Name: "shell", Arguments: `{"cmd": "ls"}`}}
+	rt.executeWithApproval(t.Context(), sess, allowed, shellTool, events, root, func(toolCall tools.ToolCall) {
+		rt.runTool(t.Context(), shellTool, toolCall, events, sess, root)
+	}, nil)
+	close(events)
+
+	var responses []*ToolCallResponseEvent
+	for ev := range events {
+		if e, ok := ev.(*ToolCallResponseEvent); ok {
+			responses = append(responses, e)
+		}
+	}
+	require.Len(t, responses, 2)
+	require.True(t, responses[0].Result.IsError)
+	require.Contains(t, responses[0].Response, "destructive command")
+	require.Contains(t, responses[1].Response, `ran {"cmd": "ls -la"}`)
+	require.Contains(t, responses[1].Response, "formatted by hook")
+}
+
+func TestRunPostToolHooks_KeepsImages(t *testing.T) {
+	runner, err := hooks.NewRunner(&latest.HooksConfig{
+		PostToolUse: []latest.HookConfig{{Command: "echo 'checked by hook'"}},
+	})
+	require.NoError(t, err)
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{}), agent.WithHooks(runner))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	image := tools.Image{MIMEType: "image/png", Data: []byte{1, 2, 3}}
+	res := &tools.ToolCallResult{Output: "screenshot taken", Images: []tools.Image{image}}
+	toolCall := tools.ToolCall{ID: "call-1", Type: "function", Function: tools.FunctionCall{Name: "screenshot"}}
+
+	out := rt.runPostToolHooks(t.Context(), session.New(), toolCall, root, res)
+	require.Contains(t, out.Output, "screenshot taken")
+	require.Contains(t, out.Output, "checked by hook")
+	require.Equal(t, []tools.Image{image}, out.Images)
+	require.Equal(t, "screenshot taken", res.Output)
+}
+
+func TestRunTool_StreamsOutput(t *testing.T) {
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{}))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Start"))
+	tool := tools.Tool{
+		Name: "build",
+		Handler: func(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+			out := tools.OutputWriter(ctx)
+			_, _ = out.Write([]byte("step 1\n"))
+			_, _ = out.Write([]byte("step 2\n"))
+			return tools.ResultSuccess("build succeeded"), nil
+		},
+	}
+
+	events := make(chan Event, 10)
+	rt.runTool(t.Context(), tool, tools.ToolCall{ID: "call-1", Type: "function", Function: tools.FunctionCall{Name: "build"}}, events, sess, root)
+	close(events)
+
+	var deltas []string
+	var response *ToolCallResponseEvent
+	for ev := range events {
+		switch e := ev.(type) {
+		case *ToolCallOutputDeltaEvent:
+			require.Equal(t, "call-1", e.ToolCallID)
+			deltas = append(deltas, e.Delta)
+		case *ToolCallResponseEvent:
+			response = e
+		}
+	}
+
+	require.Equal(t, []string{"step 1\n", "step 2\n"}, deltas)
+	require.NotNil(t, response)
+	require.Equal(t, "build succeeded", response.Response)
+
+	messages := sess.GetAllMessages()
+	require.Equal(t, "build succeeded", messages[len(messages)-1].Message.Content)
+}
+
+func TestRunStream_PromptBlockedByHook(t *testing.T) {
+	runner, err := hooks.NewRunner(&latest.HooksConfig{
+		UserPromptSubmit: []latest.HookConfig{{Command: `grep -q secret && { echo 'no secrets' >&2; exit 2; }; true`}},
+	})
+	require.NoError(t, err)
+
+	prov := &mockProvider{id: "test/mock-model", stream: newStreamBuilder().AddContent("Hello").AddStopWithUsage(1, 1).Build()}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithHooks(runner))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("here is my secret"))
+	var errorEvent *ErrorEvent
+	for ev := range rt.RunStream(t.Context(), sess) {
+		if e, ok := ev.(*ErrorEvent); ok {
+			errorEvent = e
+		}
+	}
+
+	require.NotNil(t, errorEvent)
+	require.Contains(t, errorEvent.Error, "no secrets")
+	require.Empty(t, sess.GetAllMessages(), "the blocked prompt should be removed")
+}
+
+// repeatingProvider answers every request with the same content.
+type repeatingProvider struct {
+	id string
+}
+
+func (p *repeatingProvider) ID() string { return p.id }
+
+func (p *repeatingProvider) CreateChatCompletionStream(context.Context, []chat.Message, []tools.Tool) (chat.MessageStream, error) {
+	return newStreamBuilder().AddContent("Done").AddStopWithUsage(1, 1).Build(), nil
+}
+
+func (p *repeatingProvider) BaseConfig() base.Config { return base.Config{} }
+
+func TestRunStream_StopHookContinuationsAreCapped(t *testing.T) {
+	runner, err := hooks.NewRunner(&latest.HooksConfig{
+		Stop: []latest.HookConfig{{Command: `echo 'keep going' >&2; exit 2`}},
+	})
+	require.NoError(t, err)
+
+	prov := &repeatingProvider{id: "test/mock-model"}
+	root := agent.New("root", "You are a test agent", agent.WithModel(prov), agent.WithHooks(runner))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+
+	sess := session.New(session.WithUserMessage("Hi"))
+	for range rt.RunStream(t.Context(), sess) {
+	}
+
+	var answers int
+	for _, msg := range sess.GetAllMessages() {
+		if msg.Message.Role == chat.MessageRoleAssistant {
+			answers++
+		}
+	}
+	require.Equal(t, 1+maxStopHookContinuations, answers)
+}
+
+// flakyProvider fails its first failures requests with err before returning stream,
+// or the next of streams if set.
+type flakyProvider struct {
+	id       string
+	failures int
+	err      error
+	stream   chat.MessageStream
+	streams  []chat.MessageStream
+	calls    int
+	messages []chat.Message
+}
+
+func (p *flakyProvider) ID() string { return p.id }
+
+func (p *flakyProvider) CreateChatCompletionStream(_ context.Context, messages []chat.Message, _ []tools.Tool) (chat.MessageStream, error) {
+	p.calls++
+	p.messages = messages
+	if p.calls <= p.failures {
+		return nil, p.err
+	}
+	if len(p.streams) > 0 {
+		stream := p.streams[0]
+		p.streams = p.streams[1:]
+		return stream, nil
+	}
+	return p.stream, nil
+}
+
+func (p *flakyProvider) BaseConfig() base.Config { return base.Config{} }
+
+// brokenStream streams its responses, then fails instead of ending.
+type brokenStream struct {
+	*mockStream
+	err error
+}
+
+func (b *brokenStream) Recv() (chat.MessageStreamResponse, error) {
+	resp, err := b.mockStream.Recv()
+	if errors.Is(err, io.EOF) {
+		return resp, b.err
+	}
+	return resp, err
+}
+
+// contextModelStore knows the context size of some models.
+type contextModelStore map[string]int
+
+func (s contextModelStore) GetModel(_ context.Context, modelID string) (*modelsdev.Model, error) {
+	limit, ok := s[modelID]
+	if !ok {
+		return nil, nil
+	}
+	return &modelsdev.Model{Limit: modelsdev.Limit{Context: limit}}, nil
+}
+
+func TestModelFallback_TrimsHistoryForEachModel(t *testing.T) {
+	primary := &flakyProvider{id: "test/primary", failures: 10, err: errors.New("invalid request")}
+	fallback := &flakyProvider{id: "test/fallback", stream: newStreamBuilder().AddContent("Hello").AddStopWithUsage(1, 1).Build()}
+	root := agent.New("root", "You are a test agent", agent.WithModel(primary), agent.WithFallbackModel(fallback))
+	rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(contextModelStore{"test/fallback": 2000}))
+	require.NoError(t, err)
+
+	sess := session.New()
+	sess.Title = "Unit Test"
+	for range 20 {
+		sess.AddMessage(session.UserMessage(strings.Repeat("long question ", 100)))
+		sess.AddMessage(session.NewAgentMessage(root, &chat.Message{Role: chat.MessageRoleAssistant, Content: strings.Repeat("long answer ", 100)}))
+	}
+	sess.AddMessage(session.UserMessage("Hi"))
+
+	for range rt.RunStream(t.Context(), sess) {
+	}
+
+	require.Greater(t, len(primary.messages), len(fallback.messages), "the history should be trimmed for the fallback model's context")
+	require.Equal(t, "Hi", fallback.messages[len(fallback.messages)-1].Content)
+}
+
+func TestModelFallback(t *testing.T) {
+	modelRetryBackoff = time.Millisecond
+	t.Cleanup(func() { modelRetryBackoff = time.Second })
+
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name          string
+		primary       *flakyProvider
+		fallback      *flakyProvider
+		retries       int
+		wantFallbacks []Event
+		wantError     bool
+		wantContent   string
+	}{
+		{
+			name:     "retryable error retries the primary model",
+			primary:  &flakyProvider{id: "test/primary", failures: 1, err: connReset, stream: newStreamBuilder().AddContent("Hello").AddStopWithUsage(1, 1).Build()},
+			fallback: &flakyProvider{id: "test/fallback"},
+			retries:  2,
+			wantFallbacks: []Event{
+				ModelFallback("test/primary", "test/primary", 2, "creating chat completion: "+connReset.Error(), false, "root"),
+				ModelAnswered("test/primary", 2, "root"),
+			},
+		},
+		{
+			name:     "exhausted retries move to the fallback model",
+			primary:  &flakyProvider{id: "test/primary", failures: 10, err: connReset},
+			fallback: &flakyProvider{id: "test/fallback", stream: newStreamBuilder().AddContent("Hello").AddStopWithUsage(1, 1).Build()},
+			retries:  1,
+			wantFallbacks: []Event{
+				ModelFallback("test/primary", "test/primary", 2, "creating chat completion: "+connReset.Error(), false, "root"),
+				ModelFallback("test/primary", "test/fallback", 1, "creating chat completion: "+connReset.Error(), false, "root"),
+				ModelAnswered("test/fallback", 1, "root"),
+			},
+		},
+		{
+			name:     "non-retryable error moves straight to the fallback model",
+			primary:  &flakyProvider{id: "test/primary", failures: 10, err: errors.New("invalid request")},
+			fallback: &flakyProvider{id: "test/fallback", stream: newStreamBuilder().AddContent("Hello").AddStopWithUsage(1, 1).Build()},
+			retries:  2,
+			wantFallbacks: []Event{
+				ModelFallback("test/primary", "test/fallback", 1, "creating chat completion: invalid request", false, "root"),
+				ModelAnswered("test/fallback", 1, "root"),
+			},
+		},
+		{
+			name:     "error once every model failed",
+			primary:  &flakyProvider{id: "test/primary", failures: 10, err: errors.New("invalid request")},
+			fallback: &flakyProvider{id: "test/fallback", failures: 10, err: errors.New("invalid request")},
+			wantFallbacks: []Event{
+				ModelFallback("test/primary", "test/fallback", 1, "creating chat completion: invalid request", false, "root"),
+			},
+			wantError: true,
+		},
+		{
+			name: "streams cut mid-response are restarted",
+			primary: &flakyProvider{id: "test/primary", streams: []chat.MessageStream{
+				&brokenStream{mockStream: newStreamBuilder().AddContent("Hel").Build(), err: connReset},
+				newStreamBuilder().AddContent("Hello").AddStopWithUsage(1, 1).Build(),
+			}},
+			fallback: &flakyProvider{id: "test/fallback"},
+			retries:  2,
+			wantFallbacks: []Event{
+				ModelFallback("test/primary", "test/primary", 2, "error receiving from stream: "+connReset.Error(), true, "root"),
+				ModelAnswered("test/primary", 2, "root"),
+			},
+			wantContent: "HelHello",
+		},
+		{
+			name:     "streams cut before any output are retried",
+			primary:  &flakyProvider{id: "test/primary", stream: &brokenStream{mockStream: newStreamBuilder().Build(), err: connReset}},
+			fallback: &flakyProvider{id: "test/fallback", stream: newStreamBuilder().AddContent("Hello").AddStopWithUsage(1, 1).Build()},
+			wantFallbacks: []Event{
+				ModelFallback("test/primary", "test/fallback", 1, "error receiving from stream: "+connReset.Error(), false, "root"),
+				ModelAnswered("test/fallback", 1, "root"),
+			},
+			wantContent: "Hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := agent.New("root", "You are a test agent",
+				agent.WithModel(tt.primary),
+				agent.WithFallbackModel(tt.fallback),
+				agent.WithModelRetries(tt.retries),
+			)
+			rt, err := New(team.New(team.WithAgents(root)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+			require.NoError(t, err)
+
+			sess := session.New(session.WithUserMessage("Hi"))
+			sess.Title = "Unit Test"
+
+			var fallbacks []Event
+			var sawError bool
+			var content strings.Builder
+			for ev := range rt.RunStream(t.Context(), sess) {
+				switch ev := ev.(type) {
+				case *ModelFallbackEvent, *ModelAnsweredEvent:
+					fallbacks = append(fallbacks, ev)
+				case *AgentChoiceEvent:
+					content.WriteString(ev.Content)
+				case *ErrorEvent:
+					sawError = true
+				}
+			}
+
+			require.Equal(t, tt.wantFallbacks, fallbacks)
+			require.Equal(t, tt.wantError, sawError)
+			if tt.wantContent != "" {
+				require.Equal(t, tt.wantContent, content.String())
+			}
+			if !tt.wantError {
+				require.Equal(t, "Hello", sess.GetLastAssistantMessageContent())
+			}
+		})
+	}
+}
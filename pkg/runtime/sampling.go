@@ -0,0 +1,266 @@
+package runtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/cagent/pkg/agent"
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/model/provider"
+	"github.com/docker/cagent/pkg/model/provider/options"
+	"github.com/docker/cagent/pkg/tools"
+	mcptools "github.com/docker/cagent/pkg/tools/mcp"
+)
+
+// samplingToolName is the name of the tool call users are asked to confirm
+// when an MCP server requests a completion.
+const samplingToolName = "mcp_sampling"
+
+// setSamplingHandlers routes the sampling requests of the MCP toolsets of an agent through its model.
+func (r *LocalRuntime) setSamplingHandlers(a *agent.Agent) {
+	for _, toolset := range a.ToolSets() {
+		if mcpToolset := UnwrapMCPToolset(toolset); mcpToolset != nil {
+			mcpToolset.SetSamplingHandler(r.samplingHandler(a, mcpToolset))
+		}
+	}
+}
+
+// samplingHandler answers the sampling requests of an MCP server with the model
+// of the agent owning the toolset, within the limits configured for the toolset.
+func (r *LocalRuntime) samplingHandler(a *agent.Agent, toolset *mcptools.Toolset) mcptools.SamplingHandler {
+	return func(ctx context.Context, req *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+		cfg := toolset.SamplingConfig()
+
+		model, err := samplingModel(a, cfg.Models, req.ModelPreferences)
+		if err != nil {
+			return nil, err
+		}
+
+		maxTokens := req.MaxTokens
+		if cfg.MaxTokens > 0 && (maxTokens <= 0 || maxTokens > cfg.MaxTokens) {
+			maxTokens = cfg.MaxTokens
+		}
+
+		if _, approved := r.samplingApproved.Load(toolset.ServerName()); cfg.RequireApproval && !approved {
+			approvedForSession, err := r.confirmSampling(ctx, toolset, a, model, maxTokens, req)
+			if err != nil {
+				return nil, err
+			}
+			if approvedForSession {
+				r.samplingApproved.Store(toolset.ServerName(), true)
+			}
+		}
+
+		slog.Debug("Sampling with the agent's model", "server", toolset.ServerName(), "agent", a.Name(), "model", model.ID(), "max_tokens", maxTokens)
+
+		opts := []options.Opt{options.WithStructuredOutput(nil)}
+		if req.Temperature > 0 {
+			opts = append(opts, options.WithTemperature(req.Temperature))
+		}
+		// CloneWithOptions takes the max tokens of the last option.
+		if maxTokens > 0 {
+			opts = append(opts, options.WithMaxTokens(maxTokens))
+		}
+		model = provider.CloneWithOptions(ctx, model, opts...)
+
+		return sample(ctx, model, req)
+	}
+}
+
+// samplingModel picks the model used for a sampling request: the first of the allowed
+// models matching the server's hints or, if none matches, the first allowed model.
+// The models allowed by default are the agent's model and its fallbacks.
+func samplingModel(a *agent.Agent, allowed []string, prefs *mcp.ModelPreferences) (provider.Provider, error) {
+	var candidates []provider.Provider
+	for _, model := range append([]provider.Provider{a.Model()}, a.FallbackModels()...) {
+		if model != nil && (len(allowed) == 0 || slices.Contains(allowed, model.ID())) {
+			candidates = append(candidates, model)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("none of the models of agent %q can be used for sampling", a.Name())
+	}
+
+	if prefs != nil {
+		for _, hint := range prefs.Hints {
+			if hint == nil || hint.Name == "" {
+				continue
+			}
+			for _, model := range candidates {
+				if strings.Contains(model.ID(), hint.Name) {
+					return model, nil
+				}
+			}
+		}
+	}
+
+	return candidates[0], nil
+}
+
+// confirmSampling asks the user to approve a sampling request, the same way tool calls are confirmed.
+// It returns true if the user approved all the following requests of the server.
+func (r *LocalRuntime) confirmSampling(ctx context.Context, toolset *mcptools.Toolset, a *agent.Agent, model provider.Provider, maxTokens int64, req *mcp.CreateMessageParams) (bool, error) {
+	r.elicitationEventsChannelMux.RLock()
+	events := r.elicitationEventsChannel
+	r.elicitationEventsChannelMux.RUnlock()
+
+	if events == nil {
+		return false, errors.New("sampling requires approval but no client is connected")
+	}
+
+	arguments, err := json.Marshal(map[string]any{
+		"server":        toolset.ServerName(),
+		"model":         model.ID(),
+		"max_tokens":    maxTokens,
+		"system_prompt": req.SystemPrompt,
+		"messages":      req.Messages,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	toolCall := tools.ToolCall{
+		ID:   "sampling_" + uuid.NewString(),
+		Type: "function",
+		Function: tools.FunctionCall{
+			Name:      samplingToolName,
+			Arguments: string(arguments),
+		},
+	}
+	tool := tools.Tool{
+		Name:        samplingToolName,
+		Category:    "mcp",
+		Description: fmt.Sprintf("The MCP server %s asks for a completion of the model", toolset.ServerName()),
+	}
+
+	events <- ToolCallConfirmation(toolCall, tool, a.Name())
+
+	select {
+	case resumeType := <-r.resumeChan:
+		switch resumeType {
+		case ResumeTypeApprove:
+			return false, nil
+		case ResumeTypeApproveSession:
+			return true, nil
+		default:
+			return false, errors.New("the user rejected the sampling request")
+		}
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// sample runs a completion of the model for the messages of a sampling request.
+// The completion ends at the first of the request's stop sequences, which
+// providers don't support.
+func sample(ctx context.Context, model provider.Provider, req *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	var messages []chat.Message
+	if req.SystemPrompt != "" {
+		messages = append(messages, chat.Message{
+			Role:    chat.MessageRoleSystem,
+			Content: req.SystemPrompt,
+		})
+	}
+	for _, msg := range req.Messages {
+		message, err := samplingMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	stream, err := model.CreateChatCompletionStream(ctx, messages, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sampling failed: %w", err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	stopReason := "endTurn"
+	text := ""
+stream:
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sampling failed: %w", err)
+		}
+
+		for _, choice := range response.Choices {
+			searchFrom := content.Len()
+			content.WriteString(choice.Delta.Content)
+			if end, ok := stopSequenceIndex(content.String(), searchFrom, req.StopSequences); ok {
+				text = content.String()[:end]
+				stopReason = "stopSequence"
+				break stream
+			}
+			if choice.FinishReason == chat.FinishReasonLength {
+				stopReason = "maxTokens"
+			}
+		}
+	}
+	if stopReason != "stopSequence" {
+		text = content.String()
+	}
+
+	return &mcp.CreateMessageResult{
+		Content:    &mcp.TextContent{Text: text},
+		Model:      model.ID(),
+		Role:       "assistant",
+		StopReason: stopReason,
+	}, nil
+}
+
+// stopSequenceIndex returns where the first stop sequence found in text starts.
+// Only the stop sequences ending after searchFrom are looked for, since the
+// text before was already searched.
+func stopSequenceIndex(text string, searchFrom int, stopSequences []string) (int, bool) {
+	end, found := len(text), false
+	for _, stop := range stopSequences {
+		if stop == "" {
+			continue
+		}
+		from := max(0, searchFrom-len(stop)+1)
+		if i := strings.Index(text[from:], stop); i >= 0 && from+i < end {
+			end, found = from+i, true
+		}
+	}
+	return end, found
+}
+
+func samplingMessage(msg *mcp.SamplingMessage) (chat.Message, error) {
+	role := chat.MessageRoleUser
+	if msg.Role == "assistant" {
+		role = chat.MessageRoleAssistant
+	}
+
+	switch content := msg.Content.(type) {
+	case *mcp.TextContent:
+		return chat.Message{Role: role, Content: content.Text}, nil
+	case *mcp.ImageContent:
+		return chat.Message{
+			Role: role,
+			MultiContent: []chat.MessagePart{{
+				Type: chat.MessagePartTypeImageURL,
+				ImageURL: &chat.MessageImageURL{
+					URL:    "data:" + content.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(content.Data),
+					Detail: chat.ImageURLDetailAuto,
+				},
+			}},
+		}, nil
+	default:
+		return chat.Message{}, fmt.Errorf("unsupported sampling content type %T", msg.Content)
+	}
+}
@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/agent"
+	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/team"
+	mcptools "github.com/docker/cagent/pkg/tools/mcp"
+)
+
+func newSamplingRuntime(t *testing.T, models ...*mockProvider) *LocalRuntime {
+	t.Helper()
+
+	opts := []agent.Opt{agent.WithModel(models[0])}
+	for _, model := range models[1:] {
+		opts = append(opts, agent.WithFallbackModel(model))
+	}
+	tm := team.New(team.WithAgents(agent.New("root", "You are a test agent", opts...)))
+
+	rt, err := New(tm, WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+	return rt
+}
+
+func samplingRequest(hints ...string) *mcp.CreateMessageParams {
+	req := &mcp.CreateMessageParams{
+		SystemPrompt: "Be brief",
+		MaxTokens:    100,
+		Messages:     []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: "Hello"}}},
+	}
+	if len(hints) > 0 {
+		req.ModelPreferences = &mcp.ModelPreferences{}
+		for _, hint := range hints {
+			req.ModelPreferences.Hints = append(req.ModelPreferences.Hints, &mcp.ModelHint{Name: hint})
+		}
+	}
+	return req
+}
+
+func TestSampling(t *testing.T) {
+	t.Parallel()
+
+	main := &mockProvider{id: "openai/gpt-4o", stream: newStreamBuilder().AddContent("Hi ").AddContent("there").Build()}
+	fallback := &mockProvider{id: "anthropic/claude-sonnet-4-0", stream: newStreamBuilder().AddContent("Hello!").Build()}
+	rt := newSamplingRuntime(t, main, fallback)
+
+	toolset := mcptools.NewToolsetCommand("research", "research-server", nil, nil, "")
+	handler := rt.samplingHandler(rt.CurrentAgent(), toolset)
+
+	result, err := handler(t.Context(), samplingRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "openai/gpt-4o", result.Model)
+	assert.Equal(t, &mcp.TextContent{Text: "Hi there"}, result.Content)
+	assert.Equal(t, "endTurn", result.StopReason)
+
+	// Hints select one of the agent's models.
+	result, err = handler(t.Context(), samplingRequest("gemini", "claude-sonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic/claude-sonnet-4-0", result.Model)
+	assert.Equal(t, &mcp.TextContent{Text: "Hello!"}, result.Content)
+}
+
+func TestSampling_StopSequences(t *testing.T) {
+	t.Parallel()
+
+	rt := newSamplingRuntime(t, &mockProvider{id: "openai/gpt-4o", stream: newStreamBuilder().AddContent("one, tw").AddContent("o\nthree").AddContent("\nfour").Build()})
+	toolset := mcptools.NewToolsetCommand("research", "research-server", nil, nil, "")
+
+	req := samplingRequest()
+	req.StopSequences = []string{"END", "two\n"}
+	result, err := rt.samplingHandler(rt.CurrentAgent(), toolset)(t.Context(), req)
+	require.NoError(t, err)
+	assert.Equal(t, &mcp.TextContent{Text: "one, "}, result.Content)
+	assert.Equal(t, "stopSequence", result.StopReason)
+}
+
+func TestSampling_OwningAgent(t *testing.T) {
+	t.Parallel()
+
+	root := agent.New("root", "You are a test agent", agent.WithModel(&mockProvider{id: "openai/gpt-4o", stream: newStreamBuilder().AddContent("root").Build()}))
+	helper := agent.New("helper", "You are a test agent", agent.WithModel(&mockProvider{id: "anthropic/claude-sonnet-4-0", stream: newStreamBuilder().AddContent("helper").Build()}))
+	rt, err := New(team.New(team.WithAgents(root, helper)), WithSessionCompaction(false), WithModelStore(mockModelStore{}))
+	require.NoError(t, err)
+	require.Equal(t, "root", rt.CurrentAgent().Name())
+
+	// The toolset belongs to the helper, even while the root agent is running.
+	toolset := mcptools.NewToolsetCommand("research", "research-server", nil, nil, "")
+	result, err := rt.samplingHandler(helper, toolset)(t.Context(), samplingRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic/claude-sonnet-4-0", result.Model)
+}
+
+func TestSampling_AllowedModels(t *testing.T) {
+	t.Parallel()
+
+	main := &mockProvider{id: "openai/gpt-4o", stream: newStreamBuilder().Build()}
+	fallback := &mockProvider{id: "openai/gpt-4o-mini", stream: newStreamBuilder().AddContent("ok").Build()}
+	rt := newSamplingRuntime(t, main, fallback)
+
+	toolset := mcptools.NewToolsetCommand("research", "research-server", nil, nil, "", mcptools.WithSampling(latest.SamplingConfig{
+		Models: []string{"openai/gpt-4o-mini"},
+	}))
+	result, err := rt.samplingHandler(rt.CurrentAgent(), toolset)(t.Context(), samplingRequest("gpt-4o"))
+	require.NoError(t, err)
+	assert.Equal(t, "openai/gpt-4o-mini", result.Model)
+
+	toolset = mcptools.NewToolsetCommand("research", "research-server", nil, nil, "", mcptools.WithSampling(latest.SamplingConfig{
+		Models: []string{"google/gemini-2.5-pro"},
+	}))
+	_, err = rt.samplingHandler(rt.CurrentAgent(), toolset)(t.Context(), samplingRequest())
+	require.ErrorContains(t, err, "can be used for sampling")
+}
+
+func TestSampling_RequireApproval(t *testing.T) {
+	t.Parallel()
+
+	rt := newSamplingRuntime(t, &mockProvider{id: "openai/gpt-4o", stream: newStreamBuilder().AddContent("ok").Build()})
+	toolset := mcptools.NewToolsetCommand("research", "research-server", nil, nil, "", mcptools.WithSampling(latest.SamplingConfig{
+		RequireApproval: true,
+	}))
+	handler := rt.samplingHandler(rt.CurrentAgent(), toolset)
+
+	// Without a client to ask, requests are refused.
+	_, err := handler(t.Context(), samplingRequest())
+	require.ErrorContains(t, err, "no client is connected")
+
+	events := make(chan Event, 1)
+	rt.setElicitationEventsChannel(events)
+
+	go func() {
+		confirmation := (<-events).(*ToolCallConfirmationEvent)
+		assert.Equal(t, samplingToolName, confirmation.ToolCall.Function.Name)
+		assert.Contains(t, confirmation.ToolCall.Function.Arguments, `"server":"research"`)
+		rt.resumeChan <- ResumeTypeReject
+	}()
+	_, err = handler(t.Context(), samplingRequest())
+	require.ErrorContains(t, err, "rejected")
+
+	go func() {
+		<-events
+		rt.resumeChan <- ResumeTypeApproveSession
+	}()
+	result, err := handler(t.Context(), samplingRequest())
+	require.NoError(t, err)
+	assert.Equal(t, &mcp.TextContent{Text: "ok"}, result.Content)
+
+	// Approved for the session: no more confirmation.
+	_, err = handler(t.Context(), samplingRequest())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
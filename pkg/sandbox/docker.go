@@ -0,0 +1,119 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrDockerNotFound is returned when the docker CLI isn't available to start a sandbox.
+var ErrDockerNotFound = errors.New("the docker CLI is required to run sandboxed commands")
+
+// DockerRunner runs sandboxes as Docker containers, using the docker CLI.
+type DockerRunner struct{}
+
+var _ Runner = (*DockerRunner)(nil)
+
+func NewDockerRunner() *DockerRunner {
+	return &DockerRunner{}
+}
+
+func (r *DockerRunner) Start(ctx context.Context, spec Spec) (string, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", ErrDockerNotFound
+	}
+
+	out, err := exec.CommandContext(ctx, "docker", runArgs(spec)...).Output()
+	if err != nil {
+		return "", dockerError(err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *DockerRunner) Command(id string, opts ExecOptions) *exec.Cmd {
+	cmd := exec.Command("docker", execArgs(id, opts)...)
+	cmd.Env = execEnv(os.Environ(), opts.Env)
+	return cmd
+}
+
+func (r *DockerRunner) TempDir(string) string {
+	return "/tmp"
+}
+
+func (r *DockerRunner) Remove(ctx context.Context, id string) error {
+	if err := exec.CommandContext(ctx, "docker", "rm", "--force", id).Run(); err != nil {
+		return dockerError(err)
+	}
+	return nil
+}
+
+// runArgs returns the arguments of the `docker run` command that starts the sandbox.
+// The container sleeps forever and commands are run with `docker exec`.
+func runArgs(spec Spec) []string {
+	args := []string{
+		"run", "--detach", "--rm", "--init",
+		"--name", spec.Name,
+		"--label", "com.docker.cagent.sandbox=true",
+		"--volume", spec.WorkingDir + ":" + spec.WorkingDir,
+		"--workdir", spec.WorkingDir,
+	}
+	if !spec.Network {
+		args = append(args, "--network", "none")
+	}
+	if spec.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(spec.CPUs, 'f', -1, 64))
+	}
+	if spec.Memory != "" {
+		args = append(args, "--memory", spec.Memory)
+	}
+	for _, mount := range spec.Mounts {
+		args = append(args, "--volume", mount)
+	}
+	if spec.User != "" {
+		args = append(args, "--user", spec.User)
+	}
+
+	return append(args, spec.Image, "sleep", "infinity")
+}
+
+// execArgs returns the arguments of the `docker exec` command that runs a command in the sandbox.
+// Only the names of the environment variables are passed, the docker CLI reads their values from
+// its own environment (see execEnv) so they don't show up in the process list.
+func execArgs(id string, opts ExecOptions) []string {
+	args := []string{"exec"}
+	if opts.Dir != "" {
+		args = append(args, "--workdir", opts.Dir)
+	}
+	for _, env := range opts.Env {
+		name, _, _ := strings.Cut(env, "=")
+		args = append(args, "--env", name)
+	}
+
+	args = append(args, id)
+	return append(args, opts.Args...)
+}
+
+// execEnv returns the environment of the `docker exec` command: the environment of the host,
+// the variables of the sandboxed command, and then the host's DOCKER_* variables again so that
+// the sandboxed command can't change which daemon or configuration the docker CLI uses.
+func execEnv(host, env []string) []string {
+	result := append(append([]string{}, host...), env...)
+	for _, kv := range host {
+		if strings.HasPrefix(kv, "DOCKER_") {
+			result = append(result, kv)
+		}
+	}
+	return result
+}
+
+func dockerError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
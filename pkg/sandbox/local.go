@@ -0,0 +1,60 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// LocalRunner is a stand-in for DockerRunner that runs the commands directly
+// on the host, with the same environment isolation but no filesystem, network
+// or resource isolation. It's meant for tests and for hosts without Docker.
+type LocalRunner struct {
+	mu       sync.Mutex
+	tempDirs map[string]string
+}
+
+var _ Runner = (*LocalRunner)(nil)
+
+func NewLocalRunner() *LocalRunner {
+	return &LocalRunner{
+		tempDirs: map[string]string{},
+	}
+}
+
+func (r *LocalRunner) Start(_ context.Context, spec Spec) (string, error) {
+	tempDir, err := os.MkdirTemp("", spec.Name)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tempDirs[spec.Name] = tempDir
+
+	return spec.Name, nil
+}
+
+func (r *LocalRunner) Command(_ string, opts ExecOptions) *exec.Cmd {
+	cmd := exec.Command(opts.Args[0], opts.Args[1:]...)
+	cmd.Dir = opts.Dir
+	// Like in a container, the host environment isn't inherited.
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, opts.Env...)
+	return cmd
+}
+
+func (r *LocalRunner) TempDir(id string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tempDirs[id]
+}
+
+func (r *LocalRunner) Remove(_ context.Context, id string) error {
+	r.mu.Lock()
+	tempDir := r.tempDirs[id]
+	delete(r.tempDirs, id)
+	r.mu.Unlock()
+
+	return os.RemoveAll(tempDir)
+}
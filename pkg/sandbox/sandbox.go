@@ -0,0 +1,229 @@
+// Package sandbox runs shell commands inside a long-lived container, with the
+// working directory bind-mounted, instead of directly on the host.
+package sandbox
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/docker/cagent/pkg/config/latest"
+)
+
+const defaultImage = "alpine:latest"
+
+// Spec describes the container a sandbox runs commands in.
+type Spec struct {
+	Name       string
+	Image      string
+	WorkingDir string
+	Network    bool
+	CPUs       float64
+	Memory     string
+	Mounts     []string
+	// User is the uid:gid commands run as, so that files written to the working directory belong to the user.
+	User string
+}
+
+// ExecOptions describes a command to run inside the container.
+type ExecOptions struct {
+	Args []string
+	Dir  string
+	Env  []string
+}
+
+// Runner manages the container of a sandbox.
+type Runner interface {
+	// Start creates and starts a container and returns its ID.
+	Start(ctx context.Context, spec Spec) (string, error)
+	// Command returns a command that runs inside the container when started.
+	Command(id string, opts ExecOptions) *exec.Cmd
+	// TempDir returns a directory, as seen from inside the container, where temporary files can be written.
+	TempDir(id string) string
+	// Remove stops and removes the container.
+	Remove(ctx context.Context, id string) error
+}
+
+// Process is a command running in the sandbox.
+type Process interface {
+	Wait() error
+	Kill() error
+}
+
+// Sandbox lazily starts a container on the first command and reuses it for
+// all the following commands, until it's closed.
+type Sandbox struct {
+	spec   Spec
+	runner Runner
+
+	mu          sync.Mutex
+	containerID string
+}
+
+// New creates a sandbox that mounts workingDir and runs commands with the given runner.
+func New(cfg *latest.SandboxConfig, workingDir string, runner Runner) *Sandbox {
+	return &Sandbox{
+		spec: Spec{
+			Name:       "cagent-sandbox-" + randomID(),
+			Image:      cmp.Or(cfg.Image, defaultImage),
+			WorkingDir: workingDir,
+			Network:    cfg.Network,
+			CPUs:       cfg.CPUs,
+			Memory:     cfg.Memory,
+			Mounts:     cfg.Mounts,
+			User:       currentUser(),
+		},
+		runner: runner,
+	}
+}
+
+// WorkingDir returns the directory commands run in by default. It has the same path on the host and in the sandbox.
+func (s *Sandbox) WorkingDir() string {
+	return s.spec.WorkingDir
+}
+
+// Start runs command with /bin/sh in the sandbox. Relative directories are
+// relative to the working directory. The process must be waited for.
+func (s *Sandbox) Start(ctx context.Context, command, dir string, env []string, output io.Writer) (Process, error) {
+	id, err := s.container(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir == "" || !path.IsAbs(dir) {
+		dir = path.Join(s.spec.WorkingDir, dir)
+	}
+
+	// The wrapper records the pid of the shell so that it can be killed from
+	// inside the container: killing the exec client doesn't stop the command.
+	pidFile := path.Join(s.runner.TempDir(id), "cagent-"+randomID()+".pid")
+	cmd := s.runner.Command(id, ExecOptions{
+		Args: []string{"/bin/sh", "-c", `echo $$ > "$0"; exec /bin/sh -c "$1"`, pidFile, command},
+		Dir:  dir,
+		Env:  env,
+	})
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &process{
+		sandbox: s,
+		id:      id,
+		cmd:     cmd,
+		pidFile: pidFile,
+	}, nil
+}
+
+// Run runs command in the sandbox and returns its combined output.
+// The command is killed if ctx is canceled.
+func (s *Sandbox) Run(ctx context.Context, command, dir string, env []string) ([]byte, error) {
+	var output bytes.Buffer
+	proc, err := s.Start(ctx, command, dir, env, &output)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- proc.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return output.Bytes(), err
+	case <-ctx.Done():
+		_ = proc.Kill()
+		<-done
+		return output.Bytes(), ctx.Err()
+	}
+}
+
+// Close removes the container, which stops all the commands still running.
+func (s *Sandbox) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.containerID == "" {
+		return nil
+	}
+
+	id := s.containerID
+	s.containerID = ""
+	slog.Debug("Removing sandbox container", "name", s.spec.Name)
+	return s.runner.Remove(ctx, id)
+}
+
+func (s *Sandbox) container(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.containerID != "" {
+		return s.containerID, nil
+	}
+
+	slog.Debug("Starting sandbox container", "name", s.spec.Name, "image", s.spec.Image, "working_dir", s.spec.WorkingDir)
+	id, err := s.runner.Start(ctx, s.spec)
+	if err != nil {
+		return "", fmt.Errorf("starting sandbox: %w", err)
+	}
+
+	s.containerID = id
+	return id, nil
+}
+
+type process struct {
+	sandbox *Sandbox
+	id      string
+	cmd     *exec.Cmd
+	pidFile string
+}
+
+func (p *process) Wait() error {
+	return p.cmd.Wait()
+}
+
+// Kill stops the shell and its children inside the sandbox, then the local exec client.
+func (p *process) Kill() error {
+	// The pid file might not be written yet if the command was just started.
+	// The shell is stopped while its children are killed so that it can't run the next command.
+	kill := p.sandbox.runner.Command(p.id, ExecOptions{
+		Args: []string{"/bin/sh", "-c", `for i in 1 2 3 4 5 6 7 8 9 10; do [ -s "$0" ] && break; sleep 0.1; done; pid=$(cat "$0") || exit 1; kill -STOP "$pid"; pkill -TERM -P "$pid"; kill -TERM "$pid"; kill -CONT "$pid"`, p.pidFile},
+	})
+	err := kill.Run()
+
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+
+	if err != nil {
+		return fmt.Errorf("killing sandboxed process: %w", err)
+	}
+	return nil
+}
+
+func currentUser() string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	return strconv.Itoa(os.Getuid()) + ":" + strconv.Itoa(os.Getgid())
+}
+
+func randomID() string {
+	buf := make([]byte, 6)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
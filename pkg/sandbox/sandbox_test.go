@@ -0,0 +1,147 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/config/latest"
+)
+
+func newLocalSandbox(t *testing.T) *Sandbox {
+	t.Helper()
+
+	sb := New(&latest.SandboxConfig{}, t.TempDir(), NewLocalRunner())
+	t.Cleanup(func() {
+		_ = sb.Close(t.Context())
+	})
+	return sb
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("CAGENT_SANDBOX_HOST_SECRET", "leaked")
+	sb := newLocalSandbox(t)
+	require.NoError(t, os.Mkdir(filepath.Join(sb.WorkingDir(), "sub"), 0o755))
+
+	output, err := sb.Run(t.Context(), `pwd; echo "[$GREETING] [$CAGENT_SANDBOX_HOST_SECRET]"`, "sub", []string{"GREETING=hello"})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, filepath.Join(sb.WorkingDir(), "sub"), lines[0])
+	assert.Equal(t, "[hello] []", lines[1], "the host environment should not be inherited")
+}
+
+func TestRun_Canceled(t *testing.T) {
+	sb := newLocalSandbox(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := sb.Run(ctx, "sleep 30", "", nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestProcess_Kill(t *testing.T) {
+	sb := newLocalSandbox(t)
+
+	var output bytes.Buffer
+	proc, err := sb.Start(t.Context(), "echo started; sleep 30; echo done", "", nil, &output)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return proc.Kill() == nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.Error(t, proc.Wait())
+	assert.NotContains(t, output.String(), "done")
+}
+
+func TestProcess_KillRightAfterStart(t *testing.T) {
+	sb := newLocalSandbox(t)
+	marker := filepath.Join(sb.WorkingDir(), "marker")
+
+	proc, err := sb.Start(t.Context(), "sleep 30; touch marker", "", nil, io.Discard)
+	require.NoError(t, err)
+
+	// The pid file may not be written yet.
+	require.NoError(t, proc.Kill())
+	require.Error(t, proc.Wait())
+
+	// The shell doesn't get to run the next command.
+	time.Sleep(200 * time.Millisecond)
+	assert.NoFileExists(t, marker)
+}
+
+func TestDockerArgs(t *testing.T) {
+	args := runArgs(Spec{
+		Name:       "cagent-sandbox-test",
+		Image:      "golang:1.25",
+		WorkingDir: "/home/user/project",
+		CPUs:       1.5,
+		Memory:     "2g",
+		Mounts:     []string{"/home/user/.cache:/cache:ro"},
+		User:       "1000:1000",
+	})
+	assert.Equal(t, []string{
+		"run", "--detach", "--rm", "--init",
+		"--name", "cagent-sandbox-test",
+		"--label", "com.docker.cagent.sandbox=true",
+		"--volume", "/home/user/project:/home/user/project",
+		"--workdir", "/home/user/project",
+		"--network", "none",
+		"--cpus", "1.5",
+		"--memory", "2g",
+		"--volume", "/home/user/.cache:/cache:ro",
+		"--user", "1000:1000",
+		"golang:1.25", "sleep", "infinity",
+	}, args)
+
+	assert.NotContains(t, runArgs(Spec{Network: true}), "--network")
+
+	assert.Equal(t, []string{
+		"exec", "--workdir", "/home/user/project", "--env", "A", "container-id", "/bin/sh", "-c", "ls",
+	}, execArgs("container-id", ExecOptions{
+		Args: []string{"/bin/sh", "-c", "ls"},
+		Dir:  "/home/user/project",
+		Env:  []string{"A=secret"},
+	}))
+}
+
+func TestDockerRunner_CommandKeepsValuesOffArgv(t *testing.T) {
+	cmd := NewDockerRunner().Command("container-id", ExecOptions{
+		Args: []string{"/bin/sh", "-c", "ls"},
+		Env:  []string{"TOKEN=secret"},
+	})
+
+	for _, arg := range cmd.Args {
+		assert.NotContains(t, arg, "secret")
+	}
+	assert.Contains(t, cmd.Args, "TOKEN")
+	assert.Contains(t, cmd.Env, "TOKEN=secret")
+}
+
+func TestExecEnv_KeepsDockerConfiguration(t *testing.T) {
+	env := execEnv([]string{"PATH=/bin", "DOCKER_HOST=unix:///var/run/docker.sock"}, []string{"A=b", "DOCKER_HOST=tcp://evil:2375"})
+
+	assert.Equal(t, "DOCKER_HOST=unix:///var/run/docker.sock", env[len(env)-1])
+	assert.Contains(t, env, "A=b")
+}
+
+func TestNew_Defaults(t *testing.T) {
+	sb := New(&latest.SandboxConfig{}, "/work", NewDockerRunner())
+
+	assert.Equal(t, defaultImage, sb.spec.Image)
+	assert.False(t, sb.spec.Network)
+	assert.True(t, strings.HasPrefix(sb.spec.Name, "cagent-sandbox-"))
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/labstack/echo/v4"
+
+	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/session"
+)
+
+// TokensEnv lists comma-separated bearer tokens that are granted full access to the API.
+const TokensEnv = "CAGENT_API_TOKENS"
+
+const principalKey = "principal"
+
+var errInvalidToken = errors.New("invalid token")
+
+// Principal is the identity an API request is made on behalf of.
+type Principal struct {
+	// Subject identifies the caller. Sessions are only visible to the subject that created them.
+	// The subjects of JSON Web Tokens are prefixed with their issuer and a "#".
+	Subject string
+	// Agents lists the agents the caller can use. All the agents can be used if empty.
+	Agents []string
+	// Yolo allows the caller to approve all the tool calls of a session.
+	Yolo bool
+}
+
+// CanUseAgent checks whether the principal can use an agent, given its name or the base name of its file.
+// A nil principal, used when authentication is disabled, can use every agent.
+func (p *Principal) CanUseAgent(name string) bool {
+	if p == nil || len(p.Agents) == 0 {
+		return true
+	}
+	return slices.Contains(p.Agents, name) || slices.Contains(p.Agents, filepath.Base(name))
+}
+
+// CanYolo checks whether the principal can approve all the tool calls of a session.
+func (p *Principal) CanYolo() bool {
+	return p == nil || p.Yolo
+}
+
+// Owns checks whether the principal can access a session.
+func (p *Principal) Owns(sess *session.Session) bool {
+	return p == nil || sess.Owner == p.Subject
+}
+
+// Authenticator turns a bearer token into a Principal.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// TokenConfig describes a static bearer token and what it grants access to.
+type TokenConfig struct {
+	Token   string   `yaml:"token"`
+	Subject string   `yaml:"subject,omitempty"`
+	Agents  []string `yaml:"agents,omitempty"`
+	Yolo    bool     `yaml:"yolo,omitempty"`
+}
+
+// TokenAuthenticator authenticates static bearer tokens.
+type TokenAuthenticator struct {
+	// principals are indexed by the SHA-256 of their token, so that looking
+	// up a token doesn't leak how much of it matches a valid one.
+	principals map[string]*Principal
+}
+
+// NewTokenAuthenticator creates an authenticator for the given static tokens.
+// Tokens without a subject get one derived from the token itself.
+func NewTokenAuthenticator(tokens []TokenConfig) (*TokenAuthenticator, error) {
+	principals := make(map[string]*Principal, len(tokens))
+	for i, token := range tokens {
+		if token.Token == "" {
+			return nil, fmt.Errorf("token #%d is empty", i+1)
+		}
+
+		key := hashToken(token.Token)
+		if _, exists := principals[key]; exists {
+			return nil, fmt.Errorf("token #%d is a duplicate", i+1)
+		}
+		subject := token.Subject
+		if strings.Contains(subject, "#") {
+			// Only the subjects of JSON Web Tokens have a "#".
+			return nil, fmt.Errorf("the subject of token #%d can't contain '#'", i+1)
+		}
+		if subject == "" {
+			subject = "token-" + key[:12]
+		}
+		principals[key] = &Principal{
+			Subject: subject,
+			Agents:  token.Agents,
+			Yolo:    token.Yolo,
+		}
+	}
+
+	return &TokenAuthenticator{principals: principals}, nil
+}
+
+func (a *TokenAuthenticator) Authenticate(_ context.Context, token string) (*Principal, error) {
+	if p, found := a.principals[hashToken(token)]; found {
+		return p, nil
+	}
+	return nil, errInvalidToken
+}
+
+// LoadTokensFile reads static tokens from a YAML or JSON file of the form:
+//
+//	tokens:
+//	  - token: s3cr3t
+//	    subject: alice
+//	    agents: [pirate.yaml]
+//	    yolo: true
+func LoadTokensFile(path string) ([]TokenConfig, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Tokens []TokenConfig `yaml:"tokens"`
+	}
+	if err := yaml.UnmarshalWithOptions(buf, &file, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("parsing tokens file %s: %w", path, err)
+	}
+	return file.Tokens, nil
+}
+
+// TokensFromEnv returns the tokens listed in $CAGENT_API_TOKENS. Those tokens
+// can use every agent and approve all the tool calls of their sessions.
+func TokensFromEnv() []TokenConfig {
+	var tokens []TokenConfig
+	for token := range strings.SplitSeq(os.Getenv(TokensEnv), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, TokenConfig{Token: token, Yolo: true})
+		}
+	}
+	return tokens
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// WithAuthenticators requires API requests to carry a bearer token accepted by one of the authenticators.
+// Sessions are then scoped to the subject of the token that created them.
+func WithAuthenticators(authenticators ...Authenticator) Opt {
+	return func(s *Server) {
+		s.authenticators = append(s.authenticators, authenticators...)
+	}
+}
+
+// authenticate is a middleware that authenticates the bearer token of requests.
+// It does nothing if no authenticator is configured.
+func (s *Server) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if len(s.authenticators) == 0 || c.Request().Method == http.MethodOptions || c.Path() == "/api/ping" {
+			return next(c)
+		}
+
+		token, found := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if found && token != "" {
+			for _, authenticator := range s.authenticators {
+				p, err := authenticator.Authenticate(c.Request().Context(), strings.TrimSpace(token))
+				if err == nil {
+					c.Set(principalKey, p)
+					return next(c)
+				}
+				slog.Debug("Authentication failed", "path", c.Path(), "error", err)
+			}
+		}
+
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+		if strings.HasPrefix(c.Path(), "/v1/") {
+			return c.JSON(http.StatusUnauthorized, api.OpenAIErrorResponse{Error: api.OpenAIError{
+				Message: "missing or invalid bearer token",
+				Type:    "invalid_request_error",
+				Code:    "invalid_api_key",
+			}})
+		}
+		return echo.NewHTTPError(http.StatusUnauthorized, "missing or invalid bearer token")
+	}
+}
+
+// principal returns the authenticated caller of a request, or nil if authentication is disabled.
+func principal(c echo.Context) *Principal {
+	p, _ := c.Get(principalKey).(*Principal)
+	return p
+}
+
+// sessionOwner is a middleware that hides the sessions that the caller doesn't own.
+func (s *Server) sessionOwner(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := principal(c)
+		if p == nil {
+			return next(c)
+		}
+
+		sess, err := s.sm.GetSession(c.Request().Context(), c.Param("id"))
+		if err != nil {
+			return sessionError(err, "failed to get session")
+		}
+		if !p.Owns(sess) {
+			return echo.NewHTTPError(http.StatusNotFound, "session not found")
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/session"
+)
+
+func startAuthServer(t *testing.T, ctx context.Context) string {
+	t.Helper()
+
+	authenticator, err := NewTokenAuthenticator([]TokenConfig{
+		{Token: "alice-token", Subject: "alice", Yolo: true},
+		{Token: "bob-token", Subject: "bob", Agents: []string{"pirate.yaml"}},
+	})
+	require.NoError(t, err)
+
+	return startServerWithStore(t, ctx, prepareAgentsDir(t, "pirate.yaml", "contradict.yaml"), session.NewInMemorySessionStore(), WithAuthenticators(authenticator))
+}
+
+func TestServer_Authentication(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	lnPath := startAuthServer(t, ctx)
+
+	status, _ := httpAuth(t, ctx, http.MethodGet, lnPath, "/api/ping", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions", "wrong-token", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions", "alice-token", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, buf := httpAuth(t, ctx, http.MethodGet, lnPath, "/v1/models", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	var openAIErr api.OpenAIErrorResponse
+	unmarshal(t, buf, &openAIErr)
+	assert.Equal(t, "invalid_api_key", openAIErr.Error.Code)
+}
+
+func TestServer_SessionsAreScopedToTheirOwner(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	lnPath := startAuthServer(t, ctx)
+
+	status, buf := httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions", "alice-token", map[string]any{"owner": "bob"})
+	require.Equal(t, http.StatusOK, status)
+	var sess session.Session
+	unmarshal(t, buf, &sess)
+	assert.Equal(t, "alice", sess.Owner)
+
+	status, _ = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions/"+sess.ID, "alice-token", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Bob can neither list, read, run, nor delete Alice's session.
+	status, buf = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions", "bob-token", nil)
+	require.Equal(t, http.StatusOK, status)
+	var sessions []api.SessionsResponse
+	unmarshal(t, buf, &sessions)
+	assert.Empty(t, sessions)
+
+	status, _ = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions/"+sess.ID, "bob-token", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/agent/pirate.yaml", "bob-token", []api.Message{{Content: "Hi"}})
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = httpAuth(t, ctx, http.MethodDelete, lnPath, "/api/sessions/"+sess.ID, "bob-token", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, buf = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions", "alice-token", nil)
+	require.Equal(t, http.StatusOK, status)
+	unmarshal(t, buf, &sessions)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, sess.ID, sessions[0].ID)
+}
+
+func TestServer_AgentAllowlist(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	lnPath := startAuthServer(t, ctx)
+
+	status, buf := httpAuth(t, ctx, http.MethodGet, lnPath, "/api/agents", "bob-token", nil)
+	require.Equal(t, http.StatusOK, status)
+	var agents []api.Agent
+	unmarshal(t, buf, &agents)
+	require.Len(t, agents, 1)
+	assert.Equal(t, "pirate.yaml", filepath.Base(agents[0].Name))
+
+	status, buf = httpAuth(t, ctx, http.MethodGet, lnPath, "/v1/models", "bob-token", nil)
+	require.Equal(t, http.StatusOK, status)
+	var models api.ModelsResponse
+	unmarshal(t, buf, &models)
+	require.Len(t, models.Data, 1)
+
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/v1/chat/completions", "bob-token", map[string]any{
+		"model":    "contradict.yaml",
+		"messages": []map[string]any{{"role": "user", "content": "Hi"}},
+	})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, buf = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions", "bob-token", map[string]any{})
+	require.Equal(t, http.StatusOK, status)
+	var sess session.Session
+	unmarshal(t, buf, &sess)
+
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/agent/contradict.yaml", "bob-token", []api.Message{{Content: "Hi"}})
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestServer_YoloRequiresPermission(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	lnPath := startAuthServer(t, ctx)
+
+	status, _ := httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions", "bob-token", map[string]any{"tools_approved": true})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, buf := httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions", "bob-token", map[string]any{})
+	require.Equal(t, http.StatusOK, status)
+	var sess session.Session
+	unmarshal(t, buf, &sess)
+
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/tools/toggle", "bob-token", nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/resume", "bob-token", map[string]any{"confirmation": "approve-session"})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions", "alice-token", map[string]any{"tools_approved": true})
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestLoadTokensFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tokens.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`tokens:
+  - token: s3cr3t
+    subject: alice
+    agents: [pirate.yaml]
+    yolo: true
+  - token: other
+`), 0o600))
+
+	tokens, err := LoadTokensFile(path)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, TokenConfig{Token: "s3cr3t", Subject: "alice", Agents: []string{"pirate.yaml"}, Yolo: true}, tokens[0])
+
+	authenticator, err := NewTokenAuthenticator(tokens)
+	require.NoError(t, err)
+	p, err := authenticator.Authenticate(t.Context(), "other")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(p.Subject, "token-"))
+	assert.False(t, p.Yolo)
+
+	_, err = NewTokenAuthenticator(append(tokens, TokenConfig{Token: "other"}))
+	require.Error(t, err)
+
+	// Subjects with a "#" could be taken for the subjects of JSON Web Tokens.
+	_, err = NewTokenAuthenticator([]TokenConfig{{Token: "s3cr3t", Subject: "https://accounts.example.com#alice"}})
+	require.Error(t, err)
+}
+
+func httpAuth(t *testing.T, ctx context.Context, method, socketPath, path, token string, payload any) (int, []byte) {
+	t.Helper()
+
+	body := io.Reader(http.NoBody)
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		require.NoError(t, err)
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://_"+path, body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", strings.TrimPrefix(socketPath, "unix://"))
+			},
+		},
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, buf
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/docker/cagent/pkg/runtime"
+)
+
+const (
+	// eventLogSize is the number of events kept per session for clients that reconnect.
+	eventLogSize = 10_000
+	// eventLogBytes is the size of the events kept per session, once encoded.
+	eventLogBytes = 16 << 20
+	// eventLogRetention is how long the events of a session are kept once its run stops.
+	eventLogRetention = 10 * time.Minute
+)
+
+// loggedEvent is a runtime event, numbered in the order it was emitted, and
+// encoded once for all the clients.
+type loggedEvent struct {
+	id   uint64
+	data []byte
+}
+
+// eventLog buffers the latest events of a session's runs, so that clients can
+// attach to a run, or resume after a disconnection, from any event. The log is
+// bounded both in number of events and in bytes, and is emptied once the session
+// has been idle for a while. Event ids increase monotonically across the runs
+// of a session, starting at 1.
+type eventLog struct {
+	maxEvents int
+	maxBytes  int
+	retention time.Duration
+
+	mu     sync.Mutex
+	events []loggedEvent
+	bytes  int
+	// lastID is the id of the latest event.
+	lastID uint64
+	// runStartID is the id of the first event of the latest run.
+	runStartID uint64
+	running    bool
+	// evict empties the log once the session has been idle for the retention period.
+	evict *time.Timer
+	// changed is closed, and replaced, whenever an event is added or a run stops.
+	changed chan struct{}
+}
+
+func newEventLog(maxEvents, maxBytes int) *eventLog {
+	return &eventLog{
+		maxEvents: maxEvents,
+		maxBytes:  maxBytes,
+		retention: eventLogRetention,
+		changed:   make(chan struct{}),
+	}
+}
+
+// startRun marks the beginning of a run. It returns the id of the event preceding the run.
+func (l *eventLog) startRun() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.evict != nil {
+		l.evict.Stop()
+		l.evict = nil
+	}
+	l.running = true
+	l.runStartID = l.lastID + 1
+	return l.lastID
+}
+
+// stopRun marks the end of a run and wakes up the subscribers. The events are
+// dropped after the retention period, unless another run starts in the meantime.
+func (l *eventLog) stopRun() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.running = false
+	l.evict = time.AfterFunc(l.retention, l.clear)
+	l.notify()
+}
+
+// clear drops the events of an idle session. The ids keep increasing from the last one.
+func (l *eventLog) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.running {
+		return
+	}
+	l.events = nil
+	l.bytes = 0
+}
+
+// add appends an event to the log, evicting the oldest ones if the log is full.
+func (l *eventLog) add(event runtime.Event) uint64 {
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to marshal event", "error", err)
+		return l.lastEventID()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	l.events = append(l.events, loggedEvent{id: l.lastID, data: data})
+	l.bytes += len(data)
+	// The latest event is always kept, whatever its size.
+	for len(l.events) > 1 && (len(l.events) > l.maxEvents || l.bytes > l.maxBytes) {
+		l.bytes -= len(l.events[0].data)
+		l.events[0] = loggedEvent{}
+		l.events = l.events[1:]
+	}
+	l.notify()
+
+	return l.lastID
+}
+
+func (l *eventLog) lastEventID() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastID
+}
+
+func (l *eventLog) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// since returns the events logged after the given id that are still in the log,
+// whether a run is still going on, and a channel closed when that changes.
+func (l *eventLog) since(id uint64) ([]loggedEvent, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The ids of the events in the log are contiguous.
+	skip := 0
+	if len(l.events) > 0 && id >= l.events[0].id {
+		skip = int(min(id-l.events[0].id+1, uint64(len(l.events))))
+	}
+	return slices.Clone(l.events[skip:]), l.running, l.changed
+}
+
+// lastRun returns the id of the event preceding the latest run.
+func (l *eventLog) lastRun() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.runStartID == 0 {
+		return 0
+	}
+	return l.runStartID - 1
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/runtime"
+	"github.com/docker/cagent/pkg/session"
+)
+
+func TestEventLog(t *testing.T) {
+	t.Parallel()
+
+	log := newEventLog(3, 1<<20)
+	events, running, _ := log.since(0)
+	assert.Empty(t, events)
+	assert.False(t, running)
+
+	assert.Equal(t, uint64(0), log.startRun())
+	for i := range 4 {
+		assert.Equal(t, uint64(i+1), log.add(runtime.StreamStarted("session", "root")))
+	}
+
+	// Only the latest events are kept.
+	events, running, changed := log.since(0)
+	assert.True(t, running)
+	assert.Equal(t, []uint64{2, 3, 4}, ids(events))
+	events, _, _ = log.since(3)
+	assert.Equal(t, []uint64{4}, ids(events))
+	events, _, _ = log.since(4)
+	assert.Empty(t, events)
+	events, _, _ = log.since(100)
+	assert.Empty(t, events)
+
+	log.stopRun()
+	select {
+	case <-changed:
+	default:
+		t.Fatal("subscribers should be notified when the run stops")
+	}
+
+	// Ids keep increasing across runs.
+	assert.Equal(t, uint64(4), log.startRun())
+	log.add(runtime.StreamStarted("session", "root"))
+	assert.Equal(t, uint64(4), log.lastRun())
+	events, _, _ = log.since(log.lastRun())
+	assert.Equal(t, []uint64{5}, ids(events))
+}
+
+func TestEventLog_BoundedInBytes(t *testing.T) {
+	t.Parallel()
+
+	event := runtime.AgentChoice("root", strings.Repeat("a", 100))
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	log := newEventLog(100, 2*len(data)+1)
+	log.startRun()
+	for range 5 {
+		log.add(event)
+	}
+	events, _, _ := log.since(0)
+	assert.Equal(t, []uint64{4, 5}, ids(events))
+	assert.Equal(t, data, events[1].data)
+
+	// The latest event is kept, even if it's larger than the log.
+	log.add(runtime.AgentChoice("root", strings.Repeat("a", 1000)))
+	events, _, _ = log.since(0)
+	assert.Equal(t, []uint64{6}, ids(events))
+}
+
+func TestEventLog_EvictedWhenIdle(t *testing.T) {
+	t.Parallel()
+
+	log := newEventLog(10, 1<<20)
+	log.retention = 10 * time.Millisecond
+
+	log.startRun()
+	log.add(runtime.StreamStarted("session", "root"))
+	log.stopRun()
+	require.Eventually(t, func() bool {
+		events, _, _ := log.since(0)
+		return len(events) == 0
+	}, time.Second, time.Millisecond)
+
+	// Ids keep increasing, and a new run cancels the eviction.
+	log.retention = time.Hour
+	assert.Equal(t, uint64(1), log.startRun())
+	assert.Equal(t, uint64(2), log.add(runtime.StreamStarted("session", "root")))
+	log.stopRun()
+	events, _, _ := log.since(0)
+	assert.Equal(t, []uint64{2}, ids(events))
+}
+
+func ids(events []loggedEvent) []uint64 {
+	var ids []uint64
+	for _, e := range events {
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+// startBlockingOpenAI starts a fake OpenAI API that streams a first word, then waits
+// for the returned function to be called before streaming the rest of the answer.
+func startBlockingOpenAI(t *testing.T) (string, func()) {
+	t.Helper()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Ahoy\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\", matey!\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	var once sync.Once
+	return server.URL, func() { once.Do(func() { close(release) }) }
+}
+
+type sseEvent struct {
+	id   uint64
+	Type string `json:"type"`
+	// Content of agent_choice events
+	Content string `json:"content"`
+}
+
+// openEvents sends a request answered with server-sent events. The events are sent on
+// the returned channel, which is closed once the stream ends.
+func openEvents(t *testing.T, ctx context.Context, method, socketPath, path, lastEventID string, payload any) <-chan sseEvent {
+	t.Helper()
+
+	body := io.Reader(http.NoBody)
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		require.NoError(t, err)
+		body = strings.NewReader(string(buf))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://_"+path, body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", strings.TrimPrefix(socketPath, "unix://"))
+			},
+		},
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	events := make(chan sseEvent, 1024)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		var id uint64
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if v, ok := strings.CutPrefix(line, "id: "); ok {
+				id, _ = strconv.ParseUint(v, 10, 64)
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				var e sseEvent
+				_ = json.Unmarshal([]byte(v), &e)
+				e.id = id
+				events <- e
+			}
+		}
+	}()
+	return events
+}
+
+func collect(events <-chan sseEvent) ([]sseEvent, string) {
+	var (
+		all     []sseEvent
+		content strings.Builder
+	)
+	for e := range events {
+		all = append(all, e)
+		if e.Type == "agent_choice" {
+			content.WriteString(e.Content)
+		}
+	}
+	return all, content.String()
+}
+
+func createSession(t *testing.T, ctx context.Context, socketPath string) string {
+	t.Helper()
+
+	var sess session.Session
+	unmarshal(t, httpDo(t, ctx, http.MethodPost, socketPath, "/api/sessions", map[string]any{}), &sess)
+	return sess.ID
+}
+
+func TestServer_ReattachToRunningSession(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+
+	baseURL, release := startBlockingOpenAI(t)
+	defer release()
+	ctx := t.Context()
+	store := session.NewInMemorySessionStore()
+	lnPath := startServerWithStore(t, ctx, filepath.Join(prepareOpenAIAgent(t, baseURL), "pirate.yaml"), store)
+
+	// Nothing to attach to before the session runs.
+	sessionID := createSession(t, ctx, lnPath)
+	status, _ := httpAuth(t, ctx, http.MethodGet, lnPath, "/api/sessions/"+sessionID+"/events", "", nil)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	// The client that starts the run disconnects after the first word.
+	runCtx, disconnect := context.WithCancel(ctx)
+	run := openEvents(t, runCtx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/agent/pirate.yaml", "", []api.Message{{Content: "Hi"}})
+	var seen []sseEvent
+	for e := range run {
+		seen = append(seen, e)
+		if e.Type == "agent_choice" {
+			break
+		}
+	}
+	disconnect()
+	require.NotEmpty(t, seen)
+	for i := 1; i < len(seen); i++ {
+		assert.Greater(t, seen[i].id, seen[i-1].id)
+	}
+
+	// Two clients attach to the run, one of them resuming after the last event it saw.
+	attached := openEvents(t, ctx, http.MethodGet, lnPath, "/api/sessions/"+sessionID+"/events", "", nil)
+	resumed := openEvents(t, ctx, http.MethodGet, lnPath, "/api/sessions/"+sessionID+"/events", strconv.FormatUint(seen[len(seen)-1].id, 10), nil)
+	release()
+
+	all, content := collect(attached)
+	assert.Equal(t, "Ahoy, matey!", content)
+	assert.Equal(t, seen, all[:len(seen)])
+	var types []string
+	for _, e := range all {
+		types = append(types, e.Type)
+	}
+	assert.Contains(t, types, "stream_stopped")
+
+	rest, content := collect(resumed)
+	assert.Equal(t, ", matey!", content)
+	assert.Equal(t, all[len(seen):], rest)
+
+	// The run completed despite the disconnection.
+	sess, err := store.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+	messages := sess.GetAllMessages()
+	require.NotEmpty(t, messages)
+	assert.Equal(t, "Ahoy, matey!", messages[len(messages)-1].Message.Content)
+
+	// Attaching once the run is over replays it.
+	replayed, _ := collect(openEvents(t, ctx, http.MethodGet, lnPath, "/api/sessions/"+sessionID+"/events", "", nil))
+	assert.Equal(t, all, replayed)
+}
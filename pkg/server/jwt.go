@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksMinRefresh rate limits the refreshes of the keys triggered by unknown key ids.
+	jwksMinRefresh = time.Minute
+	// jwksMaxAge is how long keys are cached before they are refreshed.
+	jwksMaxAge = time.Hour
+	// clockSkew is the leeway given when checking the validity period of tokens.
+	clockSkew = time.Minute
+)
+
+// jwtAlgorithms are the signature algorithms accepted for tokens. The
+// algorithm must match the type of the key, so that "none" or HMAC can't be forged.
+var jwtAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// JWTConfig configures the validation of JSON Web Tokens.
+type JWTConfig struct {
+	// JWKSURL is the URL of the JSON Web Key Set holding the keys that sign the tokens.
+	// It is discovered from the issuer's OpenID configuration if empty.
+	JWKSURL string
+	// Issuer is the expected "iss" claim of the tokens.
+	Issuer string
+	// Audience is the expected "aud" claim of the tokens. It's required, so that
+	// tokens issued by the same provider for other applications are rejected.
+	Audience string
+}
+
+// JWTAuthenticator authenticates JSON Web Tokens signed by an OpenID Connect
+// provider, or by any issuer publishing its keys as a JSON Web Key Set.
+type JWTAuthenticator struct {
+	config JWTConfig
+	client *http.Client
+
+	// refreshes makes concurrent requests with unknown keys share a single refresh.
+	refreshes singleflight.Group
+
+	mu        sync.Mutex
+	jwksURL   string
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+// NewJWTAuthenticator creates an authenticator for tokens signed by the keys of the JWKS,
+// or of the issuer, found in the config.
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	if config.JWKSURL == "" && config.Issuer == "" {
+		return nil, errors.New("either a JWKS URL or an issuer is required to validate JSON Web Tokens")
+	}
+	if config.Audience == "" {
+		return nil, errors.New("an audience is required to validate JSON Web Tokens")
+	}
+
+	return &JWTAuthenticator{
+		config:  config,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		jwksURL: config.JWKSURL,
+	}, nil
+}
+
+type jwtClaims struct {
+	jwt.Claims
+
+	// Agents lists the agents the caller can use. All the agents can be used if missing.
+	Agents []string `json:"cagent_agents"`
+	// Yolo allows the caller to approve all the tool calls of a session.
+	Yolo bool `json:"cagent_yolo"`
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	parsed, err := jwt.ParseSigned(token, jwtAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+
+	key, err := a.key(ctx, parsed.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := parsed.Claims(key, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	if err := a.validate(&claims, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+
+	return &Principal{
+		Subject: jwtSubject(claims.Issuer, claims.Subject),
+		Agents:  claims.Agents,
+		Yolo:    claims.Yolo,
+	}, nil
+}
+
+func (a *JWTAuthenticator) validate(claims *jwtClaims, now time.Time) error {
+	if claims.Subject == "" {
+		return errors.New("missing subject")
+	}
+	if claims.Expiry == nil {
+		return errors.New("missing expiration time")
+	}
+	return claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:      a.config.Issuer,
+		AnyAudience: jwt.Audience{a.config.Audience},
+		Time:        now,
+	}, clockSkew)
+}
+
+// jwtSubject namespaces the subject of a token by its issuer, so that the
+// subjects of different issuers, and of static tokens, never collide.
+func jwtSubject(issuer, subject string) string {
+	return issuer + "#" + subject
+}
+
+// key returns the key with the given id. The keys are fetched again if they are
+// stale or if the key is unknown, which happens when the issuer rotates its keys.
+// The keys are fetched without holding the lock, so that the requests with
+// known keys aren't blocked by a slow issuer.
+func (a *JWTAuthenticator) key(ctx context.Context, kid string) (any, error) {
+	a.mu.Lock()
+	key, found := a.lookup(kid)
+	stale := time.Since(a.fetchedAt) > jwksMaxAge
+	recent := time.Since(a.fetchedAt) < jwksMinRefresh
+	a.mu.Unlock()
+
+	if found && !stale {
+		return key, nil
+	}
+	if !stale && recent {
+		return nil, fmt.Errorf("%w: unknown key id %q", errInvalidToken, kid)
+	}
+
+	// The refresh is shared by the waiting requests: it isn't tied to the first one's cancellation.
+	_, err, _ := a.refreshes.Do("", func() (any, error) {
+		return nil, a.refresh(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		if found {
+			// Better use a stale key than fail because the issuer is unreachable
+			return key, nil
+		}
+		return nil, fmt.Errorf("fetching JSON Web Key Set: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, found := a.lookup(kid); found {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key id %q", errInvalidToken, kid)
+}
+
+// lookup finds a key by id. Tokens without a key id can only be checked
+// against a key set made of a single key. a.mu must be held.
+func (a *JWTAuthenticator) lookup(kid string) (any, bool) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, true
+		}
+	}
+	key, found := a.keys[kid]
+	return key, found
+}
+
+// refresh fetches the keys again, unless another request just did.
+func (a *JWTAuthenticator) refresh(ctx context.Context) error {
+	a.mu.Lock()
+	if time.Since(a.fetchedAt) < jwksMinRefresh {
+		a.mu.Unlock()
+		return nil
+	}
+	jwksURL := a.jwksURL
+	a.mu.Unlock()
+
+	keys, jwksURL, err := a.fetchKeys(ctx, jwksURL)
+
+	// Record failed attempts too, so that an unreachable issuer isn't hammered.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fetchedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	a.jwksURL = jwksURL
+	a.keys = keys
+	return nil
+}
+
+// fetchKeys fetches the key set, discovering its URL first if it isn't known.
+func (a *JWTAuthenticator) fetchKeys(ctx context.Context, jwksURL string) (map[string]any, string, error) {
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(ctx, strings.TrimSuffix(a.config.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, "", err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, "", errors.New("the OpenID configuration of the issuer has no jwks_uri")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	// The keys are decoded one by one, so that the keys of unsupported types are skipped.
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := a.getJSON(ctx, jwksURL, &jwks); err != nil {
+		return nil, "", err
+	}
+
+	keys := make(map[string]any, len(jwks.Keys))
+	for _, raw := range jwks.Keys {
+		var jwk jose.JSONWebKey
+		if err := json.Unmarshal(raw, &jwk); err != nil || !jwk.Valid() || !jwk.IsPublic() {
+			continue
+		}
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		keys[jwk.KeyID] = jwk.Key
+	}
+
+	return keys, jwksURL, nil
+}
+
+func (a *JWTAuthenticator) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
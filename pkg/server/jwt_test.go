@@ -0,0 +1,248 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/session"
+)
+
+func signJWT(t *testing.T, key crypto.Signer, kid string, claims map[string]any) string {
+	t.Helper()
+
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]any {
+	return map[string]any{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]any {
+	point, _ := key.PublicKey.Bytes()
+	return map[string]any{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(point[1:33]),
+		"y":   base64.RawURLEncoding.EncodeToString(point[33:]),
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var (
+		jwks     atomic.Value
+		fetches  atomic.Int32
+		provider *httptest.Server
+	)
+	jwks.Store([]map[string]any{rsaJWK("rsa-1", &rsaKey.PublicKey)})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": provider.URL, "jwks_uri": provider.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": jwks.Load()})
+	})
+	provider = httptest.NewServer(mux)
+	defer provider.Close()
+
+	authenticator, err := NewJWTAuthenticator(JWTConfig{Issuer: provider.URL, Audience: "cagent"})
+	require.NoError(t, err)
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":           "alice",
+			"iss":           provider.URL,
+			"aud":           []string{"other", "cagent"},
+			"exp":           time.Now().Add(time.Hour).Unix(),
+			"cagent_agents": []string{"pirate.yaml"},
+			"cagent_yolo":   true,
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	p, err := authenticator.Authenticate(t.Context(), signJWT(t, rsaKey, "rsa-1", claims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: provider.URL + "#alice", Agents: []string{"pirate.yaml"}, Yolo: true}, p)
+
+	invalid := map[string]string{
+		"expired":        signJWT(t, rsaKey, "rsa-1", claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"not yet valid":  signJWT(t, rsaKey, "rsa-1", claims(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})),
+		"wrong audience": signJWT(t, rsaKey, "rsa-1", claims(map[string]any{"aud": "other"})),
+		"no audience":    signJWT(t, rsaKey, "rsa-1", claims(map[string]any{"aud": nil})),
+		"wrong issuer":   signJWT(t, rsaKey, "rsa-1", claims(map[string]any{"iss": "https://evil.example.com"})),
+		"no subject":     signJWT(t, rsaKey, "rsa-1", claims(map[string]any{"sub": ""})),
+		"malformed":      "not.a.jwt",
+	}
+	for name, token := range invalid {
+		_, err := authenticator.Authenticate(t.Context(), token)
+		require.ErrorIs(t, err, errInvalidToken, name)
+	}
+
+	// Tampered signatures are rejected.
+	token := signJWT(t, rsaKey, "rsa-1", claims(nil))
+	tampered := token[:len(token)-4] + "AAAA"
+	_, err = authenticator.Authenticate(t.Context(), tampered)
+	require.ErrorIs(t, err, errInvalidToken)
+
+	// Tokens signed with "none" are rejected.
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa-1"}`))
+	payload, _ := json.Marshal(claims(nil))
+	_, err = authenticator.Authenticate(t.Context(), header+"."+base64.RawURLEncoding.EncodeToString(payload)+".")
+	require.ErrorIs(t, err, errInvalidToken)
+
+	// The keys are fetched once, until an unknown key shows up, which is rate limited.
+	assert.Equal(t, int32(1), fetches.Load())
+	jwks.Store([]map[string]any{rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", ecKey)})
+	_, err = authenticator.Authenticate(t.Context(), signJWT(t, ecKey, "ec-1", claims(nil)))
+	require.ErrorIs(t, err, errInvalidToken)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	authenticator.mu.Lock()
+	authenticator.fetchedAt = time.Now().Add(-jwksMinRefresh)
+	authenticator.mu.Unlock()
+	p, err = authenticator.Authenticate(t.Context(), signJWT(t, ecKey, "ec-1", claims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, provider.URL+"#alice", p.Subject)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestNewJWTAuthenticator_RequiresAudience(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewJWTAuthenticator(JWTConfig{Issuer: "https://accounts.example.com"})
+	require.ErrorContains(t, err, "audience")
+	_, err = NewJWTAuthenticator(JWTConfig{JWKSURL: "https://accounts.example.com/jwks"})
+	require.ErrorContains(t, err, "audience")
+}
+
+func TestServer_JWTAuthentication(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{rsaJWK("key", &key.PublicKey)}})
+	}))
+	defer provider.Close()
+
+	authenticator, err := NewJWTAuthenticator(JWTConfig{JWKSURL: provider.URL, Audience: "cagent"})
+	require.NoError(t, err)
+
+	ctx := t.Context()
+	lnPath := startServerWithStore(t, ctx, prepareAgentsDir(t, "pirate.yaml"), session.NewInMemorySessionStore(), WithAuthenticators(authenticator))
+
+	token := signJWT(t, key, "key", map[string]any{"sub": "alice", "aud": "cagent", "exp": time.Now().Add(time.Hour).Unix()})
+	status, _ := httpAuth(t, ctx, http.MethodGet, lnPath, "/api/agents", token, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = httpAuth(t, ctx, http.MethodGet, lnPath, "/api/agents", token+"x", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestJWTAuthenticator_RefreshDoesntBlock(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var (
+		fetches atomic.Int32
+		entered = make(chan struct{}, 1)
+		release = make(chan struct{})
+	)
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		keys := []map[string]any{rsaJWK("rsa-1", &rsaKey.PublicKey)}
+		if fetches.Add(1) > 1 {
+			entered <- struct{}{}
+			<-release
+			keys = append(keys, ecJWK("ec-1", ecKey))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer provider.Close()
+
+	authenticator, err := NewJWTAuthenticator(JWTConfig{JWKSURL: provider.URL, Audience: "cagent"})
+	require.NoError(t, err)
+	claims := map[string]any{"sub": "alice", "aud": "cagent", "exp": time.Now().Add(time.Hour).Unix()}
+
+	_, err = authenticator.Authenticate(t.Context(), signJWT(t, rsaKey, "rsa-1", claims))
+	require.NoError(t, err)
+	authenticator.mu.Lock()
+	authenticator.fetchedAt = time.Now().Add(-jwksMinRefresh)
+	authenticator.mu.Unlock()
+
+	// Two requests with an unknown key share one refresh...
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := authenticator.Authenticate(t.Context(), signJWT(t, ecKey, "ec-1", claims))
+			errs <- err
+		}()
+	}
+	<-entered
+
+	// ...which doesn't block the requests with a known key.
+	_, err = authenticator.Authenticate(t.Context(), signJWT(t, rsaKey, "rsa-1", claims))
+	require.NoError(t, err)
+
+	close(release)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+	assert.Equal(t, int32(2), fetches.Load())
+}
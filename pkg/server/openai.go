@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/runtime"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+// listModels lists the agents as OpenAI models.
+func (s *Server) listModels(c echo.Context) error {
+	models := []api.Model{}
+	p := principal(c)
+	for name := range s.sm.sources {
+		if !p.CanUseAgent(name) {
+			continue
+		}
+		models = append(models, api.Model{
+			ID:      name,
+			Object:  "model",
+			OwnedBy: "cagent",
+		})
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].ID < models[j].ID
+	})
+
+	return c.JSON(http.StatusOK, api.ModelsResponse{Object: "list", Data: models})
+}
+
+// chatCompletions runs an agent, selected by the model of the request, on the messages
+// of the request. Tools are executed server-side: tool calls needing a confirmation are
+// rejected, unless the server approves them all.
+func (s *Server) chatCompletions(c echo.Context) error {
+	var req api.ChatCompletionRequest
+	if err := c.Bind(&req); err != nil {
+		return openAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+	}
+	if len(req.Messages) == 0 {
+		return openAIError(c, http.StatusBadRequest, "invalid_request_error", "messages must not be empty")
+	}
+	p := principal(c)
+	agentFilename, found := s.agentForModel(req.Model, p)
+	if !found {
+		return c.JSON(http.StatusNotFound, api.OpenAIErrorResponse{Error: api.OpenAIError{
+			Message: fmt.Sprintf("model not found: %s", req.Model),
+			Type:    "invalid_request_error",
+			Code:    "model_not_found",
+		}})
+	}
+
+	ctx := c.Request().Context()
+	rt, sess, events, err := s.sm.RunCompletion(ctx, agentFilename, req.Messages, s.sm.completionToolsApproved && p.CanYolo())
+	if err != nil {
+		if errors.Is(err, errUnsupportedRole) {
+			return openAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		}
+		return openAIError(c, http.StatusInternalServerError, "server_error", fmt.Sprintf("failed to run agent: %v", err))
+	}
+
+	id := "chatcmpl-" + uuid.New().String()
+	created := time.Now().Unix()
+	newChunk := func(choices ...api.ChatCompletionChunkChoice) api.ChatCompletionChunk {
+		return api.ChatCompletionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   req.Model,
+			Choices: choices,
+		}
+	}
+
+	if req.Stream {
+		c.Response().Header().Set("Content-Type", "text/event-stream")
+		c.Response().Header().Set("Cache-Control", "no-cache")
+		c.Response().Header().Set("Connection", "keep-alive")
+		c.Response().WriteHeader(http.StatusOK)
+		writeEvent(c, newChunk(api.ChatCompletionChunkChoice{Delta: api.ChatCompletionDelta{Role: "assistant"}}))
+	}
+
+	var (
+		content      strings.Builder
+		finishReason = "stop"
+		errMsg       string
+		// Depth of the sub-agents the task is transferred to. Only the answer of the
+		// requested agent is returned, not the ones of its sub-agents.
+		depth int
+	)
+	for event := range events {
+		switch e := event.(type) {
+		case *runtime.AgentSwitchingEvent:
+			if e.Switching {
+				depth++
+			} else {
+				depth--
+			}
+		case *runtime.AgentChoiceEvent:
+			if depth > 0 {
+				continue
+			}
+			content.WriteString(e.Content)
+			if req.Stream {
+				writeEvent(c, newChunk(api.ChatCompletionChunkChoice{Delta: api.ChatCompletionDelta{Content: e.Content}}))
+			}
+		case *runtime.ToolCallConfirmationEvent:
+			rt.Resume(ctx, runtime.ResumeTypeReject)
+		case *runtime.ElicitationRequestEvent:
+			_ = rt.ResumeElicitation(ctx, tools.ElicitationActionDecline, nil)
+		case *runtime.MaxIterationsReachedEvent:
+			finishReason = "length"
+			rt.Resume(ctx, runtime.ResumeTypeReject)
+		case *runtime.ErrorEvent:
+			errMsg = e.Error
+		}
+	}
+
+	usage := &api.ChatCompletionUsage{
+		PromptTokens:     sess.InputTokens,
+		CompletionTokens: sess.OutputTokens,
+		TotalTokens:      sess.InputTokens + sess.OutputTokens,
+	}
+
+	if !req.Stream {
+		if errMsg != "" {
+			return openAIError(c, http.StatusInternalServerError, "server_error", errMsg)
+		}
+		return c.JSON(http.StatusOK, api.ChatCompletionResponse{
+			ID:      id,
+			Object:  "chat.completion",
+			Created: created,
+			Model:   req.Model,
+			Choices: []api.ChatCompletionChoice{{
+				Message:      api.ChatCompletionMessage{Role: "assistant", Content: content.String()},
+				FinishReason: finishReason,
+			}},
+			Usage: usage,
+		})
+	}
+
+	if errMsg != "" {
+		writeEvent(c, api.OpenAIErrorResponse{Error: api.OpenAIError{Message: errMsg, Type: "server_error"}})
+		return nil
+	}
+	writeEvent(c, newChunk(api.ChatCompletionChunkChoice{FinishReason: &finishReason}))
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		chunk := newChunk()
+		chunk.Choices = []api.ChatCompletionChunkChoice{}
+		chunk.Usage = usage
+		writeEvent(c, chunk)
+	}
+	fmt.Fprint(c.Response(), "data: [DONE]\n\n")
+	c.Response().Flush()
+
+	return nil
+}
+
+// agentForModel finds the agent a model stands for, among the ones the caller can use.
+// Models are identified by the names of the agents listed by /v1/models, or by the base
+// names of their files.
+func (s *Server) agentForModel(model string, p *Principal) (string, bool) {
+	if _, found := s.sm.sources[model]; found {
+		return model, p.CanUseAgent(model)
+	}
+
+	var match string
+	for name := range s.sm.sources {
+		if filepath.Base(name) == model && p.CanUseAgent(name) {
+			if match != "" {
+				// Ambiguous
+				return "", false
+			}
+			match = name
+		}
+	}
+	return match, match != ""
+}
+
+// writeEvent writes a server-sent event holding the JSON encoding of v.
+func writeEvent(c echo.Context, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Response(), "data: %s\n\n", data)
+	c.Response().Flush()
+}
+
+// openAIError returns an error in the format of the OpenAI API.
+func openAIError(c echo.Context, status int, errType, msg string) error {
+	return c.JSON(status, api.OpenAIErrorResponse{Error: api.OpenAIError{Message: msg, Type: errType}})
+}
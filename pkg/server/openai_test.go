@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/session"
+)
+
+// startFakeOpenAI starts a fake OpenAI API streaming the given words as the answer to any chat completion.
+// It records the last messages it received.
+func startFakeOpenAI(t *testing.T, words ...string) (string, func() []map[string]any) {
+	t.Helper()
+
+	var (
+		mu           sync.Mutex
+		lastMessages []map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		lastMessages = req.Messages
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range words {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", word)
+		}
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL, func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastMessages
+	}
+}
+
+func prepareOpenAIAgent(t *testing.T, baseURL string) string {
+	t.Helper()
+
+	agentsDir := filepath.Join(t.TempDir(), "agents")
+	require.NoError(t, os.MkdirAll(agentsDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(agentsDir, "pirate.yaml"), []byte(`version: "2"
+agents:
+  root:
+    instruction: Always answer by talking like a pirate.
+    description: Talk like a pirate
+    model: fake
+models:
+  fake:
+    provider: openai
+    model: gpt-4o
+    base_url: `+baseURL+`
+`), 0o600))
+	return agentsDir
+}
+
+func TestServer_ListModels(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	lnPath := startServer(t, ctx, prepareAgentsDir(t, "pirate.yaml", "contradict.yaml"))
+
+	var models api.ModelsResponse
+	unmarshal(t, httpGET(t, ctx, lnPath, "/v1/models"), &models)
+
+	assert.Equal(t, "list", models.Object)
+	require.Len(t, models.Data, 2)
+	assert.Equal(t, "contradict.yaml", filepath.Base(models.Data[0].ID))
+	assert.Equal(t, "pirate.yaml", filepath.Base(models.Data[1].ID))
+	assert.Equal(t, "model", models.Data[1].Object)
+}
+
+func TestServer_ChatCompletions(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+
+	baseURL, received := startFakeOpenAI(t, "Ahoy", ", matey!")
+	ctx := t.Context()
+	lnPath := startServerWithStore(t, ctx, prepareOpenAIAgent(t, baseURL), session.NewInMemorySessionStore())
+
+	var resp api.ChatCompletionResponse
+	unmarshal(t, httpDo(t, ctx, http.MethodPost, lnPath, "/v1/chat/completions", map[string]any{
+		"model": "pirate.yaml",
+		"messages": []map[string]any{
+			{"role": "user", "content": "Hi"},
+			{"role": "assistant", "content": "Arr"},
+			{"role": "user", "content": []map[string]any{{"type": "text", "text": "Who are you?"}}},
+		},
+	}), &resp)
+
+	assert.Equal(t, "chat.completion", resp.Object)
+	assert.Equal(t, "pirate.yaml", resp.Model)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "assistant", resp.Choices[0].Message.Role)
+	assert.Equal(t, "Ahoy, matey!", resp.Choices[0].Message.Content)
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, int64(3), resp.Usage.CompletionTokens)
+
+	// The history of the conversation is sent to the model, after the agent's instructions.
+	var contents []any
+	for _, msg := range received() {
+		contents = append(contents, msg["content"])
+	}
+	assert.Contains(t, contents, "Hi")
+	assert.Contains(t, contents, "Arr")
+	assert.Contains(t, fmt.Sprint(contents...), "Who are you?")
+	assert.Contains(t, fmt.Sprint(contents[0]), "talking like a pirate")
+
+	// Chat completions are not persisted as sessions.
+	var sessions []api.SessionsResponse
+	unmarshal(t, httpGET(t, ctx, lnPath, "/api/sessions"), &sessions)
+	assert.Empty(t, sessions)
+}
+
+func TestServer_ChatCompletionsStream(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+
+	baseURL, _ := startFakeOpenAI(t, "Ahoy", ", matey!")
+	ctx := t.Context()
+	lnPath := startServer(t, ctx, prepareOpenAIAgent(t, baseURL))
+
+	buf := httpDo(t, ctx, http.MethodPost, lnPath, "/v1/chat/completions", map[string]any{
+		"model":          "pirate.yaml",
+		"stream":         true,
+		"stream_options": map[string]any{"include_usage": true},
+		"messages":       []map[string]any{{"role": "user", "content": "Hi"}},
+	})
+
+	var (
+		chunks []api.ChatCompletionChunk
+		done   bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk api.ChatCompletionChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		chunks = append(chunks, chunk)
+	}
+	require.True(t, done)
+
+	var content strings.Builder
+	for _, chunk := range chunks {
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		assert.Equal(t, chunks[0].ID, chunk.ID)
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+	}
+	assert.Equal(t, "Ahoy, matey!", content.String())
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+
+	finish := chunks[len(chunks)-2]
+	require.Len(t, finish.Choices, 1)
+	require.NotNil(t, finish.Choices[0].FinishReason)
+	assert.Equal(t, "stop", *finish.Choices[0].FinishReason)
+
+	usage := chunks[len(chunks)-1]
+	assert.Empty(t, usage.Choices)
+	require.NotNil(t, usage.Usage)
+	assert.Equal(t, int64(3), usage.Usage.CompletionTokens)
+}
+
+func TestServer_ChatCompletionsErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	lnPath := startServer(t, ctx, prepareAgentsDir(t, "pirate.yaml"))
+
+	status, buf := httpDoStatus(t, ctx, lnPath, "/v1/chat/completions", `{"model":"unknown.yaml","messages":[{"role":"user","content":"Hi"}]}`)
+	assert.Equal(t, http.StatusNotFound, status)
+	var notFound api.OpenAIErrorResponse
+	unmarshal(t, buf, &notFound)
+	assert.Equal(t, "model_not_found", notFound.Error.Code)
+
+	status, buf = httpDoStatus(t, ctx, lnPath, "/v1/chat/completions", `{"model":"pirate.yaml","messages":[]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	var invalid api.OpenAIErrorResponse
+	unmarshal(t, buf, &invalid)
+	assert.Equal(t, "invalid_request_error", invalid.Error.Type)
+}
+
+func httpDoStatus(t *testing.T, ctx context.Context, socketPath, path, body string) (int, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://_"+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", strings.TrimPrefix(socketPath, "unix://"))
+			},
+		},
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, buf
+}
@@ -4,27 +4,66 @@ import (
 	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/config"
+	"github.com/docker/cagent/pkg/runtime"
 	"github.com/docker/cagent/pkg/session"
+	"github.com/docker/cagent/pkg/webhook"
 )
 
 type Server struct {
-	e  *echo.Echo
-	sm *sessionManager
+	e              *echo.Echo
+	sm             *sessionManager
+	authenticators []Authenticator
 }
 
-func New(ctx context.Context, sessionStore session.Store, runConfig *config.RuntimeConfig, refreshInterval time.Duration, agentSources config.Sources) (*Server, error) {
+type Opt func(*Server)
+
+// WithCheckpoints snapshots files before agents modify them so that the changes can be undone
+func WithCheckpoints(store *checkpoint.Store) Opt {
+	return func(s *Server) {
+		s.sm.checkpoints = store
+	}
+}
+
+// WithCompletionToolsApproved approves all the tool calls made while serving chat completions.
+// Chat completions can't ask for confirmations, so the tool calls that need one are rejected otherwise.
+func WithCompletionToolsApproved(approved bool) Opt {
+	return func(s *Server) {
+		s.sm.completionToolsApproved = approved
+	}
+}
+
+// WithWebhooks delivers the runtime events of all the sessions to the dispatcher's subscriptions.
+// The dispatcher also delivers the events to the webhooks given when creating sessions.
+func WithWebhooks(dispatcher *webhook.Dispatcher) Opt {
+	return func(s *Server) {
+		s.sm.webhooks = dispatcher
+	}
+}
+
+// WithSessionWebhookHosts lets the webhooks given when creating sessions target these hosts.
+// Sessions can't have their own webhooks otherwise.
+func WithSessionWebhookHosts(hosts ...string) Opt {
+	return func(s *Server) {
+		s.sm.webhookHosts = append(s.sm.webhookHosts, hosts...)
+	}
+}
+
+func New(ctx context.Context, sessionStore session.Store, runConfig *config.RuntimeConfig, refreshInterval time.Duration, agentSources config.Sources, opts ...Opt) (*Server, error) {
 	e := echo.New()
 	e.Use(middleware.CORS())
 	e.Use(middleware.Logger())
@@ -33,6 +72,17 @@ func New(ctx context.Context, sessionStore session.Store, runConfig *config.Runt
 		e:  e,
 		sm: newSessionManager(ctx, agentSources, sessionStore, refreshInterval, runConfig),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.sm.webhooks == nil {
+		webhooks, err := webhook.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		s.sm.webhooks = webhooks
+	}
+	e.Use(s.authenticate)
 
 	group := e.Group("/api")
 
@@ -44,19 +94,40 @@ func New(ctx context.Context, sessionStore session.Store, runConfig *config.Runt
 	// List all sessions
 	group.GET("/sessions", s.getSessions)
 	// Get a session by id
-	group.GET("/sessions/:id", s.getSession)
+	group.GET("/sessions/:id", s.getSession, s.sessionOwner)
 	// Resume a session by id
-	group.POST("/sessions/:id/resume", s.resumeSession)
+	group.POST("/sessions/:id/resume", s.resumeSession, s.sessionOwner)
+	// Fork a session into a new session
+	group.POST("/sessions/:id/fork", s.forkSession, s.sessionOwner)
+	// List the sessions forked from a session
+	group.GET("/sessions/:id/forks", s.getSessionForks, s.sessionOwner)
+	// Rewind a session to one of its user messages
+	group.POST("/sessions/:id/rewind", s.rewindSession, s.sessionOwner)
+	// List the file checkpoints of a session
+	group.GET("/sessions/:id/checkpoints", s.getCheckpoints, s.sessionOwner)
+	// Restore the files modified by a session from a given message onwards
+	group.POST("/sessions/:id/checkpoints/restore", s.restoreCheckpoint, s.sessionOwner)
+	// Undo the file changes of the last turn of a session
+	group.POST("/sessions/:id/undo", s.undoFileChanges, s.sessionOwner)
 	// Toggle YOLO mode for a session
-	group.POST("/sessions/:id/tools/toggle", s.toggleSessionYolo)
+	group.POST("/sessions/:id/tools/toggle", s.toggleSessionYolo, s.sessionOwner)
 	// Create a new session
 	group.POST("/sessions", s.createSession)
 	// Delete a session
-	group.DELETE("/sessions/:id", s.deleteSession)
+	group.DELETE("/sessions/:id", s.deleteSession, s.sessionOwner)
 	// Run an agent loop
-	group.POST("/sessions/:id/agent/:agent", s.runAgent)
-	group.POST("/sessions/:id/agent/:agent/:agent_name", s.runAgent)
-	group.POST("/sessions/:id/elicitation", s.elicitation)
+	group.POST("/sessions/:id/agent/:agent", s.runAgent, s.sessionOwner)
+	group.POST("/sessions/:id/agent/:agent/:agent_name", s.runAgent, s.sessionOwner)
+	group.POST("/sessions/:id/elicitation", s.elicitation, s.sessionOwner)
+	// Stop the agent loop of a session
+	group.POST("/sessions/:id/cancel", s.cancelSession, s.sessionOwner)
+	// Attach to the latest run of a session
+	group.GET("/sessions/:id/events", s.getSessionEvents, s.sessionOwner)
+
+	// OpenAI-compatible API, where each agent is a model
+	v1 := e.Group("/v1")
+	v1.GET("/models", s.listModels)
+	v1.POST("/chat/completions", s.chatCompletions)
 
 	// Health check endpoint
 	group.GET("/ping", func(c echo.Context) error {
@@ -81,7 +152,11 @@ func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
 
 func (s *Server) getAgents(c echo.Context) error {
 	agents := []api.Agent{}
+	p := principal(c)
 	for k, agentSource := range s.sm.sources {
+		if !p.CanUseAgent(k) {
+			continue
+		}
 		slog.Debug("API source", "source", agentSource.Name())
 
 		c, err := config.Load(c.Request().Context(), agentSource)
@@ -129,7 +204,7 @@ func (s *Server) getAgentConfig(c echo.Context) error {
 	agentID := c.Param("id")
 
 	for k, agentSource := range s.sm.sources {
-		if k != agentID {
+		if k != agentID || !principal(c).CanUseAgent(k) {
 			continue
 		}
 
@@ -152,30 +227,56 @@ func (s *Server) getSessions(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to get sessions: %v", err))
 	}
 
-	responses := make([]api.SessionsResponse, len(sessions))
-	for i, sess := range sessions {
-		responses[i] = api.SessionsResponse{
-			ID:           sess.ID,
-			Title:        sess.Title,
-			CreatedAt:    sess.CreatedAt.Format(time.RFC3339),
-			NumMessages:  len(sess.GetAllMessages()),
-			InputTokens:  sess.InputTokens,
-			OutputTokens: sess.OutputTokens,
-			WorkingDir:   sess.WorkingDir,
+	p := principal(c)
+	responses := []api.SessionsResponse{}
+	for _, sess := range sessions {
+		if p.Owns(sess) {
+			responses = append(responses, sessionsResponse(sess, s.sm.SessionStatus(sess.ID)))
 		}
 	}
 	return c.JSON(http.StatusOK, responses)
 }
 
+func sessionsResponse(sess *session.Session, status api.SessionStatus) api.SessionsResponse {
+	return api.SessionsResponse{
+		ID:           sess.ID,
+		Title:        sess.Title,
+		CreatedAt:    sess.CreatedAt.Format(time.RFC3339),
+		NumMessages:  len(sess.GetAllMessages()),
+		InputTokens:  sess.InputTokens,
+		OutputTokens: sess.OutputTokens,
+		WorkingDir:   sess.WorkingDir,
+		ParentID:     sess.ParentID,
+		ForkPoint:    sess.ForkPoint,
+		Status:       status,
+	}
+}
+
+// createSessionRequest is a session template, with the webhooks notified of the session's events.
+type createSessionRequest struct {
+	session.Session
+	Webhooks []webhook.Subscription `json:"webhooks,omitempty"`
+}
+
 func (s *Server) createSession(c echo.Context) error {
-	var sessionTemplate session.Session
-	if err := c.Bind(&sessionTemplate); err != nil {
+	var req createSessionRequest
+	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
 	}
+	sessionTemplate := req.Session
 
-	sess, err := s.sm.CreateSession(c.Request().Context(), &sessionTemplate)
+	p := principal(c)
+	if sessionTemplate.ToolsApproved && !p.CanYolo() {
+		return echo.NewHTTPError(http.StatusForbidden, "not allowed to approve all the tool calls")
+	}
+	sessionTemplate.Owner = ""
+	if p != nil {
+		sessionTemplate.Owner = p.Subject
+	}
+
+	sess, err := s.sm.CreateSession(c.Request().Context(), &sessionTemplate, req.Webhooks)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to create session: %v", err))
+		return sessionError(err, "failed to create session")
 	}
 
 	return c.JSON(http.StatusOK, sess)
@@ -187,7 +288,11 @@ func (s *Server) getSession(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("session not found: %v", err))
 	}
 
-	sr := api.SessionResponse{
+	return c.JSON(http.StatusOK, sessionResponse(sess, s.sm.SessionStatus(sess.ID)))
+}
+
+func sessionResponse(sess *session.Session, status api.SessionStatus) api.SessionResponse {
+	return api.SessionResponse{
 		ID:            sess.ID,
 		Title:         sess.Title,
 		CreatedAt:     sess.CreatedAt,
@@ -196,9 +301,101 @@ func (s *Server) getSession(c echo.Context) error {
 		InputTokens:   sess.InputTokens,
 		OutputTokens:  sess.OutputTokens,
 		WorkingDir:    sess.WorkingDir,
+		ParentID:      sess.ParentID,
+		ForkPoint:     sess.ForkPoint,
+		Status:        status,
+	}
+}
+
+func (s *Server) forkSession(c echo.Context) error {
+	var req api.ForkSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+	}
+
+	sess, err := s.sm.ForkSession(c.Request().Context(), c.Param("id"), req.MessageIndex)
+	if err != nil {
+		return sessionError(err, "failed to fork session")
 	}
 
-	return c.JSON(http.StatusOK, sr)
+	return c.JSON(http.StatusOK, sessionResponse(sess, api.SessionStatusIdle))
+}
+
+func (s *Server) getSessionForks(c echo.Context) error {
+	forks, err := s.sm.GetSessionForks(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return sessionError(err, "failed to get session forks")
+	}
+
+	responses := make([]api.SessionsResponse, len(forks))
+	for i, sess := range forks {
+		responses[i] = sessionsResponse(sess, s.sm.SessionStatus(sess.ID))
+	}
+	return c.JSON(http.StatusOK, responses)
+}
+
+func (s *Server) rewindSession(c echo.Context) error {
+	var req api.RewindSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+	}
+
+	content, err := s.sm.RewindSession(c.Request().Context(), c.Param("id"), req.MessageIndex)
+	if err != nil {
+		return sessionError(err, "failed to rewind session")
+	}
+
+	return c.JSON(http.StatusOK, api.RewindSessionResponse{Content: content})
+}
+
+func (s *Server) getCheckpoints(c echo.Context) error {
+	checkpoints, err := s.sm.GetCheckpoints(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return sessionError(err, "failed to get checkpoints")
+	}
+	if checkpoints == nil {
+		checkpoints = []checkpoint.Checkpoint{}
+	}
+	return c.JSON(http.StatusOK, checkpoints)
+}
+
+func (s *Server) restoreCheckpoint(c echo.Context) error {
+	var req api.RestoreFilesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+	}
+
+	files, err := s.sm.RestoreFiles(c.Request().Context(), c.Param("id"), req.MessageIndex)
+	if err != nil {
+		return sessionError(err, "failed to restore files")
+	}
+	return c.JSON(http.StatusOK, api.RestoreFilesResponse{MessageIndex: req.MessageIndex, Files: files})
+}
+
+func (s *Server) undoFileChanges(c echo.Context) error {
+	messageIndex, files, err := s.sm.UndoFileChanges(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return sessionError(err, "failed to undo file changes")
+	}
+	return c.JSON(http.StatusOK, api.RestoreFilesResponse{MessageIndex: messageIndex, Files: files})
+}
+
+// sessionError maps session errors to the matching HTTP status.
+func sessionError(err error, msg string) error {
+	switch {
+	case errors.Is(err, session.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("%s: %v", msg, err))
+	case errors.Is(err, session.ErrInvalidMessageIndex), errors.Is(err, session.ErrNotUserMessage),
+		errors.Is(err, errInvalidWebhook):
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s: %v", msg, err))
+	case errors.Is(err, errWebhookNotAllowed):
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("%s: %v", msg, err))
+	case errors.Is(err, checkpoint.ErrNoCheckpoint), errors.Is(err, errCheckpointsDisabled),
+		errors.Is(err, errSessionBusy), errors.Is(err, errSessionNotRunning):
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("%s: %v", msg, err))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", msg, err))
+	}
 }
 
 func (s *Server) resumeSession(c echo.Context) error {
@@ -206,6 +403,9 @@ func (s *Server) resumeSession(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
 	}
+	if runtime.ResumeType(req.Confirmation) == runtime.ResumeTypeApproveSession && !principal(c).CanYolo() {
+		return echo.NewHTTPError(http.StatusForbidden, "not allowed to approve all the tool calls")
+	}
 
 	if err := s.sm.ResumeSession(c.Request().Context(), c.Param("id"), req.Confirmation); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to resume session: %v", err))
@@ -215,12 +415,23 @@ func (s *Server) resumeSession(c echo.Context) error {
 }
 
 func (s *Server) toggleSessionYolo(c echo.Context) error {
+	if !principal(c).CanYolo() {
+		return echo.NewHTTPError(http.StatusForbidden, "not allowed to approve all the tool calls")
+	}
 	if err := s.sm.ToggleToolApproval(c.Request().Context(), c.Param("id")); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to toggle session tool approval mode: %v", err))
 	}
 	return c.JSON(http.StatusOK, nil)
 }
 
+func (s *Server) cancelSession(c echo.Context) error {
+	if err := s.sm.CancelSession(c.Request().Context(), c.Param("id")); err != nil {
+		return sessionError(err, "failed to cancel session")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "session canceled"})
+}
+
 func (s *Server) deleteSession(c echo.Context) error {
 	sessionID := c.Param("id")
 
@@ -238,30 +449,70 @@ func (s *Server) runAgent(c echo.Context) error {
 
 	slog.Debug("Running agent", "agent_filename", agentFilename, "session_id", sessionID, "current_agent", currentAgent)
 
+	if !principal(c).CanUseAgent(agentFilename) {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("not allowed to use agent %s", agentFilename))
+	}
+
 	var messages []api.Message
 	if err := json.NewDecoder(c.Request().Body).Decode(&messages); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
 	}
 
-	streamChan, err := s.sm.RunSession(c.Request().Context(), sessionID, agentFilename, currentAgent, messages)
+	events, after, err := s.sm.RunSession(c.Request().Context(), sessionID, agentFilename, currentAgent, messages)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to run session: %v", err))
+		return sessionError(err, "failed to run session")
+	}
+
+	return streamEvents(c, events, after)
+}
+
+// getSessionEvents attaches to the latest run of a session: it streams the events of
+// the run and follows it until it stops. Clients that reconnect only get the events
+// following the one of their Last-Event-ID header.
+func (s *Server) getSessionEvents(c echo.Context) error {
+	events, found := s.sm.SessionEvents(c.Param("id"))
+	if !found {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	after := events.lastRun()
+	if lastEventID := c.Request().Header.Get("Last-Event-ID"); lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid Last-Event-ID: %v", err))
+		}
+		after = id
 	}
 
+	return streamEvents(c, events, after)
+}
+
+// streamEvents streams, as server-sent events, the events logged after the given id,
+// until the run stops or the client disconnects.
+func streamEvents(c echo.Context, events *eventLog, after uint64) error {
 	c.Response().Header().Set("Content-Type", "text/event-stream")
 	c.Response().Header().Set("Cache-Control", "no-cache")
 	c.Response().Header().Set("Connection", "keep-alive")
 	c.Response().WriteHeader(http.StatusOK)
-	for event := range streamChan {
-		data, err := json.Marshal(event)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to marshal event: %v", err))
+
+	ctx := c.Request().Context()
+	for {
+		logged, running, changed := events.since(after)
+		for _, e := range logged {
+			fmt.Fprintf(c.Response(), "id: %d\ndata: %s\n\n", e.id, e.data)
+			after = e.id
 		}
-		fmt.Fprintf(c.Response(), "data: %s\n\n", string(data))
 		c.Response().Flush()
-	}
 
-	return nil
+		if !running {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
 func (s *Server) elicitation(c echo.Context) error {
@@ -7,17 +7,22 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/config"
+	"github.com/docker/cagent/pkg/runtime"
 	"github.com/docker/cagent/pkg/session"
+	"github.com/docker/cagent/pkg/webhook"
 )
 
 func TestServer_ListAgents(t *testing.T) {
@@ -69,6 +74,247 @@ func TestServer_ListSessions(t *testing.T) {
 	assert.Empty(t, sessions)
 }
 
+func TestServer_ForkAndRewindSession(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	store := session.NewInMemorySessionStore()
+	sess := session.New(session.WithUserMessage("first"), session.WithUserMessage("second"))
+	require.NoError(t, store.AddSession(ctx, sess))
+	lnPath := startServerWithStore(t, ctx, prepareAgentsDir(t, "pirate.yaml"), store)
+
+	messageIndex := 1
+	var fork api.SessionResponse
+	unmarshal(t, httpDo(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/fork", api.ForkSessionRequest{MessageIndex: &messageIndex}), &fork)
+	assert.Equal(t, sess.ID, fork.ParentID)
+	assert.Equal(t, 1, fork.ForkPoint)
+	assert.Len(t, fork.Messages, 1)
+
+	var forks []api.SessionsResponse
+	unmarshal(t, httpGET(t, ctx, lnPath, "/api/sessions/"+sess.ID+"/forks"), &forks)
+	require.Len(t, forks, 1)
+	assert.Equal(t, fork.ID, forks[0].ID)
+
+	var rewind api.RewindSessionResponse
+	unmarshal(t, httpDo(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/rewind", api.RewindSessionRequest{MessageIndex: 1}), &rewind)
+	assert.Equal(t, "second", rewind.Content)
+
+	rewound, err := store.GetSession(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Len(t, rewound.GetAllMessages(), 1)
+}
+
+func TestServer_UndoFileChanges(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	store := session.NewInMemorySessionStore()
+	sess := session.New(session.WithUserMessage("write it"))
+	require.NoError(t, store.AddSession(ctx, sess))
+
+	checkpoints := checkpoint.NewStore(t.TempDir())
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("before"), 0o600))
+	require.NoError(t, checkpoints.Snapshot(sess.ID, 0, file))
+	require.NoError(t, os.WriteFile(file, []byte("after"), 0o600))
+
+	lnPath := startServerWithStore(t, ctx, prepareAgentsDir(t, "pirate.yaml"), store, WithCheckpoints(checkpoints))
+
+	var list []checkpoint.Checkpoint
+	unmarshal(t, httpGET(t, ctx, lnPath, "/api/sessions/"+sess.ID+"/checkpoints"), &list)
+	require.Len(t, list, 1)
+	assert.Equal(t, 0, list[0].MessageIndex)
+
+	var undo api.RestoreFilesResponse
+	unmarshal(t, httpDo(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/undo", nil), &undo)
+	assert.Equal(t, []string{file}, undo.Files)
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "before", string(content))
+}
+
+func TestServer_DeleteSessionDeletesCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	store := session.NewInMemorySessionStore()
+	sess := session.New(session.WithUserMessage("write it"))
+	require.NoError(t, store.AddSession(ctx, sess))
+
+	dir := t.TempDir()
+	checkpoints := checkpoint.NewStore(dir)
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("before"), 0o600))
+	require.NoError(t, checkpoints.Snapshot(sess.ID, 0, file))
+	require.DirExists(t, filepath.Join(dir, sess.ID))
+
+	lnPath := startServerWithStore(t, ctx, prepareAgentsDir(t, "pirate.yaml"), store, WithCheckpoints(checkpoints))
+	httpDo(t, ctx, http.MethodDelete, lnPath, "/api/sessions/"+sess.ID, nil)
+
+	assert.NoDirExists(t, filepath.Join(dir, sess.ID))
+}
+
+func TestServer_CancelSession(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+
+	baseURL, release := startBlockingOpenAI(t)
+	defer release()
+	ctx := t.Context()
+	lnPath := startServerWithStore(t, ctx, filepath.Join(prepareOpenAIAgent(t, baseURL), "pirate.yaml"), session.NewInMemorySessionStore())
+	sessionID := createSession(t, ctx, lnPath)
+	runPath := "/api/sessions/" + sessionID + "/agent/pirate.yaml"
+
+	var sess api.SessionResponse
+	unmarshal(t, httpGET(t, ctx, lnPath, "/api/sessions/"+sessionID), &sess)
+	assert.Equal(t, api.SessionStatusIdle, sess.Status)
+	status, _ := httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/cancel", "", nil)
+	assert.Equal(t, http.StatusConflict, status)
+
+	run := openEvents(t, ctx, http.MethodPost, lnPath, runPath, "", []api.Message{{Content: "Hi"}})
+	for e := range run {
+		if e.Type == "agent_choice" {
+			break
+		}
+	}
+
+	unmarshal(t, httpGET(t, ctx, lnPath, "/api/sessions/"+sessionID), &sess)
+	assert.Equal(t, api.SessionStatusRunning, sess.Status)
+	var sessions []api.SessionsResponse
+	unmarshal(t, httpGET(t, ctx, lnPath, "/api/sessions"), &sessions)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, api.SessionStatusRunning, sessions[0].Status)
+
+	// A session runs one agent loop at a time.
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, runPath, "", []api.Message{{Content: "Hello?"}})
+	assert.Equal(t, http.StatusConflict, status)
+
+	httpDo(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/cancel", nil)
+	unmarshal(t, httpGET(t, ctx, lnPath, "/api/sessions/"+sessionID), &sess)
+	assert.Equal(t, api.SessionStatusIdle, sess.Status)
+	_, content := collect(run)
+	assert.Equal(t, "", content)
+
+	// Once canceled, the session can run again.
+	release()
+	_, content = collect(openEvents(t, ctx, http.MethodPost, lnPath, runPath, "", []api.Message{{Content: "Hi again"}}))
+	assert.Equal(t, "Ahoy, matey!", content)
+}
+
+func TestServer_BusySessionsCantBeRewound(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+
+	baseURL, release := startBlockingOpenAI(t)
+	defer release()
+	ctx := t.Context()
+	checkpoints := checkpoint.NewStore(t.TempDir())
+	lnPath := startServerWithStore(t, ctx, filepath.Join(prepareOpenAIAgent(t, baseURL), "pirate.yaml"), session.NewInMemorySessionStore(), WithCheckpoints(checkpoints))
+	sessionID := createSession(t, ctx, lnPath)
+
+	run := openEvents(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/agent/pirate.yaml", "", []api.Message{{Content: "Hi"}})
+	for e := range run {
+		if e.Type == "agent_choice" {
+			break
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("before"), 0o600))
+	require.NoError(t, checkpoints.Snapshot(sessionID, 0, file))
+	require.NoError(t, os.WriteFile(file, []byte("after"), 0o600))
+
+	// The running agent would see its session and files change under it.
+	status, _ := httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/rewind", "", api.RewindSessionRequest{MessageIndex: 0})
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/checkpoints/restore", "", api.RestoreFilesRequest{MessageIndex: 0})
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/undo", "", nil)
+	assert.Equal(t, http.StatusConflict, status)
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "after", string(content))
+
+	httpDo(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/cancel", nil)
+	collect(run)
+
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/undo", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sessionID+"/rewind", "", api.RewindSessionRequest{MessageIndex: 0})
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestStatusForEvent(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, api.SessionStatusWaitingForConfirmation, statusForEvent(&runtime.ToolCallConfirmationEvent{}))
+	assert.Equal(t, api.SessionStatusWaitingForConfirmation, statusForEvent(&runtime.MaxIterationsReachedEvent{}))
+	assert.Equal(t, api.SessionStatusWaitingForElicitation, statusForEvent(&runtime.ElicitationRequestEvent{}))
+	assert.Equal(t, api.SessionStatusRunning, statusForEvent(&runtime.AgentChoiceEvent{}))
+}
+
+func TestServer_SessionWebhooks(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "dummy")
+
+	deliveries := make(chan *http.Request, 16)
+	receiver := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		deliveries <- r
+	}))
+	defer receiver.Close()
+
+	global, err := webhook.New([]webhook.Subscription{{URL: receiver.URL + "/global", Events: []string{webhook.EventStreamStopped}}})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, global.Close(context.Background())) }()
+
+	baseURL, _ := startFakeOpenAI(t, "Ahoy!")
+	ctx := t.Context()
+	lnPath := startServerWithStore(t, ctx, filepath.Join(prepareOpenAIAgent(t, baseURL), "pirate.yaml"), session.NewInMemorySessionStore(), WithWebhooks(global), WithSessionWebhookHosts("127.0.0.1"))
+
+	status, _ := httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions", "", map[string]any{
+		"webhooks": []map[string]any{{"url": receiver.URL, "events": []string{"agent_choice"}}},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Sessions can only POST to the allowed hosts.
+	status, _ = httpAuth(t, ctx, http.MethodPost, lnPath, "/api/sessions", "", map[string]any{
+		"webhooks": []map[string]any{{"url": "http://169.254.169.254/latest/meta-data"}},
+	})
+	assert.Equal(t, http.StatusForbidden, status)
+	otherLnPath := startServerWithStore(t, ctx, filepath.Join(prepareOpenAIAgent(t, baseURL), "pirate.yaml"), session.NewInMemorySessionStore(), WithWebhooks(global))
+	status, _ = httpAuth(t, ctx, http.MethodPost, otherLnPath, "/api/sessions", "", map[string]any{
+		"webhooks": []map[string]any{{"url": receiver.URL}},
+	})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	var sess session.Session
+	unmarshal(t, httpDo(t, ctx, http.MethodPost, lnPath, "/api/sessions", map[string]any{
+		"webhooks": []map[string]any{{"url": receiver.URL + "/session", "secret": "s3cr3t", "events": []string{webhook.EventStreamStopped}}},
+	}), &sess)
+	collect(openEvents(t, ctx, http.MethodPost, lnPath, "/api/sessions/"+sess.ID+"/agent/pirate.yaml", "", []api.Message{{Content: "Hi"}}))
+
+	received := map[string]webhook.Payload{}
+	for len(received) < 2 {
+		select {
+		case r := <-deliveries:
+			body, _ := io.ReadAll(r.Body)
+			if r.URL.Path == "/session" {
+				require.NoError(t, webhook.Verify("s3cr3t", r.Header, body))
+			}
+			var payload webhook.Payload
+			unmarshal(t, body, &payload)
+			received[r.URL.Path] = payload
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the webhooks")
+		}
+	}
+	for _, payload := range received {
+		assert.Equal(t, webhook.EventStreamStopped, payload.Type)
+		assert.Equal(t, sess.ID, payload.SessionID)
+	}
+}
+
 func prepareAgentsDir(t *testing.T, testFiles ...string) string {
 	t.Helper()
 
@@ -89,13 +335,17 @@ func prepareAgentsDir(t *testing.T, testFiles ...string) string {
 
 func startServer(t *testing.T, ctx context.Context, agentsDir string) string {
 	t.Helper()
+	return startServerWithStore(t, ctx, agentsDir, mockStore{})
+}
+
+func startServerWithStore(t *testing.T, ctx context.Context, agentsDir string, store session.Store, opts ...Opt) string {
+	t.Helper()
 
-	var store mockStore
 	runConfig := config.RuntimeConfig{}
 
 	sources, err := config.ResolveSources(agentsDir)
 	require.NoError(t, err)
-	srv, err := New(ctx, store, &runConfig, 0, sources)
+	srv, err := New(ctx, store, &runConfig, 0, sources, opts...)
 	require.NoError(t, err)
 
 	socketPath := "unix://" + filepath.Join(t.TempDir(), "sock")
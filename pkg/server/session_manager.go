@@ -5,13 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/cagent/pkg/api"
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/concurrent"
 	"github.com/docker/cagent/pkg/config"
 	"github.com/docker/cagent/pkg/runtime"
@@ -19,17 +24,66 @@ import (
 	"github.com/docker/cagent/pkg/team"
 	"github.com/docker/cagent/pkg/teamloader"
 	"github.com/docker/cagent/pkg/tools"
+	"github.com/docker/cagent/pkg/webhook"
 )
 
 type activeRuntimes struct {
 	runtime runtime.Runtime
 	cancel  context.CancelFunc
+	events  *eventLog
+	// done is closed when the latest run stops.
+	done   chan struct{}
+	status atomic.Value // api.SessionStatus
 }
 
+func (ar *activeRuntimes) getStatus() api.SessionStatus {
+	if status, ok := ar.status.Load().(api.SessionStatus); ok {
+		return status
+	}
+	return api.SessionStatusIdle
+}
+
+func (ar *activeRuntimes) setStatus(status api.SessionStatus) {
+	ar.status.Store(status)
+}
+
+// statusForEvent returns the status of a run after it emitted an event.
+func statusForEvent(event runtime.Event) api.SessionStatus {
+	switch event.(type) {
+	case *runtime.ToolCallConfirmationEvent, *runtime.MaxIterationsReachedEvent:
+		return api.SessionStatusWaitingForConfirmation
+	case *runtime.ElicitationRequestEvent:
+		return api.SessionStatusWaitingForElicitation
+	default:
+		return api.SessionStatusRunning
+	}
+}
+
+var (
+	errCheckpointsDisabled = errors.New("file checkpoints are disabled")
+	errUnsupportedRole     = errors.New("unsupported message role")
+	errSessionBusy         = errors.New("the agent is already running in this session")
+	errSessionNotRunning   = errors.New("the agent is not running in this session")
+	errInvalidWebhook      = errors.New("invalid webhook")
+	errWebhookNotAllowed   = errors.New("webhook host not allowed")
+)
+
 type sessionManager struct {
 	runtimeSessions *concurrent.Map[string, *activeRuntimes]
 	sessionStore    session.Store
 	sources         config.Sources
+	checkpoints     *checkpoint.Store
+	webhooks        *webhook.Dispatcher
+	// sessionWebhooks holds the webhook subscriptions of each session, on top of the global ones.
+	// They are not persisted with the sessions, and are lost when the server restarts.
+	sessionWebhooks *concurrent.Map[string, []webhook.Subscription]
+	// webhookHosts lists the hosts that the webhooks of sessions can target. Otherwise,
+	// API clients could have the server POST to any host it can reach.
+	webhookHosts []string
+
+	// completionToolsApproved approves the tool calls of chat completions,
+	// which have no way to ask for a confirmation.
+	completionToolsApproved bool
 
 	// TODO: We have to do something about this, it's weird, session creation should send everything that is needed.
 	// This is only used for the working directory...
@@ -48,6 +102,7 @@ func newSessionManager(ctx context.Context, sources config.Sources, sessionStore
 
 	sm := &sessionManager{
 		runtimeSessions: concurrent.NewMap[string, *activeRuntimes](),
+		sessionWebhooks: concurrent.NewMap[string, []webhook.Subscription](),
 		sessionStore:    sessionStore,
 		sources:         loaders,
 		refreshInterval: refreshInterval,
@@ -65,11 +120,22 @@ func (sm *sessionManager) GetSession(ctx context.Context, id string) (*session.S
 	return sess, nil
 }
 
-func (sm *sessionManager) CreateSession(ctx context.Context, sessionTemplate *session.Session) (*session.Session, error) {
+// CreateSession creates a session whose events are also delivered to the given webhook subscriptions.
+func (sm *sessionManager) CreateSession(ctx context.Context, sessionTemplate *session.Session, webhooks []webhook.Subscription) (*session.Session, error) {
+	for _, w := range webhooks {
+		if err := w.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidWebhook, err)
+		}
+		if host, allowed := sm.webhookHostAllowed(w.URL); !allowed {
+			return nil, fmt.Errorf("%w: %s", errWebhookNotAllowed, host)
+		}
+	}
+
 	var opts []session.Opt
 	opts = append(opts,
 		session.WithMaxIterations(sessionTemplate.MaxIterations),
 		session.WithToolsApproved(sessionTemplate.ToolsApproved),
+		session.WithOwner(sessionTemplate.Owner),
 	)
 
 	if wd := strings.TrimSpace(sessionTemplate.WorkingDir); wd != "" {
@@ -88,7 +154,25 @@ func (sm *sessionManager) CreateSession(ctx context.Context, sessionTemplate *se
 	}
 
 	sess := session.New(opts...)
-	return sess, sm.sessionStore.AddSession(ctx, sess)
+	if err := sm.sessionStore.AddSession(ctx, sess); err != nil {
+		return nil, err
+	}
+	if len(webhooks) > 0 {
+		sm.sessionWebhooks.Store(sess.ID, webhooks)
+	}
+	return sess, nil
+}
+
+// webhookHostAllowed checks whether the webhooks of sessions can target the host of a URL.
+func (sm *sessionManager) webhookHostAllowed(webhookURL string) (string, bool) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", false
+	}
+	host := u.Hostname()
+	return host, slices.ContainsFunc(sm.webhookHosts, func(allowed string) bool {
+		return strings.EqualFold(allowed, host)
+	})
 }
 
 func (sm *sessionManager) GetSessions(ctx context.Context) ([]*session.Session, error) {
@@ -115,17 +199,143 @@ func (sm *sessionManager) DeleteSession(ctx context.Context, sessionID string) e
 		sessionRuntime.cancel()
 		sm.runtimeSessions.Delete(sess.ID)
 	}
+	sm.sessionWebhooks.Delete(sess.ID)
+	if sm.checkpoints != nil {
+		if err := sm.checkpoints.Delete(sess.ID); err != nil {
+			slog.Error("Failed to delete the checkpoints of the session", "session_id", sess.ID, "error", err)
+		}
+	}
 
 	return nil
 }
 
-func (sm *sessionManager) RunSession(ctx context.Context, sessionID, agentFilename, currentAgent string, messages []api.Message) (<-chan runtime.Event, error) {
+// ForkSession forks a session at the given message, or copies all its messages if messageIndex is nil.
+func (sm *sessionManager) ForkSession(ctx context.Context, sessionID string, messageIndex *int) (*session.Session, error) {
 	sm.mux.Lock()
 	defer sm.mux.Unlock()
+
+	if messageIndex != nil {
+		return sm.sessionStore.ForkSession(ctx, sessionID, *messageIndex)
+	}
+
 	sess, err := sm.sessionStore.GetSession(ctx, sessionID)
 	if err != nil {
 		return nil, err
 	}
+	return sm.sessionStore.ForkSession(ctx, sessionID, len(sess.GetAllMessages()))
+}
+
+// GetSessionForks returns the sessions that were forked from the given session.
+func (sm *sessionManager) GetSessionForks(ctx context.Context, sessionID string) ([]*session.Session, error) {
+	if _, err := sm.sessionStore.GetSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	sessions, err := sm.sessionStore.GetSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var forks []*session.Session
+	for _, sess := range sessions {
+		if sess.ParentID == sessionID {
+			forks = append(forks, sess)
+		}
+	}
+	return forks, nil
+}
+
+// RewindSession removes the user message at messageIndex and everything after it.
+// It returns the content of the removed message.
+func (sm *sessionManager) RewindSession(ctx context.Context, sessionID string, messageIndex int) (string, error) {
+	sm.mux.Lock()
+	defer sm.mux.Unlock()
+	if sm.isBusy(sessionID) {
+		return "", errSessionBusy
+	}
+
+	sess, err := sm.sessionStore.GetSession(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := sess.Rewind(messageIndex)
+	if err != nil {
+		return "", err
+	}
+
+	return content, sm.sessionStore.UpdateSession(ctx, sess)
+}
+
+// GetCheckpoints returns the file checkpoints of a session.
+func (sm *sessionManager) GetCheckpoints(ctx context.Context, sessionID string) ([]checkpoint.Checkpoint, error) {
+	if sm.checkpoints == nil {
+		return nil, errCheckpointsDisabled
+	}
+	if _, err := sm.sessionStore.GetSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return sm.checkpoints.List(sessionID)
+}
+
+// RestoreFiles reverts the file changes made because of the message at messageIndex or any later message.
+func (sm *sessionManager) RestoreFiles(ctx context.Context, sessionID string, messageIndex int) ([]string, error) {
+	if sm.checkpoints == nil {
+		return nil, errCheckpointsDisabled
+	}
+
+	sm.mux.Lock()
+	defer sm.mux.Unlock()
+	if sm.isBusy(sessionID) {
+		return nil, errSessionBusy
+	}
+	if _, err := sm.sessionStore.GetSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return sm.checkpoints.Restore(sessionID, messageIndex)
+}
+
+// UndoFileChanges reverts the file changes of the last turn of a session.
+func (sm *sessionManager) UndoFileChanges(ctx context.Context, sessionID string) (int, []string, error) {
+	if sm.checkpoints == nil {
+		return 0, nil, errCheckpointsDisabled
+	}
+
+	sm.mux.Lock()
+	defer sm.mux.Unlock()
+	if sm.isBusy(sessionID) {
+		return 0, nil, errSessionBusy
+	}
+	sess, err := sm.sessionStore.GetSession(ctx, sessionID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return sm.checkpoints.Undo(sess)
+}
+
+// isBusy checks whether an agent is running in a session. sm.mux must be held,
+// so that no run starts until the caller is done with the session.
+func (sm *sessionManager) isBusy(sessionID string) bool {
+	runtimeSession, exists := sm.runtimeSessions.Load(sessionID)
+	return exists && runtimeSession.getStatus() != api.SessionStatusIdle
+}
+
+// RunSession adds the messages to a session and runs the agent in the background.
+// Runs outlive the requests that start them: their events are buffered in the
+// session's event log, from which any number of clients can stream them. It returns
+// the event log and the id of the event preceding the run.
+func (sm *sessionManager) RunSession(ctx context.Context, sessionID, agentFilename, currentAgent string, messages []api.Message) (*eventLog, uint64, error) {
+	sm.mux.Lock()
+	defer sm.mux.Unlock()
+	if sm.isBusy(sessionID) {
+		return nil, 0, errSessionBusy
+	}
+	runtimeSession, exists := sm.runtimeSessions.Load(sessionID)
+
+	sess, err := sm.sessionStore.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	rc := sm.runConfig.Clone()
 	rc.WorkingDir = sess.WorkingDir
@@ -134,43 +344,156 @@ func (sm *sessionManager) RunSession(ctx context.Context, sessionID, agentFilena
 	}
 
 	if err := sm.sessionStore.UpdateSession(ctx, sess); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	runtimeSession, exists := sm.runtimeSessions.Load(sessionID)
-	streamCtx, cancel := context.WithCancel(ctx)
+	runCtx := context.WithoutCancel(ctx)
+	streamCtx, cancel := context.WithCancel(runCtx)
 	if !exists {
 		rt, err := sm.runtimeForSession(ctx, sess, agentFilename, currentAgent, rc)
 		if err != nil {
 			cancel()
-			return nil, err
+			return nil, 0, err
 		}
 		runtimeSession = &activeRuntimes{
 			runtime: rt,
-			cancel:  cancel,
+			events:  newEventLog(eventLogSize, eventLogBytes),
 		}
 		sm.runtimeSessions.Store(sessionID, runtimeSession)
 	}
+	done := make(chan struct{})
+	runtimeSession.cancel = cancel
+	runtimeSession.done = done
+	runtimeSession.setStatus(api.SessionStatusRunning)
 
-	streamChan := make(chan runtime.Event)
+	events := runtimeSession.events
+	after := events.startRun()
+	webhooks, _ := sm.sessionWebhooks.Load(sessionID)
 
 	go func() {
-		stream := runtimeSession.runtime.RunStream(streamCtx, sess)
+		defer close(done)
+		// The session is idle before subscribers learn that the run stopped,
+		// so that they can start a new run right away.
+		defer events.stopRun()
+		defer runtimeSession.setStatus(api.SessionStatusIdle)
 		defer cancel()
-		defer close(streamChan)
-		for event := range stream {
-			if streamCtx.Err() != nil {
-				return
-			}
-			streamChan <- event
+
+		// Events are drained even if the run is canceled, so that the runtime can wind down.
+		for event := range runtimeSession.runtime.RunStream(streamCtx, sess) {
+			runtimeSession.setStatus(statusForEvent(event))
+			events.add(event)
+			sm.webhooks.Notify(sess.ID, event, webhooks...)
 		}
 
-		if err := sm.sessionStore.UpdateSession(ctx, sess); err != nil {
-			return
+		if err := sm.sessionStore.UpdateSession(runCtx, sess); err != nil {
+			slog.Error("Failed to save session", "session_id", sess.ID, "error", err)
 		}
 	}()
 
-	return streamChan, nil
+	return events, after, nil
+}
+
+// SessionStatus returns what the agent loop of a session is doing.
+func (sm *sessionManager) SessionStatus(sessionID string) api.SessionStatus {
+	runtimeSession, exists := sm.runtimeSessions.Load(sessionID)
+	if !exists {
+		return api.SessionStatusIdle
+	}
+	return runtimeSession.getStatus()
+}
+
+// CancelSession stops the agent loop of a session and waits for it to wind down.
+func (sm *sessionManager) CancelSession(ctx context.Context, sessionID string) error {
+	sm.mux.Lock()
+	runtimeSession, exists := sm.runtimeSessions.Load(sessionID)
+	if !exists || runtimeSession.getStatus() == api.SessionStatusIdle {
+		sm.mux.Unlock()
+		return errSessionNotRunning
+	}
+	runtimeSession.cancel()
+	done := runtimeSession.done
+	sm.mux.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SessionEvents returns the event log of a session, if it was run since the server started.
+func (sm *sessionManager) SessionEvents(sessionID string) (*eventLog, bool) {
+	runtimeSession, exists := sm.runtimeSessions.Load(sessionID)
+	if !exists || runtimeSession.events == nil {
+		return nil, false
+	}
+	return runtimeSession.events, true
+}
+
+// RunCompletion runs an agent on a transient session holding the messages of an
+// OpenAI-compatible chat completion. The session is not persisted and the agent's
+// toolsets are stopped once the run is over. If toolsApproved is true, the tool
+// calls are executed without asking for a confirmation.
+func (sm *sessionManager) RunCompletion(ctx context.Context, agentFilename string, messages []api.ChatCompletionMessage, toolsApproved bool) (runtime.Runtime, *session.Session, <-chan runtime.Event, error) {
+	t, err := sm.loadTeam(ctx, agentFilename, sm.runConfig.Clone())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stopToolSets := func() {
+		if err := t.StopToolSets(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("Failed to stop toolsets", "agent", agentFilename, "error", err)
+		}
+	}
+
+	a, err := t.Agent("root")
+	if err != nil {
+		stopToolSets()
+		return nil, nil, nil, err
+	}
+
+	sess := session.New(
+		// Chat completions don't need a generated title
+		session.WithTitle("Chat completion"),
+		session.WithMaxIterations(a.MaxIterations()),
+		session.WithToolsApproved(toolsApproved),
+		session.WithWorkingDir(sm.runConfig.WorkingDir),
+	)
+	for _, msg := range messages {
+		switch chat.MessageRole(msg.Role) {
+		case chat.MessageRoleSystem, "developer":
+			sess.AddMessage(session.SystemMessage(msg.Content))
+		case chat.MessageRoleUser:
+			sess.AddMessage(session.UserMessage(msg.Content, msg.MultiContent...))
+		case chat.MessageRoleAssistant:
+			sess.AddMessage(session.NewAgentMessage(a, &chat.Message{
+				Role:      chat.MessageRoleAssistant,
+				Content:   msg.Content,
+				CreatedAt: time.Now().Format(time.RFC3339),
+			}))
+		default:
+			stopToolSets()
+			return nil, nil, nil, fmt.Errorf("%w: %q", errUnsupportedRole, msg.Role)
+		}
+	}
+
+	rt, err := runtime.New(t, runtime.WithCurrentAgent("root"), runtime.WithManagedOAuth(false))
+	if err != nil {
+		stopToolSets()
+		return nil, nil, nil, err
+	}
+
+	events := make(chan runtime.Event)
+	go func() {
+		defer stopToolSets()
+		defer close(events)
+		for event := range rt.RunStream(ctx, sess) {
+			events <- event
+		}
+	}()
+
+	return rt, sess, events, nil
 }
 
 func (sm *sessionManager) ResumeSession(ctx context.Context, sessionID, confirmation string) error {
@@ -181,6 +504,9 @@ func (sm *sessionManager) ResumeSession(ctx context.Context, sessionID, confirma
 		return errors.New("session not found")
 	}
 
+	if rt.getStatus() == api.SessionStatusWaitingForConfirmation {
+		rt.setStatus(api.SessionStatusRunning)
+	}
 	rt.runtime.Resume(ctx, runtime.ResumeType(confirmation))
 	return nil
 }
@@ -193,7 +519,13 @@ func (sm *sessionManager) ResumeElicitation(ctx context.Context, sessionID, acti
 		return errors.New("session not found")
 	}
 
-	return rt.runtime.ResumeElicitation(ctx, tools.ElicitationAction(action), content)
+	if err := rt.runtime.ResumeElicitation(ctx, tools.ElicitationAction(action), content); err != nil {
+		return err
+	}
+	if rt.getStatus() == api.SessionStatusWaitingForElicitation {
+		rt.setStatus(api.SessionStatusRunning)
+	}
+	return nil
 }
 
 func (sm *sessionManager) ToggleToolApproval(ctx context.Context, sessionID string) error {
@@ -231,6 +563,9 @@ func (sm *sessionManager) runtimeForSession(ctx context.Context, sess *session.S
 		runtime.WithManagedOAuth(false),
 		runtime.WithSessionStore(sm.sessionStore),
 	}
+	if sm.checkpoints != nil {
+		opts = append(opts, runtime.WithCheckpoints(sm.checkpoints))
+	}
 	run, err := runtime.New(t, opts...)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,178 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+const (
+	// charsPerToken is the average number of characters per token used to
+	// estimate token counts before the provider has reported any usage.
+	charsPerToken = 4
+	// messageOverheadTokens accounts for the role and formatting tokens
+	// providers add around each message.
+	messageOverheadTokens = 4
+	// imageTokens is a rough estimate of the cost of an image part.
+	imageTokens = 1000
+	// truncatedToolOutputChars is how much of an old tool output is kept
+	// when it gets truncated to make room in the context window.
+	truncatedToolOutputChars = 1000
+)
+
+// EstimateMessageTokens returns an estimate of the number of tokens a message
+// takes in the context window.
+func EstimateMessageTokens(msg *chat.Message) int64 {
+	chars := len(msg.Content) + len(msg.ReasoningContent)
+	images := 0
+	for _, part := range msg.MultiContent {
+		chars += len(part.Text)
+		if part.ImageURL != nil {
+			images++
+		}
+	}
+	for _, call := range msg.ToolCalls {
+		chars += len(call.ID) + len(call.Function.Name) + len(call.Function.Arguments)
+	}
+
+	return messageOverheadTokens + int64(chars/charsPerToken) + int64(images*imageTokens)
+}
+
+// EstimateMessagesTokens returns an estimate of the number of tokens a list of messages takes.
+func EstimateMessagesTokens(messages []chat.Message) int64 {
+	var total int64
+	for i := range messages {
+		total += EstimateMessageTokens(&messages[i])
+	}
+	return total
+}
+
+// EstimateToolsTokens returns an estimate of the number of tokens the tool
+// definitions sent along with each request take.
+func EstimateToolsTokens(toolDefs []tools.Tool) int64 {
+	var chars int
+	for _, tool := range toolDefs {
+		chars += len(tool.Name) + len(tool.Description)
+		if tool.Parameters != nil {
+			if buf, err := json.Marshal(tool.Parameters); err == nil {
+				chars += len(buf)
+			}
+		}
+	}
+	return int64(chars / charsPerToken)
+}
+
+// CalibrateTokenEstimate records how many input tokens the provider actually
+// reported for a request whose size was estimated to estimated tokens.
+// Subsequent estimates for this session are scaled accordingly.
+func (s *Session) CalibrateTokenEstimate(estimated, actual int64) {
+	if estimated <= 0 || actual <= 0 {
+		return
+	}
+	s.tokenRatio = float64(actual) / float64(estimated)
+}
+
+// estimateTokens estimates the size of a message, scaled by the ratio
+// observed on the last request, if any.
+func (s *Session) estimateTokens(msg *chat.Message) int64 {
+	tokens := EstimateMessageTokens(msg)
+	if s.tokenRatio > 0 {
+		return int64(float64(tokens) * s.tokenRatio)
+	}
+	return tokens
+}
+
+// fitToTokenBudget makes sure the messages fit in maxTokens.
+// System messages are always kept. Old tool outputs are truncated first, then
+// the oldest turns are dropped, keeping assistant tool calls and their results
+// together. The latest turn is only truncated as a last resort.
+func (s *Session) fitToTokenBudget(messages []chat.Message, maxTokens int64) []chat.Message {
+	sizes := make([]int64, len(messages))
+	var total int64
+	for i := range messages {
+		sizes[i] = s.estimateTokens(&messages[i])
+		total += sizes[i]
+	}
+	if total <= maxTokens {
+		return messages
+	}
+
+	result := make([]chat.Message, len(messages))
+	copy(result, messages)
+
+	// The latest turn is the last user message, or the last assistant message
+	// and the tool results the model is about to look at. It's never dropped.
+	lastTurn := len(result)
+	for i := len(result) - 1; i >= 0; i-- {
+		if result[i].Role == chat.MessageRoleUser || result[i].Role == chat.MessageRoleAssistant {
+			lastTurn = i
+			break
+		}
+	}
+
+	drop := make([]bool, len(result))
+	truncated := make([]bool, len(result))
+	truncate := func(from, to int) {
+		for i := from; i < to && total > maxTokens; i++ {
+			if result[i].Role != chat.MessageRoleTool || drop[i] || truncated[i] || len(result[i].Content) <= truncatedToolOutputChars {
+				continue
+			}
+			result[i].Content = truncateToolOutput(result[i].Content)
+			truncated[i] = true
+			newSize := s.estimateTokens(&result[i])
+			total -= sizes[i] - newSize
+			sizes[i] = newSize
+		}
+	}
+
+	// 1. Truncate old tool outputs, oldest first.
+	truncate(0, lastTurn)
+
+	// 2. Drop the oldest turns. A turn is a user message, or an assistant message
+	// followed by the results of its tool calls.
+	for i := 0; i < lastTurn && total > maxTokens; {
+		if result[i].Role == chat.MessageRoleSystem {
+			i++
+			continue
+		}
+
+		end := i + 1
+		if result[i].Role == chat.MessageRoleAssistant {
+			for end < len(result) && result[end].Role == chat.MessageRoleTool {
+				end++
+			}
+		}
+		if end > lastTurn {
+			break
+		}
+
+		for j := i; j < end; j++ {
+			drop[j] = true
+			total -= sizes[j]
+		}
+		i = end
+	}
+
+	// 3. Truncate what's left, including the latest tool outputs.
+	truncate(0, len(result))
+
+	kept := make([]chat.Message, 0, len(result))
+	for i := range result {
+		if !drop[i] {
+			kept = append(kept, result[i])
+		}
+	}
+	return kept
+}
+
+func truncateToolOutput(content string) string {
+	n := truncatedToolOutputChars
+	for n > 0 && !utf8.RuneStart(content[n]) {
+		n--
+	}
+	omitted := len(content) - n
+	return content[:n] + fmt.Sprintf("\n\n[... %d characters of this tool output were truncated to save context ...]", omitted)
+}
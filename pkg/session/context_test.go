@@ -0,0 +1,130 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/agent"
+	"github.com/docker/cagent/pkg/chat"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+func toolTurn(id, output string) []chat.Message {
+	return []chat.Message{
+		{Role: chat.MessageRoleAssistant, ToolCalls: []tools.ToolCall{{ID: id, Function: tools.FunctionCall{Name: "read_file"}}}},
+		{Role: chat.MessageRoleTool, ToolCallID: id, Content: output},
+	}
+}
+
+func TestEstimateMessageTokens(t *testing.T) {
+	short := EstimateMessageTokens(&chat.Message{Role: chat.MessageRoleUser, Content: "hi"})
+	long := EstimateMessageTokens(&chat.Message{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 4000)})
+	image := EstimateMessageTokens(&chat.Message{
+		Role:         chat.MessageRoleUser,
+		MultiContent: []chat.MessagePart{{Type: chat.MessagePartTypeImageURL, ImageURL: &chat.MessageImageURL{URL: "data:"}}},
+	})
+
+	assert.Equal(t, int64(messageOverheadTokens), short)
+	assert.Equal(t, int64(messageOverheadTokens+1000), long)
+	assert.Equal(t, int64(messageOverheadTokens+imageTokens), image)
+}
+
+func TestFitToTokenBudget_UnderBudget(t *testing.T) {
+	messages := []chat.Message{
+		{Role: chat.MessageRoleSystem, Content: "system"},
+		{Role: chat.MessageRoleUser, Content: "hello"},
+	}
+
+	assert.Equal(t, messages, New().fitToTokenBudget(messages, 1000))
+}
+
+func TestFitToTokenBudget_TruncatesOldToolOutputsFirst(t *testing.T) {
+	huge := strings.Repeat("x", 40000)
+
+	var messages []chat.Message
+	messages = append(messages, chat.Message{Role: chat.MessageRoleSystem, Content: "system"})
+	messages = append(messages, chat.Message{Role: chat.MessageRoleUser, Content: "read the files"})
+	messages = append(messages, toolTurn("call1", huge)...)
+	messages = append(messages, toolTurn("call2", "small")...)
+
+	fitted := New().fitToTokenBudget(messages, 2000)
+
+	require.Len(t, fitted, len(messages), "no message should be dropped")
+	assert.Less(t, len(fitted[3].Content), 2000)
+	assert.Contains(t, fitted[3].Content, "truncated to save context")
+	assert.Equal(t, "small", fitted[5].Content)
+	assert.Equal(t, huge, messages[3].Content, "the original messages should not be modified")
+}
+
+func TestFitToTokenBudget_DropsOldestTurns(t *testing.T) {
+	var messages []chat.Message
+	messages = append(messages, chat.Message{Role: chat.MessageRoleSystem, Content: "system"})
+	for i := range 10 {
+		messages = append(messages, chat.Message{Role: chat.MessageRoleUser, Content: fmt.Sprintf("question %d %s", i, strings.Repeat("q", 400))})
+		messages = append(messages, toolTurn(fmt.Sprintf("call%d", i), strings.Repeat("o", 400))...)
+	}
+
+	fitted := New().fitToTokenBudget(messages, 1000)
+
+	require.Less(t, len(fitted), len(messages))
+	assert.LessOrEqual(t, EstimateMessagesTokens(fitted), int64(1000))
+	assert.Equal(t, chat.MessageRoleSystem, fitted[0].Role)
+	assert.Equal(t, "call9", fitted[len(fitted)-1].ToolCallID, "the latest turn should be kept")
+
+	// Every tool result must follow the assistant message that called it.
+	calls := map[string]bool{}
+	for _, msg := range fitted {
+		for _, call := range msg.ToolCalls {
+			calls[call.ID] = true
+		}
+		if msg.Role == chat.MessageRoleTool {
+			assert.True(t, calls[msg.ToolCallID], "orphan tool result %s", msg.ToolCallID)
+		}
+	}
+	assert.NotEqual(t, chat.MessageRoleTool, fitted[1].Role)
+}
+
+func TestFitToTokenBudget_KeepsLatestUserMessage(t *testing.T) {
+	messages := []chat.Message{
+		{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 4000)},
+		{Role: chat.MessageRoleAssistant, Content: strings.Repeat("b", 4000)},
+		{Role: chat.MessageRoleUser, Content: strings.Repeat("c", 4000)},
+	}
+
+	fitted := New().fitToTokenBudget(messages, 100)
+
+	require.Len(t, fitted, 1)
+	assert.Equal(t, messages[2], fitted[0])
+}
+
+func TestCalibrateTokenEstimate(t *testing.T) {
+	s := New()
+	msg := chat.Message{Role: chat.MessageRoleUser, Content: strings.Repeat("a", 400)}
+	estimated := EstimateMessageTokens(&msg)
+
+	s.CalibrateTokenEstimate(100, 200)
+	assert.Equal(t, 2*estimated, s.estimateTokens(&msg))
+
+	s.CalibrateTokenEstimate(0, 200)
+	assert.Equal(t, 2*estimated, s.estimateTokens(&msg), "invalid usage should be ignored")
+}
+
+func TestGetMessagesWithinBudget(t *testing.T) {
+	a := agent.New("root", "instructions")
+
+	s := New()
+	s.AddMessage(UserMessage("read it"))
+	for _, msg := range toolTurn("call1", strings.Repeat("x", 40000)) {
+		s.AddMessage(NewAgentMessage(a, &msg))
+	}
+	s.AddMessage(UserMessage("thanks"))
+
+	messages := s.GetMessagesWithinBudget(a, 3000)
+
+	assert.LessOrEqual(t, EstimateMessagesTokens(messages), int64(3000))
+	assert.Equal(t, "thanks", messages[len(messages)-1].Content)
+}
@@ -0,0 +1,136 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/docker/cagent/pkg/chat"
+)
+
+var (
+	ErrInvalidMessageIndex = errors.New("invalid message index")
+	ErrNotUserMessage      = errors.New("message is not a user message")
+)
+
+// itemIndex returns the index of the item holding the message at messageIndex,
+// as numbered by GetAllMessages. messageIndex can be the number of messages,
+// in which case the number of items is returned.
+// Messages inside a sub-session can't be addressed individually.
+func (s *Session) itemIndex(messageIndex int) (int, error) {
+	if messageIndex < 0 {
+		return 0, ErrInvalidMessageIndex
+	}
+
+	count := 0
+	for i := range s.Messages {
+		item := &s.Messages[i]
+
+		var size int
+		switch {
+		case item.IsMessage() && item.Message.Message.Role != chat.MessageRoleSystem:
+			size = 1
+		case item.IsSubSession():
+			size = len(item.SubSession.GetAllMessages())
+		}
+		if size == 0 {
+			continue
+		}
+
+		if count == messageIndex {
+			return i, nil
+		}
+		if messageIndex < count+size {
+			return 0, fmt.Errorf("%w: message %d is part of a sub-session", ErrInvalidMessageIndex, messageIndex)
+		}
+		count += size
+	}
+
+	if count == messageIndex {
+		return len(s.Messages), nil
+	}
+	return 0, fmt.Errorf("%w: %d (session has %d messages)", ErrInvalidMessageIndex, messageIndex, count)
+}
+
+// Fork creates a new session with a copy of the messages that come before
+// messageIndex, as numbered by GetAllMessages. The new session remembers its
+// parent and the fork point, so the original transcript stays untouched.
+func (s *Session) Fork(messageIndex int) (*Session, error) {
+	end, err := s.itemIndex(messageIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deep copy the items so that the two sessions don't share messages or sub-sessions.
+	buf, err := json.Marshal(s.Messages[:end])
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	if err := json.Unmarshal(buf, &items); err != nil {
+		return nil, err
+	}
+
+	fork := &Session{
+		ID:              uuid.New().String(),
+		Title:           s.Title,
+		Messages:        items,
+		CreatedAt:       time.Now(),
+		ToolsApproved:   s.ToolsApproved,
+		WorkingDir:      s.WorkingDir,
+		SendUserMessage: s.SendUserMessage,
+		MaxIterations:   s.MaxIterations,
+		ParentID:        s.ID,
+		ForkPoint:       messageIndex,
+		Owner:           s.Owner,
+	}
+	slog.Debug("Forked session", "session_id", fork.ID, "parent_id", s.ID, "fork_point", messageIndex)
+
+	return fork, nil
+}
+
+// Rewind removes the user message at messageIndex, as numbered by GetAllMessages,
+// and everything that came after it. It returns the content of the removed
+// message so that it can be edited and sent again.
+func (s *Session) Rewind(messageIndex int) (string, error) {
+	i, err := s.itemIndex(messageIndex)
+	if err != nil {
+		return "", err
+	}
+	if i == len(s.Messages) || !s.Messages[i].IsMessage() || s.Messages[i].Message.Message.Role != chat.MessageRoleUser {
+		return "", fmt.Errorf("%w: %d", ErrNotUserMessage, messageIndex)
+	}
+
+	content := s.Messages[i].Message.Message.Content
+	s.Messages = s.Messages[:i]
+	slog.Debug("Rewound session", "session_id", s.ID, "message_index", messageIndex)
+
+	return content, nil
+}
+
+// UserMessageIndex returns the index, as numbered by GetAllMessages, of the
+// n-th (1-based) user message of the session. Implicit messages are skipped.
+// When n is 0, the index of the last user message is returned.
+func (s *Session) UserMessageIndex(n int) (int, error) {
+	var indexes []int
+	for i, msg := range s.GetAllMessages() {
+		if msg.Message.Role == chat.MessageRoleUser && !msg.Implicit {
+			indexes = append(indexes, i)
+		}
+	}
+
+	switch {
+	case len(indexes) == 0:
+		return 0, fmt.Errorf("%w: the session has no user messages", ErrInvalidMessageIndex)
+	case n == 0:
+		return indexes[len(indexes)-1], nil
+	case n < 0 || n > len(indexes):
+		return 0, fmt.Errorf("%w: there are %d user messages", ErrInvalidMessageIndex, len(indexes))
+	default:
+		return indexes[n-1], nil
+	}
+}
@@ -0,0 +1,104 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/agent"
+	"github.com/docker/cagent/pkg/chat"
+)
+
+func newForkTestSession() *Session {
+	a := agent.New("root", "instructions")
+
+	s := New(WithSystemMessage("system"), WithTitle("title"), WithWorkingDir("/work"))
+	s.AddMessage(UserMessage("first"))
+	s.AddMessage(NewAgentMessage(a, &chat.Message{Role: chat.MessageRoleAssistant, Content: "answer 1"}))
+	sub := New(WithImplicitUserMessage("task"))
+	sub.AddMessage(NewAgentMessage(a, &chat.Message{Role: chat.MessageRoleAssistant, Content: "sub answer"}))
+	s.AddSubSession(sub)
+	s.AddMessage(UserMessage("second"))
+	s.AddMessage(NewAgentMessage(a, &chat.Message{Role: chat.MessageRoleAssistant, Content: "answer 2"}))
+	return s
+}
+
+func TestFork(t *testing.T) {
+	s := newForkTestSession()
+
+	// Messages: first, answer 1, task, sub answer, second, answer 2
+	fork, err := s.Fork(4)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, s.ID, fork.ID)
+	assert.Equal(t, s.ID, fork.ParentID)
+	assert.Equal(t, 4, fork.ForkPoint)
+	assert.Equal(t, "title", fork.Title)
+	assert.Equal(t, "/work", fork.WorkingDir)
+
+	messages := fork.GetAllMessages()
+	require.Len(t, messages, 4)
+	assert.Equal(t, "sub answer", messages[3].Message.Content)
+	assert.Len(t, s.GetAllMessages(), 6, "the parent should be left untouched")
+
+	// The fork must not share messages with its parent.
+	fork.Messages[1].Message.Message.Content = "changed"
+	assert.Equal(t, "first", s.Messages[1].Message.Message.Content)
+}
+
+func TestFork_AllMessages(t *testing.T) {
+	s := newForkTestSession()
+
+	fork, err := s.Fork(6)
+	require.NoError(t, err)
+
+	assert.Len(t, fork.GetAllMessages(), 6)
+}
+
+func TestFork_InvalidIndex(t *testing.T) {
+	s := newForkTestSession()
+
+	for _, index := range []int{-1, 3, 7} {
+		_, err := s.Fork(index)
+		require.ErrorIs(t, err, ErrInvalidMessageIndex, "index %d", index)
+	}
+}
+
+func TestRewind(t *testing.T) {
+	s := newForkTestSession()
+
+	content, err := s.Rewind(4)
+	require.NoError(t, err)
+
+	assert.Equal(t, "second", content)
+	messages := s.GetAllMessages()
+	require.Len(t, messages, 4)
+	assert.Equal(t, "sub answer", messages[3].Message.Content)
+}
+
+func TestRewind_NotUserMessage(t *testing.T) {
+	s := newForkTestSession()
+
+	_, err := s.Rewind(1)
+	require.ErrorIs(t, err, ErrNotUserMessage)
+	assert.Len(t, s.GetAllMessages(), 6)
+}
+
+func TestUserMessageIndex(t *testing.T) {
+	s := newForkTestSession()
+
+	index, err := s.UserMessageIndex(0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, index)
+
+	index, err = s.UserMessageIndex(1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+
+	_, err = s.UserMessageIndex(3)
+	require.ErrorIs(t, err, ErrInvalidMessageIndex)
+
+	_, err = New().UserMessageIndex(0)
+	require.ErrorIs(t, err, ErrInvalidMessageIndex)
+}
@@ -207,6 +207,27 @@ func getAllMigrations() []Migration {
 			UpSQL:       `ALTER TABLE sessions ADD COLUMN working_dir TEXT DEFAULT ''`,
 			DownSQL:     `ALTER TABLE sessions DROP COLUMN working_dir`,
 		},
+		{
+			ID:          9,
+			Name:        "009_add_parent_id_column",
+			Description: "Add parent_id column to sessions table",
+			UpSQL:       `ALTER TABLE sessions ADD COLUMN parent_id TEXT DEFAULT ''`,
+			DownSQL:     `ALTER TABLE sessions DROP COLUMN parent_id`,
+		},
+		{
+			ID:          10,
+			Name:        "010_add_fork_point_column",
+			Description: "Add fork_point column to sessions table",
+			UpSQL:       `ALTER TABLE sessions ADD COLUMN fork_point INTEGER DEFAULT 0`,
+			DownSQL:     `ALTER TABLE sessions DROP COLUMN fork_point`,
+		},
+		{
+			ID:          11,
+			Name:        "011_add_owner_column",
+			Description: "Add owner column to sessions table",
+			UpSQL:       `ALTER TABLE sessions ADD COLUMN owner TEXT DEFAULT ''`,
+			DownSQL:     `ALTER TABLE sessions DROP COLUMN owner`,
+		},
 		// Add more migrations here as needed
 	}
 }
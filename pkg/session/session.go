@@ -13,9 +13,9 @@ import (
 	"github.com/docker/cagent/pkg/skills"
 )
 
-// TODO: instead of trimming, we should compact the history when it nears the
-// context size of the current LLM
-var maxMessages = 100 // Maximum number of messages to keep in context
+// maxMessages is the maximum number of messages to keep in context when the
+// context size of the current LLM is unknown.
+var maxMessages = 100
 
 // Item represents either a message or a sub-session
 type Item struct {
@@ -69,6 +69,18 @@ type Session struct {
 	InputTokens  int64   `json:"input_tokens"`
 	OutputTokens int64   `json:"output_tokens"`
 	Cost         float64 `json:"cost"`
+
+	// ParentID is the ID of the session this session was forked from, if any
+	ParentID string `json:"parent_id,omitempty"`
+
+	// ForkPoint is the index of the parent's message at which this session was forked
+	ForkPoint int `json:"fork_point,omitempty"`
+
+	// Owner is the subject of the API token that created the session, if any
+	Owner string `json:"owner,omitempty"`
+
+	// tokenRatio scales token estimates to match the usage last reported by the provider
+	tokenRatio float64
 }
 
 // Message is a message from an agent
@@ -253,6 +265,12 @@ func WithSendUserMessage(sendUserMessage bool) Opt {
 	}
 }
 
+func WithOwner(owner string) Opt {
+	return func(s *Session) {
+		s.Owner = owner
+	}
+}
+
 // New creates a new agent session
 func New(opts ...Opt) *Session {
 	sessionID := uuid.New().String()
@@ -271,7 +289,63 @@ func New(opts ...Opt) *Session {
 	return s
 }
 
+// GetMessages returns the messages to send to the agent's model, keeping at
+// most the agent's num_history_items conversation messages, or 100 by default.
 func (s *Session) GetMessages(a *agent.Agent) []chat.Message {
+	maxItems := a.NumHistoryItems()
+	if maxItems <= 0 {
+		maxItems = maxMessages
+	}
+
+	messages := s.buildMessages(a)
+	trimmed := trimMessages(messages, maxItems)
+
+	systemCount := 0
+	conversationCount := 0
+	for i := range trimmed {
+		if trimmed[i].Role == chat.MessageRoleSystem {
+			systemCount++
+		} else {
+			conversationCount++
+		}
+	}
+
+	slog.Debug("Retrieved messages for agent",
+		"agent", a.Name(),
+		"session_id", s.ID,
+		"total_messages", len(messages),
+		"trimmed_total", len(trimmed),
+		"system_messages", systemCount,
+		"conversation_messages", conversationCount,
+		"max_history_items", maxItems)
+
+	return trimmed
+}
+
+// GetMessagesWithinBudget returns the messages to send to the agent's model,
+// keeping as much of the history as fits in maxTokens. Old tool outputs are
+// truncated first, then the oldest turns are dropped.
+// The agent's num_history_items, if set, still caps the number of messages.
+func (s *Session) GetMessagesWithinBudget(a *agent.Agent, maxTokens int64) []chat.Message {
+	messages := s.buildMessages(a)
+	if maxItems := a.NumHistoryItems(); maxItems > 0 {
+		messages = trimMessages(messages, maxItems)
+	}
+
+	fitted := s.fitToTokenBudget(messages, maxTokens)
+
+	slog.Debug("Retrieved messages for agent",
+		"agent", a.Name(),
+		"session_id", s.ID,
+		"total_messages", len(messages),
+		"trimmed_total", len(fitted),
+		"estimated_tokens", EstimateMessagesTokens(fitted),
+		"max_tokens", maxTokens)
+
+	return fitted
+}
+
+func (s *Session) buildMessages(a *agent.Agent) []chat.Message {
 	slog.Debug("Getting messages for agent", "agent", a.Name(), "session_id", s.ID)
 
 	var messages []chat.Message
@@ -400,33 +474,7 @@ func (s *Session) GetMessages(a *agent.Agent) []chat.Message {
 		}
 	}
 
-	maxItems := a.NumHistoryItems()
-	if maxItems <= 0 {
-		maxItems = maxMessages
-	}
-
-	trimmed := trimMessages(messages, maxItems)
-
-	systemCount := 0
-	conversationCount := 0
-	for i := range trimmed {
-		if trimmed[i].Role == chat.MessageRoleSystem {
-			systemCount++
-		} else {
-			conversationCount++
-		}
-	}
-
-	slog.Debug("Retrieved messages for agent",
-		"agent", a.Name(),
-		"session_id", s.ID,
-		"total_messages", len(messages),
-		"trimmed_total", len(trimmed),
-		"system_messages", systemCount,
-		"conversation_messages", conversationCount,
-		"max_history_items", maxItems)
-
-	return trimmed
+	return messages
 }
 
 // trimMessages ensures we don't exceed the maximum number of messages while maintaining
@@ -34,6 +34,7 @@ type Store interface {
 	GetSessions(ctx context.Context) ([]*Session, error)
 	DeleteSession(ctx context.Context, id string) error
 	UpdateSession(ctx context.Context, session *Session) error
+	ForkSession(ctx context.Context, id string, messageIndex int) (*Session, error)
 }
 
 type InMemorySessionStore struct {
@@ -98,6 +99,28 @@ func (s *InMemorySessionStore) UpdateSession(ctx context.Context, session *Sessi
 	return nil
 }
 
+func (s *InMemorySessionStore) ForkSession(ctx context.Context, id string, messageIndex int) (*Session, error) {
+	return forkSession(ctx, s, id, messageIndex)
+}
+
+// forkSession forks a stored session at the given message and stores the new session.
+func forkSession(ctx context.Context, store Store, id string, messageIndex int) (*Session, error) {
+	parent, err := store.GetSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	fork, err := parent.Fork(messageIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.AddSession(ctx, fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
 // SQLiteSessionStore implements Store using SQLite
 type SQLiteSessionStore struct {
 	db *sql.DB
@@ -152,8 +175,8 @@ func (s *SQLiteSessionStore) AddSession(ctx context.Context, session *Session) e
 	}
 
 	_, err = s.db.ExecContext(ctx,
-		"INSERT INTO sessions (id, messages, tools_approved, input_tokens, output_tokens, title, send_user_message, max_iterations, working_dir, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		session.ID, string(itemsJSON), session.ToolsApproved, session.InputTokens, session.OutputTokens, session.Title, session.SendUserMessage, session.MaxIterations, session.WorkingDir, session.CreatedAt.Format(time.RFC3339))
+		"INSERT INTO sessions (id, messages, tools_approved, input_tokens, output_tokens, title, send_user_message, max_iterations, working_dir, parent_id, fork_point, owner, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		session.ID, string(itemsJSON), session.ToolsApproved, session.InputTokens, session.OutputTokens, session.Title, session.SendUserMessage, session.MaxIterations, session.WorkingDir, session.ParentID, session.ForkPoint, session.Owner, session.CreatedAt.Format(time.RFC3339))
 	return err
 }
 
@@ -164,13 +187,14 @@ func (s *SQLiteSessionStore) GetSession(ctx context.Context, id string) (*Sessio
 	}
 
 	row := s.db.QueryRowContext(ctx,
-		"SELECT id, messages, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at FROM sessions WHERE id = ?", id)
+		"SELECT id, messages, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, parent_id, fork_point, owner, created_at FROM sessions WHERE id = ?", id)
 
 	var messagesJSON, toolsApprovedStr, inputTokensStr, outputTokensStr, titleStr, costStr, sendUserMessageStr, maxIterationsStr, createdAtStr string
 	var sessionID string
-	var workingDir sql.NullString
+	var workingDir, parentID, owner sql.NullString
+	var forkPoint sql.NullInt64
 
-	err := row.Scan(&sessionID, &messagesJSON, &toolsApprovedStr, &inputTokensStr, &outputTokensStr, &titleStr, &costStr, &sendUserMessageStr, &maxIterationsStr, &workingDir, &createdAtStr)
+	err := row.Scan(&sessionID, &messagesJSON, &toolsApprovedStr, &inputTokensStr, &outputTokensStr, &titleStr, &costStr, &sendUserMessageStr, &maxIterationsStr, &workingDir, &parentID, &forkPoint, &owner, &createdAtStr)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -242,13 +266,16 @@ func (s *SQLiteSessionStore) GetSession(ctx context.Context, id string) (*Sessio
 		MaxIterations:   maxIterations,
 		CreatedAt:       createdAt,
 		WorkingDir:      workingDir.String,
+		ParentID:        parentID.String,
+		ForkPoint:       int(forkPoint.Int64),
+		Owner:           owner.String,
 	}, nil
 }
 
 // GetSessions retrieves all sessions
 func (s *SQLiteSessionStore) GetSessions(ctx context.Context) ([]*Session, error) {
 	rows, err := s.db.QueryContext(ctx,
-		"SELECT id, messages, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, created_at FROM sessions ORDER BY created_at DESC")
+		"SELECT id, messages, tools_approved, input_tokens, output_tokens, title, cost, send_user_message, max_iterations, working_dir, parent_id, fork_point, owner, created_at FROM sessions ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -258,9 +285,10 @@ func (s *SQLiteSessionStore) GetSessions(ctx context.Context) ([]*Session, error
 	for rows.Next() {
 		var messagesJSON, toolsApprovedStr, inputTokensStr, outputTokensStr, titleStr, costStr, sendUserMessageStr, maxIterationsStr, createdAtStr string
 		var sessionID string
-		var workingDir sql.NullString
+		var workingDir, parentID, owner sql.NullString
+		var forkPoint sql.NullInt64
 
-		err := rows.Scan(&sessionID, &messagesJSON, &toolsApprovedStr, &inputTokensStr, &outputTokensStr, &titleStr, &costStr, &sendUserMessageStr, &maxIterationsStr, &workingDir, &createdAtStr)
+		err := rows.Scan(&sessionID, &messagesJSON, &toolsApprovedStr, &inputTokensStr, &outputTokensStr, &titleStr, &costStr, &sendUserMessageStr, &maxIterationsStr, &workingDir, &parentID, &forkPoint, &owner, &createdAtStr)
 		if err != nil {
 			return nil, err
 		}
@@ -329,6 +357,9 @@ func (s *SQLiteSessionStore) GetSessions(ctx context.Context) ([]*Session, error
 			MaxIterations:   maxIterations,
 			CreatedAt:       createdAt,
 			WorkingDir:      workingDir.String,
+			ParentID:        parentID.String,
+			ForkPoint:       int(forkPoint.Int64),
+			Owner:           owner.String,
 		}
 
 		sessions = append(sessions, session)
@@ -390,6 +421,12 @@ func (s *SQLiteSessionStore) UpdateSession(ctx context.Context, session *Session
 	return nil
 }
 
+// ForkSession forks a session at the given message into a new session
+// that keeps track of its parent and fork point
+func (s *SQLiteSessionStore) ForkSession(ctx context.Context, id string, messageIndex int) (*Session, error) {
+	return forkSession(ctx, s, id, messageIndex)
+}
+
 // Close closes the database connection
 func (s *SQLiteSessionStore) Close() error {
 	return s.db.Close()
@@ -200,3 +200,39 @@ func TestStoreAgentNameJSON(t *testing.T) {
 	assert.Equal(t, "my-agent", retrievedSession.Messages[1].Message.AgentName)      // First agent
 	assert.Equal(t, "another-agent", retrievedSession.Messages[2].Message.AgentName) // Second agent
 }
+
+func TestForkSession(t *testing.T) {
+	tempDB := filepath.Join(t.TempDir(), "test_store.db")
+
+	store, err := NewSQLiteSessionStore(tempDB)
+	require.NoError(t, err)
+	defer store.(*SQLiteSessionStore).Close()
+
+	parent := newForkTestSession()
+	parent.Owner = "alice"
+	err = store.AddSession(t.Context(), parent)
+	require.NoError(t, err)
+
+	fork, err := store.ForkSession(t.Context(), parent.ID, 4)
+	require.NoError(t, err)
+
+	retrievedFork, err := store.GetSession(t.Context(), fork.ID)
+	require.NoError(t, err)
+	assert.Equal(t, parent.ID, retrievedFork.ParentID)
+	assert.Equal(t, 4, retrievedFork.ForkPoint)
+	assert.Equal(t, "alice", retrievedFork.Owner)
+	assert.Len(t, retrievedFork.GetAllMessages(), 4)
+
+	retrievedParent, err := store.GetSession(t.Context(), parent.ID)
+	require.NoError(t, err)
+	assert.Empty(t, retrievedParent.ParentID)
+	assert.Equal(t, "alice", retrievedParent.Owner)
+	assert.Len(t, retrievedParent.GetAllMessages(), 6)
+
+	sessions, err := store.GetSessions(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	_, err = store.ForkSession(t.Context(), "unknown", 0)
+	require.ErrorIs(t, err, ErrNotFound)
+}
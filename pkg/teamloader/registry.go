@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/docker/cagent/pkg/config"
@@ -14,6 +15,7 @@ import (
 	"github.com/docker/cagent/pkg/js"
 	"github.com/docker/cagent/pkg/memory/database/sqlite"
 	"github.com/docker/cagent/pkg/path"
+	"github.com/docker/cagent/pkg/sandbox"
 	"github.com/docker/cagent/pkg/tools"
 	"github.com/docker/cagent/pkg/tools/a2a"
 	"github.com/docker/cagent/pkg/tools/builtin"
@@ -67,6 +69,7 @@ func NewDefaultToolsetRegistry() *ToolsetRegistry {
 	r.Register("fetch", createFetchTool)
 	r.Register("mcp", createMCPTool)
 	r.Register("api", createAPITool)
+	r.Register("openapi", createOpenAPITool)
 	r.Register("a2a", createA2ATool)
 	return r
 }
@@ -113,6 +116,15 @@ func createShellTool(ctx context.Context, toolset latest.Toolset, _ string, runC
 	if err != nil {
 		return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
 	}
+	if toolset.Sandbox != nil {
+		sb, err := newSandbox(toolset.Sandbox, runConfig)
+		if err != nil {
+			return nil, err
+		}
+		// The host environment isn't leaked into the sandbox.
+		return builtin.NewShellTool(env, runConfig, builtin.WithShellSandbox(sb)), nil
+	}
+
 	env = append(env, os.Environ()...)
 	return builtin.NewShellTool(env, runConfig), nil
 }
@@ -126,10 +138,37 @@ func createScriptTool(ctx context.Context, toolset latest.Toolset, _ string, run
 	if err != nil {
 		return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
 	}
+	if toolset.Sandbox != nil {
+		sb, err := newSandbox(toolset.Sandbox, runConfig)
+		if err != nil {
+			return nil, err
+		}
+		// The host environment isn't leaked into the sandbox.
+		return builtin.NewScriptShellTool(toolset.Shell, env, builtin.WithScriptSandbox(sb))
+	}
+
 	env = append(env, os.Environ()...)
 	return builtin.NewScriptShellTool(toolset.Shell, env)
 }
 
+// newSandbox creates a Docker sandbox that mounts the working directory.
+func newSandbox(cfg *latest.SandboxConfig, runConfig *config.RuntimeConfig) (*sandbox.Sandbox, error) {
+	wd := runConfig.WorkingDir
+	if wd == "" {
+		var err error
+		wd, err = os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+
+	wd, err := filepath.Abs(wd)
+	if err != nil {
+		return nil, err
+	}
+	return sandbox.New(cfg, wd, sandbox.NewDockerRunner()), nil
+}
+
 func createFilesystemTool(_ context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig) (tools.ToolSet, error) {
 	wd := runConfig.WorkingDir
 	if wd == "" {
@@ -175,6 +214,22 @@ func createAPITool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 	return builtin.NewAPITool(toolset.APIConfig), nil
 }
 
+func createOpenAPITool(ctx context.Context, toolset latest.Toolset, parentDir string, runConfig *config.RuntimeConfig) (tools.ToolSet, error) {
+	if toolset.OpenAPIConfig == nil || toolset.OpenAPIConfig.Spec == "" {
+		return nil, fmt.Errorf("openapi tool requires a spec in openapi_config")
+	}
+
+	cfg := *toolset.OpenAPIConfig
+	if !strings.HasPrefix(cfg.Spec, "http://") && !strings.HasPrefix(cfg.Spec, "https://") && !filepath.IsAbs(cfg.Spec) {
+		cfg.Spec = filepath.Join(parentDir, cfg.Spec)
+	}
+
+	expander := js.NewJsExpander(runConfig.EnvProvider())
+	cfg.Headers = expander.ExpandMap(ctx, cfg.Headers)
+
+	return builtin.NewOpenAPITool(cfg), nil
+}
+
 func createFetchTool(_ context.Context, toolset latest.Toolset, _ string, _ *config.RuntimeConfig) (tools.ToolSet, error) {
 	var opts []builtin.FetchToolOption
 	if toolset.Timeout > 0 {
@@ -185,6 +240,14 @@ func createFetchTool(_ context.Context, toolset latest.Toolset, _ string, _ *con
 }
 
 func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runConfig *config.RuntimeConfig) (tools.ToolSet, error) {
+	var opts []mcp.ToolsetOpt
+	if toolset.ResourceTools {
+		opts = append(opts, mcp.WithResourceTools())
+	}
+	if toolset.Sampling != nil {
+		opts = append(opts, mcp.WithSampling(*toolset.Sampling))
+	}
+
 	// MCP tool has three different modes: ref, command, and remote
 	if toolset.Ref != "" {
 		mcpServerName := gateway.ParseServerRef(toolset.Ref)
@@ -195,7 +258,7 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 
 		// TODO(dga): until the MCP Gateway supports oauth with cagent, we fetch the remote url and directly connect to it.
 		if serverSpec.Type == "remote" {
-			return mcp.NewRemoteToolset(toolset.Name, serverSpec.Remote.URL, serverSpec.Remote.TransportType, nil), nil
+			return mcp.NewRemoteToolset(toolset.Name, serverSpec.Remote.URL, serverSpec.Remote.TransportType, nil, opts...), nil
 		}
 
 		env, err := environment.ExpandAll(ctx, environment.ToValues(toolset.Env), runConfig.EnvProvider())
@@ -208,7 +271,7 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 			runConfig.EnvProvider(),
 		)
 
-		return mcp.NewGatewayToolset(ctx, toolset.Name, mcpServerName, toolset.Config, envProvider, runConfig.WorkingDir)
+		return mcp.NewGatewayToolset(ctx, toolset.Name, mcpServerName, toolset.Config, envProvider, runConfig.WorkingDir, opts...)
 	}
 
 	if toolset.Command != "" {
@@ -217,7 +280,7 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 			return nil, fmt.Errorf("failed to expand the tool's environment variables: %w", err)
 		}
 		env = append(env, os.Environ()...)
-		return mcp.NewToolsetCommand(toolset.Name, toolset.Command, toolset.Args, env, runConfig.WorkingDir), nil
+		return mcp.NewToolsetCommand(toolset.Name, toolset.Command, toolset.Args, env, runConfig.WorkingDir, opts...), nil
 	}
 
 	if toolset.Remote.URL != "" {
@@ -231,7 +294,7 @@ func createMCPTool(ctx context.Context, toolset latest.Toolset, _ string, runCon
 			headers[k] = expanded
 		}
 
-		return mcp.NewRemoteToolset(toolset.Name, toolset.Remote.URL, toolset.Remote.TransportType, headers), nil
+		return mcp.NewRemoteToolset(toolset.Name, toolset.Remote.URL, toolset.Remote.TransportType, headers, opts...), nil
 	}
 
 	return nil, fmt.Errorf("mcp toolset requires either ref, command, or remote configuration")
@@ -11,10 +11,12 @@ import (
 	"github.com/docker/cagent/pkg/agent"
 	"github.com/docker/cagent/pkg/config"
 	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/hooks"
 	"github.com/docker/cagent/pkg/js"
 	"github.com/docker/cagent/pkg/model/provider"
 	"github.com/docker/cagent/pkg/model/provider/options"
 	"github.com/docker/cagent/pkg/modelsdev"
+	"github.com/docker/cagent/pkg/permissions"
 	"github.com/docker/cagent/pkg/rag"
 	"github.com/docker/cagent/pkg/team"
 	"github.com/docker/cagent/pkg/tools"
@@ -111,10 +113,24 @@ func Load(ctx context.Context, agentSource config.Source, runConfig *config.Runt
 			agent.WithAddPromptFiles(agentConfig.AddPromptFiles),
 			agent.WithMaxIterations(agentConfig.MaxIterations),
 			agent.WithNumHistoryItems(agentConfig.NumHistoryItems),
+			agent.WithToolConcurrency(agentConfig.ToolConcurrency),
+			agent.WithConcurrentTools(agentConfig.ConcurrentTools),
 			agent.WithCommands(expander.ExpandMap(ctx, agentConfig.Commands)),
 			agent.WithSkillsEnabled(skillsEnabled),
 		}
 
+		permissionChecker, err := permissions.NewChecker(agentConfig.Permissions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid permissions for agent %q: %w", name, err)
+		}
+		opts = append(opts, agent.WithPermissions(permissionChecker))
+
+		hooksRunner, err := hooks.NewRunner(agentConfig.Hooks)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hooks for agent %q: %w", name, err)
+		}
+		opts = append(opts, agent.WithHooks(hooksRunner))
+
 		models, err := getModelsForAgent(ctx, cfg, &agentConfig, autoModel, runConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get models: %w", err)
@@ -123,7 +139,14 @@ func Load(ctx context.Context, agentSource config.Source, runConfig *config.Runt
 			opts = append(opts, agent.WithModel(model))
 		}
 
+		fallbackModels, fallbackWarnings := getFallbackModelsForAgent(ctx, cfg, &agentConfig, autoModel, runConfig)
+		for _, model := range fallbackModels {
+			opts = append(opts, agent.WithFallbackModel(model))
+		}
+		opts = append(opts, agent.WithModelRetries(agentConfig.Fallback.GetRetries()))
+
 		agentTools, warnings := getToolsForAgent(ctx, &agentConfig, parentDir, runConfig, loadOpts.toolsetRegistry)
+		warnings = append(fallbackWarnings, warnings...)
 		if len(warnings) > 0 {
 			opts = append(opts, agent.WithLoadTimeWarnings(warnings))
 		}
@@ -178,45 +201,74 @@ func getModelsForAgent(ctx context.Context, cfg *latest.Config, a *latest.AgentC
 	var models []provider.Provider
 
 	for name := range strings.SplitSeq(a.Model, ",") {
-		modelCfg, exists := cfg.Models[name]
-		if !exists {
-			if name == "auto" {
-				modelCfg = autoModelFn()
-			} else {
-				return nil, fmt.Errorf("model '%s' not found in configuration", name)
-			}
-		}
-
-		opts := []options.Opt{
-			options.WithGateway(runConfig.ModelsGateway),
-			options.WithStructuredOutput(a.StructuredOutput),
-		}
-
-		maxTokens := &defaultMaxTokens
-		modelsStore, err := modelsdev.NewStore()
+		model, err := getModel(ctx, cfg, name, a, autoModelFn, runConfig)
 		if err != nil {
 			return nil, err
 		}
-		m, err := modelsStore.GetModel(ctx, modelCfg.Provider+"/"+modelCfg.Model)
-		if err == nil {
-			maxTokens = &m.Limit.Output
-		}
-		if maxTokens != nil {
-			opts = append(opts, options.WithMaxTokens(*maxTokens))
-		}
+		models = append(models, model)
+	}
+
+	return models, nil
+}
 
-		model, err := provider.New(ctx,
-			&modelCfg,
-			runConfig.EnvProvider(),
-			opts...,
-		)
+// getFallbackModelsForAgent creates the agent's fallback models. A fallback
+// model that can't be created is skipped with a warning rather than failing
+// the whole agent.
+func getFallbackModelsForAgent(ctx context.Context, cfg *latest.Config, a *latest.AgentConfig, autoModelFn func() latest.ModelConfig, runConfig *config.RuntimeConfig) ([]provider.Provider, []string) {
+	if a.Fallback == nil {
+		return nil, nil
+	}
+
+	var (
+		models   []provider.Provider
+		warnings []string
+	)
+	for _, name := range a.Fallback.Models {
+		model, err := getModel(ctx, cfg, name, a, autoModelFn, runConfig)
 		if err != nil {
-			return nil, err
+			slog.Warn("Fallback model creation failed; skipping", "model", name, "error", err)
+			warnings = append(warnings, fmt.Sprintf("fallback model %s: %v", name, err))
+			continue
 		}
 		models = append(models, model)
 	}
 
-	return models, nil
+	return models, warnings
+}
+
+func getModel(ctx context.Context, cfg *latest.Config, name string, a *latest.AgentConfig, autoModelFn func() latest.ModelConfig, runConfig *config.RuntimeConfig) (provider.Provider, error) {
+	modelCfg, exists := cfg.Models[name]
+	if !exists {
+		if name == "auto" {
+			modelCfg = autoModelFn()
+		} else {
+			return nil, fmt.Errorf("model '%s' not found in configuration", name)
+		}
+	}
+
+	opts := []options.Opt{
+		options.WithGateway(runConfig.ModelsGateway),
+		options.WithStructuredOutput(a.StructuredOutput),
+	}
+
+	maxTokens := &defaultMaxTokens
+	modelsStore, err := modelsdev.NewStore()
+	if err != nil {
+		return nil, err
+	}
+	m, err := modelsStore.GetModel(ctx, modelCfg.Provider+"/"+modelCfg.Model)
+	if err == nil {
+		maxTokens = &m.Limit.Output
+	}
+	if maxTokens != nil {
+		opts = append(opts, options.WithMaxTokens(*maxTokens))
+	}
+
+	return provider.New(ctx,
+		&modelCfg,
+		runConfig.EnvProvider(),
+		opts...,
+	)
 }
 
 // getToolsForAgent returns the tool definitions for an agent based on its configuration
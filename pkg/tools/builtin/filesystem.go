@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/bmatcuk/doublestar/v4"
 
+	"github.com/docker/cagent/pkg/checkpoint"
 	"github.com/docker/cagent/pkg/fsx"
 	"github.com/docker/cagent/pkg/tools"
 )
@@ -257,7 +259,7 @@ func (t *FilesystemTool) Tools(context.Context) ([]tools.Tool, error) {
 		{
 			Name:         ToolNameReadFile,
 			Category:     "filesystem",
-			Description:  "Read the complete contents of a file from the file system.",
+			Description:  "Read the complete contents of a file from the file system. Images are returned as images.",
 			Parameters:   tools.MustSchemaFor[ReadFileArgs](),
 			OutputSchema: tools.MustSchemaFor[string](),
 			Handler:      NewHandler(t.handleReadFile),
@@ -473,6 +475,10 @@ func (t *FilesystemTool) handleEditFile(ctx context.Context, args EditFileArgs)
 		changes = append(changes, fmt.Sprintf("Edit %d: Replaced %d characters", i+1, len(edit.OldText)))
 	}
 
+	if err := checkpoint.RecorderFromContext(ctx).Snapshot(args.Path); err != nil {
+		slog.Warn("Failed to snapshot file before editing it", "path", args.Path, "error", err)
+	}
+
 	if err := os.WriteFile(args.Path, []byte(modifiedContent), 0o644); err != nil {
 		return tools.ResultError(fmt.Sprintf("Error writing file: %s", err)), nil
 	}
@@ -618,6 +624,22 @@ func (t *FilesystemTool) handleReadFile(_ context.Context, args ReadFileArgs) (*
 		}, nil
 	}
 
+	// Images are returned as such, so that models that support them can look at them.
+	if mimeType := http.DetectContentType(content); strings.HasPrefix(mimeType, "image/") {
+		image := tools.Image{MIMEType: mimeType, Data: content}
+		if !image.Supported() {
+			return &tools.ToolCallResult{
+				Output: fmt.Sprintf("Image file %s %s", args.Path, image.Placeholder()),
+				Meta:   ReadFileMeta{},
+			}, nil
+		}
+		return &tools.ToolCallResult{
+			Output: fmt.Sprintf("Image file %s (%s, %d bytes)", args.Path, mimeType, len(content)),
+			Images: []tools.Image{image},
+			Meta:   ReadFileMeta{},
+		}, nil
+	}
+
 	return &tools.ToolCallResult{
 		Output: string(content),
 		Meta: ReadFileMeta{
@@ -866,6 +888,10 @@ func (t *FilesystemTool) handleWriteFile(ctx context.Context, args WriteFileArgs
 		return tools.ResultError(fmt.Sprintf("Error creating directory structure: %s", err)), nil
 	}
 
+	if err := checkpoint.RecorderFromContext(ctx).Snapshot(args.Path); err != nil {
+		slog.Warn("Failed to snapshot file before writing it", "path", args.Path, "error", err)
+	}
+
 	if err := os.WriteFile(args.Path, []byte(args.Content), 0o644); err != nil {
 		return tools.ResultError(fmt.Sprintf("Error writing file: %s", err)), nil
 	}
@@ -9,6 +9,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/checkpoint"
+	"github.com/docker/cagent/pkg/tools"
 )
 
 // initGitRepo initializes a git repository in the given directory
@@ -143,6 +146,31 @@ func TestFilesystemTool_WriteFile(t *testing.T) {
 	assert.Contains(t, result.Output, "not within allowed directories")
 }
 
+func TestFilesystemTool_WriteFile_Checkpoint(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	tool := NewFilesystemTool([]string{tmpDir})
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("original"), 0o644))
+
+	store := checkpoint.NewStore(t.TempDir())
+	ctx := checkpoint.WithRecorder(t.Context(), store.NewRecorder("sess", 0))
+
+	_, err := tool.handleWriteFile(ctx, WriteFileArgs{Path: testFile, Content: "written"})
+	require.NoError(t, err)
+	_, err = tool.handleEditFile(ctx, EditFileArgs{Path: testFile, Edits: []Edit{{OldText: "written", NewText: "edited"}}})
+	require.NoError(t, err)
+
+	restored, err := store.Restore("sess", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{testFile}, restored)
+
+	content, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(content))
+}
+
 func TestFilesystemTool_WriteFile_NestedDirectory(t *testing.T) {
 	t.Parallel()
 	tmpDir := t.TempDir()
@@ -198,6 +226,42 @@ func TestFilesystemTool_ReadFile(t *testing.T) {
 	assert.Contains(t, result.Output, "not within allowed directories")
 }
 
+func TestFilesystemTool_ReadImage(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	tool := NewFilesystemTool([]string{tmpDir})
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	testFile := filepath.Join(tmpDir, "screenshot.png")
+	require.NoError(t, os.WriteFile(testFile, png, 0o644))
+
+	result, err := tool.handleReadFile(t.Context(), ReadFileArgs{
+		Path: testFile,
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, result.Output, "image/png")
+	require.Len(t, result.Images, 1)
+	assert.Equal(t, "image/png", result.Images[0].MIMEType)
+	assert.Equal(t, png, result.Images[0].Data)
+
+	// Images that models don't support aren't sent to them.
+	bmp := filepath.Join(tmpDir, "icon.bmp")
+	require.NoError(t, os.WriteFile(bmp, []byte("BM\x00\x00\x00\x00"), 0o644))
+	result, err = tool.handleReadFile(t.Context(), ReadFileArgs{Path: bmp})
+	require.NoError(t, err)
+	assert.Empty(t, result.Images)
+	assert.Contains(t, result.Output, "image/bmp")
+	assert.Contains(t, result.Output, "not shown")
+
+	large := filepath.Join(tmpDir, "large.png")
+	require.NoError(t, os.WriteFile(large, append(png, make([]byte, tools.MaxImageSize)...), 0o644))
+	result, err = tool.handleReadFile(t.Context(), ReadFileArgs{Path: large})
+	require.NoError(t, err)
+	assert.Empty(t, result.Images)
+	assert.Contains(t, result.Output, "not shown")
+}
+
 func TestFilesystemTool_ReadMultipleFiles(t *testing.T) {
 	t.Parallel()
 	tmpDir := t.TempDir()
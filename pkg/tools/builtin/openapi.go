@@ -0,0 +1,654 @@
+package builtin
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/tools"
+	"github.com/docker/cagent/pkg/useragent"
+)
+
+// OpenAPITool exposes every operation of an OpenAPI 3 document as a tool.
+// The document is loaded the first time the tools are listed.
+type OpenAPITool struct {
+	tools.BaseToolSet
+	config latest.OpenAPIToolConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	tools []tools.Tool
+}
+
+var _ tools.ToolSet = (*OpenAPITool)(nil)
+
+// maxOpenAPINodes bounds the size of a document once its $refs are resolved,
+// since references used several times are copied as many times.
+const maxOpenAPINodes = 1 << 20
+
+// maxToolNameLength is the longest tool name models accept.
+const maxToolNameLength = 64
+
+var openAPIMethods = []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodOptions, http.MethodHead, http.MethodPatch}
+
+type openAPIDocument struct {
+	Servers []openAPIServer                       `json:"servers"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+type openAPIServer struct {
+	URL       string `json:"url"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description"`
+	Tags        []string                   `json:"tags"`
+	Parameters  []openAPIParameter         `json:"parameters"`
+	RequestBody *openAPIRequestBody        `json:"requestBody"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description"`
+	Required    bool           `json:"required"`
+	Schema      map[string]any `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Description string                      `json:"description"`
+	Required    bool                        `json:"required"`
+	Content     map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Content map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema map[string]any `json:"schema"`
+}
+
+// openAPIHandler calls a single operation.
+type openAPIHandler struct {
+	client      *http.Client
+	baseURL     string
+	method      string
+	path        string
+	parameters  []openAPIParameter
+	contentType string
+	bodyArg     string
+	headers     map[string]string
+}
+
+func NewOpenAPITool(config latest.OpenAPIToolConfig) *OpenAPITool {
+	return &OpenAPITool{
+		config: config,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+func (t *OpenAPITool) Tools(ctx context.Context) ([]tools.Tool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tools != nil {
+		return t.tools, nil
+	}
+
+	generated, err := t.generateTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading OpenAPI document %s: %w", t.config.Spec, err)
+	}
+
+	t.tools = generated
+	return generated, nil
+}
+
+func (t *OpenAPITool) generateTools(ctx context.Context) ([]tools.Tool, error) {
+	raw, err := t.readSpec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc openAPIDocument
+	if err := decodeOpenAPIDocument(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	baseURL, err := t.baseURL(doc.Servers)
+	if err != nil {
+		return nil, err
+	}
+	// The headers usually hold credentials: they are only sent to a server the user chose.
+	if len(t.config.Headers) > 0 && t.config.BaseURL == "" {
+		return nil, errors.New("base_url must be set with headers, so that they are only sent to a known server")
+	}
+
+	var result []tools.Tool
+	names := map[string]bool{}
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths)) {
+		item := doc.Paths[path]
+
+		var shared []openAPIParameter
+		if rawParams, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(rawParams, &shared); err != nil {
+				return nil, fmt.Errorf("invalid parameters for %s: %w", path, err)
+			}
+		}
+
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid path %q, paths must start with /", path)
+		}
+
+		for _, method := range openAPIMethods {
+			rawOp, ok := item[strings.ToLower(method)]
+			if !ok {
+				continue
+			}
+
+			var op openAPIOperation
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return nil, fmt.Errorf("invalid operation %s %s: %w", method, path, err)
+			}
+			if !t.keep(&op) {
+				continue
+			}
+
+			tool, err := t.newTool(&op, method, path, baseURL, mergeParameters(shared, op.Parameters))
+			if err != nil {
+				return nil, fmt.Errorf("operation %s %s: %w", method, path, err)
+			}
+
+			// Tool names must be unique.
+			name := tool.Name
+			for i := 2; names[tool.Name]; i++ {
+				tool.Name = sanitizeToolName(name + "_" + strconv.Itoa(i))
+			}
+			names[tool.Name] = true
+
+			result = append(result, tool)
+		}
+	}
+
+	slog.Debug("Generated tools from OpenAPI document", "spec", t.config.Spec, "tools", len(result))
+	return result, nil
+}
+
+// readSpec reads the document from a URL or a local file.
+func (t *OpenAPITool) readSpec(ctx context.Context) ([]byte, error) {
+	if !isHTTPURL(t.config.Spec) {
+		return os.ReadFile(t.config.Spec)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.config.Spec, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", useragent.Header)
+	for key, value := range t.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// baseURL returns the URL the operations are relative to.
+func (t *OpenAPITool) baseURL(servers []openAPIServer) (string, error) {
+	baseURL := t.config.BaseURL
+	if baseURL == "" && len(servers) > 0 {
+		baseURL = servers[0].URL
+		for name, variable := range servers[0].Variables {
+			baseURL = strings.ReplaceAll(baseURL, "{"+name+"}", variable.Default)
+		}
+	}
+
+	// Server URLs can be relative to the location of the document.
+	if isHTTPURL(t.config.Spec) && !isHTTPURL(baseURL) {
+		specURL, err := url.Parse(t.config.Spec)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(cmp.Or(baseURL, "/"))
+		if err != nil {
+			return "", fmt.Errorf("invalid server URL: %w", err)
+		}
+		baseURL = specURL.ResolveReference(ref).String()
+	}
+
+	if !isHTTPURL(baseURL) {
+		return "", errors.New("the document has no http(s) server, set base_url")
+	}
+
+	return strings.TrimSuffix(baseURL, "/"), nil
+}
+
+// keep applies the include and exclude filters, which match operationIds and tags.
+func (t *OpenAPITool) keep(op *openAPIOperation) bool {
+	matches := func(filter []string) bool {
+		return slices.Contains(filter, op.OperationID) || slices.ContainsFunc(op.Tags, func(tag string) bool {
+			return slices.Contains(filter, tag)
+		})
+	}
+
+	if len(t.config.Include) > 0 && !matches(t.config.Include) {
+		return false
+	}
+	return !matches(t.config.Exclude)
+}
+
+func (t *OpenAPITool) newTool(op *openAPIOperation, method, path, baseURL string, parameters []openAPIParameter) (tools.Tool, error) {
+	properties := map[string]any{}
+	var required []string
+
+	var kept []openAPIParameter
+	for _, param := range parameters {
+		// Cookies are left to the headers of the configuration.
+		if param.In == "cookie" {
+			continue
+		}
+		kept = append(kept, param)
+
+		schema := map[string]any{"type": "string"}
+		if param.Schema != nil {
+			schema = maps.Clone(param.Schema)
+		}
+		if param.Description != "" && schema["description"] == nil {
+			schema["description"] = param.Description
+		}
+		properties[param.Name] = schema
+
+		if param.Required || param.In == "path" {
+			required = append(required, param.Name)
+		}
+	}
+
+	contentType, bodyArg := "", ""
+	if op.RequestBody != nil {
+		var media openAPIMediaType
+		contentType, media = jsonMediaType(op.RequestBody.Content)
+		if contentType != "" {
+			schema := map[string]any{"type": "object"}
+			if media.Schema != nil {
+				schema = maps.Clone(media.Schema)
+			}
+			if op.RequestBody.Description != "" && schema["description"] == nil {
+				schema["description"] = op.RequestBody.Description
+			}
+			// The body mustn't take the place of a parameter with the same name.
+			bodyArg = "body"
+			for i := 2; properties[bodyArg] != nil; i++ {
+				bodyArg = "body_" + strconv.Itoa(i)
+			}
+			properties[bodyArg] = schema
+
+			if op.RequestBody.Required {
+				required = append(required, bodyArg)
+			}
+		}
+	}
+
+	inputSchema, err := tools.SchemaToMap(map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	})
+	if err != nil {
+		return tools.Tool{}, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	outputSchema := tools.MustSchemaFor[string]()
+	if schema := responseSchema(op.Responses); schema != nil {
+		outputSchema = schema
+	}
+
+	name := op.OperationID
+	if name == "" {
+		name = strings.ToLower(method) + path
+	}
+	name = sanitizeToolName(name)
+
+	handler := &openAPIHandler{
+		client:      t.client,
+		baseURL:     baseURL,
+		method:      method,
+		path:        path,
+		parameters:  kept,
+		contentType: contentType,
+		bodyArg:     bodyArg,
+		headers:     t.config.Headers,
+	}
+
+	description := strings.TrimSpace(op.Summary + "\n\n" + op.Description)
+	if description == "" {
+		description = method + " " + path
+	}
+
+	return tools.Tool{
+		Name:         name,
+		Category:     "api",
+		Description:  description,
+		Parameters:   inputSchema,
+		OutputSchema: outputSchema,
+		Handler:      handler.CallTool,
+		Annotations: tools.ToolAnnotations{
+			ReadOnlyHint: method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions,
+			Title:        cmp.Or(op.Summary, name),
+		},
+	}, nil
+}
+
+func (h *openAPIHandler) CallTool(ctx context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+	args := map[string]any{}
+	if toolCall.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	path := h.path
+	query := url.Values{}
+	headers := http.Header{}
+	for _, param := range h.parameters {
+		value, ok := args[param.Name]
+		if !ok || value == nil {
+			if param.In == "path" {
+				return tools.ResultError(fmt.Sprintf("missing required path parameter %q", param.Name)), nil
+			}
+			continue
+		}
+
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(parameterValue(value)))
+		case "query":
+			if values, ok := value.([]any); ok {
+				for _, v := range values {
+					query.Add(param.Name, parameterValue(v))
+				}
+			} else {
+				query.Add(param.Name, parameterValue(value))
+			}
+		case "header":
+			headers.Set(param.Name, parameterValue(value))
+		}
+	}
+
+	endpoint := h.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader = http.NoBody
+	if body, ok := args[h.bodyArg]; ok && h.bodyArg != "" {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, h.method, endpoint, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if base, err := url.Parse(h.baseURL); err != nil || req.URL.Scheme != base.Scheme || req.URL.Host != base.Host {
+		return nil, fmt.Errorf("refusing to send a request outside of %s", h.baseURL)
+	}
+
+	req.Header.Set("User-Agent", useragent.Header)
+	if reqBody != http.NoBody {
+		req.Header.Set("Content-Type", h.contentType)
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	for key, value := range h.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return tools.ResultError(limitOutput(fmt.Sprintf("%s %s returned %s\n%s", h.method, path, resp.Status, body))), nil
+	}
+
+	return tools.ResultSuccess(limitOutput(string(body))), nil
+}
+
+// decodeOpenAPIDocument decodes a JSON or YAML document, with all its local $refs resolved.
+func decodeOpenAPIDocument(raw []byte, doc *openAPIDocument) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		converted, err := yaml.YAMLToJSON(raw)
+		if err != nil {
+			return fmt.Errorf("invalid document: %w", err)
+		}
+		raw = converted
+	}
+
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return fmt.Errorf("invalid document: %w", err)
+	}
+
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return fmt.Errorf("unsupported OpenAPI version %q, only 3.x is supported", version)
+	}
+
+	resolver := &refResolver{root: root, cache: map[string]resolvedRef{}}
+	resolvedRoot, _ := resolver.resolve(root, nil)
+	if resolver.nodes > maxOpenAPINodes {
+		return fmt.Errorf("the document is too large once its references are resolved (more than %d nodes)", maxOpenAPINodes)
+	}
+
+	resolved, err := json.Marshal(resolvedRoot)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resolved, doc)
+}
+
+// refResolver replaces the local $refs of a document with what they point to.
+// Each reference is resolved once, and the number of nodes of the resolved
+// document is counted so that chains of references can't blow it up.
+type refResolver struct {
+	root  any
+	cache map[string]resolvedRef
+	nodes int
+}
+
+type resolvedRef struct {
+	value any
+	nodes int
+}
+
+// resolve returns the value with its references resolved, and its number of
+// nodes. Recursive references are replaced with an object schema.
+func (r *refResolver) resolve(value any, resolving []string) (any, int) {
+	if r.nodes > maxOpenAPINodes {
+		return nil, 0
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if slices.Contains(resolving, ref) {
+				r.nodes++
+				return map[string]any{"type": "object"}, 1
+			}
+			if cached, ok := r.cache[ref]; ok {
+				r.nodes += cached.nodes
+				return cached.value, cached.nodes
+			}
+			target, err := lookupRef(r.root, ref)
+			if err != nil {
+				slog.Warn("Unresolved reference in OpenAPI document", "ref", ref, "error", err)
+				r.nodes++
+				return map[string]any{}, 1
+			}
+			resolved, nodes := r.resolve(target, append(slices.Clip(resolving), ref))
+			r.cache[ref] = resolvedRef{value: resolved, nodes: nodes}
+			return resolved, nodes
+		}
+
+		r.nodes++
+		resolved, nodes := make(map[string]any, len(v)), 1
+		for key, child := range v {
+			var childNodes int
+			resolved[key], childNodes = r.resolve(child, resolving)
+			nodes += childNodes
+		}
+		return resolved, nodes
+	case []any:
+		r.nodes++
+		resolved, nodes := make([]any, len(v)), 1
+		for i, child := range v {
+			var childNodes int
+			resolved[i], childNodes = r.resolve(child, resolving)
+			nodes += childNodes
+		}
+		return resolved, nodes
+	default:
+		r.nodes++
+		return value, 1
+	}
+}
+
+// lookupRef follows a local JSON pointer such as #/components/schemas/Pet.
+func lookupRef(root any, ref string) (any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, errors.New("only local references are supported")
+	}
+
+	current := root
+	for token := range strings.SplitSeq(pointer, "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		if current, ok = m[token]; !ok {
+			return nil, errors.New("not found")
+		}
+	}
+	return current, nil
+}
+
+// mergeParameters combines the parameters of a path with the ones of an
+// operation, which take precedence.
+func mergeParameters(shared, own []openAPIParameter) []openAPIParameter {
+	merged := slices.Clone(own)
+	for _, param := range shared {
+		if !slices.ContainsFunc(own, func(p openAPIParameter) bool { return p.Name == param.Name && p.In == param.In }) {
+			merged = append(merged, param)
+		}
+	}
+	return merged
+}
+
+// jsonMediaType returns the first JSON content type of a request body.
+func jsonMediaType(content map[string]openAPIMediaType) (string, openAPIMediaType) {
+	for _, contentType := range slices.Sorted(maps.Keys(content)) {
+		mediaType, _, _ := strings.Cut(contentType, ";")
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return contentType, content[contentType]
+		}
+	}
+	return "", openAPIMediaType{}
+}
+
+// responseSchema returns the schema of the successful JSON response of an operation, if any.
+func responseSchema(responses map[string]openAPIResponse) map[string]any {
+	for _, status := range []string{"200", "201", "202", "2XX", "default"} {
+		response, ok := responses[status]
+		if !ok {
+			continue
+		}
+		if _, media := jsonMediaType(response.Content); media.Schema != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+func parameterValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		buf, _ := json.Marshal(v)
+		return string(buf)
+	}
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// sanitizeToolName turns an operationId, or a method and path, into a valid tool name.
+// Long names are truncated, and end with a hash of the full name to stay unique.
+func sanitizeToolName(name string) string {
+	name = strings.Trim(invalidToolNameChars.ReplaceAllString(name, "_"), "_")
+	if len(name) > maxToolNameLength {
+		sum := sha256.Sum256([]byte(name))
+		suffix := "_" + hex.EncodeToString(sum[:4])
+		name = name[:maxToolNameLength-len(suffix)] + suffix
+	}
+	return name
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
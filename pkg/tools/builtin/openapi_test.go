@@ -0,0 +1,382 @@
+package builtin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+const petstoreSpec = `openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: /api
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List pets
+      tags: [pets]
+      parameters:
+        - name: tag
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+        - name: X-Request-Id
+          in: header
+          schema:
+            type: string
+      responses:
+        "200":
+          description: The pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      operationId: createPet
+      summary: Create a pet
+      tags: [pets, admin]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "201":
+          description: Created
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        description: The id of the pet
+        schema:
+          type: integer
+    get:
+      summary: Get a pet
+      tags: [pets]
+      responses:
+        "200":
+          description: The pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+    delete:
+      operationId: deletePet
+      tags: [admin]
+      responses:
+        "204":
+          description: Deleted
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        parent:
+          $ref: '#/components/schemas/Pet'
+`
+
+type openAPITestServer struct {
+	url      string
+	requests []*http.Request
+	bodies   []string
+}
+
+func newOpenAPITestServer(t *testing.T) *openAPITestServer {
+	t.Helper()
+
+	ts := &openAPITestServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(petstoreSpec))
+	})
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts.requests = append(ts.requests, r)
+		ts.bodies = append(ts.bodies, string(body))
+
+		if r.URL.Path == "/api/pets/404" {
+			http.Error(w, "no such pet", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ts.url = server.URL
+
+	return ts
+}
+
+func toolsByName(t *testing.T, toolset tools.ToolSet) map[string]tools.Tool {
+	t.Helper()
+
+	allTools, err := toolset.Tools(t.Context())
+	require.NoError(t, err)
+
+	byName := map[string]tools.Tool{}
+	for _, tool := range allTools {
+		byName[tool.Name] = tool
+	}
+	return byName
+}
+
+func TestOpenAPITool_Tools(t *testing.T) {
+	t.Parallel()
+	ts := newOpenAPITestServer(t)
+
+	byName := toolsByName(t, NewOpenAPITool(latest.OpenAPIToolConfig{Spec: ts.url + "/openapi.yaml"}))
+	require.Len(t, byName, 4)
+	require.Contains(t, byName, "get_pets_petId")
+
+	listPets := byName["listPets"]
+	assert.Equal(t, "List pets", listPets.Description)
+	assert.True(t, listPets.Annotations.ReadOnlyHint)
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, listPets.Parameters.(map[string]any)["properties"].(map[string]any)["tag"])
+
+	// References are resolved, recursive ones are cut short.
+	output := listPets.OutputSchema.(map[string]any)
+	pet := output["items"].(map[string]any)
+	assert.Equal(t, []any{"name"}, pet["required"])
+	assert.Equal(t, map[string]any{"type": "object"}, pet["properties"].(map[string]any)["parent"])
+
+	getPet := byName["get_pets_petId"]
+	params := getPet.Parameters.(map[string]any)
+	assert.Equal(t, []any{"petId"}, params["required"])
+	assert.Equal(t, "The id of the pet", params["properties"].(map[string]any)["petId"].(map[string]any)["description"])
+
+	createPet := byName["createPet"]
+	assert.False(t, createPet.Annotations.ReadOnlyHint)
+	assert.Equal(t, []any{"body"}, createPet.Parameters.(map[string]any)["required"])
+	assert.Equal(t, tools.MustSchemaFor[string](), createPet.OutputSchema)
+}
+
+func TestOpenAPITool_Filters(t *testing.T) {
+	t.Parallel()
+	ts := newOpenAPITestServer(t)
+
+	byName := toolsByName(t, NewOpenAPITool(latest.OpenAPIToolConfig{
+		Spec:    ts.url + "/openapi.yaml",
+		Include: []string{"pets", "deletePet"},
+		Exclude: []string{"admin"},
+	}))
+
+	assert.Len(t, byName, 2)
+	assert.Contains(t, byName, "listPets")
+	assert.Contains(t, byName, "get_pets_petId")
+}
+
+func TestOpenAPITool_CallTool(t *testing.T) {
+	t.Parallel()
+	ts := newOpenAPITestServer(t)
+
+	// Headers are only sent to a configured server.
+	_, err := NewOpenAPITool(latest.OpenAPIToolConfig{
+		Spec:    ts.url + "/openapi.yaml",
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}).Tools(t.Context())
+	require.ErrorContains(t, err, "base_url must be set with headers")
+
+	byName := toolsByName(t, NewOpenAPITool(latest.OpenAPIToolConfig{
+		Spec:    ts.url + "/openapi.yaml",
+		BaseURL: ts.url + "/api",
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}))
+
+	result, err := byName["listPets"].Handler(t.Context(), tools.ToolCall{
+		Function: tools.FunctionCall{Arguments: `{"tag": ["cat", "dog"], "X-Request-Id": "42"}`},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"ok"}`, result.Output)
+
+	result, err = byName["createPet"].Handler(t.Context(), tools.ToolCall{
+		Function: tools.FunctionCall{Arguments: `{"body": {"name": "Rex"}}`},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	result, err = byName["get_pets_petId"].Handler(t.Context(), tools.ToolCall{
+		Function: tools.FunctionCall{Arguments: `{"petId": 404}`},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Output, "404 Not Found")
+	assert.Contains(t, result.Output, "no such pet")
+
+	require.Len(t, ts.requests, 3)
+
+	assert.Equal(t, "/api/pets?tag=cat&tag=dog", ts.requests[0].URL.String())
+	assert.Equal(t, "42", ts.requests[0].Header.Get("X-Request-Id"))
+	assert.Equal(t, "Bearer secret", ts.requests[0].Header.Get("Authorization"))
+
+	assert.Equal(t, http.MethodPost, ts.requests[1].Method)
+	assert.Equal(t, "application/json", ts.requests[1].Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"name": "Rex"}`, ts.bodies[1])
+
+	assert.Equal(t, "/api/pets/404", ts.requests[2].URL.Path)
+}
+
+func TestOpenAPITool_LocalFile(t *testing.T) {
+	t.Parallel()
+	ts := newOpenAPITestServer(t)
+
+	spec := map[string]any{
+		"openapi": "3.1.0",
+		"paths": map[string]any{
+			"/pets/{petId}": map[string]any{
+				"get": map[string]any{
+					"operationId": "getPet",
+					"parameters":  []any{map[string]any{"name": "petId", "in": "path", "required": true}},
+				},
+			},
+		},
+	}
+	buf, err := json.Marshal(spec)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	require.NoError(t, os.WriteFile(path, buf, 0o600))
+
+	// Without servers, the base URL must be configured.
+	_, err = NewOpenAPITool(latest.OpenAPIToolConfig{Spec: path}).Tools(t.Context())
+	require.ErrorContains(t, err, "set base_url")
+
+	byName := toolsByName(t, NewOpenAPITool(latest.OpenAPIToolConfig{Spec: path, BaseURL: ts.url + "/api/"}))
+	require.Contains(t, byName, "getPet")
+
+	result, err := byName["getPet"].Handler(t.Context(), tools.ToolCall{})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	result, err = byName["getPet"].Handler(t.Context(), tools.ToolCall{
+		Function: tools.FunctionCall{Arguments: `{"petId": "a b"}`},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "/api/pets/a%20b", ts.requests[0].URL.EscapedPath())
+}
+
+func TestOpenAPITool_UnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "swagger.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("swagger: '2.0'\npaths: {}\n"), 0o600))
+
+	_, err := NewOpenAPITool(latest.OpenAPIToolConfig{Spec: path}).Tools(t.Context())
+	require.ErrorContains(t, err, "only 3.x is supported")
+}
+
+func TestOpenAPITool_BodyParameterCollision(t *testing.T) {
+	t.Parallel()
+	ts := newOpenAPITestServer(t)
+
+	path := writeOpenAPISpec(t, map[string]any{
+		"openapi": "3.0.3",
+		"paths": map[string]any{
+			"/notes": map[string]any{
+				"post": map[string]any{
+					"operationId": "createNote",
+					"parameters":  []any{map[string]any{"name": "body", "in": "query", "required": true}},
+					"requestBody": map[string]any{
+						"required": true,
+						"content":  map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}},
+					},
+				},
+			},
+		},
+	})
+
+	createNote := toolsByName(t, NewOpenAPITool(latest.OpenAPIToolConfig{Spec: path, BaseURL: ts.url + "/api"}))["createNote"]
+	assert.Equal(t, []any{"body", "body_2"}, createNote.Parameters.(map[string]any)["required"])
+
+	result, err := createNote.Handler(t.Context(), tools.ToolCall{
+		Function: tools.FunctionCall{Arguments: `{"body": "markdown", "body_2": {"text": "Hello"}}`},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	require.Len(t, ts.requests, 1)
+	assert.Equal(t, "/api/notes?body=markdown", ts.requests[0].URL.String())
+	assert.JSONEq(t, `{"text": "Hello"}`, ts.bodies[0])
+}
+
+func TestOpenAPITool_LongNames(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", 70)
+	path := writeOpenAPISpec(t, map[string]any{
+		"openapi": "3.0.3",
+		"paths": map[string]any{
+			"/first":  map[string]any{"get": map[string]any{"operationId": long + "_first"}},
+			"/second": map[string]any{"get": map[string]any{"operationId": long + "_second"}},
+		},
+	})
+
+	allTools, err := NewOpenAPITool(latest.OpenAPIToolConfig{Spec: path, BaseURL: "https://api.example.com"}).Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 2)
+	for _, tool := range allTools {
+		assert.Len(t, tool.Name, 64)
+	}
+	assert.NotEqual(t, allTools[0].Name, allTools[1].Name)
+}
+
+func TestOpenAPITool_ReferenceBlowUp(t *testing.T) {
+	t.Parallel()
+
+	// Each schema references the next one twice: inlining doubles the size at every level.
+	schemas := map[string]any{"S40": map[string]any{"type": "string"}}
+	for i := range 40 {
+		next := map[string]any{"$ref": "#/components/schemas/S" + strconv.Itoa(i+1)}
+		schemas["S"+strconv.Itoa(i)] = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"left": next, "right": next},
+		}
+	}
+	path := writeOpenAPISpec(t, map[string]any{
+		"openapi": "3.0.3",
+		"paths": map[string]any{
+			"/tree": map[string]any{"get": map[string]any{
+				"operationId": "getTree",
+				"responses": map[string]any{"200": map[string]any{
+					"content": map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/S0"}}},
+				}},
+			}},
+		},
+		"components": map[string]any{"schemas": schemas},
+	})
+
+	_, err := NewOpenAPITool(latest.OpenAPIToolConfig{Spec: path, BaseURL: "https://api.example.com"}).Tools(t.Context())
+	require.ErrorContains(t, err, "too large")
+}
+
+func writeOpenAPISpec(t *testing.T, spec map[string]any) string {
+	t.Helper()
+
+	buf, err := json.Marshal(spec)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	require.NoError(t, os.WriteFile(path, buf, 0o600))
+	return path
+}
@@ -38,6 +38,9 @@ type QueryResult struct {
 	Content    string  `json:"content" jsonschema:"Relevant document chunk content"`
 	Similarity float64 `json:"similarity" jsonschema:"Similarity score (0-1)"`
 	ChunkIndex int     `json:"chunk_index" jsonschema:"Index of the chunk within the source document"`
+	StartLine  int     `json:"start_line,omitempty" jsonschema:"First line of the chunk in the source document, when known"`
+	EndLine    int     `json:"end_line,omitempty" jsonschema:"Last line of the chunk in the source document, when known"`
+	Location   string  `json:"location,omitempty" jsonschema:"Location of the chunk to cite, as path:start-end"`
 }
 
 func (t *RAGTool) Instructions() string {
@@ -60,7 +63,7 @@ func (t *RAGTool) Tools(context.Context) ([]tools.Tool, error) {
 	}
 	description = cmp.Or(description, fmt.Sprintf("Search project documents from %s to find relevant code or documentation. "+
 		"Provide a natural language query describing what you need. "+
-		"Returns the most relevant document chunks with file paths.", t.toolName))
+		"Returns the most relevant document chunks with file paths, and line ranges for code to cite as path:start-end.", t.toolName))
 
 	paramsSchema := tools.MustSchemaFor[QueryRAGArgs]()
 	outputSchema := tools.MustSchemaFor[[]QueryResult]()
@@ -118,6 +121,9 @@ func (t *RAGTool) handleQueryRAG(ctx context.Context, args QueryRAGArgs) (*tools
 			Content:    result.Document.Content,
 			Similarity: result.Similarity,
 			ChunkIndex: result.Document.ChunkIndex,
+			StartLine:  result.Document.StartLine,
+			EndLine:    result.Document.EndLine,
+			Location:   location(result.Document.SourcePath, result.Document.StartLine, result.Document.EndLine),
 		})
 	}
 
@@ -136,6 +142,19 @@ func (t *RAGTool) handleQueryRAG(ctx context.Context, args QueryRAGArgs) (*tools
 	return tools.ResultSuccess(string(resultJSON)), nil
 }
 
+// location formats the location of a chunk as path:start-end, or returns
+// an empty string if its lines are unknown.
+func location(path string, startLine, endLine int) string {
+	switch {
+	case startLine <= 0:
+		return ""
+	case endLine <= startLine:
+		return fmt.Sprintf("%s:%d", path, startLine)
+	default:
+		return fmt.Sprintf("%s:%d-%d", path, startLine, endLine)
+	}
+}
+
 // sortResults sorts query results by similarity in descending order
 func sortResults(results []QueryResult) {
 	for i := range results {
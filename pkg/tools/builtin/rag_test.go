@@ -77,3 +77,9 @@ func TestRAGTool_SortResults(t *testing.T) {
 	assert.Equal(t, "a.txt", results[2].SourcePath)
 	assert.Equal(t, "c.txt", results[3].SourcePath)
 }
+
+func TestRAGTool_Location(t *testing.T) {
+	assert.Equal(t, "pkg/calc.py:10-42", location("pkg/calc.py", 10, 42))
+	assert.Equal(t, "pkg/calc.py:7", location("pkg/calc.py", 7, 7))
+	assert.Empty(t, location("README.md", 0, 0))
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/sandbox"
 	"github.com/docker/cagent/pkg/tools"
 )
 
@@ -18,21 +19,35 @@ type ScriptShellTool struct {
 	tools.BaseToolSet
 	shellTools map[string]latest.ScriptShellToolConfig
 	env        []string
+	sandbox    *sandbox.Sandbox
 }
 
 var _ tools.ToolSet = (*ScriptShellTool)(nil)
 
-func NewScriptShellTool(shellTools map[string]latest.ScriptShellToolConfig, env []string) (*ScriptShellTool, error) {
+type ScriptShellToolOpt func(*ScriptShellTool)
+
+// WithScriptSandbox runs the scripts in a sandbox instead of on the host.
+func WithScriptSandbox(sb *sandbox.Sandbox) ScriptShellToolOpt {
+	return func(t *ScriptShellTool) {
+		t.sandbox = sb
+	}
+}
+
+func NewScriptShellTool(shellTools map[string]latest.ScriptShellToolConfig, env []string, opts ...ScriptShellToolOpt) (*ScriptShellTool, error) {
 	for toolName, tool := range shellTools {
 		if err := validateConfig(toolName, tool); err != nil {
 			return nil, err
 		}
 	}
 
-	return &ScriptShellTool{
+	t := &ScriptShellTool{
 		shellTools: shellTools,
 		env:        env,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
 }
 
 func validateConfig(toolName string, tool latest.ScriptShellToolConfig) error {
@@ -136,22 +151,37 @@ func (t *ScriptShellTool) execute(ctx context.Context, toolConfig *latest.Script
 		}
 	}
 
-	// Use default shell
-	shell := cmp.Or(os.Getenv("SHELL"), "/bin/sh")
-
-	cmd := exec.CommandContext(ctx, shell, "-c", toolConfig.Cmd)
-	cmd.Dir = toolConfig.WorkingDir
-	cmd.Env = t.env
+	env := slices.Clone(t.env)
 	for key, value := range params {
 		if value != nil {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+			env = append(env, fmt.Sprintf("%s=%s", key, value))
 		}
 	}
 
-	output, err := cmd.CombinedOutput()
+	var output []byte
+	var err error
+	if t.sandbox != nil {
+		output, err = t.sandbox.Run(ctx, toolConfig.Cmd, toolConfig.WorkingDir, env)
+	} else {
+		// Use default shell
+		shell := cmp.Or(os.Getenv("SHELL"), "/bin/sh")
+
+		cmd := exec.CommandContext(ctx, shell, "-c", toolConfig.Cmd)
+		cmd.Dir = toolConfig.WorkingDir
+		cmd.Env = env
+
+		output, err = cmd.CombinedOutput()
+	}
 	if err != nil {
 		return tools.ResultError(fmt.Sprintf("Error executing command '%s': %s\nOutput: %s", toolConfig.Cmd, err, limitOutput(string(output)))), nil
 	}
 
 	return tools.ResultSuccess(limitOutput(string(output))), nil
 }
+
+func (t *ScriptShellTool) Stop(ctx context.Context) error {
+	if t.sandbox != nil {
+		return t.sandbox.Close(ctx)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"cmp"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"runtime"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/docker/cagent/pkg/concurrent"
 	"github.com/docker/cagent/pkg/config"
+	"github.com/docker/cagent/pkg/sandbox"
 	"github.com/docker/cagent/pkg/tools"
 )
 
@@ -42,8 +44,24 @@ type shellHandler struct {
 	workingDir      string
 	jobs            *concurrent.Map[string, *backgroundJob]
 	jobCounter      atomic.Int64
+	sandbox         *sandbox.Sandbox
 }
 
+// process is a shell command started on the host or in the sandbox
+type process interface {
+	Wait() error
+	Kill() error
+}
+
+type hostProcess struct {
+	cmd *exec.Cmd
+	pg  *processGroup
+}
+
+func (p *hostProcess) Wait() error { return p.cmd.Wait() }
+
+func (p *hostProcess) Kill() error { return kill(p.cmd.Process, p.pg) }
+
 // Job status constants
 const (
 	statusRunning int32 = iota
@@ -54,17 +72,16 @@ const (
 
 // backgroundJob tracks a background shell command
 type backgroundJob struct {
-	id           string
-	cmd          string
-	cwd          string
-	process      *os.Process
-	processGroup *processGroup
-	outputMu     sync.RWMutex
-	output       *bytes.Buffer
-	startTime    time.Time
-	status       atomic.Int32
-	exitCode     int
-	err          error
+	id        string
+	cmd       string
+	cwd       string
+	process   process
+	outputMu  sync.RWMutex
+	output    *bytes.Buffer
+	startTime time.Time
+	status    atomic.Int32
+	exitCode  int
+	err       error
 }
 
 // limitedWriter wraps a buffer and stops writing after maxSize bytes
@@ -142,39 +159,22 @@ func (h *shellHandler) RunShell(ctx context.Context, params RunShellArgs) (*tool
 	timeoutCtx, cancel := context.WithTimeout(ctx, effectiveTimeout)
 	defer cancel()
 
-	cmd := exec.Command(h.shell, append(h.shellArgsPrefix, params.Cmd)...)
-	cmd.Env = h.env
-	cmd.Dir = params.Cwd
-	if params.Cwd == "" || params.Cwd == "." {
-		cmd.Dir = h.workingDir
-	}
-
-	cmd.SysProcAttr = platformSpecificSysProcAttr()
-
+	// The output is streamed while the command runs, but only the buffered output is returned to the model.
 	var outBuf bytes.Buffer
-	cmd.Stdout = &outBuf
-	cmd.Stderr = &outBuf
-
-	if err := cmd.Start(); err != nil {
-		return tools.ResultError(fmt.Sprintf("Error starting command: %s", err)), nil
-	}
-
-	pg, err := createProcessGroup(cmd.Process)
+	proc, err := h.start(ctx, params.Cmd, params.Cwd, io.MultiWriter(&outBuf, tools.OutputWriter(ctx)))
 	if err != nil {
-		return tools.ResultError(fmt.Sprintf("Error creating process group: %s", err)), nil
+		return tools.ResultError(fmt.Sprintf("Error starting command: %s", err)), nil
 	}
 
 	done := make(chan error, 1)
 	go func() {
-		done <- cmd.Wait()
+		done <- proc.Wait()
 	}()
 
 	var output string
 	select {
 	case <-timeoutCtx.Done():
-		if cmd.Process != nil {
-			_ = kill(cmd.Process, pg)
-		}
+		_ = proc.Kill()
 
 		if ctx.Err() != nil {
 			output = "Command cancelled"
@@ -196,55 +196,36 @@ func (h *shellHandler) RunShell(ctx context.Context, params RunShellArgs) (*tool
 	return tools.ResultSuccess(limitOutput(output)), nil
 }
 
-func (h *shellHandler) RunShellBackground(_ context.Context, params RunShellBackgroundArgs) (*tools.ToolCallResult, error) {
+func (h *shellHandler) RunShellBackground(ctx context.Context, params RunShellBackgroundArgs) (*tools.ToolCallResult, error) {
 	// Generate unique job ID
 	counter := h.jobCounter.Add(1)
 	jobID := fmt.Sprintf("job_%d_%d", time.Now().Unix(), counter)
 
-	// Setup command (no context - background jobs run independently)
-	cmd := exec.Command(h.shell, append(h.shellArgsPrefix, params.Cmd)...)
-	cmd.Env = h.env
-	cmd.Dir = params.Cwd
-	if params.Cwd == "" || params.Cwd == "." {
-		cmd.Dir = h.workingDir
-	}
-
-	cmd.SysProcAttr = platformSpecificSysProcAttr()
-
 	// Create output buffer with 10MB limit
 	outputBuf := &bytes.Buffer{}
 	limitedWriter := &limitedWriter{buf: outputBuf, maxSize: 10 * 1024 * 1024}
-	cmd.Stdout = limitedWriter
-	cmd.Stderr = limitedWriter
-
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return tools.ResultError(fmt.Sprintf("Error starting background command: %s", err)), nil
-	}
 
-	// Create process group
-	pg, err := createProcessGroup(cmd.Process)
+	// Start the command (the context is only used to start the sandbox - background jobs run independently)
+	proc, err := h.start(ctx, params.Cmd, params.Cwd, limitedWriter)
 	if err != nil {
-		_ = kill(cmd.Process, pg)
-		return tools.ResultError(fmt.Sprintf("Error creating process group: %s", err)), nil
+		return tools.ResultError(fmt.Sprintf("Error starting background command: %s", err)), nil
 	}
 
 	// Create and store job
 	job := &backgroundJob{
-		id:           jobID,
-		cmd:          params.Cmd,
-		cwd:          params.Cwd,
-		process:      cmd.Process,
-		processGroup: pg,
-		output:       outputBuf,
-		startTime:    time.Now(),
+		id:        jobID,
+		cmd:       params.Cmd,
+		cwd:       params.Cwd,
+		process:   proc,
+		output:    outputBuf,
+		startTime: time.Now(),
 	}
 	job.status.Store(statusRunning)
 	h.jobs.Store(jobID, job)
 
 	// Monitor job completion in background
 	go func() {
-		err := cmd.Wait()
+		err := proc.Wait()
 
 		job.outputMu.Lock()
 		defer job.outputMu.Unlock()
@@ -353,14 +334,57 @@ func (h *shellHandler) StopBackgroundJob(_ context.Context, params StopBackgroun
 	}
 
 	// Kill the process
-	if err := kill(job.process, job.processGroup); err != nil {
+	if err := job.process.Kill(); err != nil {
 		return tools.ResultError(fmt.Sprintf("Job %s marked as stopped, but error killing process: %s", params.JobID, err)), nil
 	}
 
 	return tools.ResultSuccess(fmt.Sprintf("Job %s stopped successfully", params.JobID)), nil
 }
 
-func NewShellTool(env []string, runConfig *config.RuntimeConfig) *ShellTool {
+// start starts a shell command, in the sandbox if there's one.
+// Host commands run in their own process group so that they can be killed with their children.
+func (h *shellHandler) start(ctx context.Context, command, cwd string, output io.Writer) (process, error) {
+	if h.sandbox != nil {
+		if cwd == "." {
+			cwd = ""
+		}
+		return h.sandbox.Start(ctx, command, cwd, h.env, output)
+	}
+
+	cmd := exec.Command(h.shell, append(h.shellArgsPrefix, command)...)
+	cmd.Env = h.env
+	cmd.Dir = cwd
+	if cwd == "" || cwd == "." {
+		cmd.Dir = h.workingDir
+	}
+
+	cmd.SysProcAttr = platformSpecificSysProcAttr()
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	pg, err := createProcessGroup(cmd.Process)
+	if err != nil {
+		_ = kill(cmd.Process, pg)
+		return nil, fmt.Errorf("creating process group: %w", err)
+	}
+
+	return &hostProcess{cmd: cmd, pg: pg}, nil
+}
+
+type ShellToolOpt func(*ShellTool)
+
+// WithShellSandbox runs the commands, including background jobs, in a sandbox instead of on the host.
+func WithShellSandbox(sb *sandbox.Sandbox) ShellToolOpt {
+	return func(t *ShellTool) {
+		t.handler.sandbox = sb
+	}
+}
+
+func NewShellTool(env []string, runConfig *config.RuntimeConfig, opts ...ShellToolOpt) *ShellTool {
 	var shell string
 	var argsPrefix []string
 
@@ -387,7 +411,7 @@ func NewShellTool(env []string, runConfig *config.RuntimeConfig) *ShellTool {
 		argsPrefix = []string{"-c"}
 	}
 
-	return &ShellTool{
+	t := &ShellTool{
 		handler: &shellHandler{
 			shell:           shell,
 			shellArgsPrefix: argsPrefix,
@@ -397,10 +421,28 @@ func NewShellTool(env []string, runConfig *config.RuntimeConfig) *ShellTool {
 			workingDir:      runConfig.WorkingDir,
 		},
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 func (t *ShellTool) Instructions() string {
-	return `# Shell Tool Usage Guide
+	if t.handler.sandbox != nil {
+		return shellInstructions + sandboxInstructions
+	}
+	return shellInstructions
+}
+
+const sandboxInstructions = `
+
+## Sandbox
+
+Commands, including background jobs, run with /bin/sh inside an isolated container, not on the user's machine.
+Only the working directory is shared with the host, at the same path. Environment variables of the user are not available,
+and the network may be disabled. Install missing tools inside the container if needed.`
+
+const shellInstructions = `# Shell Tool Usage Guide
 
 Execute shell commands in the user's environment with full control over working directories and command parameters.
 
@@ -565,7 +607,6 @@ Use list_background_jobs to see all jobs with their status
 3. Perform tasks: use other tools while services run
 4. Check logs: view_background_job to see service output
 5. Cleanup: stop_background_job for each service (or let agent cleanup automatically)`
-}
 
 func (t *ShellTool) Tools(context.Context) ([]tools.Tool, error) {
 	return []tools.Tool{
@@ -628,13 +669,17 @@ func (t *ShellTool) Tools(context.Context) ([]tools.Tool, error) {
 	}, nil
 }
 
-func (t *ShellTool) Stop(context.Context) error {
+func (t *ShellTool) Stop(ctx context.Context) error {
 	// Terminate all running background jobs
 	t.handler.jobs.Range(func(_ string, job *backgroundJob) bool {
 		if job.status.CompareAndSwap(statusRunning, statusStopped) {
-			_ = kill(job.process, job.processGroup)
+			_ = job.process.Kill()
 		}
 		return true
 	})
+
+	if t.handler.sandbox != nil {
+		return t.handler.sandbox.Close(ctx)
+	}
 	return nil
 }
@@ -1,12 +1,17 @@
 package builtin
 
 import (
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/docker/cagent/pkg/config"
+	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/sandbox"
 	"github.com/docker/cagent/pkg/tools"
 )
 
@@ -121,3 +126,54 @@ func TestShellTool_ListBackgroundJobs(t *testing.T) {
 	assert.Contains(t, listResult.Output, "Background Jobs:")
 	assert.Contains(t, listResult.Output, "ID: job_")
 }
+
+func TestShellTool_StreamsOutput(t *testing.T) {
+	tool := NewShellTool(nil, &config.RuntimeConfig{Config: config.Config{WorkingDir: t.TempDir()}})
+
+	var mu sync.Mutex
+	var streamed strings.Builder
+	ctx := tools.WithOutput(t.Context(), func(delta string) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed.WriteString(delta)
+	})
+
+	result, err := tool.handler.RunShell(ctx, RunShellArgs{Cmd: "echo out; echo err >&2"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "out\nerr\n", result.Output)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, result.Output, streamed.String())
+}
+
+func TestShellTool_Sandbox(t *testing.T) {
+	t.Setenv("CAGENT_SHELL_HOST_SECRET", "leaked")
+	wd := t.TempDir()
+	sb := sandbox.New(&latest.SandboxConfig{}, wd, sandbox.NewLocalRunner())
+	tool := NewShellTool([]string{"GREETING=hello"}, &config.RuntimeConfig{Config: config.Config{WorkingDir: wd}}, WithShellSandbox(sb))
+	t.Cleanup(func() {
+		_ = tool.Stop(t.Context())
+	})
+
+	assert.Contains(t, tool.Instructions(), "isolated container")
+
+	result, err := tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: `pwd; echo "[$GREETING] [$CAGENT_SHELL_HOST_SECRET]"`, Cwd: "."})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, wd)
+	assert.Contains(t, result.Output, "[hello] []")
+
+	result, err = tool.handler.RunShell(t.Context(), RunShellArgs{Cmd: "sleep 30", Timeout: 1})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "Command timed out")
+
+	// Background jobs run in the sandbox too.
+	result, err = tool.handler.RunShellBackground(t.Context(), RunShellBackgroundArgs{Cmd: "sleep 30"})
+	require.NoError(t, err)
+	jobID := strings.TrimPrefix(strings.Split(result.Output, "\n")[0], "Background job started with ID: ")
+
+	require.Eventually(t, func() bool {
+		result, err := tool.handler.StopBackgroundJob(t.Context(), StopBackgroundJobArgs{JobID: jobID})
+		return err == nil && !result.IsError
+	}, 5*time.Second, 50*time.Millisecond)
+}
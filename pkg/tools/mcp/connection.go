@@ -0,0 +1,210 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ConnectionState is the state of the connection to an MCP server.
+type ConnectionState string
+
+const (
+	StateConnected    ConnectionState = "connected"
+	StateReconnecting ConnectionState = "reconnecting"
+	StateFailed       ConnectionState = "failed"
+)
+
+// ConnectionStateHandler is called when the connection to an MCP server changes state.
+// err is the reason why the connection was lost or couldn't be restored.
+type ConnectionStateHandler func(state ConnectionState, err error)
+
+// keepAliveInterval is how often servers are pinged to check they're still alive.
+// The session ends if a ping fails.
+const keepAliveInterval = 30 * time.Second
+
+// Lost connections are restored with exponential backoff. After maxReconnectAttempts
+// failures, the toolset is marked as failed and reconnects on its next use.
+var (
+	reconnectBackoff     = time.Second
+	maxReconnectBackoff  = 30 * time.Second
+	maxReconnectAttempts = 5
+)
+
+// ConnectionState returns the state of the connection to the server.
+func (ts *Toolset) ConnectionState() ConnectionState {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.state
+}
+
+// SetConnectionStateHandler sets the function called when the connection to the server changes state.
+func (ts *Toolset) SetConnectionStateHandler(handler ConnectionStateHandler) {
+	ts.mu.Lock()
+	ts.stateHandler = handler
+	ts.mu.Unlock()
+}
+
+func (ts *Toolset) setState(state ConnectionState, err error) {
+	ts.mu.Lock()
+	changed := ts.state != state
+	ts.state = state
+	handler := ts.stateHandler
+	ts.mu.Unlock()
+
+	if changed && handler != nil {
+		handler(state, err)
+	}
+}
+
+// currentSession returns the number of the current session with the server,
+// which increases every time the session is restarted.
+func (ts *Toolset) currentSession() uint64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.session
+}
+
+// watch waits for the session to end and restores it, until ctx is canceled.
+func (ts *Toolset) watch(ctx context.Context) {
+	for {
+		session := ts.currentSession()
+		err := ts.mcpClient.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+		if ts.currentSession() != session {
+			// A request restarted the session in the meantime: watch the new one.
+			continue
+		}
+
+		slog.Warn("Lost connection to MCP server", "server", ts.logID, "error", err)
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		if !ts.reconnect(ctx, session, err) {
+			return
+		}
+	}
+}
+
+// reconnect restores the session with exponential backoff. It returns false if it gave up.
+func (ts *Toolset) reconnect(ctx context.Context, session uint64, cause error) bool {
+	ts.setState(StateReconnecting, cause)
+
+	backoff := reconnectBackoff
+	err := cause
+	for attempt := 1; attempt <= maxReconnectAttempts; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return false
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+
+		slog.Debug("Reconnecting to MCP server", "server", ts.logID, "attempt", attempt)
+		if err = ts.restart(ctx, session); err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		slog.Warn("Failed to reconnect to MCP server", "server", ts.logID, "attempt", attempt, "error", err)
+	}
+
+	ts.setState(StateFailed, err)
+	return false
+}
+
+// ensureConnected tries to reconnect once to a server the toolset gave up on.
+func (ts *Toolset) ensureConnected(ctx context.Context) error {
+	if ts.ConnectionState() != StateFailed {
+		return nil
+	}
+
+	ts.mu.Lock()
+	if ts.stopWatching == nil {
+		// The toolset was stopped.
+		ts.mu.Unlock()
+		return nil
+	}
+	ts.stopWatching()
+	watchCtx, cancel := context.WithCancel(context.Background())
+	ts.stopWatching = cancel
+	ts.mu.Unlock()
+
+	if err := ts.restart(context.WithoutCancel(ctx), ts.currentSession()); err != nil {
+		return err
+	}
+	go ts.watch(watchCtx)
+	return nil
+}
+
+// reconnectNow restores a session that a request found closed, without waiting
+// for the watcher to notice.
+func (ts *Toolset) reconnectNow(ctx context.Context, session uint64, cause error) error {
+	ts.mu.Lock()
+	stopped := ts.stopWatching == nil
+	ts.mu.Unlock()
+	if stopped {
+		return cause
+	}
+
+	slog.Warn("MCP session closed", "server", ts.logID, "error", cause)
+	ts.setState(StateReconnecting, cause)
+	return ts.restart(context.WithoutCancel(ctx), session)
+}
+
+// isSessionClosed returns true if a request failed because the session with the server ended.
+func isSessionClosed(err error) bool {
+	return errors.Is(err, mcp.ErrConnectionClosed)
+}
+
+// restart closes the given dead session and replays the initialization: the
+// tools are listed again and the resources are subscribed to again. Sessions
+// that were already restarted are left alone.
+func (ts *Toolset) restart(ctx context.Context, session uint64) error {
+	ts.reconnectMu.Lock()
+	defer ts.reconnectMu.Unlock()
+
+	if ts.currentSession() != session {
+		return nil
+	}
+
+	// Closing the session stops the server process of stdio servers.
+	_ = ts.mcpClient.Close(ctx)
+	if err := ts.initialize(ctx); err != nil {
+		return err
+	}
+
+	ts.mu.Lock()
+	ts.session++
+	ts.invalidateTools()
+	subscribed := make([]string, 0, len(ts.subscribed))
+	for uri := range ts.subscribed {
+		subscribed = append(subscribed, uri)
+	}
+	ts.subscribed = map[string]bool{}
+	toolsChanged := ts.toolsChangedHandler
+	ts.mu.Unlock()
+
+	slices.Sort(subscribed)
+	for _, uri := range subscribed {
+		if err := ts.Subscribe(ctx, uri); err != nil {
+			slog.Warn("Failed to subscribe again to MCP resource", "server", ts.logID, "uri", uri, "error", err)
+		}
+	}
+
+	slog.Info("Reconnected to MCP server", "server", ts.logID)
+	ts.setState(StateConnected, nil)
+
+	// The server might have come back with different tools.
+	if toolsChanged != nil {
+		toolsChanged()
+	}
+	return nil
+}
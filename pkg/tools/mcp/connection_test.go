@@ -0,0 +1,231 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/tools"
+)
+
+// flakyClient is an MCP client whose sessions can be killed, and which can refuse to connect.
+type flakyClient struct {
+	mcpClient // Methods the tests don't use aren't implemented.
+
+	mu          sync.Mutex
+	initialized int
+	refuse      bool
+	session     chan struct{}
+}
+
+func (c *flakyClient) Initialize(context.Context, *mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refuse {
+		return nil, errors.New("connection refused")
+	}
+	c.initialized++
+	c.session = make(chan struct{})
+	return &mcp.InitializeResult{Capabilities: &mcp.ServerCapabilities{}}, nil
+}
+
+func (c *flakyClient) Wait() error {
+	c.mu.Lock()
+	session := c.session
+	c.mu.Unlock()
+
+	if session != nil {
+		<-session
+	}
+	return errors.New("server exited")
+}
+
+func (c *flakyClient) CallTool(context.Context, *mcp.CallToolParams) (*mcp.CallToolResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil {
+		return nil, fmt.Errorf("%w: calling \"tools/call\"", mcp.ErrConnectionClosed)
+	}
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "ok"}}}, nil
+}
+
+func (c *flakyClient) ListTools(context.Context, *mcp.ListToolsParams) iter.Seq2[*mcp.Tool, error] {
+	return func(yield func(*mcp.Tool, error) bool) {
+		c.mu.Lock()
+		closed := c.session == nil
+		c.mu.Unlock()
+
+		if closed {
+			yield(nil, fmt.Errorf("%w: calling \"tools/list\"", mcp.ErrConnectionClosed))
+			return
+		}
+		yield(&mcp.Tool{Name: "echo"}, nil)
+	}
+}
+
+func (c *flakyClient) Close(context.Context) error {
+	c.kill()
+	return nil
+}
+
+func (c *flakyClient) kill() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != nil {
+		close(c.session)
+		c.session = nil
+	}
+}
+
+func (c *flakyClient) setRefuse(refuse bool) {
+	c.mu.Lock()
+	c.refuse = refuse
+	c.mu.Unlock()
+}
+
+func (c *flakyClient) initializations() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.initialized
+}
+
+func (c *flakyClient) SetResourceUpdatedHandler(func(string)) {}
+func (c *flakyClient) SetToolListChangedHandler(func())       {}
+func (c *flakyClient) SetSamplingHandler(SamplingHandler)     {}
+
+func TestToolset_Reconnect(t *testing.T) {
+	reconnectBackoff = time.Millisecond
+	t.Cleanup(func() { reconnectBackoff = time.Second })
+
+	client := &flakyClient{}
+	toolset := newToolset("flaky", client, "flaky", nil)
+
+	states := make(chan ConnectionState, 20)
+	toolset.SetConnectionStateHandler(func(state ConnectionState, _ error) { states <- state })
+	toolsChanged := make(chan struct{}, 5)
+	toolset.SetToolsChangedHandler(func() { toolsChanged <- struct{}{} })
+
+	require.NoError(t, toolset.Start(t.Context()))
+	t.Cleanup(func() { _ = toolset.Stop(context.Background()) })
+	assert.Equal(t, StateConnected, <-states)
+
+	// The server crashes and is restarted.
+	client.kill()
+	assert.Equal(t, StateReconnecting, <-states)
+	assert.Equal(t, StateConnected, <-states)
+	assert.Equal(t, 2, client.initializations())
+	<-toolsChanged
+
+	// The server can't be restarted: the toolset gives up after a few attempts.
+	client.setRefuse(true)
+	client.kill()
+	assert.Equal(t, StateReconnecting, <-states)
+	assert.Equal(t, StateFailed, <-states)
+	assert.Equal(t, StateFailed, toolset.ConnectionState())
+
+	// It tries again the next time it's used.
+	require.Error(t, toolset.ensureConnected(t.Context()))
+	client.setRefuse(false)
+	require.NoError(t, toolset.ensureConnected(t.Context()))
+	assert.Equal(t, StateConnected, <-states)
+	assert.Equal(t, 3, client.initializations())
+
+	// And keeps watching the new session.
+	client.kill()
+	assert.Equal(t, StateReconnecting, <-states)
+	assert.Equal(t, StateConnected, <-states)
+}
+
+func TestToolset_ReconnectAfterStartContextIsCanceled(t *testing.T) {
+	reconnectBackoff = time.Millisecond
+	t.Cleanup(func() { reconnectBackoff = time.Second })
+
+	client := &flakyClient{}
+	toolset := newToolset("flaky", client, "flaky", nil)
+
+	states := make(chan ConnectionState, 20)
+	toolset.SetConnectionStateHandler(func(state ConnectionState, _ error) { states <- state })
+
+	// The toolset is started by a request that then completes.
+	ctx, cancel := context.WithCancel(t.Context())
+	require.NoError(t, toolset.Start(ctx))
+	t.Cleanup(func() { _ = toolset.Stop(context.Background()) })
+	assert.Equal(t, StateConnected, <-states)
+	cancel()
+
+	client.kill()
+	assert.Equal(t, StateReconnecting, <-states)
+	assert.Equal(t, StateConnected, <-states)
+	assert.Equal(t, 2, client.initializations())
+}
+
+func TestToolset_ReconnectOnClosedSession(t *testing.T) {
+	// The watcher waits so that requests find the session closed.
+	reconnectBackoff = time.Hour
+	t.Cleanup(func() { reconnectBackoff = time.Second })
+
+	client := &flakyClient{}
+	toolset := newToolset("flaky", client, "flaky", nil)
+	require.NoError(t, toolset.Start(t.Context()))
+	t.Cleanup(func() { _ = toolset.Stop(context.Background()) })
+
+	// Tool calls aren't sent again after reconnecting, as they might have run.
+	client.kill()
+	_, err := toolset.callTool(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: "echo"}})
+	require.ErrorIs(t, err, mcp.ErrConnectionClosed)
+	assert.Equal(t, 2, client.initializations())
+	assert.Equal(t, StateConnected, toolset.ConnectionState())
+
+	result, err := toolset.callTool(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: "echo"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Output)
+	assert.Equal(t, 2, client.initializations())
+
+	client.kill()
+	toolsList, err := toolset.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, toolsList, 1)
+	assert.Equal(t, "flaky_echo", toolsList[0].Name)
+	assert.Equal(t, 3, client.initializations())
+
+	// Requests don't reconnect stopped toolsets.
+	require.NoError(t, toolset.Stop(t.Context()))
+	_, err = toolset.callTool(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: "echo"}})
+	require.ErrorIs(t, err, mcp.ErrConnectionClosed)
+	assert.Equal(t, 3, client.initializations())
+}
+
+func TestToolset_WatcherIgnoresRestartedSession(t *testing.T) {
+	reconnectBackoff = time.Millisecond
+	t.Cleanup(func() { reconnectBackoff = time.Second })
+
+	client := &flakyClient{}
+	toolset := newToolset("flaky", client, "flaky", nil)
+	require.NoError(t, toolset.Start(t.Context()))
+	t.Cleanup(func() { _ = toolset.Stop(context.Background()) })
+
+	// The request restarts the session before the watcher sees the old one end.
+	client.kill()
+	_, err := toolset.Tools(t.Context())
+	require.NoError(t, err)
+
+	// The watcher must not restart the new session.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 2, client.initializations())
+	assert.Equal(t, StateConnected, toolset.ConnectionState())
+
+	result, err := toolset.callTool(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: "echo"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Output)
+}
@@ -22,7 +22,7 @@ type GatewayToolset struct {
 
 var _ tools.ToolSet = (*GatewayToolset)(nil)
 
-func NewGatewayToolset(ctx context.Context, name, mcpServerName string, config any, envProvider environment.Provider, cwd string) (*GatewayToolset, error) {
+func NewGatewayToolset(ctx context.Context, name, mcpServerName string, config any, envProvider environment.Provider, cwd string, opts ...ToolsetOpt) (*GatewayToolset, error) {
 	slog.Debug("Creating MCP Gateway toolset", "name", mcpServerName)
 
 	// Check which secrets (env vars) are required by the MCP server.
@@ -54,8 +54,11 @@ func NewGatewayToolset(ctx context.Context, name, mcpServerName string, config a
 		"--config", fileConfig,
 	}
 
+	toolset := NewToolsetCommand(name, "docker", args, nil, cwd, opts...)
+	toolset.ref = mcpServerName
+
 	return &GatewayToolset{
-		Toolset: NewToolsetCommand(name, "docker", args, nil, cwd),
+		Toolset: toolset,
 		cleanUp: func() error {
 			return errors.Join(os.Remove(fileSecrets), os.Remove(fileConfig))
 		},
@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"iter"
 	"log/slog"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"github.com/docker/cagent/pkg/config/latest"
 	"github.com/docker/cagent/pkg/tools"
 )
 
@@ -23,6 +26,14 @@ type mcpClient interface {
 	CallTool(ctx context.Context, request *mcp.CallToolParams) (*mcp.CallToolResult, error)
 	ListPrompts(ctx context.Context, request *mcp.ListPromptsParams) iter.Seq2[*mcp.Prompt, error]
 	GetPrompt(ctx context.Context, request *mcp.GetPromptParams) (*mcp.GetPromptResult, error)
+	ListResources(ctx context.Context, request *mcp.ListResourcesParams) iter.Seq2[*mcp.Resource, error]
+	ListResourceTemplates(ctx context.Context, request *mcp.ListResourceTemplatesParams) iter.Seq2[*mcp.ResourceTemplate, error]
+	ReadResource(ctx context.Context, request *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error)
+	Subscribe(ctx context.Context, request *mcp.SubscribeParams) error
+	Wait() error
+	SetResourceUpdatedHandler(handler func(uri string))
+	SetToolListChangedHandler(handler func())
+	SetSamplingHandler(handler SamplingHandler)
 	SetElicitationHandler(handler tools.ElicitationHandler)
 	SetOAuthSuccessHandler(handler func())
 	SetManagedOAuth(managed bool)
@@ -42,32 +53,73 @@ type Toolset struct {
 	name         string
 	mcpClient    mcpClient
 	logID        string
+	ref          string // The name of the server in the MCP catalog, if any
 	instructions string
+	serverName   string
+	capabilities *mcp.ServerCapabilities
 	started      atomic.Bool
+
+	resourceTools bool
+	sampling      *latest.SamplingConfig
+
+	mu                  sync.Mutex
+	subscribed          map[string]bool
+	updated             []string
+	samplingHandler     SamplingHandler
+	cachedTools         []tools.Tool
+	toolsCached         bool
+	toolsVersion        int
+	toolsChangedHandler func()
+
+	state        ConnectionState
+	stateHandler ConnectionStateHandler
+	session      uint64
+	stopWatching context.CancelFunc
+	reconnectMu  sync.Mutex
 }
 
 var _ tools.ToolSet = (*Toolset)(nil)
 
+// ToolsetOpt configures an MCP toolset.
+type ToolsetOpt func(*Toolset)
+
+// WithResourceTools adds tools that let the model list and read the resources of the server.
+func WithResourceTools() ToolsetOpt {
+	return func(ts *Toolset) {
+		ts.resourceTools = true
+	}
+}
+
 // NewToolsetCommand creates a new MCP toolset from a command.
-func NewToolsetCommand(name, command string, args, env []string, cwd string) *Toolset {
+func NewToolsetCommand(name, command string, args, env []string, cwd string, opts ...ToolsetOpt) *Toolset {
 	slog.Debug("Creating Stdio MCP toolset", "command", command, "args", args)
 
-	return &Toolset{
-		name:      name,
-		mcpClient: newStdioCmdClient(command, args, env, cwd),
-		logID:     command,
-	}
+	return newToolset(name, newStdioCmdClient(command, args, env, cwd), command, opts)
 }
 
 // NewRemoteToolset creates a new MCP toolset from a remote MCP Server.
-func NewRemoteToolset(name, url, transport string, headers map[string]string) *Toolset {
+func NewRemoteToolset(name, url, transport string, headers map[string]string, opts ...ToolsetOpt) *Toolset {
 	slog.Debug("Creating Remote MCP toolset", "url", url, "transport", transport, "headers", headers)
 
-	return &Toolset{
-		name:      name,
-		mcpClient: newRemoteClient(url, transport, headers, NewInMemoryTokenStore()),
-		logID:     url,
+	return newToolset(name, newRemoteClient(url, transport, headers, DefaultTokenStore()), url, opts)
+}
+
+func newToolset(name string, client mcpClient, logID string, opts []ToolsetOpt) *Toolset {
+	ts := &Toolset{
+		name:       name,
+		mcpClient:  client,
+		logID:      logID,
+		subscribed: map[string]bool{},
 	}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	client.SetResourceUpdatedHandler(ts.resourceUpdated)
+	client.SetToolListChangedHandler(ts.toolListChanged)
+	if ts.sampling != nil {
+		client.SetSamplingHandler(ts.createMessage)
+	}
+	return ts
 }
 
 func (ts *Toolset) Start(ctx context.Context) error {
@@ -84,6 +136,26 @@ func (ts *Toolset) Start(ctx context.Context) error {
 
 	slog.Debug("Starting MCP toolset", "server", ts.logID)
 
+	if err := ts.initialize(ctx); err != nil {
+		return err
+	}
+
+	slog.Debug("Started MCP toolset successfully", "server", ts.logID)
+	ts.started.Store(true)
+	ts.setState(StateConnected, nil)
+
+	// The watcher outlives the request that started the toolset. It's stopped by Stop.
+	watchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	ts.mu.Lock()
+	ts.stopWatching = cancel
+	ts.mu.Unlock()
+	go ts.watch(watchCtx)
+
+	return nil
+}
+
+// initialize connects to the server, starting it if needed, and records what it offers.
+func (ts *Toolset) initialize(ctx context.Context) error {
 	initRequest := &mcp.InitializeRequest{
 		Params: &mcp.InitializeParams{
 			ClientInfo: &mcp.Implementation{
@@ -122,9 +194,13 @@ func (ts *Toolset) Start(ctx context.Context) error {
 		}
 	}
 
-	slog.Debug("Started MCP toolset successfully", "server", ts.logID)
+	ts.mu.Lock()
 	ts.instructions = result.Instructions
-	ts.started.Store(true)
+	ts.capabilities = result.Capabilities
+	if result.ServerInfo != nil {
+		ts.serverName = result.ServerInfo.Name
+	}
+	ts.mu.Unlock()
 	return nil
 }
 
@@ -133,6 +209,8 @@ func (ts *Toolset) Instructions() string {
 		// TODO: this should never happen...
 		return ""
 	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
 	return ts.instructions
 }
 
@@ -141,6 +219,47 @@ func (ts *Toolset) Tools(ctx context.Context) ([]tools.Tool, error) {
 		return nil, errors.New("toolset not started")
 	}
 
+	if err := ts.ensureConnected(ctx); err != nil {
+		return nil, err
+	}
+
+	// The list of tools is cached until the server says it changed.
+	ts.mu.Lock()
+	if ts.toolsCached {
+		cached := slices.Clone(ts.cachedTools)
+		ts.mu.Unlock()
+		return cached, nil
+	}
+	version := ts.toolsVersion
+	session := ts.session
+	ts.mu.Unlock()
+
+	toolsList, err := ts.listTools(ctx)
+	if isSessionClosed(err) {
+		if err := ts.reconnectNow(ctx, session, err); err != nil {
+			return nil, fmt.Errorf("failed to reconnect to MCP server: %w", err)
+		}
+		ts.mu.Lock()
+		version = ts.toolsVersion
+		ts.mu.Unlock()
+		toolsList, err = ts.listTools(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't cache a list the server changed while it was being fetched.
+	ts.mu.Lock()
+	if ts.toolsVersion == version {
+		ts.cachedTools = slices.Clone(toolsList)
+		ts.toolsCached = true
+	}
+	ts.mu.Unlock()
+
+	return toolsList, nil
+}
+
+func (ts *Toolset) listTools(ctx context.Context) ([]tools.Tool, error) {
 	slog.Debug("Listing MCP tools")
 
 	resp := ts.mcpClient.ListTools(ctx, &mcp.ListToolsParams{})
@@ -171,10 +290,41 @@ func (ts *Toolset) Tools(ctx context.Context) ([]tools.Tool, error) {
 		slog.Debug("Added MCP tool", "tool", name)
 	}
 
+	if ts.resourceTools && ts.hasResources() {
+		toolsList = append(toolsList, ts.resourceToolsList()...)
+	}
+
 	slog.Debug("Listed MCP tools", "count", len(toolsList))
 	return toolsList, nil
 }
 
+// SetToolsChangedHandler sets the function called when the server's list of tools changes.
+func (ts *Toolset) SetToolsChangedHandler(handler func()) {
+	ts.mu.Lock()
+	ts.toolsChangedHandler = handler
+	ts.mu.Unlock()
+}
+
+func (ts *Toolset) toolListChanged() {
+	slog.Debug("MCP tool list changed", "server", ts.logID)
+
+	ts.mu.Lock()
+	ts.invalidateTools()
+	handler := ts.toolsChangedHandler
+	ts.mu.Unlock()
+
+	if handler != nil {
+		handler()
+	}
+}
+
+// invalidateTools drops the cached list of tools. ts.mu must be held.
+func (ts *Toolset) invalidateTools() {
+	ts.cachedTools = nil
+	ts.toolsCached = false
+	ts.toolsVersion++
+}
+
 func (ts *Toolset) callTool(ctx context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
 	slog.Debug("Calling MCP tool", "tool", toolCall.Function.Name, "arguments", toolCall.Function.Arguments)
 
@@ -187,11 +337,23 @@ func (ts *Toolset) callTool(ctx context.Context, toolCall tools.ToolCall) (*tool
 		return nil, fmt.Errorf("failed to parse tool arguments: %w", err)
 	}
 
+	if err := ts.ensureConnected(ctx); err != nil {
+		return nil, fmt.Errorf("failed to reconnect to MCP server: %w", err)
+	}
+
 	request := &mcp.CallToolParams{}
 	request.Name = toolCall.Function.Name
 	request.Arguments = args
 
+	session := ts.currentSession()
 	resp, err := ts.mcpClient.CallTool(ctx, request)
+	if isSessionClosed(err) {
+		// The call isn't sent again: the tool might have run before the session ended.
+		if err := ts.reconnectNow(ctx, session, err); err != nil {
+			return nil, fmt.Errorf("failed to reconnect to MCP server: %w", err)
+		}
+		return nil, fmt.Errorf("the connection to the MCP server was lost during the tool call, it may or may not have run: %w", err)
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
 			slog.Debug("CallTool canceled by context", "tool", toolCall.Function.Name)
@@ -202,7 +364,7 @@ func (ts *Toolset) callTool(ctx context.Context, toolCall tools.ToolCall) (*tool
 	}
 
 	result := processMCPContent(resp)
-	slog.Debug("MCP tool call completed", "tool", toolCall.Function.Name, "output_length", len(result.Output))
+	slog.Debug("MCP tool call completed", "tool", toolCall.Function.Name, "output_length", len(result.Output), "images", len(result.Images))
 	slog.Debug(result.Output)
 	return result, nil
 }
@@ -210,6 +372,14 @@ func (ts *Toolset) callTool(ctx context.Context, toolCall tools.ToolCall) (*tool
 func (ts *Toolset) Stop(ctx context.Context) error {
 	slog.Debug("Stopping MCP toolset", "server", ts.logID)
 
+	ts.mu.Lock()
+	ts.invalidateTools()
+	if ts.stopWatching != nil {
+		ts.stopWatching()
+		ts.stopWatching = nil
+	}
+	ts.mu.Unlock()
+
 	if err := ts.mcpClient.Close(context.WithoutCancel(ctx)); err != nil {
 		if ctx.Err() != nil {
 			return nil
@@ -237,20 +407,60 @@ func isInitNotificationSendError(err error) bool {
 }
 
 func processMCPContent(toolResult *mcp.CallToolResult) *tools.ToolCallResult {
-	finalContent := ""
+	var (
+		finalContent strings.Builder
+		images       []tools.Image
+	)
+	appendText := func(text string) {
+		if finalContent.Len() > 0 && !strings.HasSuffix(finalContent.String(), "\n") {
+			finalContent.WriteString("\n")
+		}
+		finalContent.WriteString(text)
+	}
+	appendImage := func(image tools.Image) {
+		if !image.Supported() {
+			appendText(image.Placeholder())
+			return
+		}
+		images = append(images, image)
+	}
+
 	for _, resultContent := range toolResult.Content {
-		if textContent, ok := resultContent.(*mcp.TextContent); ok {
-			finalContent += textContent.Text
+		switch content := resultContent.(type) {
+		case *mcp.TextContent:
+			finalContent.WriteString(content.Text)
+		case *mcp.ImageContent:
+			appendImage(tools.Image{MIMEType: content.MIMEType, Data: content.Data})
+		case *mcp.AudioContent:
+			appendText(fmt.Sprintf("[audio: %s, %d bytes]", cmp.Or(content.MIMEType, "unknown type"), len(content.Data)))
+		case *mcp.ResourceLink:
+			appendText(fmt.Sprintf("[resource: %s]", content.URI))
+		case *mcp.EmbeddedResource:
+			resource := content.Resource
+			switch {
+			case resource == nil:
+			case resource.Text != "":
+				appendText(resource.Text)
+			case strings.HasPrefix(resource.MIMEType, "image/"):
+				appendImage(tools.Image{MIMEType: resource.MIMEType, Data: resource.Blob})
+			case len(resource.Blob) > 0:
+				appendText(fmt.Sprintf("[binary content of %s: %s, %d bytes]", resource.URI, cmp.Or(resource.MIMEType, "unknown type"), len(resource.Blob)))
+			}
 		}
 	}
 
 	// Handle an empty response. This can happen if the MCP tool does not return any content.
-	finalContent = cmp.Or(finalContent, "no output")
+	output := finalContent.String()
+	if len(images) == 0 {
+		output = cmp.Or(output, "no output")
+	}
 
+	result := tools.ResultSuccess(output)
 	if toolResult.IsError {
-		return tools.ResultError(finalContent)
+		result = tools.ResultError(output)
 	}
-	return tools.ResultSuccess(finalContent)
+	result.Images = images
+	return result
 }
 
 func (ts *Toolset) SetElicitationHandler(handler tools.ElicitationHandler) {
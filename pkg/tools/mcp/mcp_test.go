@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/docker/cagent/pkg/model/provider/dmr"
 	"github.com/docker/cagent/pkg/model/provider/gemini"
 	"github.com/docker/cagent/pkg/model/provider/openai"
+	"github.com/docker/cagent/pkg/tools"
 )
 
 const schemaJSON = `
@@ -301,3 +303,45 @@ func TestSchemaForDMR(t *testing.T) {
 	"required": ["repo"]
 }`, string(schemaJSON))
 }
+
+func TestProcessMCPContent(t *testing.T) {
+	result := processMCPContent(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: "Screenshot taken"},
+			&mcp.ImageContent{MIMEType: "image/png", Data: []byte{1, 2, 3}},
+			&mcp.AudioContent{MIMEType: "audio/wav", Data: []byte{4, 5}},
+			&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///page.html", Text: "<html></html>"}},
+			&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///logo.jpg", MIMEType: "image/jpeg", Blob: []byte{6}}},
+			&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///doc.pdf", MIMEType: "application/pdf", Blob: []byte{7, 8}}},
+		},
+	})
+
+	assert.False(t, result.IsError)
+	assert.Equal(t, "Screenshot taken\n[audio: audio/wav, 2 bytes]\n<html></html>\n[binary content of file:///doc.pdf: application/pdf, 2 bytes]", result.Output)
+	assert.Equal(t, []tools.Image{
+		{MIMEType: "image/png", Data: []byte{1, 2, 3}},
+		{MIMEType: "image/jpeg", Data: []byte{6}},
+	}, result.Images)
+
+	// A tool returning only an image has no text output.
+	result = processMCPContent(&mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.ImageContent{MIMEType: "image/png", Data: []byte{1}}},
+	})
+	assert.Empty(t, result.Output)
+	assert.Len(t, result.Images, 1)
+
+	// Images that models don't support are replaced with a placeholder.
+	result = processMCPContent(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.ImageContent{MIMEType: "image/x-icon", Data: []byte{1}},
+			&mcp.ImageContent{MIMEType: "image/png", Data: make([]byte, tools.MaxImageSize+1)},
+		},
+	})
+	assert.Empty(t, result.Images)
+	assert.Contains(t, result.Output, "[image: image/x-icon, 1 bytes, not shown")
+	assert.Contains(t, result.Output, "[image: image/png")
+
+	result = processMCPContent(&mcp.CallToolResult{IsError: true})
+	assert.True(t, result.IsError)
+	assert.Equal(t, "no output", result.Output)
+}
@@ -15,6 +15,7 @@ import (
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/docker/cagent/pkg/tools"
 )
@@ -188,8 +189,13 @@ func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	reqClone := req.Clone(req.Context())
 
-	if token, err := t.tokenStore.GetToken(t.baseURL); err == nil && !token.IsExpired() {
-		reqClone.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
+	if token, err := t.tokenStore.GetToken(t.baseURL); err == nil {
+		if token.IsExpired() {
+			token = t.refreshToken(req.Context(), token)
+		}
+		if token != nil {
+			reqClone.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
+		}
 	}
 
 	resp, err := t.base.RoundTrip(reqClone)
@@ -218,6 +224,52 @@ func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
+// tokenRefreshes makes sure that a single request refreshes the token of a
+// server at a time, since refresh tokens can be single use.
+var tokenRefreshes singleflight.Group
+
+// refreshToken gets a new access token for an expired token. It returns nil if
+// the token can't be refreshed, in which case the OAuth flow starts over.
+func (t *oauthTransport) refreshToken(ctx context.Context, token *OAuthToken) *OAuthToken {
+	refreshed, _, _ := tokenRefreshes.Do(t.baseURL, func() (any, error) {
+		// Another request, or another cagent process, might have refreshed the token already.
+		if current, err := t.tokenStore.GetToken(t.baseURL); err == nil {
+			if !current.IsExpired() {
+				return current, nil
+			}
+			token = current
+		}
+		return t.doRefreshToken(ctx, token), nil
+	})
+	return refreshed.(*OAuthToken)
+}
+
+func (t *oauthTransport) doRefreshToken(ctx context.Context, token *OAuthToken) *OAuthToken {
+	if !token.CanRefresh() {
+		return nil
+	}
+
+	slog.Debug("Refreshing OAuth token", "url", t.baseURL)
+
+	refreshed, err := RefreshAccessToken(ctx, token.TokenEndpoint, token.RefreshToken, token.ClientID, token.ClientSecret)
+	if err != nil {
+		slog.Debug("Failed to refresh OAuth token", "url", t.baseURL, "error", err)
+		return nil
+	}
+
+	// Servers don't have to rotate refresh tokens.
+	refreshed.RefreshToken = cmp.Or(refreshed.RefreshToken, token.RefreshToken)
+	refreshed.TokenEndpoint = token.TokenEndpoint
+	refreshed.ClientID = token.ClientID
+	refreshed.ClientSecret = token.ClientSecret
+
+	if err := t.tokenStore.StoreToken(t.baseURL, refreshed); err != nil {
+		slog.Warn("Failed to store refreshed OAuth token", "url", t.baseURL, "error", err)
+	}
+
+	return refreshed
+}
+
 // handleOAuthFlow performs the OAuth flow when a 401 response is received
 func (t *oauthTransport) handleOAuthFlow(ctx context.Context, authServer, wwwAuth string) error {
 	if t.managed {
@@ -355,6 +407,9 @@ func (t *oauthTransport) handleManagedOAuthFlow(ctx context.Context, authServer,
 	if err != nil {
 		return fmt.Errorf("failed to exchange code for token: %w", err)
 	}
+	token.TokenEndpoint = authServerMetadata.TokenEndpoint
+	token.ClientID = clientID
+	token.ClientSecret = clientSecret
 
 	if err := t.tokenStore.StoreToken(t.baseURL, token); err != nil {
 		return fmt.Errorf("failed to store token: %w", err)
@@ -451,6 +506,11 @@ func (t *oauthTransport) handleUnmanagedOAuthFlow(ctx context.Context, authServe
 	if refreshToken, ok := tokenData["refresh_token"].(string); ok {
 		token.RefreshToken = refreshToken
 	}
+	if clientID, ok := tokenData["client_id"].(string); ok {
+		token.ClientID = clientID
+	}
+	token.TokenEndpoint = authServerMetadata.TokenEndpoint
+
 	if err := t.tokenStore.StoreToken(t.baseURL, token); err != nil {
 		return fmt.Errorf("failed to store token: %w", err)
 	}
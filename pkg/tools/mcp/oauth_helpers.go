@@ -51,6 +51,26 @@ func ExchangeCodeForToken(ctx context.Context, tokenEndpoint, code, codeVerifier
 		data.Set("client_secret", clientSecret)
 	}
 
+	return requestToken(ctx, tokenEndpoint, data)
+}
+
+// RefreshAccessToken gets a new access token with a refresh_token grant
+func RefreshAccessToken(ctx context.Context, tokenEndpoint, refreshToken, clientID, clientSecret string) (*OAuthToken, error) {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", refreshToken)
+	if clientID != "" {
+		data.Set("client_id", clientID)
+	}
+	if clientSecret != "" {
+		data.Set("client_secret", clientSecret)
+	}
+
+	return requestToken(ctx, tokenEndpoint, data)
+}
+
+// requestToken sends a token request to the token endpoint of an authorization server
+func requestToken(ctx context.Context, tokenEndpoint string, data url.Values) (*OAuthToken, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token request: %w", err)
@@ -60,13 +80,13 @@ func ExchangeCodeForToken(ctx context.Context, tokenEndpoint, code, codeVerifier
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
+		return nil, fmt.Errorf("failed to request token: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("token exchange failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var token OAuthToken
@@ -108,7 +128,7 @@ func RegisterClient(ctx context.Context, authMetadata *AuthorizationServerMetada
 	reqBody := map[string]any{
 		"redirect_uris": []string{redirectURI},
 		"client_name":   "cagent",
-		"grant_types":   []string{"authorization_code"},
+		"grant_types":   []string{"authorization_code", "refresh_token"},
 		"response_types": []string{
 			"code",
 		},
@@ -21,6 +21,9 @@ type remoteMCPClient struct {
 	tokenStore          OAuthTokenStore
 	elicitationHandler  tools.ElicitationHandler
 	oauthSuccessHandler func()
+	resourceUpdated     func(uri string)
+	toolListChanged     func()
+	samplingHandler     SamplingHandler
 	managed             bool
 	mu                  sync.RWMutex
 }
@@ -76,6 +79,28 @@ func (c *remoteMCPClient) handleElicitationRequest(ctx context.Context, req *mcp
 	}, nil
 }
 
+// handleResourceUpdated forwards notifications about updated resources from the MCP server
+func (c *remoteMCPClient) handleResourceUpdated(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+	c.mu.RLock()
+	handler := c.resourceUpdated
+	c.mu.RUnlock()
+
+	if handler != nil {
+		handler(req.Params.URI)
+	}
+}
+
+// handleToolListChanged forwards notifications about changes of the list of tools from the MCP server
+func (c *remoteMCPClient) handleToolListChanged(context.Context, *mcp.ToolListChangedRequest) {
+	c.mu.RLock()
+	handler := c.toolListChanged
+	c.mu.RUnlock()
+
+	if handler != nil {
+		handler()
+	}
+}
+
 func (c *remoteMCPClient) Initialize(ctx context.Context, _ *mcp.InitializeRequest) (*mcp.InitializeResult, error) {
 	// Create HTTP client with OAuth support
 	httpClient := c.createHTTPClient()
@@ -104,7 +129,21 @@ func (c *remoteMCPClient) Initialize(ctx context.Context, _ *mcp.InitializeReque
 	}
 
 	opts := &mcp.ClientOptions{
-		ElicitationHandler: c.handleElicitationRequest,
+		ElicitationHandler:     c.handleElicitationRequest,
+		ResourceUpdatedHandler: c.handleResourceUpdated,
+		ToolListChangedHandler: c.handleToolListChanged,
+		// Pings detect servers that stopped answering.
+		KeepAlive: keepAliveInterval,
+	}
+
+	// The sampling capability is only advertised if there's a handler.
+	c.mu.RLock()
+	samplingHandler := c.samplingHandler
+	c.mu.RUnlock()
+	if samplingHandler != nil {
+		opts.CreateMessageHandler = func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			return samplingHandler(ctx, req.Params)
+		}
 	}
 
 	client := mcp.NewClient(impl, opts)
@@ -168,9 +207,10 @@ func (c *remoteMCPClient) createHTTPClient() *http.Client {
 }
 
 func (c *remoteMCPClient) Close(context.Context) error {
-	c.mu.RLock()
+	c.mu.Lock()
 	session := c.session
-	c.mu.RUnlock()
+	c.session = nil
+	c.mu.Unlock()
 
 	if session != nil {
 		return session.Close()
@@ -178,6 +218,18 @@ func (c *remoteMCPClient) Close(context.Context) error {
 	return nil
 }
 
+// Wait blocks until the session ends, for example because the connection was lost.
+func (c *remoteMCPClient) Wait() error {
+	c.mu.RLock()
+	session := c.session
+	c.mu.RUnlock()
+
+	if session == nil {
+		return nil
+	}
+	return session.Wait()
+}
+
 func (c *remoteMCPClient) ListTools(ctx context.Context, params *mcp.ListToolsParams) iter.Seq2[*mcp.Tool, error] {
 	c.mu.RLock()
 	session := c.session
@@ -232,6 +284,84 @@ func (c *remoteMCPClient) GetPrompt(ctx context.Context, request *mcp.GetPromptP
 	return session.GetPrompt(ctx, request)
 }
 
+// ListResources retrieves available resources from the remote MCP server
+func (c *remoteMCPClient) ListResources(ctx context.Context, request *mcp.ListResourcesParams) iter.Seq2[*mcp.Resource, error] {
+	c.mu.RLock()
+	session := c.session
+	c.mu.RUnlock()
+
+	if session == nil {
+		return func(yield func(*mcp.Resource, error) bool) {
+			yield(nil, fmt.Errorf("session not initialized"))
+		}
+	}
+
+	return session.Resources(ctx, request)
+}
+
+// ListResourceTemplates retrieves available resource templates from the remote MCP server
+func (c *remoteMCPClient) ListResourceTemplates(ctx context.Context, request *mcp.ListResourceTemplatesParams) iter.Seq2[*mcp.ResourceTemplate, error] {
+	c.mu.RLock()
+	session := c.session
+	c.mu.RUnlock()
+
+	if session == nil {
+		return func(yield func(*mcp.ResourceTemplate, error) bool) {
+			yield(nil, fmt.Errorf("session not initialized"))
+		}
+	}
+
+	return session.ResourceTemplates(ctx, request)
+}
+
+// ReadResource reads a resource from the remote MCP server
+func (c *remoteMCPClient) ReadResource(ctx context.Context, request *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+	c.mu.RLock()
+	session := c.session
+	c.mu.RUnlock()
+
+	if session == nil {
+		return nil, fmt.Errorf("session not initialized")
+	}
+
+	return session.ReadResource(ctx, request)
+}
+
+// Subscribe asks the remote MCP server to send notifications when a resource is updated
+func (c *remoteMCPClient) Subscribe(ctx context.Context, request *mcp.SubscribeParams) error {
+	c.mu.RLock()
+	session := c.session
+	c.mu.RUnlock()
+
+	if session == nil {
+		return fmt.Errorf("session not initialized")
+	}
+
+	return session.Subscribe(ctx, request)
+}
+
+// SetResourceUpdatedHandler sets the function called when a subscribed resource is updated
+func (c *remoteMCPClient) SetResourceUpdatedHandler(handler func(uri string)) {
+	c.mu.Lock()
+	c.resourceUpdated = handler
+	c.mu.Unlock()
+}
+
+// SetToolListChangedHandler sets the function called when the server's list of tools changes
+func (c *remoteMCPClient) SetToolListChangedHandler(handler func()) {
+	c.mu.Lock()
+	c.toolListChanged = handler
+	c.mu.Unlock()
+}
+
+// SetSamplingHandler sets the function answering sampling requests.
+// It must be set before the client is initialized.
+func (c *remoteMCPClient) SetSamplingHandler(handler SamplingHandler) {
+	c.mu.Lock()
+	c.samplingHandler = handler
+	c.mu.Unlock()
+}
+
 // SetElicitationHandler sets the elicitation handler for remote MCP clients
 // This allows the runtime to provide a handler that propagates elicitation requests
 func (c *remoteMCPClient) SetElicitationHandler(handler tools.ElicitationHandler) {
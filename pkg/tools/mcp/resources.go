@@ -0,0 +1,265 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/cagent/pkg/tools"
+)
+
+// ResourceInfo contains metadata about a resource, or a resource template, exposed by an MCP server
+type ResourceInfo struct {
+	Server      string `json:"server"`                // The name of the server exposing the resource
+	URI         string `json:"uri"`                   // The URI of the resource, or the URI template of a template
+	Name        string `json:"name"`                  // The resource name
+	Title       string `json:"title,omitempty"`       // Human-readable name of the resource
+	Description string `json:"description,omitempty"` // Human-readable description of the resource
+	MIMEType    string `json:"mime_type,omitempty"`   // The MIME type of the resource, if known
+	Template    bool   `json:"template,omitempty"`    // Whether URI is a URI template
+}
+
+// Ref returns the reference used to attach the resource to a prompt, as server:uri.
+func (r ResourceInfo) Ref() string {
+	return r.Server + ":" + r.URI
+}
+
+// ParseResourceRef splits a server:uri reference. The URI must itself have a scheme,
+// which tells resource references apart from file paths.
+func ParseResourceRef(ref string) (server, uri string, ok bool) {
+	server, uri, ok = strings.Cut(ref, ":")
+	if !ok || server == "" || !strings.Contains(uri, ":") {
+		return "", "", false
+	}
+	return server, uri, true
+}
+
+// ServerName returns the name used to refer to the server in resource references:
+// the name of the toolset or, if it has none, the name of the server in the catalog
+// or the name the server gave itself.
+func (ts *Toolset) ServerName() string {
+	ts.mu.Lock()
+	name := cmp.Or(ts.name, ts.ref, ts.serverName, ts.logID)
+	ts.mu.Unlock()
+
+	return strings.Map(func(r rune) rune {
+		if r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func (ts *Toolset) serverCapabilities() *mcp.ServerCapabilities {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.capabilities
+}
+
+func (ts *Toolset) hasResources() bool {
+	capabilities := ts.serverCapabilities()
+	return capabilities != nil && capabilities.Resources != nil
+}
+
+// ListResources retrieves the resources and resource templates of the MCP server.
+// Servers that don't support resources have none.
+func (ts *Toolset) ListResources(ctx context.Context) ([]ResourceInfo, error) {
+	if !ts.started.Load() {
+		return nil, errors.New("toolset not started")
+	}
+	if !ts.hasResources() {
+		return nil, nil
+	}
+
+	slog.Debug("Listing MCP resources", "server", ts.logID)
+
+	server := ts.ServerName()
+
+	var resources []ResourceInfo
+	for resource, err := range ts.mcpClient.ListResources(ctx, &mcp.ListResourcesParams{}) {
+		if err != nil {
+			return resources, fmt.Errorf("failed to list resources: %w", err)
+		}
+		resources = append(resources, ResourceInfo{
+			Server:      server,
+			URI:         resource.URI,
+			Name:        resource.Name,
+			Title:       resource.Title,
+			Description: resource.Description,
+			MIMEType:    resource.MIMEType,
+		})
+	}
+
+	// Templates are optional: a server failing to list them still has its resources listed.
+	for template, err := range ts.mcpClient.ListResourceTemplates(ctx, &mcp.ListResourceTemplatesParams{}) {
+		if err != nil {
+			slog.Debug("Failed to list MCP resource templates", "server", ts.logID, "error", err)
+			break
+		}
+		resources = append(resources, ResourceInfo{
+			Server:      server,
+			URI:         template.URITemplate,
+			Name:        template.Name,
+			Title:       template.Title,
+			Description: template.Description,
+			MIMEType:    template.MIMEType,
+			Template:    true,
+		})
+	}
+
+	slog.Debug("Listed MCP resources", "server", ts.logID, "count", len(resources))
+	return resources, nil
+}
+
+// ReadResource reads a resource from the MCP server and returns its content as text.
+func (ts *Toolset) ReadResource(ctx context.Context, uri string) (string, error) {
+	if !ts.started.Load() {
+		return "", errors.New("toolset not started")
+	}
+
+	slog.Debug("Reading MCP resource", "server", ts.logID, "uri", uri)
+
+	result, err := ts.mcpClient.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return "", fmt.Errorf("failed to read resource %s: %w", uri, err)
+	}
+
+	return resourceText(result), nil
+}
+
+// resourceText concatenates the contents of a resource. Binary contents are only described.
+func resourceText(result *mcp.ReadResourceResult) string {
+	var parts []string
+	for _, content := range result.Contents {
+		switch {
+		case content.Text != "":
+			parts = append(parts, content.Text)
+		case len(content.Blob) > 0:
+			parts = append(parts, fmt.Sprintf("[binary content of %s: %s, %d bytes]", content.URI, cmp.Or(content.MIMEType, "unknown type"), len(content.Blob)))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Subscribe asks the server to notify updates of a resource, if it supports it.
+// Updated resources are then returned by UpdatedResources.
+func (ts *Toolset) Subscribe(ctx context.Context, uri string) error {
+	if !ts.hasResources() || !ts.serverCapabilities().Resources.Subscribe {
+		return nil
+	}
+
+	ts.mu.Lock()
+	subscribed := ts.subscribed[uri]
+	ts.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+
+	if err := ts.mcpClient.Subscribe(ctx, &mcp.SubscribeParams{URI: uri}); err != nil {
+		return fmt.Errorf("failed to subscribe to resource %s: %w", uri, err)
+	}
+
+	ts.mu.Lock()
+	ts.subscribed[uri] = true
+	ts.mu.Unlock()
+
+	slog.Debug("Subscribed to MCP resource", "server", ts.logID, "uri", uri)
+	return nil
+}
+
+func (ts *Toolset) resourceUpdated(uri string) {
+	slog.Debug("MCP resource updated", "server", ts.logID, "uri", uri)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for _, u := range ts.updated {
+		if u == uri {
+			return
+		}
+	}
+	ts.updated = append(ts.updated, uri)
+}
+
+// UpdatedResources returns the URIs of the resources updated since the last call.
+func (ts *Toolset) UpdatedResources() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	updated := ts.updated
+	ts.updated = nil
+	return updated
+}
+
+type readResourceArgs struct {
+	URI string `json:"uri" jsonschema:"The URI of the resource to read"`
+}
+
+func (ts *Toolset) resourceToolsList() []tools.Tool {
+	prefix := ""
+	if ts.name != "" {
+		prefix = ts.name + "_"
+	}
+
+	return []tools.Tool{
+		{
+			Name:        prefix + "list_resources",
+			Category:    "mcp",
+			Description: "List the resources, and resource templates, the server exposes.",
+			Annotations: tools.ToolAnnotations{
+				Title:        "List Resources",
+				ReadOnlyHint: true,
+			},
+			Parameters:   map[string]any{"type": "object", "properties": map[string]any{}},
+			OutputSchema: tools.MustSchemaFor[[]ResourceInfo](),
+			Handler:      ts.handleListResources,
+		},
+		{
+			Name:        prefix + "read_resource",
+			Category:    "mcp",
+			Description: "Read a resource of the server by its URI. URI templates must be expanded first.",
+			Annotations: tools.ToolAnnotations{
+				Title:        "Read Resource",
+				ReadOnlyHint: true,
+			},
+			Parameters:   tools.MustSchemaFor[readResourceArgs](),
+			OutputSchema: tools.MustSchemaFor[string](),
+			Handler:      ts.handleReadResource,
+		},
+	}
+}
+
+func (ts *Toolset) handleListResources(ctx context.Context, _ tools.ToolCall) (*tools.ToolCallResult, error) {
+	resources, err := ts.ListResources(ctx)
+	if err != nil {
+		return tools.ResultError(err.Error()), nil
+	}
+
+	buf, err := json.Marshal(resources)
+	if err != nil {
+		return nil, err
+	}
+	return tools.ResultSuccess(string(buf)), nil
+}
+
+func (ts *Toolset) handleReadResource(ctx context.Context, toolCall tools.ToolCall) (*tools.ToolCallResult, error) {
+	var args readResourceArgs
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+		return nil, fmt.Errorf("failed to parse tool arguments: %w", err)
+	}
+	if args.URI == "" {
+		return tools.ResultError("uri is required"), nil
+	}
+
+	text, err := ts.ReadResource(ctx, args.URI)
+	if err != nil {
+		return tools.ResultError(err.Error()), nil
+	}
+	return tools.ResultSuccess(cmp.Or(text, "no content")), nil
+}
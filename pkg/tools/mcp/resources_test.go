@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/tools"
+)
+
+func newResourcesServer(t *testing.T) *mcp.Server {
+	t.Helper()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs server", Version: "1.0.0"}, &mcp.ServerOptions{
+		SubscribeHandler:   func(context.Context, *mcp.SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *mcp.UnsubscribeRequest) error { return nil },
+	})
+	server.AddResource(&mcp.Resource{
+		URI:         "docs://readme",
+		Name:        "readme",
+		Title:       "README",
+		Description: "The readme",
+		MIMEType:    "text/markdown",
+	}, func(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+			{URI: req.Params.URI, MIMEType: "text/markdown", Text: "# Hello"},
+			{URI: req.Params.URI, MIMEType: "image/png", Blob: []byte{1, 2, 3}},
+		}}, nil
+	})
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "docs://pages/{page}",
+		Name:        "page",
+	}, func(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: req.Params.URI, Text: "page " + req.Params.URI}}}, nil
+	})
+
+	return server
+}
+
+func startHTTPToolset(t *testing.T, server *mcp.Server, name string, opts ...ToolsetOpt) *Toolset {
+	t.Helper()
+
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(httpServer.Close)
+
+	toolset := NewRemoteToolset(name, httpServer.URL, "streamable", nil, opts...)
+	require.NoError(t, toolset.Start(t.Context()))
+	t.Cleanup(func() { _ = toolset.Stop(context.Background()) })
+
+	return toolset
+}
+
+func TestToolset_Resources(t *testing.T) {
+	toolset := startHTTPToolset(t, newResourcesServer(t), "")
+
+	assert.Equal(t, "docs_server", toolset.ServerName())
+
+	resources, err := toolset.ListResources(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceInfo{
+		{Server: "docs_server", URI: "docs://readme", Name: "readme", Title: "README", Description: "The readme", MIMEType: "text/markdown"},
+		{Server: "docs_server", URI: "docs://pages/{page}", Name: "page", Template: true},
+	}, resources)
+
+	content, err := toolset.ReadResource(t.Context(), "docs://readme")
+	require.NoError(t, err)
+	assert.Equal(t, "# Hello\n\n[binary content of docs://readme: image/png, 3 bytes]", content)
+
+	content, err = toolset.ReadResource(t.Context(), "docs://pages/intro")
+	require.NoError(t, err)
+	assert.Equal(t, "page docs://pages/intro", content)
+
+	// Resource tools are only added when asked for.
+	allTools, err := toolset.Tools(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, allTools)
+}
+
+func TestToolset_ResourceTools(t *testing.T) {
+	toolset := startHTTPToolset(t, newResourcesServer(t), "docs", WithResourceTools())
+
+	allTools, err := toolset.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 2)
+	assert.Equal(t, "docs_list_resources", allTools[0].Name)
+	assert.Equal(t, "docs_read_resource", allTools[1].Name)
+
+	result, err := allTools[0].Handler(t.Context(), tools.ToolCall{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, `"uri":"docs://readme"`)
+
+	result, err = allTools[1].Handler(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Arguments: `{"uri": "docs://pages/faq"}`}})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "page docs://pages/faq", result.Output)
+
+	result, err = allTools[1].Handler(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Arguments: `{"uri": "unknown://x"}`}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestToolset_ResourceUpdates(t *testing.T) {
+	server := newResourcesServer(t)
+	toolset := startHTTPToolset(t, server, "docs")
+
+	require.NoError(t, toolset.Subscribe(t.Context(), "docs://readme"))
+	require.NoError(t, server.ResourceUpdated(t.Context(), &mcp.ResourceUpdatedNotificationParams{URI: "docs://readme"}))
+	// Unsubscribed resources aren't notified.
+	require.NoError(t, server.ResourceUpdated(t.Context(), &mcp.ResourceUpdatedNotificationParams{URI: "docs://other"}))
+
+	var updated []string
+	require.Eventually(t, func() bool {
+		updated = append(updated, toolset.UpdatedResources()...)
+		return len(updated) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"docs://readme"}, updated)
+	assert.Empty(t, toolset.UpdatedResources())
+}
+
+func TestParseResourceRef(t *testing.T) {
+	t.Parallel()
+
+	server, uri, ok := ParseResourceRef("docs:file:///guide.md")
+	assert.True(t, ok)
+	assert.Equal(t, "docs", server)
+	assert.Equal(t, "file:///guide.md", uri)
+
+	_, _, ok = ParseResourceRef("pkg/agent/agent.go")
+	assert.False(t, ok)
+	_, _, ok = ParseResourceRef("docs:README.md")
+	assert.False(t, ok)
+}
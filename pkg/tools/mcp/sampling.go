@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/docker/cagent/pkg/config/latest"
+)
+
+// defaultSamplingMaxTokens caps the completions of servers whose configuration doesn't.
+const defaultSamplingMaxTokens = 4096
+
+// SamplingHandler answers the sampling/createMessage requests of an MCP server
+// with a completion of the agent's model.
+type SamplingHandler func(ctx context.Context, req *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error)
+
+// WithSampling lets the server request completions, within the given limits.
+// Without it, the sampling capability isn't advertised to the server.
+func WithSampling(cfg latest.SamplingConfig) ToolsetOpt {
+	return func(ts *Toolset) {
+		if cfg.MaxTokens <= 0 {
+			cfg.MaxTokens = defaultSamplingMaxTokens
+		}
+		ts.sampling = &cfg
+	}
+}
+
+// SamplingConfig returns the limits of the completions the server can request.
+func (ts *Toolset) SamplingConfig() latest.SamplingConfig {
+	if ts.sampling == nil {
+		return latest.SamplingConfig{}
+	}
+	return *ts.sampling
+}
+
+// SetSamplingHandler sets the handler that answers the sampling requests of the server.
+// Without a handler, sampling requests fail.
+func (ts *Toolset) SetSamplingHandler(handler SamplingHandler) {
+	ts.mu.Lock()
+	ts.samplingHandler = handler
+	ts.mu.Unlock()
+}
+
+func (ts *Toolset) createMessage(ctx context.Context, req *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	slog.Debug("Sampling request received from MCP server", "server", ts.logID, "messages", len(req.Messages), "max_tokens", req.MaxTokens)
+
+	ts.mu.Lock()
+	handler := ts.samplingHandler
+	ts.mu.Unlock()
+
+	if handler == nil {
+		return nil, errors.New("sampling is not available")
+	}
+	return handler(ctx, req)
+}
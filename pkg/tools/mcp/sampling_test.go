@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/config/latest"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+// newSamplingServer creates a server with a tool that asks the client for a completion.
+func newSamplingServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "research", Version: "1.0.0"}, nil)
+	server.AddTool(&mcp.Tool{
+		Name:        "summarize",
+		InputSchema: map[string]any{"type": "object"},
+	}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if req.Session.InitializeParams().Capabilities.Sampling == nil {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "no sampling"}}}, nil
+		}
+
+		result, err := req.Session.CreateMessage(ctx, &mcp.CreateMessageParams{
+			MaxTokens: 100,
+			Messages:  []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: "Summarize"}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{result.Content}}, nil
+	})
+	return server
+}
+
+func TestToolset_Sampling(t *testing.T) {
+	toolset := startHTTPToolset(t, newSamplingServer(), "", WithSampling(latest.SamplingConfig{}))
+
+	var received *mcp.CreateMessageParams
+	toolset.SetSamplingHandler(func(_ context.Context, req *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+		received = req
+		return &mcp.CreateMessageResult{Content: &mcp.TextContent{Text: "A summary"}, Model: "test/model", Role: "assistant"}, nil
+	})
+
+	result, err := toolset.callTool(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: "summarize"}})
+	require.NoError(t, err)
+	assert.Equal(t, "A summary", result.Output)
+	require.NotNil(t, received)
+	assert.Equal(t, int64(100), received.MaxTokens)
+	assert.Equal(t, int64(defaultSamplingMaxTokens), toolset.SamplingConfig().MaxTokens)
+}
+
+func TestToolset_SamplingNotConfigured(t *testing.T) {
+	toolset := startHTTPToolset(t, newSamplingServer(), "")
+
+	result, err := toolset.callTool(t.Context(), tools.ToolCall{Function: tools.FunctionCall{Name: "summarize"}})
+	require.NoError(t, err)
+	assert.Equal(t, "no sampling", result.Output)
+}
@@ -7,6 +7,7 @@ import (
 	"iter"
 	"os/exec"
 	"runtime"
+	"sync"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
@@ -18,8 +19,16 @@ type stdioMCPClient struct {
 	command string
 	args    []string
 	env     []string
-	session *mcp.ClientSession
 	cwd     string
+
+	// The session is replaced when the server is restarted. The handlers
+	// can be replaced while notifications are received.
+	mu      sync.RWMutex
+	session *mcp.ClientSession
+
+	resourceUpdatedHandler func(uri string)
+	toolListChangedHandler func()
+	samplingHandler        SamplingHandler
 }
 
 func newStdioCmdClient(command string, args, env []string, cwd string) *stdioMCPClient {
@@ -38,10 +47,50 @@ func (c *stdioMCPClient) Initialize(ctx context.Context, _ *mcp.InitializeReques
 		return nil, errors.New("Docker Desktop is not running") //nolint:staticcheck // Don't lowercase Docker Desktop
 	}
 
+	opts := &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			c.mu.RLock()
+			handler := c.resourceUpdatedHandler
+			c.mu.RUnlock()
+
+			if handler != nil {
+				handler(req.Params.URI)
+			}
+		},
+		ToolListChangedHandler: func(context.Context, *mcp.ToolListChangedRequest) {
+			c.mu.RLock()
+			handler := c.toolListChangedHandler
+			c.mu.RUnlock()
+
+			if handler != nil {
+				handler()
+			}
+		},
+	}
+	// The sampling capability is only advertised if there's a handler.
+	c.mu.RLock()
+	sampling := c.samplingHandler != nil
+	c.mu.RUnlock()
+	if sampling {
+		opts.CreateMessageHandler = func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			c.mu.RLock()
+			handler := c.samplingHandler
+			c.mu.RUnlock()
+
+			if handler == nil {
+				return nil, errors.New("sampling is not available")
+			}
+			return handler(ctx, req.Params)
+		}
+	}
+
+	// Pings detect servers that stopped answering.
+	opts.KeepAlive = keepAliveInterval
+
 	client := mcp.NewClient(&mcp.Implementation{
 		Name:    "cagent",
 		Version: "1.0.0",
-	}, nil)
+	}, opts)
 
 	cmd := exec.CommandContext(ctx, c.command, c.args...)
 	cmd.Env = c.env
@@ -53,52 +102,147 @@ func (c *stdioMCPClient) Initialize(ctx context.Context, _ *mcp.InitializeReques
 		return nil, err
 	}
 
+	c.mu.Lock()
 	c.session = session
-	return c.session.InitializeResult(), nil
+	c.mu.Unlock()
+
+	return session.InitializeResult(), nil
+}
+
+func (c *stdioMCPClient) getSession() *mcp.ClientSession {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.session
 }
 
 func (c *stdioMCPClient) Close(context.Context) error {
-	if c.session == nil {
+	c.mu.Lock()
+	session := c.session
+	c.session = nil
+	c.mu.Unlock()
+
+	if session == nil {
 		return nil
 	}
 
-	return c.session.Close()
+	return session.Close()
+}
+
+// Wait blocks until the session ends, for example because the server process exited.
+func (c *stdioMCPClient) Wait() error {
+	session := c.getSession()
+	if session == nil {
+		return nil
+	}
+
+	return session.Wait()
 }
 
 func (c *stdioMCPClient) ListTools(ctx context.Context, request *mcp.ListToolsParams) iter.Seq2[*mcp.Tool, error] {
-	if c.session == nil {
+	session := c.getSession()
+	if session == nil {
 		return func(yield func(*mcp.Tool, error) bool) {
 			yield(nil, fmt.Errorf("session not initialized"))
 		}
 	}
 
-	return c.session.Tools(ctx, request)
+	return session.Tools(ctx, request)
 }
 
 func (c *stdioMCPClient) CallTool(ctx context.Context, request *mcp.CallToolParams) (*mcp.CallToolResult, error) {
-	if c.session == nil {
+	session := c.getSession()
+	if session == nil {
 		return nil, fmt.Errorf("session not initialized")
 	}
 
-	return c.session.CallTool(ctx, request)
+	return session.CallTool(ctx, request)
 }
 
 // ListPrompts retrieves available prompts from the MCP server via stdio transport
 func (c *stdioMCPClient) ListPrompts(ctx context.Context, request *mcp.ListPromptsParams) iter.Seq2[*mcp.Prompt, error] {
-	if c.session == nil {
+	session := c.getSession()
+	if session == nil {
 		return func(yield func(*mcp.Prompt, error) bool) {
 			yield(nil, fmt.Errorf("session not initialized"))
 		}
 	}
 
-	return c.session.Prompts(ctx, request)
+	return session.Prompts(ctx, request)
 }
 
 // GetPrompt retrieves a specific prompt with arguments from the MCP server via stdio transport
 func (c *stdioMCPClient) GetPrompt(ctx context.Context, request *mcp.GetPromptParams) (*mcp.GetPromptResult, error) {
-	if c.session == nil {
+	session := c.getSession()
+	if session == nil {
+		return nil, fmt.Errorf("session not initialized")
+	}
+
+	return session.GetPrompt(ctx, request)
+}
+
+// ListResources retrieves available resources from the MCP server via stdio transport
+func (c *stdioMCPClient) ListResources(ctx context.Context, request *mcp.ListResourcesParams) iter.Seq2[*mcp.Resource, error] {
+	session := c.getSession()
+	if session == nil {
+		return func(yield func(*mcp.Resource, error) bool) {
+			yield(nil, fmt.Errorf("session not initialized"))
+		}
+	}
+
+	return session.Resources(ctx, request)
+}
+
+// ListResourceTemplates retrieves available resource templates from the MCP server via stdio transport
+func (c *stdioMCPClient) ListResourceTemplates(ctx context.Context, request *mcp.ListResourceTemplatesParams) iter.Seq2[*mcp.ResourceTemplate, error] {
+	session := c.getSession()
+	if session == nil {
+		return func(yield func(*mcp.ResourceTemplate, error) bool) {
+			yield(nil, fmt.Errorf("session not initialized"))
+		}
+	}
+
+	return session.ResourceTemplates(ctx, request)
+}
+
+// ReadResource reads a resource from the MCP server via stdio transport
+func (c *stdioMCPClient) ReadResource(ctx context.Context, request *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+	session := c.getSession()
+	if session == nil {
 		return nil, fmt.Errorf("session not initialized")
 	}
 
-	return c.session.GetPrompt(ctx, request)
+	return session.ReadResource(ctx, request)
+}
+
+// Subscribe asks the MCP server to send notifications when a resource is updated
+func (c *stdioMCPClient) Subscribe(ctx context.Context, request *mcp.SubscribeParams) error {
+	session := c.getSession()
+	if session == nil {
+		return fmt.Errorf("session not initialized")
+	}
+
+	return session.Subscribe(ctx, request)
+}
+
+// SetResourceUpdatedHandler sets the function called when a subscribed resource is updated.
+func (c *stdioMCPClient) SetResourceUpdatedHandler(handler func(uri string)) {
+	c.mu.Lock()
+	c.resourceUpdatedHandler = handler
+	c.mu.Unlock()
+}
+
+// SetToolListChangedHandler sets the function called when the server's list of tools changes.
+func (c *stdioMCPClient) SetToolListChangedHandler(handler func()) {
+	c.mu.Lock()
+	c.toolListChangedHandler = handler
+	c.mu.Unlock()
+}
+
+// SetSamplingHandler sets the function answering sampling requests.
+// It must be set before the client is initialized for the sampling capability
+// to be advertised.
+func (c *stdioMCPClient) SetSamplingHandler(handler SamplingHandler) {
+	c.mu.Lock()
+	c.samplingHandler = handler
+	c.mu.Unlock()
 }
@@ -24,6 +24,11 @@ type OAuthToken struct {
 	RefreshToken string    `json:"refresh_token,omitempty"`
 	Scope        string    `json:"scope,omitempty"`
 	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+
+	// Needed to refresh the token once it's expired
+	TokenEndpoint string `json:"token_endpoint,omitempty"`
+	ClientID      string `json:"client_id,omitempty"`
+	ClientSecret  string `json:"client_secret,omitempty"`
 }
 
 // IsExpired checks if the token is expired
@@ -35,6 +40,11 @@ func (t *OAuthToken) IsExpired() bool {
 	return time.Now().Add(30 * time.Second).After(t.ExpiresAt)
 }
 
+// CanRefresh checks if the token can be refreshed with a refresh_token grant
+func (t *OAuthToken) CanRefresh() bool {
+	return t.RefreshToken != "" && t.TokenEndpoint != ""
+}
+
 // InMemoryTokenStore implements OAuthTokenStore in memory
 type InMemoryTokenStore struct {
 	tokens *concurrent.Map[string, *OAuthToken]
@@ -0,0 +1,353 @@
+package mcp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/cagent/pkg/environment"
+	"github.com/docker/cagent/pkg/paths"
+)
+
+// secretName is the name of the keychain, or pass, entry holding the tokens.
+const secretName = "cagent/mcp-oauth-tokens"
+
+// tokenStorage persists all the tokens of a PersistentTokenStore at once.
+type tokenStorage interface {
+	// Load returns the stored data, or nil if nothing was stored yet
+	Load(ctx context.Context) ([]byte, error)
+	// Save replaces the stored data
+	Save(ctx context.Context, data []byte) error
+	// String describes where the data is stored
+	String() string
+}
+
+// tokenCacheTTL is how long a PersistentTokenStore trusts the tokens it read.
+// Other cagent processes can refresh or remove them in the meantime.
+const tokenCacheTTL = 30 * time.Second
+
+// PersistentTokenStore implements OAuthTokenStore on top of the OS keychain or,
+// if there's none, of an encrypted file, so that OAuth flows survive restarts.
+type PersistentTokenStore struct {
+	storage tokenStorage
+
+	mu       sync.Mutex
+	tokens   map[string]*OAuthToken
+	loadedAt time.Time
+}
+
+var defaultTokenStore = sync.OnceValue(func() OAuthTokenStore {
+	return NewPersistentTokenStore()
+})
+
+// DefaultTokenStore returns the token store shared by all the remote MCP toolsets.
+func DefaultTokenStore() OAuthTokenStore {
+	return defaultTokenStore()
+}
+
+// NewPersistentTokenStore creates a token store backed by the macOS keychain, by
+// pass if it's initialized, by the Secret Service keyring, or by a file encrypted
+// with a key stored next to it.
+func NewPersistentTokenStore() *PersistentTokenStore {
+	return newPersistentTokenStore(defaultTokenStorage())
+}
+
+func newPersistentTokenStore(storage tokenStorage) *PersistentTokenStore {
+	return &PersistentTokenStore{storage: storage}
+}
+
+func defaultTokenStorage() tokenStorage {
+	if keychain, err := environment.NewKeychainProvider(); err == nil {
+		return &secretsStorage{secrets: keychain, name: secretName, kind: "macOS keychain"}
+	}
+	if pass, err := environment.NewPassProvider(); err == nil && passInitialized() {
+		return &secretsStorage{secrets: pass, name: secretName, kind: "pass"}
+	}
+	if keyring, err := environment.NewSecretToolProvider(); err == nil {
+		return &secretsStorage{secrets: keyring, name: secretName, kind: "keyring"}
+	}
+
+	dir := filepath.Join(paths.GetDataDir(), "oauth")
+	return &encryptedFileStorage{
+		path:    filepath.Join(dir, "tokens.enc"),
+		keyPath: filepath.Join(dir, "tokens.key"),
+	}
+}
+
+// passInitialized checks that the pass store has a GPG key to encrypt secrets with.
+func passInitialized() bool {
+	dir := os.Getenv("PASSWORD_STORE_DIR")
+	if dir == "" {
+		dir = filepath.Join(paths.GetHomeDir(), ".password-store")
+	}
+	_, err := os.Stat(filepath.Join(dir, ".gpg-id"))
+	return err == nil
+}
+
+// Location describes where the tokens are stored.
+func (s *PersistentTokenStore) Location() string {
+	return s.storage.String()
+}
+
+func (s *PersistentTokenStore) GetToken(resourceURL string) (*OAuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Tokens are cached since they're looked up on every request to the servers.
+	// An expired token is read again: another process might have refreshed it.
+	token, ok := s.tokens[resourceURL]
+	if s.tokens == nil || time.Since(s.loadedAt) > tokenCacheTTL || (ok && token.IsExpired()) {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		token, ok = s.tokens[resourceURL]
+	}
+	if !ok {
+		return nil, fmt.Errorf("no token found for resource: %s", resourceURL)
+	}
+	return token, nil
+}
+
+func (s *PersistentTokenStore) StoreToken(resourceURL string, token *OAuthToken) error {
+	return s.update(func(tokens map[string]*OAuthToken) {
+		tokens[resourceURL] = token
+	})
+}
+
+func (s *PersistentTokenStore) RemoveToken(resourceURL string) error {
+	return s.update(func(tokens map[string]*OAuthToken) {
+		delete(tokens, resourceURL)
+	})
+}
+
+// Tokens returns all the stored tokens, by server URL.
+func (s *PersistentTokenStore) Tokens() (map[string]*OAuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]*OAuthToken, len(s.tokens))
+	for url, token := range s.tokens {
+		tokens[url] = token
+	}
+	return tokens, nil
+}
+
+// update reloads the tokens before changing them, not to lose the tokens
+// stored by other cagent processes in the meantime.
+func (s *PersistentTokenStore) update(change func(tokens map[string]*OAuthToken)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	change(s.tokens)
+
+	data, err := json.Marshal(s.tokens)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.Save(context.Background(), data); err != nil {
+		return fmt.Errorf("failed to save tokens in %s: %w", s.storage, err)
+	}
+	return nil
+}
+
+// load reads the tokens from the storage. Must be called with s.mu held.
+func (s *PersistentTokenStore) load() error {
+	data, err := s.storage.Load(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to load tokens from %s: %w", s.storage, err)
+	}
+
+	tokens := map[string]*OAuthToken{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			// Don't get stuck on corrupted data: the user will be asked to authorize again.
+			slog.Warn("Ignoring invalid stored OAuth tokens", "location", s.storage.String(), "error", err)
+			tokens = map[string]*OAuthToken{}
+		}
+	}
+	s.tokens = tokens
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// secrets is implemented by the keychain and pass providers.
+type secrets interface {
+	Get(ctx context.Context, name string) (string, bool)
+	Set(ctx context.Context, name, value string) error
+	Delete(ctx context.Context, name string) error
+}
+
+// secretsStorage stores the tokens as a single secret of a password manager.
+type secretsStorage struct {
+	secrets secrets
+	name    string
+	kind    string
+}
+
+func (s *secretsStorage) Load(ctx context.Context) ([]byte, error) {
+	value, found := s.secrets.Get(ctx, s.name)
+	if !found {
+		return nil, nil
+	}
+	return []byte(value), nil
+}
+
+func (s *secretsStorage) Save(ctx context.Context, data []byte) error {
+	if string(data) == "{}" {
+		return s.secrets.Delete(ctx, s.name)
+	}
+	return s.secrets.Set(ctx, s.name, string(data))
+}
+
+func (s *secretsStorage) String() string {
+	return s.kind
+}
+
+// encryptedFileStorage stores the tokens in a file encrypted with AES-GCM. The key
+// is generated on first use and stored in a separate file only readable by the user.
+// It's only used when there's no OS keyring to keep the tokens in: the encryption
+// doesn't protect against someone who can read the user's files, only against the
+// tokens leaking through a copy of the tokens file alone.
+type encryptedFileStorage struct {
+	path    string
+	keyPath string
+}
+
+func (s *encryptedFileStorage) Load(context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := os.ReadFile(s.keyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Ignoring encrypted OAuth tokens without an encryption key", "location", s.path)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like corrupted tokens, tokens that can't be decrypted, because they're truncated
+	// or the key changed, are dropped: the user will be asked to authorize again.
+	if len(data) < gcm.NonceSize() {
+		slog.Warn("Ignoring truncated encrypted OAuth tokens", "location", s.path)
+		return nil, nil
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		slog.Warn("Ignoring OAuth tokens that can't be decrypted", "location", s.path, "error", err)
+		return nil, nil
+	}
+	return plaintext, nil
+}
+
+func (s *encryptedFileStorage) Save(_ context.Context, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	key, err := s.key()
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	encrypted := gcm.Seal(nonce, nonce, data, nil)
+
+	// Write then rename so that a crash never leaves a half-written file.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, encrypted, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *encryptedFileStorage) String() string {
+	return s.path
+}
+
+// key reads the encryption key, or generates it if it doesn't exist yet. The key
+// is generated under a file lock, and written then renamed, so that concurrent
+// cagent processes always end up using the same, complete, key.
+func (s *encryptedFileStorage) key() ([]byte, error) {
+	key, err := os.ReadFile(s.keyPath)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+
+	lock, err := os.OpenFile(s.keyPath+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock encryption key: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return nil, fmt.Errorf("failed to lock encryption key: %w", err)
+	}
+	defer func() { _ = unlockFile(lock) }()
+
+	// Another process might have generated the key while we waited for the lock.
+	key, err = os.ReadFile(s.keyPath)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	tmp := s.keyPath + ".tmp"
+	if err := os.WriteFile(tmp, key, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write encryption key: %w", err)
+	}
+	if err := os.Rename(tmp, s.keyPath); err != nil {
+		return nil, fmt.Errorf("failed to write encryption key: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,237 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecrets struct {
+	values map[string]string
+}
+
+func (f *fakeSecrets) Get(_ context.Context, name string) (string, bool) {
+	value, ok := f.values[name]
+	return value, ok
+}
+
+func (f *fakeSecrets) Set(_ context.Context, name, value string) error {
+	f.values[name] = value
+	return nil
+}
+
+func (f *fakeSecrets) Delete(_ context.Context, name string) error {
+	delete(f.values, name)
+	return nil
+}
+
+func TestPersistentTokenStore_Secrets(t *testing.T) {
+	t.Parallel()
+
+	secrets := &fakeSecrets{values: map[string]string{}}
+	store := newPersistentTokenStore(&secretsStorage{secrets: secrets, name: secretName, kind: "fake"})
+
+	_, err := store.GetToken("https://mcp.example.com")
+	require.Error(t, err)
+
+	require.NoError(t, store.StoreToken("https://mcp.example.com", &OAuthToken{AccessToken: "abc", RefreshToken: "def"}))
+	assert.Contains(t, secrets.values[secretName], `"access_token":"abc"`)
+
+	// Another process sees the token.
+	other := newPersistentTokenStore(&secretsStorage{secrets: secrets, name: secretName, kind: "fake"})
+	token, err := other.GetToken("https://mcp.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", token.AccessToken)
+	assert.Equal(t, "def", token.RefreshToken)
+
+	require.NoError(t, other.RemoveToken("https://mcp.example.com"))
+	assert.Empty(t, secrets.values)
+}
+
+func TestPersistentTokenStore_ReadsChangesFromOtherProcesses(t *testing.T) {
+	t.Parallel()
+
+	secrets := &fakeSecrets{values: map[string]string{}}
+	store := newPersistentTokenStore(&secretsStorage{secrets: secrets, name: secretName, kind: "fake"})
+	other := newPersistentTokenStore(&secretsStorage{secrets: secrets, name: secretName, kind: "fake"})
+
+	require.NoError(t, store.StoreToken("https://mcp.example.com", &OAuthToken{AccessToken: "expired", ExpiresAt: time.Now().Add(-time.Minute)}))
+	_, err := store.GetToken("https://mcp.example.com")
+	require.NoError(t, err)
+
+	// Expired tokens are read again.
+	require.NoError(t, other.StoreToken("https://mcp.example.com", &OAuthToken{AccessToken: "refreshed", ExpiresAt: time.Now().Add(time.Hour)}))
+	token, err := store.GetToken("https://mcp.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed", token.AccessToken)
+
+	// Other tokens are read again once the cache is stale.
+	require.NoError(t, other.RemoveToken("https://mcp.example.com"))
+	_, err = store.GetToken("https://mcp.example.com")
+	require.NoError(t, err)
+	store.mu.Lock()
+	store.loadedAt = time.Now().Add(-tokenCacheTTL)
+	store.mu.Unlock()
+	_, err = store.GetToken("https://mcp.example.com")
+	require.Error(t, err)
+}
+
+func TestPersistentTokenStore_EncryptedFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	storage := &encryptedFileStorage{
+		path:    filepath.Join(dir, "oauth", "tokens.enc"),
+		keyPath: filepath.Join(dir, "oauth", "tokens.key"),
+	}
+	store := newPersistentTokenStore(storage)
+
+	require.NoError(t, store.StoreToken("https://a.example.com", &OAuthToken{AccessToken: "secret-a"}))
+	require.NoError(t, store.StoreToken("https://b.example.com", &OAuthToken{AccessToken: "secret-b"}))
+
+	encrypted, err := os.ReadFile(storage.path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "secret-a")
+
+	info, err := os.Stat(storage.keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	tokens, err := newPersistentTokenStore(storage).Tokens()
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, "secret-b", tokens["https://b.example.com"].AccessToken)
+
+	// Tokens another key can't decrypt are dropped, and replaced on the next save.
+	require.NoError(t, os.WriteFile(storage.keyPath, make([]byte, 32), 0o600))
+	store = newPersistentTokenStore(storage)
+	tokens, err = store.Tokens()
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+	require.NoError(t, store.StoreToken("https://c.example.com", &OAuthToken{AccessToken: "secret-c"}))
+	tokens, err = newPersistentTokenStore(storage).Tokens()
+	require.NoError(t, err)
+	assert.Len(t, tokens, 1)
+}
+
+func TestEncryptedFileStorage_ConcurrentKeyGeneration(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	keys := make([][]byte, 8)
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Go(func() {
+			storage := &encryptedFileStorage{
+				path:    filepath.Join(dir, "tokens.enc"),
+				keyPath: filepath.Join(dir, "tokens.key"),
+			}
+			key, err := storage.key()
+			assert.NoError(t, err)
+			keys[i] = key
+		})
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		assert.Len(t, key, 32)
+		assert.Equal(t, keys[0], key)
+	}
+}
+
+func TestOAuthTransport_RefreshesExpiredToken(t *testing.T) {
+	t.Parallel()
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "old-refresh", r.PostForm.Get("refresh_token"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "new-access", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	var authorization string
+	mcpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	t.Cleanup(mcpServer.Close)
+
+	store := NewInMemoryTokenStore()
+	require.NoError(t, store.StoreToken(mcpServer.URL, &OAuthToken{
+		AccessToken:   "old-access",
+		RefreshToken:  "old-refresh",
+		ExpiresAt:     time.Now().Add(-time.Minute),
+		TokenEndpoint: tokenServer.URL,
+		ClientID:      "client",
+	}))
+
+	transport := &oauthTransport{base: http.DefaultTransport, tokenStore: store, baseURL: mcpServer.URL}
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, mcpServer.URL, http.NoBody)
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "Bearer new-access", authorization)
+
+	token, err := store.GetToken(mcpServer.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "new-access", token.AccessToken)
+	assert.Equal(t, "old-refresh", token.RefreshToken)
+	assert.Equal(t, "client", token.ClientID)
+	assert.False(t, token.IsExpired())
+}
+
+func TestOAuthTransport_RefreshesTokenOnce(t *testing.T) {
+	t.Parallel()
+
+	var refreshes atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		refreshes.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "new-access", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	mcpServer := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	t.Cleanup(mcpServer.Close)
+
+	store := NewInMemoryTokenStore()
+	require.NoError(t, store.StoreToken(mcpServer.URL, &OAuthToken{
+		AccessToken:   "old-access",
+		RefreshToken:  "single-use",
+		ExpiresAt:     time.Now().Add(-time.Minute),
+		TokenEndpoint: tokenServer.URL,
+		ClientID:      "client",
+	}))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			transport := &oauthTransport{base: http.DefaultTransport, tokenStore: store, baseURL: mcpServer.URL}
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, mcpServer.URL, http.NoBody)
+			assert.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), refreshes.Load())
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func addEchoTool(server *mcp.Server, name string) {
+	server.AddTool(&mcp.Tool{
+		Name:        name,
+		InputSchema: map[string]any{"type": "object"},
+	}, func(context.Context, *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: name}}}, nil
+	})
+}
+
+func TestToolset_ToolsCache(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "dynamic", Version: "1.0.0"}, nil)
+	addEchoTool(server, "first")
+
+	var listCalls atomic.Int32
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method == "tools/list" {
+				listCalls.Add(1)
+			}
+			return next(ctx, method, req)
+		}
+	})
+
+	toolset := startHTTPToolset(t, server, "")
+	changed := make(chan struct{}, 1)
+	toolset.SetToolsChangedHandler(func() { changed <- struct{}{} })
+
+	// The list of tools is only fetched once.
+	for range 3 {
+		allTools, err := toolset.Tools(t.Context())
+		require.NoError(t, err)
+		require.Len(t, allTools, 1)
+		assert.Equal(t, "first", allTools[0].Name)
+	}
+	assert.Equal(t, int32(1), listCalls.Load())
+
+	// Until the server says the list changed.
+	addEchoTool(server, "second")
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the tool list change wasn't notified")
+	}
+
+	allTools, err := toolset.Tools(t.Context())
+	require.NoError(t, err)
+	require.Len(t, allTools, 2)
+	assert.Equal(t, int32(2), listCalls.Load())
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// MaxOutputTail is how much of the partial output of a tool call clients keep to display it.
+const MaxOutputTail = 64 << 10
+
+type outputKey struct{}
+
+// OutputFunc receives the output of a tool call while it's being produced.
+type OutputFunc func(delta string)
+
+// WithOutput returns a context that carries a function receiving the partial
+// output of the tool call, for tools that can stream it.
+func WithOutput(ctx context.Context, fn OutputFunc) context.Context {
+	return context.WithValue(ctx, outputKey{}, fn)
+}
+
+// OutputWriter returns a writer that forwards the partial output of a tool call
+// to the function carried by the context, or io.Discard if there's none.
+// What's written doesn't change the final result of the tool call.
+func OutputWriter(ctx context.Context) io.Writer {
+	fn, _ := ctx.Value(outputKey{}).(OutputFunc)
+	if fn == nil {
+		return io.Discard
+	}
+	return &outputWriter{fn: fn}
+}
+
+// outputWriter is a pointer type so that it can be compared, as os/exec does with Stdout and Stderr.
+type outputWriter struct {
+	fn OutputFunc
+	mu sync.Mutex
+	// pending holds the first bytes of a multi-byte character split across writes.
+	pending []byte
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buf := append(w.pending, p...)
+	n := len(buf) - incompleteSuffix(buf)
+	w.pending = append([]byte(nil), buf[n:]...)
+	if n > 0 {
+		w.fn(string(buf[:n]))
+	}
+	return len(p), nil
+}
+
+// AppendOutputTail appends a delta to the partial output of a tool call, and drops
+// the beginning of the output beyond MaxOutputTail bytes, at a character boundary.
+func AppendOutputTail(output, delta string) string {
+	output += delta
+	if len(output) <= MaxOutputTail {
+		return output
+	}
+
+	start := len(output) - MaxOutputTail
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return output[start:]
+}
+
+// incompleteSuffix returns the length of the incomplete UTF-8 character at the end of p, if any.
+func incompleteSuffix(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				return 0
+			}
+			return len(p) - i
+		}
+	}
+	return 0
+}
@@ -2,6 +2,9 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -23,6 +26,53 @@ type ToolCallResult struct {
 	Output  string `json:"output"`
 	IsError bool   `json:"isError,omitempty"`
 	Meta    any    `json:"meta,omitempty"`
+	// Images returned by the tool, next to its text output.
+	Images []Image `json:"images,omitempty"`
+}
+
+// Image is an image returned by a tool, such as a screenshot.
+type Image struct {
+	MIMEType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+}
+
+// MaxImageSize is the size of the largest images sent to models. Once base64
+// encoded, they stay under the 5MB limit of Anthropic's API.
+const MaxImageSize = 3_750_000
+
+// Supported reports whether the image can be sent to models: only JPEG, PNG,
+// GIF and WebP images up to MaxImageSize are accepted by all the providers.
+// Since images are kept in sessions, an image rejected once would fail every
+// later turn.
+func (i Image) Supported() bool {
+	switch i.MIMEType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return len(i.Data) <= MaxImageSize
+	}
+	return false
+}
+
+// Placeholder describes an image that isn't sent to models.
+func (i Image) Placeholder() string {
+	return fmt.Sprintf("[image: %s, %d bytes, not shown: only JPEG, PNG, GIF and WebP images up to %d bytes are supported]", i.MIMEType, len(i.Data), MaxImageSize)
+}
+
+// DataURL returns the image as a base64 data URL.
+func (i Image) DataURL() string {
+	return "data:" + i.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// ImageFromDataURL decodes an image stored as a base64 data URL.
+func ImageFromDataURL(url string) (Image, bool) {
+	mimeType, encoded, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ";base64,")
+	if !ok || !strings.HasPrefix(url, "data:") {
+		return Image{}, false
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Image{}, false
+	}
+	return Image{MIMEType: mimeType, Data: data}, true
 }
 
 func ResultError(output string) *ToolCallResult {
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageDataURL(t *testing.T) {
+	image := Image{MIMEType: "image/png", Data: []byte{1, 2, 3}}
+	assert.Equal(t, "data:image/png;base64,AQID", image.DataURL())
+
+	decoded, ok := ImageFromDataURL(image.DataURL())
+	require.True(t, ok)
+	assert.Equal(t, image, decoded)
+
+	_, ok = ImageFromDataURL("https://example.com/image.png")
+	assert.False(t, ok)
+	_, ok = ImageFromDataURL("data:image/png;base64,!!!")
+	assert.False(t, ok)
+}
+
+func TestOutputWriter_KeepsMultiByteCharacters(t *testing.T) {
+	var chunks []string
+	w := OutputWriter(WithOutput(t.Context(), func(chunk string) { chunks = append(chunks, chunk) }))
+
+	text := []byte("café ☕")
+	for _, b := range text {
+		n, err := w.Write([]byte{b})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+
+	assert.Equal(t, "café ☕", strings.Join(chunks, ""))
+	for _, chunk := range chunks {
+		assert.True(t, utf8.ValidString(chunk), "invalid chunk %q", chunk)
+	}
+}
+
+func TestAppendOutputTail(t *testing.T) {
+	assert.Equal(t, "ab", AppendOutputTail("a", "b"))
+
+	output := strings.Repeat("a", MaxOutputTail-1)
+	output = AppendOutputTail(output, "☕")
+	assert.LessOrEqual(t, len(output), MaxOutputTail)
+	assert.True(t, utf8.ValidString(output))
+	assert.True(t, strings.HasSuffix(output, "a☕"))
+
+	// The first character is dropped whole.
+	output = AppendOutputTail(strings.Repeat("☕", MaxOutputTail/3), "a")
+	assert.True(t, utf8.ValidString(output))
+	assert.True(t, strings.HasSuffix(output, "☕a"))
+}
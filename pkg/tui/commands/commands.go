@@ -61,6 +61,46 @@ func builtInSessionCommands() []Item {
 				return core.CmdHandler(messages.CompactSessionMsg{})
 			},
 		},
+		{
+			ID:           "session.fork",
+			Label:        "Fork",
+			SlashCommand: "/fork",
+			Description:  "Retry a prompt in a new session, keeping this one (usage: /fork [n])",
+			Category:     "Session",
+			Execute: func() tea.Cmd {
+				return core.CmdHandler(messages.ForkSessionMsg{})
+			},
+		},
+		{
+			ID:           "session.rewind",
+			Label:        "Rewind",
+			SlashCommand: "/rewind",
+			Description:  "Rewind the conversation to a prompt (usage: /rewind [n])",
+			Category:     "Session",
+			Execute: func() tea.Cmd {
+				return core.CmdHandler(messages.RewindSessionMsg{})
+			},
+		},
+		{
+			ID:           "session.undo",
+			Label:        "Undo",
+			SlashCommand: "/undo",
+			Description:  "Undo the file changes made during the last turn",
+			Category:     "Session",
+			Execute: func() tea.Cmd {
+				return core.CmdHandler(messages.UndoFileChangesMsg{})
+			},
+		},
+		{
+			ID:           "session.restore",
+			Label:        "Restore",
+			SlashCommand: "/restore",
+			Description:  "Restore the files changed since a prompt (usage: /restore [n])",
+			Category:     "Session",
+			Execute: func() tea.Cmd {
+				return core.CmdHandler(messages.RestoreFilesMsg{})
+			},
+		},
 		{
 			ID:           "session.clipboard",
 			Label:        "Copy",
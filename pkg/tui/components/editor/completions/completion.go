@@ -15,6 +15,6 @@ type Completion interface {
 func Completions(a *app.App) []Completion {
 	return []Completion{
 		NewCommandCompletion(a),
-		NewFileCompletion(),
+		NewFileCompletion(a),
 	}
 }
@@ -1,14 +1,23 @@
 package completions
 
 import (
+	"cmp"
+	"context"
+
+	"github.com/docker/cagent/pkg/app"
 	"github.com/docker/cagent/pkg/fsx"
 	"github.com/docker/cagent/pkg/tui/components/completion"
 )
 
-type fileCompletion struct{}
+type fileCompletion struct {
+	app *app.App
+}
 
-func NewFileCompletion() Completion {
-	return &fileCompletion{}
+// NewFileCompletion completes @ references to files and to the resources of MCP servers.
+func NewFileCompletion(a *app.App) Completion {
+	return &fileCompletion{
+		app: a,
+	}
 }
 
 func (c *fileCompletion) AutoSubmit() bool {
@@ -24,6 +33,8 @@ func (c *fileCompletion) Trigger() string {
 }
 
 func (c *fileCompletion) Items() []completion.Item {
+	items := c.resourceItems()
+
 	// Try to create VCS matcher for current directory
 	vcsMatcher, err := fsx.NewVCSMatcher(".")
 
@@ -37,16 +48,37 @@ func (c *fileCompletion) Items() []completion.Item {
 	// Get files with optional VCS filtering
 	files, err := fsx.ListDirectory(".", shouldIgnore)
 	if err != nil {
-		return nil
+		return items
 	}
 
-	items := make([]completion.Item, len(files))
-	for i, f := range files {
-		items[i] = completion.Item{
+	for _, f := range files {
+		items = append(items, completion.Item{
 			Label: f,
 			Value: "@" + f, // Include @ prefix since completion handler removes trigger
-		}
+		})
 	}
 
 	return items
 }
+
+// resourceItems lists the MCP resources as @server:uri. Templates are skipped
+// since their URIs have to be expanded before they can be read.
+func (c *fileCompletion) resourceItems() []completion.Item {
+	if c.app == nil {
+		return nil
+	}
+
+	var items []completion.Item
+	for _, resource := range c.app.CurrentMCPResources(context.Background()) {
+		if resource.Template {
+			continue
+		}
+
+		items = append(items, completion.Item{
+			Label:       resource.Ref(),
+			Description: cmp.Or(resource.Title, resource.Name),
+			Value:       "@" + resource.Ref(),
+		})
+	}
+	return items
+}
@@ -1,12 +1,15 @@
 package editor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/textarea"
@@ -19,6 +22,7 @@ import (
 	"github.com/docker/cagent/pkg/app"
 	"github.com/docker/cagent/pkg/history"
 	"github.com/docker/cagent/pkg/paths"
+	mcptools "github.com/docker/cagent/pkg/tools/mcp"
 	"github.com/docker/cagent/pkg/tui/components/completion"
 	"github.com/docker/cagent/pkg/tui/components/editor/completions"
 	"github.com/docker/cagent/pkg/tui/core"
@@ -37,6 +41,8 @@ const (
 	// maxInlinePasteChars is the character limit for inline pastes.
 	// This catches very long single-line pastes that would clutter the editor.
 	maxInlinePasteChars = 500
+	// resourceReadTimeout bounds how long reading an MCP resource attachment can take.
+	resourceReadTimeout = 10 * time.Second
 )
 
 type attachment struct {
@@ -44,7 +50,8 @@ type attachment struct {
 	placeholder string // @paste-1 or @filename
 	label       string // Display label like "paste-1 (21.1 KB)"
 	sizeBytes   int
-	isTemp      bool // True for paste temp files that need cleanup
+	isTemp      bool   // True for paste temp files that need cleanup
+	resource    string // server:uri of an MCP resource, read when the message is sent
 }
 
 // AttachmentPreview describes an attachment and its contents for dialog display.
@@ -53,6 +60,11 @@ type AttachmentPreview struct {
 	Content string
 }
 
+// AttachmentPreviewMsg is sent once the content of an attachment to preview was read.
+type AttachmentPreviewMsg struct {
+	Preview AttachmentPreview
+}
+
 // SendMsg represents a message to send
 type SendMsg struct {
 	Content     string            // Full content sent to the agent (with file contents expanded)
@@ -73,11 +85,14 @@ type Editor interface {
 	Cleanup()
 	GetSize() (width, height int)
 	BannerHeight() int
-	AttachmentAt(x int) (AttachmentPreview, bool)
+	// PreviewAttachmentAt returns a command reading the attachment rendered at the given
+	// X position and sending an AttachmentPreviewMsg, or nil if there's no attachment there.
+	PreviewAttachmentAt(x int) tea.Cmd
 }
 
 // editor implements [Editor]
 type editor struct {
+	app      *app.App
 	textarea textarea.Model
 	hist     *history.History
 	width    int
@@ -120,6 +135,7 @@ func New(a *app.App, hist *history.History) Editor {
 	ta.ShowLineNumbers = false
 
 	e := &editor{
+		app:                           a,
 		textarea:                      ta,
 		hist:                          hist,
 		completions:                   completions.Completions(a),
@@ -577,13 +593,13 @@ func (e *editor) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 				if prev != "" && !e.working {
 					e.tryAddFileRef(e.pendingFileRef) // Add any pending @filepath before send
 					e.pendingFileRef = ""
-					attachments := e.collectAttachments(prev)
+					send := e.sendCmd(prev)
 					e.textarea.SetValue(prev)
 					e.textarea.MoveToEnd()
 					e.textarea.Reset()
 					e.userTyped = false
 					e.refreshSuggestion()
-					return e, core.CmdHandler(SendMsg{Content: prev, Attachments: attachments})
+					return e, send
 				}
 				return e, nil
 			}
@@ -593,11 +609,11 @@ func (e *editor) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 				slog.Debug(value)
 				e.tryAddFileRef(e.pendingFileRef) // Add any pending @filepath before send
 				e.pendingFileRef = ""
-				attachments := e.collectAttachments(value)
+				send := e.sendCmd(value)
 				e.textarea.Reset()
 				e.userTyped = false
 				e.refreshSuggestion()
-				return e, core.CmdHandler(SendMsg{Content: value, Attachments: attachments})
+				return e, send
 			}
 
 			return e, nil
@@ -791,15 +807,17 @@ func (e *editor) GetSize() (width, height int) {
 		e.height + styles.EditorStyle.GetVerticalFrameSize()
 }
 
-// AttachmentAt returns preview information for the attachment rendered at the given X position.
-func (e *editor) AttachmentAt(x int) (AttachmentPreview, bool) {
+// PreviewAttachmentAt returns a command reading the attachment rendered at the given X position
+// and sending an AttachmentPreviewMsg. The content is read off the UI thread since MCP
+// resources are read from their servers.
+func (e *editor) PreviewAttachmentAt(x int) tea.Cmd {
 	if e.banner == nil || e.banner.Height() == 0 {
-		return AttachmentPreview{}, false
+		return nil
 	}
 
 	item, ok := e.banner.HitTest(x)
 	if !ok {
-		return AttachmentPreview{}, false
+		return nil
 	}
 
 	for _, att := range e.attachments {
@@ -807,19 +825,22 @@ func (e *editor) AttachmentAt(x int) (AttachmentPreview, bool) {
 			continue
 		}
 
-		data, err := os.ReadFile(att.path)
-		if err != nil {
-			slog.Warn("failed to read attachment preview", "path", att.path, "error", err)
-			return AttachmentPreview{}, false
-		}
+		a := e.app
+		return func() tea.Msg {
+			data, err := readAttachment(a, att)
+			if err != nil {
+				slog.Warn("failed to read attachment preview", "path", att.path, "resource", att.resource, "error", err)
+				return nil
+			}
 
-		return AttachmentPreview{
-			Title:   item.label,
-			Content: string(data),
-		}, true
+			return AttachmentPreviewMsg{Preview: AttachmentPreview{
+				Title:   item.label,
+				Content: data,
+			}}
+		}
 	}
 
-	return AttachmentPreview{}, false
+	return nil
 }
 
 // Focus gives focus to the component
@@ -873,12 +894,16 @@ func (e *editor) tryAddFileRef(word string) {
 }
 
 // addFileAttachment adds a file reference as an attachment if valid.
+// References to MCP resources, as @server:uri, are attached too.
 func (e *editor) addFileAttachment(placeholder string) {
 	path := strings.TrimPrefix(placeholder, "@")
 
 	// Check if it's an existing file (not directory)
 	info, err := os.Stat(path)
 	if err != nil || info.IsDir() {
+		if _, _, ok := mcptools.ParseResourceRef(path); ok && err != nil {
+			e.addResourceAttachment(placeholder, path)
+		}
 		return
 	}
 
@@ -898,40 +923,83 @@ func (e *editor) addFileAttachment(placeholder string) {
 	})
 }
 
-// collectAttachments returns a map of placeholder to file content for all attachments
-// referenced in content. Unreferenced attachments are cleaned up.
-func (e *editor) collectAttachments(content string) map[string]string {
-	if len(e.attachments) == 0 {
-		return nil
-	}
-
-	attachments := make(map[string]string)
+// addResourceAttachment adds a reference to an MCP resource as an attachment.
+func (e *editor) addResourceAttachment(placeholder, ref string) {
 	for _, att := range e.attachments {
-		if !strings.Contains(content, att.placeholder) {
-			if att.isTemp {
-				_ = os.Remove(att.path)
-			}
-			continue
+		if att.placeholder == placeholder {
+			return
 		}
+	}
 
+	e.attachments = append(e.attachments, attachment{
+		placeholder: placeholder,
+		label:       ref,
+		resource:    ref,
+	})
+}
+
+// readAttachment returns the content of an attachment: the file, or the MCP resource.
+func readAttachment(a *app.App, att attachment) (string, error) {
+	if att.resource == "" {
 		data, err := os.ReadFile(att.path)
-		if err != nil {
-			slog.Warn("failed to read attachment", "path", att.path, "error", err)
+		return string(data), err
+	}
+
+	if a == nil {
+		return "", errors.New("MCP resources are not available")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resourceReadTimeout)
+	defer cancel()
+	return a.ReadMCPResource(ctx, att.resource)
+}
+
+// sendCmd returns the command sending content, with its attachments read off the UI thread.
+func (e *editor) sendCmd(content string) tea.Cmd {
+	collect := e.takeAttachments(content)
+	return func() tea.Msg {
+		return SendMsg{Content: content, Attachments: collect()}
+	}
+}
+
+// takeAttachments removes the attachments from the editor and returns a function reading
+// those referenced in content, as a map of placeholder to file or resource content.
+// Unreferenced attachments are cleaned up.
+func (e *editor) takeAttachments(content string) func() map[string]string {
+	pending, a := e.attachments, e.app
+	e.attachments = nil
+
+	return func() map[string]string {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		attachments := make(map[string]string)
+		for _, att := range pending {
+			if !strings.Contains(content, att.placeholder) {
+				if att.isTemp {
+					_ = os.Remove(att.path)
+				}
+				continue
+			}
+
+			data, err := readAttachment(a, att)
+			if err != nil {
+				slog.Warn("failed to read attachment", "path", att.path, "resource", att.resource, "error", err)
+				if att.isTemp {
+					_ = os.Remove(att.path)
+				}
+				continue
+			}
+
+			attachments[att.placeholder] = data
+
 			if att.isTemp {
 				_ = os.Remove(att.path)
 			}
-			continue
 		}
 
-		attachments[att.placeholder] = string(data)
-
-		if att.isTemp {
-			_ = os.Remove(att.path)
-		}
+		return attachments
 	}
-	e.attachments = nil
-
-	return attachments
 }
 
 // Cleanup removes any temporary paste files that haven't been sent yet.
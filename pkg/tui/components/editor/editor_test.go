@@ -17,7 +17,7 @@ func TestCollectAttachments(t *testing.T) {
 		e := &editor{attachments: nil}
 		content := "hello world"
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		assert.Nil(t, result)
 	})
@@ -27,7 +27,7 @@ func TestCollectAttachments(t *testing.T) {
 		e := &editor{attachments: []attachment{}}
 		content := "hello world"
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		assert.Nil(t, result)
 	})
@@ -49,7 +49,7 @@ func TestCollectAttachments(t *testing.T) {
 		}}}
 		content := "analyze " + ref
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		require.NotNil(t, result)
 		assert.Equal(t, "file content here", result[ref])
@@ -73,7 +73,7 @@ func TestCollectAttachments(t *testing.T) {
 		}}
 		content := "compare " + ref1 + " with " + ref2
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		require.NotNil(t, result)
 		assert.Equal(t, "package first", result[ref1])
@@ -95,7 +95,7 @@ func TestCollectAttachments(t *testing.T) {
 		}}}
 		content := "message without the reference"
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		assert.Empty(t, result, "should return empty map when ref not in content")
 		assert.Nil(t, e.attachments, "attachments should be cleared after collection")
@@ -112,7 +112,7 @@ func TestCollectAttachments(t *testing.T) {
 		}}}
 		content := "analyze " + ref
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		// Map is created but empty since file doesn't exist
 		assert.Empty(t, result)
@@ -125,7 +125,7 @@ func TestCollectAttachments(t *testing.T) {
 		tmpDir := t.TempDir()
 		ref := "@" + tmpDir
 		// Note: addFileAttachment would normally reject directories, but we test
-		// collectAttachments directly here - it will fail to read as file
+		// takeAttachments directly here - it will fail to read as file
 		e := &editor{attachments: []attachment{{
 			path:        tmpDir,
 			placeholder: ref,
@@ -133,7 +133,7 @@ func TestCollectAttachments(t *testing.T) {
 		}}}
 		content := "analyze " + ref
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		// os.ReadFile on a directory returns an error, so no attachment added
 		assert.Empty(t, result)
@@ -155,7 +155,7 @@ func TestCollectAttachments(t *testing.T) {
 		}}
 		content := "check " + validRef + " and " + invalidRef
 
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		require.NotNil(t, result)
 		assert.Equal(t, "valid content", result[validRef])
@@ -252,7 +252,7 @@ func TestTryAddFileRef(t *testing.T) {
 
 		// Verify both get collected
 		content := "compare @" + completedFile + " with @" + manualFile
-		result := e.collectAttachments(content)
+		result := e.takeAttachments(content)()
 
 		assert.Equal(t, "package completed", result["@"+completedFile])
 		assert.Equal(t, "package manual", result["@"+manualFile])
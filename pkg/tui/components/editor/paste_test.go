@@ -122,7 +122,7 @@ func TestCollectAttachments_WithPastes(t *testing.T) {
 	}
 
 	input := "Hello " + att.placeholder + " world"
-	result := e.collectAttachments(input)
+	result := e.takeAttachments(input)()
 
 	// Content should be in the attachments map keyed by placeholder
 	require.NotNil(t, result)
@@ -145,7 +145,7 @@ func TestCollectAttachments_RemovesUnusedFiles(t *testing.T) {
 	}
 
 	// Collect with content that doesn't include the placeholder
-	result := e.collectAttachments("no placeholder here")
+	result := e.takeAttachments("no placeholder here")()
 
 	assert.Empty(t, result)
 	assert.NoFileExists(t, att.path, "unused paste file should be removed")
@@ -19,6 +19,7 @@ import (
 	"github.com/docker/cagent/pkg/tui/components/notification"
 	"github.com/docker/cagent/pkg/tui/components/tool"
 	"github.com/docker/cagent/pkg/tui/components/tool/editfile"
+	"github.com/docker/cagent/pkg/tui/components/toolcommon"
 	"github.com/docker/cagent/pkg/tui/core"
 	"github.com/docker/cagent/pkg/tui/core/layout"
 	"github.com/docker/cagent/pkg/tui/service"
@@ -51,7 +52,9 @@ type Model interface {
 	AddWelcomeMessage(content string) tea.Cmd
 	AddOrUpdateToolCall(agentName string, toolCall tools.ToolCall, toolDef tools.Tool, status types.ToolStatus) tea.Cmd
 	AddToolResult(msg *runtime.ToolCallResponseEvent, status types.ToolStatus) tea.Cmd
+	AppendToolOutput(toolCallID, delta string) tea.Cmd
 	AppendToLastMessage(agentName string, messageType types.MessageType, content string) tea.Cmd
+	DiscardPartialResponse() tea.Cmd
 	AddShellOutputMessage(content string) tea.Cmd
 
 	ScrollToBottom() tea.Cmd
@@ -848,6 +851,9 @@ func (m *model) AddToolResult(msg *runtime.ToolCallResponseEvent, status types.T
 		toolMessage := m.messages[i]
 		if toolMessage.ToolCall.ID == msg.ToolCall.ID {
 			toolMessage.Content = strings.ReplaceAll(msg.Response, "\t", "    ")
+			if msg.Result != nil && len(msg.Result.Images) > 0 {
+				toolMessage.Content = strings.TrimSpace(toolMessage.Content + "\n" + toolcommon.ImagePlaceholders(msg.Result.Images))
+			}
 			toolMessage.ToolStatus = status
 			toolMessage.ToolResult = msg.Result
 			m.invalidateItem(i)
@@ -860,6 +866,23 @@ func (m *model) AddToolResult(msg *runtime.ToolCallResponseEvent, status types.T
 	return nil
 }
 
+// AppendToolOutput appends the output streamed by a running tool to its message.
+// Only the tail of the output is kept, and it's replaced by the tool's response once it's done.
+func (m *model) AppendToolOutput(toolCallID, delta string) tea.Cmd {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		toolMessage := m.messages[i]
+		if toolMessage.ToolCall.ID == toolCallID {
+			if toolMessage.ToolStatus != types.ToolStatusRunning {
+				return nil
+			}
+			toolMessage.Content = tools.AppendOutputTail(toolMessage.Content, strings.ReplaceAll(delta, "\t", "    "))
+			m.invalidateItem(i)
+			return nil
+		}
+	}
+	return nil
+}
+
 // AppendToLastMessage appends content to the last message (for streaming)
 func (m *model) AppendToLastMessage(agentName string, messageType types.MessageType, content string) tea.Cmd {
 	m.removeSpinner()
@@ -883,6 +906,38 @@ func (m *model) AppendToLastMessage(agentName string, messageType types.MessageT
 	return m.addMessage(types.Agent(messageType, agentName, content))
 }
 
+// DiscardPartialResponse removes the text, reasoning and pending tool calls streamed
+// since the last message of another kind, when a response is restarted from scratch.
+// A spinner takes their place until the new response streams in.
+func (m *model) DiscardPartialResponse() tea.Cmd {
+	m.removeSpinner()
+
+	end := len(m.messages)
+	for end > 0 {
+		msg := m.messages[end-1]
+		partial := msg.Type == types.MessageTypeAssistant || msg.Type == types.MessageTypeAssistantReasoning ||
+			(msg.Type == types.MessageTypeToolCall && msg.ToolStatus == types.ToolStatusPending)
+		if !partial {
+			break
+		}
+		end--
+	}
+	if end == len(m.messages) {
+		return m.AddAssistantMessage()
+	}
+
+	m.clearSelection()
+	m.messages = m.messages[:end]
+	m.views = m.views[:min(end, len(m.views))]
+	if end > 0 {
+		m.sessionState.PreviousMessage = m.messages[end-1]
+	} else {
+		m.sessionState.PreviousMessage = nil
+	}
+	m.invalidateAllItems()
+	return m.AddAssistantMessage()
+}
+
 // ScrollToBottom scrolls to the bottom of the chat
 // It only scrolls if the user hasn't manually scrolled away from the bottom
 func (m *model) ScrollToBottom() tea.Cmd {
@@ -41,6 +41,7 @@ type Model interface {
 	SetTeamInfo(availableAgents []runtime.AgentDetails)
 	SetAgentSwitching(switching bool)
 	SetToolsetInfo(availableTools int)
+	SetMCPServerStatus(server, status string)
 	GetSize() (width, height int)
 }
 
@@ -69,6 +70,7 @@ type model struct {
 	availableAgents  []runtime.AgentDetails
 	agentSwitching   bool
 	availableTools   int
+	mcpServers       map[string]string // server name -> connection status
 	sessionState     *service.SessionState
 }
 
@@ -82,6 +84,7 @@ func New(sessionState *service.SessionState) Model {
 		spinner:      spinner.New(spinner.ModeSpinnerOnly),
 		sessionTitle: "New session",
 		ragIndexing:  make(map[string]*ragIndexingState),
+		mcpServers:   make(map[string]string),
 		sessionState: sessionState,
 	}
 }
@@ -127,6 +130,11 @@ func (m *model) SetToolsetInfo(availableTools int) {
 	m.availableTools = availableTools
 }
 
+// SetMCPServerStatus sets the status of the connection to an MCP server
+func (m *model) SetMCPServerStatus(server, status string) {
+	m.mcpServers[server] = status
+}
+
 // formatTokenCount formats a token count with K/M suffixes for readability
 func formatTokenCount(count int64) string {
 	if count >= 1000000 {
@@ -221,6 +229,9 @@ func (m *model) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 	case *runtime.ToolsetInfoEvent:
 		m.SetToolsetInfo(msg.AvailableTools)
 		return m, nil
+	case *runtime.MCPServerStatusEvent:
+		m.SetMCPServerStatus(msg.Server, msg.Status)
+		return m, nil
 	default:
 		var cmds []tea.Cmd
 
@@ -562,6 +573,7 @@ func (m *model) toolsetInfo() string {
 	if m.availableTools > 0 {
 		lines = append(lines, styles.TabAccentStyle.Render("█")+styles.TabPrimaryStyle.Render(fmt.Sprintf(" %d tools available", m.availableTools)))
 	}
+	lines = append(lines, m.mcpServersStatus()...)
 
 	if m.sessionState.YoloMode {
 		indicator := styles.TabAccentStyle.Render("✓") + styles.TabPrimaryStyle.Render(" YOLO mode enabled")
@@ -582,6 +594,36 @@ func (m *model) toolsetInfo() string {
 	return m.renderTab("Tools", lipgloss.JoinVertical(lipgloss.Top, lines...))
 }
 
+// mcpServersStatus renders one line per MCP server with the status of its connection
+func (m *model) mcpServersStatus() []string {
+	servers := make([]string, 0, len(m.mcpServers))
+	for server := range m.mcpServers {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	var lines []string
+	for _, server := range servers {
+		status := m.mcpServers[server]
+
+		var indicator string
+		switch status {
+		case "connected":
+			indicator = styles.SuccessStyle.Render("●")
+		case "reconnecting":
+			indicator = styles.WarningStyle.Render("●")
+		default:
+			indicator = styles.ErrorStyle.Render("●")
+		}
+		line := indicator + styles.TabPrimaryStyle.Render(" "+server)
+		if status != "connected" {
+			line += styles.MutedStyle.Render(" " + status)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 // SetSize sets the dimensions of the component
 func (m *model) SetSize(width, height int) tea.Cmd {
 	m.width = width
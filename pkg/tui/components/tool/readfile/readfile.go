@@ -28,5 +28,8 @@ func extractResult(msg *types.Message) string {
 	if meta.Error != "" {
 		return meta.Error
 	}
+	if len(msg.ToolResult.Images) > 0 {
+		return toolcommon.ImagePlaceholders(msg.ToolResult.Images)
+	}
 	return fmt.Sprintf("%d lines", meta.LineCount)
 }
@@ -2,14 +2,24 @@ package shell
 
 import (
 	"github.com/docker/cagent/pkg/tools/builtin"
+	"github.com/docker/cagent/pkg/tui/components/spinner"
 	"github.com/docker/cagent/pkg/tui/components/toolcommon"
 	"github.com/docker/cagent/pkg/tui/core/layout"
 	"github.com/docker/cagent/pkg/tui/service"
 	"github.com/docker/cagent/pkg/tui/types"
 )
 
+var extractCmd = toolcommon.ExtractField(func(a builtin.RunShellArgs) string { return a.Cmd })
+
 func New(msg *types.Message, sessionState *service.SessionState) layout.Model {
-	return toolcommon.NewBase(msg, sessionState, toolcommon.SimpleRenderer(
-		toolcommon.ExtractField(func(a builtin.RunShellArgs) string { return a.Cmd }),
-	))
+	return toolcommon.NewBase(msg, sessionState, render)
+}
+
+// render shows the command and, while it runs, the tail of its output.
+func render(msg *types.Message, s spinner.Spinner, width, _ int) string {
+	cmd := ""
+	if msg.ToolCall.Function.Arguments != "" {
+		cmd = extractCmd(msg.ToolCall.Function.Arguments)
+	}
+	return toolcommon.RenderTool(msg, s, cmd, toolcommon.RunningOutput(msg, width), width)
 }
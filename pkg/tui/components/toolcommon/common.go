@@ -6,8 +6,10 @@ import (
 	"strings"
 
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 
 	"github.com/docker/cagent/pkg/paths"
+	"github.com/docker/cagent/pkg/tools"
 	"github.com/docker/cagent/pkg/tui/components/spinner"
 	"github.com/docker/cagent/pkg/tui/styles"
 	"github.com/docker/cagent/pkg/tui/types"
@@ -76,6 +78,74 @@ func FormatToolResult(content string, width int) string {
 	return strings.Join(lines, "\n")
 }
 
+// ImagePlaceholders describes the images returned by a tool, which can't be displayed.
+func ImagePlaceholders(images []tools.Image) string {
+	placeholders := make([]string, 0, len(images))
+	for _, image := range images {
+		placeholders = append(placeholders, fmt.Sprintf("[image: %s, %s]", image.MIMEType, formatSize(len(image.Data))))
+	}
+	return strings.Join(placeholders, "\n")
+}
+
+func formatSize(size int) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%d KB", size/1024)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
+
+// maxRunningOutputLines is the number of lines of output shown while a tool is running.
+const maxRunningOutputLines = 10
+
+// RunningOutput returns the last lines of the output streamed by a running tool,
+// or an empty string if the tool isn't running or hasn't produced any output yet.
+func RunningOutput(msg *types.Message, width int) string {
+	if msg.ToolStatus != types.ToolStatusRunning || msg.Content == "" {
+		return ""
+	}
+
+	padding := styles.ToolCallResult.Padding().GetHorizontalPadding()
+	availableWidth := max(width-1-padding, 10)
+
+	// Only the last lines are displayed: the output isn't split as a whole on every render.
+	content := strings.TrimRight(msg.Content, "\n")
+	skipped := false
+	for i, n := len(content), 0; ; n++ {
+		j := strings.LastIndexByte(content[:i], '\n')
+		if j < 0 {
+			break
+		}
+		if n == maxRunningOutputLines-1 {
+			content, skipped = content[j+1:], true
+			break
+		}
+		i = j
+	}
+
+	var lines []string
+	for line := range strings.SplitSeq(ansi.Strip(content), "\n") {
+		// Only keep what's displayed last on lines redrawn with carriage returns, like progress bars.
+		line = strings.TrimRight(line, "\r")
+		if i := strings.LastIndex(line, "\r"); i >= 0 {
+			line = line[i+1:]
+		}
+		lines = append(lines, wrapLines(line, availableWidth)...)
+	}
+
+	if len(lines) > maxRunningOutputLines {
+		lines, skipped = lines[len(lines)-maxRunningOutputLines:], true
+	}
+	if skipped {
+		lines = append([]string{"…"}, lines...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func RenderTool(msg *types.Message, inProgress spinner.Spinner, args, result string, width int) string {
 	nameStyle := styles.ToolName
 	resultStyle := styles.ToolMessageStyle
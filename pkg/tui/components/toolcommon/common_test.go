@@ -1,9 +1,13 @@
 package toolcommon
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/docker/cagent/pkg/tui/types"
 )
 
 func TestWrapLines(t *testing.T) {
@@ -164,3 +168,29 @@ func TestWrapLines(t *testing.T) {
 		})
 	}
 }
+
+func TestRunningOutput(t *testing.T) {
+	t.Parallel()
+
+	msg := &types.Message{ToolStatus: types.ToolStatusRunning, Content: "building\r 50%\r100%\n\x1b[32mok\x1b[0m\n"}
+	assert.Equal(t, "100%\nok", RunningOutput(msg, 80))
+
+	var content strings.Builder
+	for i := range 15 {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	msg.Content = content.String()
+	lines := strings.Split(RunningOutput(msg, 80), "\n")
+	assert.Len(t, lines, maxRunningOutputLines+1)
+	assert.Equal(t, "…", lines[0])
+	assert.Equal(t, "line 14", lines[len(lines)-1])
+
+	// Long lines are wrapped, and only the last ones are shown.
+	msg.Content = strings.Repeat("x", 80*20)
+	lines = strings.Split(RunningOutput(msg, 80), "\n")
+	assert.Len(t, lines, maxRunningOutputLines+1)
+	assert.Equal(t, "…", lines[0])
+
+	msg.ToolStatus = types.ToolStatusCompleted
+	assert.Empty(t, RunningOutput(msg, 80))
+}
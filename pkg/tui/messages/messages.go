@@ -10,6 +10,10 @@ type (
 	ToggleYoloMsg             struct{}
 	StartShellMsg             struct{}
 	SwitchAgentMsg            struct{ AgentName string } // Switch to a specific agent by name
+	ForkSessionMsg            struct{ UserMessage int }  // Fork before the n-th user message, 0 for the last one
+	RewindSessionMsg          struct{ UserMessage int }  // Rewind to the n-th user message, 0 for the last one
+	UndoFileChangesMsg        struct{}
+	RestoreFilesMsg           struct{ UserMessage int } // Restore the files modified since the n-th user message, 0 for the last one
 )
 
 // AgentCommandMsg command message
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 
 	"charm.land/bubbles/v2/help"
@@ -13,8 +14,10 @@ import (
 	"charm.land/lipgloss/v2"
 
 	"github.com/docker/cagent/pkg/app"
+	chatmsg "github.com/docker/cagent/pkg/chat"
 	"github.com/docker/cagent/pkg/history"
 	"github.com/docker/cagent/pkg/runtime"
+	"github.com/docker/cagent/pkg/tools"
 	"github.com/docker/cagent/pkg/tui/components/editor"
 	"github.com/docker/cagent/pkg/tui/components/messages"
 	"github.com/docker/cagent/pkg/tui/components/notification"
@@ -55,6 +58,8 @@ type Page interface {
 	layout.Sizeable
 	layout.Help
 	CompactSession() tea.Cmd
+	// LoadSession displays the messages of the app's current session and puts prompt in the editor
+	LoadSession(prompt string) tea.Cmd
 	Cleanup()
 	// GetInputHeight returns the current height of the editor/input area (including padding)
 	GetInputHeight() int
@@ -279,6 +284,9 @@ func (p *chatPage) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 		cmd := p.routeMouseEvent(msg, msg.Y)
 		return p, cmd
 
+	case editor.AttachmentPreviewMsg:
+		return p, p.openAttachmentPreview(msg.Preview)
+
 	case editor.SendMsg:
 		slog.Debug(msg.Content)
 		cmd := p.processMessage(msg)
@@ -306,6 +314,17 @@ func (p *chatPage) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 		return p, cmd
 	case *runtime.WarningEvent:
 		return p, notification.WarningCmd(msg.Message)
+	case *runtime.ModelFallbackEvent:
+		var cmd tea.Cmd
+		if msg.Discard {
+			cmd = p.messages.DiscardPartialResponse()
+		}
+		if msg.FailedModel == msg.Model {
+			return p, tea.Batch(cmd, notification.WarningCmd(fmt.Sprintf("%s failed, retrying (attempt %d): %s", msg.FailedModel, msg.Attempt, msg.Reason)))
+		}
+		return p, tea.Batch(cmd, notification.WarningCmd(fmt.Sprintf("%s failed, falling back to %s: %s", msg.FailedModel, msg.Model, msg.Reason)))
+	case *runtime.ModelAnsweredEvent:
+		return p, notification.InfoCmd(msg.Model + " answered")
 	case *runtime.RAGIndexingStartedEvent, *runtime.RAGIndexingProgressEvent, *runtime.RAGIndexingCompletedEvent:
 		// Forward RAG events to sidebar
 		slog.Debug("Chat page forwarding RAG event to sidebar", "event_type", fmt.Sprintf("%T", msg))
@@ -346,6 +365,8 @@ func (p *chatPage) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 		p.sidebar.SetAgentSwitching(msg.Switching)
 	case *runtime.ToolsetInfoEvent:
 		p.sidebar.SetToolsetInfo(msg.AvailableTools)
+	case *runtime.MCPServerStatusEvent:
+		p.sidebar.SetMCPServerStatus(msg.Server, msg.Status)
 	case *runtime.StreamStoppedEvent:
 		spinnerCmd := p.setWorking(false)
 		if p.msgCancel != nil {
@@ -379,6 +400,9 @@ func (p *chatPage) Update(msg tea.Msg) (layout.Model, tea.Cmd) {
 		spinnerCmd := p.setWorking(true)
 		cmd := p.messages.AddOrUpdateToolCall(msg.AgentName, msg.ToolCall, msg.ToolDefinition, types.ToolStatusRunning)
 		return p, tea.Batch(cmd, p.messages.ScrollToBottom(), spinnerCmd)
+	case *runtime.ToolCallOutputDeltaEvent:
+		cmd := p.messages.AppendToolOutput(msg.ToolCallID, msg.Delta)
+		return p, tea.Batch(cmd, p.messages.ScrollToBottom())
 	case *runtime.ToolCallResponseEvent:
 		spinnerCmd := p.setWorking(true)
 
@@ -673,6 +697,23 @@ func (p *chatPage) processMessage(msg editor.SendMsg) tea.Cmd {
 		filename := strings.TrimSpace(rest)
 		return core.CmdHandler(msgtypes.EvalSessionMsg{Filename: filename})
 	}
+	if command, arg, _ := strings.Cut(strings.TrimSpace(msg.Content), " "); command == "/fork" || command == "/rewind" || command == "/restore" {
+		n := 0
+		if arg = strings.TrimSpace(arg); arg != "" {
+			var err error
+			if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+				return notification.ErrorCmd(fmt.Sprintf("Usage: %s [n], where n is the number of a prompt of this conversation", command))
+			}
+		}
+		switch command {
+		case "/fork":
+			return core.CmdHandler(msgtypes.ForkSessionMsg{UserMessage: n})
+		case "/restore":
+			return core.CmdHandler(msgtypes.RestoreFilesMsg{UserMessage: n})
+		default:
+			return core.CmdHandler(msgtypes.RewindSessionMsg{UserMessage: n})
+		}
+	}
 
 	p.app.Run(ctx, p.msgCancel, msg.Content, msg.Attachments)
 
@@ -689,6 +730,58 @@ func (p *chatPage) CompactSession() tea.Cmd {
 	return p.messages.ScrollToBottom()
 }
 
+// LoadSession displays the messages of the app's current session and puts prompt in the editor
+func (p *chatPage) LoadSession(prompt string) tea.Cmd {
+	var cmds []tea.Cmd
+
+	for _, msg := range p.app.Session().GetAllMessages() {
+		if msg.Implicit {
+			continue
+		}
+
+		switch m := msg.Message; m.Role {
+		case chatmsg.MessageRoleUser:
+			cmds = append(cmds, p.messages.AddUserMessage(m.Content))
+		case chatmsg.MessageRoleAssistant:
+			if m.ReasoningContent != "" {
+				cmds = append(cmds, p.messages.AppendToLastMessage(msg.AgentName, types.MessageTypeAssistantReasoning, m.ReasoningContent))
+			}
+			if m.Content != "" {
+				cmds = append(cmds, p.messages.AppendToLastMessage(msg.AgentName, types.MessageTypeAssistant, m.Content))
+			}
+			for _, call := range m.ToolCalls {
+				var toolDef tools.Tool
+				for _, def := range m.ToolDefinitions {
+					if def.Name == call.Function.Name {
+						toolDef = def
+						break
+					}
+				}
+				cmds = append(cmds, p.messages.AddOrUpdateToolCall(msg.AgentName, call, toolDef, types.ToolStatusCompleted))
+			}
+		case chatmsg.MessageRoleTool:
+			result := tools.ResultSuccess(m.Content)
+			for _, part := range m.MultiContent {
+				if part.ImageURL != nil {
+					if image, ok := tools.ImageFromDataURL(part.ImageURL.URL); ok {
+						result.Images = append(result.Images, image)
+					}
+				}
+			}
+			cmds = append(cmds, p.messages.AddToolResult(&runtime.ToolCallResponseEvent{
+				ToolCall: tools.ToolCall{ID: m.ToolCallID},
+				Response: m.Content,
+				Result:   result,
+			}, types.ToolStatusCompleted))
+		}
+	}
+
+	p.editor.SetValue(prompt)
+	cmds = append(cmds, p.messages.ScrollToBottom())
+
+	return tea.Batch(cmds...)
+}
+
 func (p *chatPage) Cleanup() {
 	p.stopProgressBar()
 	p.editor.Cleanup()
@@ -709,8 +802,8 @@ func (p *chatPage) routeMouseEvent(msg tea.Msg, y int) tea.Cmd {
 		localY := y - editorTop - editorTopPadding
 		if localY >= 0 && localY < p.editor.BannerHeight() {
 			localX := max(0, click.X-styles.AppPaddingLeft)
-			if preview, ok := p.editor.AttachmentAt(localX); ok {
-				return p.openAttachmentPreview(preview)
+			if cmd := p.editor.PreviewAttachmentAt(localX); cmd != nil {
+				return cmd
 			}
 		}
 	}
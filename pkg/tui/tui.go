@@ -131,6 +131,26 @@ func (a *appModel) Init() tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// reloadSession rebuilds the chat page for the app's current session,
+// replays its messages and puts prompt in the editor.
+func (a *appModel) reloadSession(prompt string) tea.Cmd {
+	sess := a.application.Session()
+	a.sessionState = service.NewSessionState(sess)
+	a.chatPage = chat.New(a.application, a.sessionState)
+	a.dialog = dialog.New()
+	a.statusBar = statusbar.New(a.chatPage)
+
+	return tea.Sequence(
+		tea.Batch(
+			a.dialog.Init(),
+			a.chatPage.Init(),
+			a.emitStartupInfo(),
+			a.handleWindowResize(a.wWidth, a.wHeight),
+		),
+		a.chatPage.LoadSession(prompt),
+	)
+}
+
 // emitStartupInfo creates a command that emits startup events for immediate sidebar display
 func (a *appModel) emitStartupInfo() tea.Cmd {
 	return func() tea.Msg {
@@ -273,6 +293,34 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return a, tea.Batch(a.Init(), a.handleWindowResize(a.wWidth, a.wHeight))
 
+	case messages.ForkSessionMsg:
+		prompt, err := a.application.ForkSession(context.Background(), msg.UserMessage)
+		if err != nil {
+			return a, notification.ErrorCmd(fmt.Sprintf("Failed to fork session: %v", err))
+		}
+		return a, tea.Batch(a.reloadSession(prompt), notification.SuccessCmd("Forked into a new session. The original conversation was kept."))
+
+	case messages.RewindSessionMsg:
+		prompt, err := a.application.RewindSession(context.Background(), msg.UserMessage)
+		if err != nil {
+			return a, notification.ErrorCmd(fmt.Sprintf("Failed to rewind session: %v", err))
+		}
+		return a, a.reloadSession(prompt)
+
+	case messages.UndoFileChangesMsg:
+		files, err := a.application.UndoFileChanges()
+		if err != nil {
+			return a, notification.ErrorCmd(fmt.Sprintf("Failed to undo file changes: %v", err))
+		}
+		return a, notification.SuccessCmd(restoredFilesMessage(files))
+
+	case messages.RestoreFilesMsg:
+		files, err := a.application.RestoreFiles(msg.UserMessage)
+		if err != nil {
+			return a, notification.ErrorCmd(fmt.Sprintf("Failed to restore files: %v", err))
+		}
+		return a, notification.SuccessCmd(restoredFilesMessage(files))
+
 	case messages.StartShellMsg:
 		return a.startShell()
 
@@ -591,3 +639,14 @@ func toFullscreenView(content string) tea.View {
 
 	return view
 }
+
+func restoredFilesMessage(files []string) string {
+	switch len(files) {
+	case 0:
+		return "No files to restore"
+	case 1:
+		return "Restored " + files[0]
+	default:
+		return fmt.Sprintf("Restored %d files", len(files))
+	}
+}
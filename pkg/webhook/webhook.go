@@ -0,0 +1,514 @@
+// Package webhook notifies HTTP endpoints of the runtime events that need
+// attention, so that headless agents can be followed without streaming their events.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/docker/cagent/pkg/httpclient"
+	"github.com/docker/cagent/pkg/runtime"
+)
+
+// Types of the events that can be subscribed to. They match the types of the runtime events.
+const (
+	EventToolCallConfirmation = "tool_call_confirmation"
+	EventElicitationRequest   = "elicitation_request"
+	EventMaxIterationsReached = "max_iterations_reached"
+	EventStreamStopped        = "stream_stopped"
+	EventError                = "error"
+)
+
+// Events lists the types of the events that can be subscribed to.
+var Events = []string{
+	EventToolCallConfirmation,
+	EventElicitationRequest,
+	EventMaxIterationsReached,
+	EventStreamStopped,
+	EventError,
+}
+
+// Headers of the deliveries.
+const (
+	// SignatureHeader holds "sha256=" followed by the hex encoded HMAC-SHA256 of
+	// the timestamp, a dot and the body, keyed with the subscription's secret.
+	SignatureHeader = "X-Cagent-Signature"
+	// TimestampHeader holds the Unix time of the delivery attempt.
+	TimestampHeader = "X-Cagent-Timestamp"
+	EventHeader     = "X-Cagent-Event"
+	DeliveryHeader  = "X-Cagent-Delivery"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = time.Minute
+	requestTimeout     = 10 * time.Second
+
+	// defaultQueueSize is how many deliveries can wait for each endpoint. The
+	// following ones go straight to the dead-letter log.
+	defaultQueueSize = 256
+	// defaultWorkers is how many deliveries are sent to each endpoint at the same time.
+	defaultWorkers = 4
+	// workerIdleTimeout is how long the workers of an endpoint wait for deliveries before stopping.
+	workerIdleTimeout = time.Minute
+
+	// maxSignatureAge is how old a delivery can be before Verify rejects it.
+	maxSignatureAge = 5 * time.Minute
+)
+
+// Subscription is an endpoint notified of runtime events.
+type Subscription struct {
+	URL string `json:"url" yaml:"url"`
+	// Secret signs the deliveries. Deliveries are not signed without a secret.
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// Events filters the types of the events delivered. All of them are delivered if empty.
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+// Validate checks that the subscription has an HTTP(S) URL and only known event types.
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", s.URL)
+	}
+	for _, event := range s.Events {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("unknown webhook event %q, expected one of %v", event, Events)
+		}
+	}
+	return nil
+}
+
+func (s Subscription) wants(eventType string) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, eventType)
+}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	// ID identifies the delivery. It is the same for all the attempts.
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id"`
+	Timestamp time.Time `json:"timestamp"`
+	// Event is the runtime event, as streamed by the API.
+	Event json.RawMessage `json:"event"`
+}
+
+// deadLetter is an entry of the dead-letter log.
+type deadLetter struct {
+	Time     time.Time `json:"time"`
+	URL      string    `json:"url"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Payload  Payload   `json:"payload"`
+}
+
+// Dispatcher delivers runtime events to subscriptions in the background. Each
+// endpoint has a bounded queue of deliveries, sent by a few workers. Failed
+// deliveries are retried with an exponential backoff, then written to the
+// dead-letter log, as are the deliveries that don't fit in the queue.
+type Dispatcher struct {
+	subscriptions []Subscription
+	client        *http.Client
+	maxAttempts   int
+	backoff       time.Duration
+	maxBackoff    time.Duration
+	deadLetters   string
+	queueSize     int
+	workers       int
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	closed  bool
+	queues  map[string]*queue
+	pending sync.WaitGroup
+	// logMu serializes the writes to the dead-letter log.
+	logMu sync.Mutex
+}
+
+// queue holds the deliveries waiting for an endpoint. Its fields are guarded by the dispatcher's mu.
+type queue struct {
+	jobs    chan job
+	workers int
+}
+
+type job struct {
+	subscription Subscription
+	payload      Payload
+}
+
+type Opt func(*Dispatcher)
+
+// WithHTTPClient sets the client that sends the deliveries.
+func WithHTTPClient(client *http.Client) Opt {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithRetries sets how many times a delivery is attempted, and the delay before the
+// first retry. The delay doubles after each attempt, up to maxBackoff.
+func WithRetries(maxAttempts int, backoff, maxBackoff time.Duration) Opt {
+	return func(d *Dispatcher) {
+		d.maxAttempts = max(1, maxAttempts)
+		d.backoff = backoff
+		d.maxBackoff = maxBackoff
+	}
+}
+
+// WithQueue sets how many deliveries can wait for each endpoint, and how many
+// are sent to it at the same time.
+func WithQueue(size, workers int) Opt {
+	return func(d *Dispatcher) {
+		d.queueSize = max(1, size)
+		d.workers = max(1, workers)
+	}
+}
+
+// WithDeadLetterLog appends the deliveries that failed to a JSON Lines file.
+func WithDeadLetterLog(path string) Opt {
+	return func(d *Dispatcher) {
+		d.deadLetters = path
+	}
+}
+
+// New creates a dispatcher notifying the given subscriptions of the events of all the sessions.
+func New(subscriptions []Subscription, opts ...Opt) (*Dispatcher, error) {
+	for _, s := range subscriptions {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	client := httpclient.NewHTTPClient()
+	client.Timeout = requestTimeout
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		subscriptions: subscriptions,
+		client:        client,
+		maxAttempts:   defaultMaxAttempts,
+		backoff:       defaultBackoff,
+		maxBackoff:    defaultMaxBackoff,
+		queueSize:     defaultQueueSize,
+		workers:       defaultWorkers,
+		ctx:           ctx,
+		cancel:        cancel,
+		queues:        map[string]*queue{},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	// Redirects aren't followed: they would send the deliveries, and their
+	// signatures, to an endpoint that wasn't subscribed.
+	noRedirects := *d.client
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	d.client = &noRedirects
+
+	return d, nil
+}
+
+// Notify delivers an event of a session to the global subscriptions, and to the
+// given ones, that subscribed to its type. It doesn't wait for the deliveries.
+func (d *Dispatcher) Notify(sessionID string, event runtime.Event, subscriptions ...Subscription) {
+	if d == nil {
+		return
+	}
+	eventType, ok := typeOf(event)
+	if !ok {
+		return
+	}
+
+	var targets []Subscription
+	for _, s := range slices.Concat(d.subscriptions, subscriptions) {
+		if s.wants(eventType) {
+			targets = append(targets, s)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	buf, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to marshal webhook event", "type", eventType, "error", err)
+		return
+	}
+	payload := Payload{
+		Type:      eventType,
+		SessionID: sessionID,
+		Timestamp: time.Now().UTC(),
+		Event:     buf,
+	}
+
+	var overflow []job
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	for _, s := range targets {
+		payload.ID = uuid.NewString()
+		if !d.enqueue(job{subscription: s, payload: payload}) {
+			overflow = append(overflow, job{subscription: s, payload: payload})
+		}
+	}
+	d.mu.Unlock()
+
+	for _, j := range overflow {
+		slog.Error("Dropping webhook delivery, too many are waiting", "url", j.subscription.URL, "type", j.payload.Type, "delivery", j.payload.ID)
+		d.writeDeadLetter(deadLetter{
+			Time:    time.Now().UTC(),
+			URL:     j.subscription.URL,
+			Error:   "the delivery queue of the endpoint is full",
+			Payload: j.payload,
+		})
+	}
+}
+
+// enqueue adds a delivery to the queue of its endpoint, and starts a worker if
+// there are fewer than allowed. It returns false if the queue is full. d.mu must be held.
+func (d *Dispatcher) enqueue(j job) bool {
+	q, ok := d.queues[j.subscription.URL]
+	if !ok {
+		q = &queue{jobs: make(chan job, d.queueSize)}
+		d.queues[j.subscription.URL] = q
+	}
+
+	select {
+	case q.jobs <- j:
+	default:
+		return false
+	}
+	d.pending.Add(1)
+
+	if q.workers < d.workers {
+		q.workers++
+		go d.work(j.subscription.URL, q)
+	}
+	return true
+}
+
+// work sends the deliveries of a queue. It stops once the queue has been empty
+// for a while, or once the dispatcher is closed and the queue is drained.
+func (d *Dispatcher) work(url string, q *queue) {
+	idle := time.NewTimer(workerIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case j := <-q.jobs:
+			d.deliver(j.subscription, j.payload)
+			d.pending.Done()
+			idle.Reset(workerIdleTimeout)
+			continue
+		case <-idle.C:
+		case <-d.ctx.Done():
+		}
+
+		// Deliveries are only enqueued with d.mu held: the queue can't get a
+		// delivery without a worker after this check.
+		d.mu.Lock()
+		if len(q.jobs) > 0 {
+			d.mu.Unlock()
+			idle.Reset(workerIdleTimeout)
+			continue
+		}
+		q.workers--
+		if q.workers == 0 {
+			delete(d.queues, url)
+		}
+		d.mu.Unlock()
+		return
+	}
+}
+
+// Close stops accepting events and waits for the pending deliveries. Once ctx is
+// done, the pending deliveries are abandoned and written to the dead-letter log.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// deliver sends a payload to a subscription until it succeeds, fails permanently
+// or runs out of attempts.
+func (d *Dispatcher) deliver(s Subscription, payload Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Failed to marshal webhook payload", "type", payload.Type, "error", err)
+		return
+	}
+
+	backoff := d.backoff
+	attempt := 1
+	for ; ; attempt++ {
+		var retry bool
+		retry, err = d.send(s, payload, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.maxAttempts {
+			break
+		}
+
+		slog.Debug("Retrying webhook delivery", "url", s.URL, "delivery", payload.ID, "attempt", attempt, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+			err = fmt.Errorf("%w (after %v)", d.ctx.Err(), err)
+		}
+		if d.ctx.Err() != nil {
+			break
+		}
+		backoff = min(2*backoff, d.maxBackoff)
+	}
+
+	slog.Error("Failed to deliver webhook", "url", s.URL, "type", payload.Type, "delivery", payload.ID, "attempts", attempt, "error", err)
+	d.writeDeadLetter(deadLetter{
+		Time:     time.Now().UTC(),
+		URL:      s.URL,
+		Attempts: attempt,
+		Error:    err.Error(),
+		Payload:  payload,
+	})
+}
+
+// send makes one delivery attempt. It reports whether a failed attempt is worth retrying.
+func (d *Dispatcher) send(s Subscription, payload Payload, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, payload.Type)
+	req.Header.Set(DeliveryHeader, payload.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	if s.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return d.ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+func (d *Dispatcher) writeDeadLetter(entry deadLetter) {
+	if d.deadLetters == "" {
+		return
+	}
+
+	d.logMu.Lock()
+	defer d.logMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(d.deadLetters), 0o700); err != nil {
+		slog.Error("Failed to create the webhook dead-letter log", "path", d.deadLetters, "error", err)
+		return
+	}
+	f, err := os.OpenFile(d.deadLetters, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		slog.Error("Failed to open the webhook dead-letter log", "path", d.deadLetters, "error", err)
+		return
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		slog.Error("Failed to write the webhook dead-letter log", "path", d.deadLetters, "error", err)
+	}
+}
+
+// Sign returns the signature of a delivery's body sent at the given Unix time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery received with the given headers.
+// Deliveries older than five minutes are rejected to prevent replays.
+func Verify(secret string, header http.Header, body []byte) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid webhook timestamp")
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		return errors.New("webhook timestamp is too old or too far in the future")
+	}
+	if !hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign(secret, timestamp, body))) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
+
+// typeOf returns the type of an event, if it can be subscribed to.
+func typeOf(event runtime.Event) (string, bool) {
+	switch event.(type) {
+	case *runtime.ToolCallConfirmationEvent:
+		return EventToolCallConfirmation, true
+	case *runtime.ElicitationRequestEvent:
+		return EventElicitationRequest, true
+	case *runtime.MaxIterationsReachedEvent:
+		return EventMaxIterationsReached, true
+	case *runtime.StreamStoppedEvent:
+		return EventStreamStopped, true
+	case *runtime.ErrorEvent:
+		return EventError, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/cagent/pkg/runtime"
+	"github.com/docker/cagent/pkg/tools"
+)
+
+type delivery struct {
+	header  http.Header
+	body    []byte
+	payload Payload
+}
+
+// receiver is a webhook endpoint that answers with the given status codes, in turn,
+// then with 200 OK.
+type receiver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	deliveries []delivery
+	statuses   []int
+	attempts   atomic.Int32
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempt := int(r.attempts.Add(1))
+		body, _ := io.ReadAll(req.Body)
+		if attempt <= len(r.statuses) {
+			w.WriteHeader(r.statuses[attempt-1])
+			return
+		}
+
+		var payload Payload
+		_ = json.Unmarshal(body, &payload)
+		r.mu.Lock()
+		r.deliveries = append(r.deliveries, delivery{header: req.Header.Clone(), body: body, payload: payload})
+		r.mu.Unlock()
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.deliveries)
+}
+
+func newDispatcher(t *testing.T, subscriptions []Subscription, opts ...Opt) *Dispatcher {
+	t.Helper()
+
+	d, err := New(subscriptions, append([]Opt{WithRetries(3, time.Millisecond, 5*time.Millisecond)}, opts...)...)
+	require.NoError(t, err)
+	return d
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	t.Parallel()
+
+	all := newReceiver(t)
+	filtered := newReceiver(t)
+	perSession := newReceiver(t)
+	d := newDispatcher(t, []Subscription{
+		{URL: all.URL, Secret: "s3cr3t"},
+		{URL: filtered.URL, Events: []string{EventStreamStopped}},
+	})
+
+	toolCall := tools.ToolCall{ID: "call-1", Function: tools.FunctionCall{Name: "shell"}}
+	d.Notify("session-1", runtime.ToolCallConfirmation(toolCall, tools.Tool{Name: "shell"}, "root"))
+	d.Notify("session-1", runtime.AgentChoice("root", "Not delivered"))
+	d.Notify("session-1", runtime.StreamStopped("session-1", "root"), Subscription{URL: perSession.URL})
+	require.NoError(t, d.Close(t.Context()))
+
+	deliveries := all.received()
+	require.Len(t, deliveries, 2)
+	types := []string{deliveries[0].payload.Type, deliveries[1].payload.Type}
+	assert.ElementsMatch(t, []string{EventToolCallConfirmation, EventStreamStopped}, types)
+	for _, d := range deliveries {
+		assert.Equal(t, "session-1", d.payload.SessionID)
+		assert.NotEmpty(t, d.payload.ID)
+		assert.Equal(t, d.payload.ID, d.header.Get(DeliveryHeader))
+		assert.Equal(t, d.payload.Type, d.header.Get(EventHeader))
+		require.NoError(t, Verify("s3cr3t", d.header, d.body))
+		require.Error(t, Verify("wrong", d.header, d.body))
+		require.Error(t, Verify("s3cr3t", d.header, append(d.body, ' ')))
+	}
+
+	// The runtime event is delivered as is.
+	for _, d := range deliveries {
+		if d.payload.Type == EventToolCallConfirmation {
+			var event runtime.ToolCallConfirmationEvent
+			require.NoError(t, json.Unmarshal(d.payload.Event, &event))
+			assert.Equal(t, "call-1", event.ToolCall.ID)
+		}
+	}
+
+	deliveries = filtered.received()
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, EventStreamStopped, deliveries[0].payload.Type)
+	assert.Empty(t, deliveries[0].header.Get(SignatureHeader))
+
+	deliveries = perSession.received()
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, EventStreamStopped, deliveries[0].payload.Type)
+}
+
+func TestDispatcher_RetriesFailedDeliveries(t *testing.T) {
+	t.Parallel()
+
+	r := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	d := newDispatcher(t, []Subscription{{URL: r.URL}})
+
+	d.Notify("session-1", runtime.Error("boom"))
+	require.NoError(t, d.Close(t.Context()))
+
+	assert.Equal(t, int32(3), r.attempts.Load())
+	deliveries := r.received()
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, EventError, deliveries[0].payload.Type)
+}
+
+func TestDispatcher_DeadLetters(t *testing.T) {
+	t.Parallel()
+
+	unavailable := newReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	rejecting := newReceiver(t, http.StatusBadRequest)
+	deadLetters := filepath.Join(t.TempDir(), "webhooks", "dead-letters.jsonl")
+	d := newDispatcher(t, []Subscription{{URL: unavailable.URL, Secret: "s3cr3t", Events: []string{EventMaxIterationsReached}}}, WithDeadLetterLog(deadLetters))
+
+	d.Notify("session-1", runtime.MaxIterationsReached(10))
+	d.Notify("session-2", runtime.ElicitationRequest("Sign in", nil, nil, "root"), Subscription{URL: rejecting.URL})
+	require.NoError(t, d.Close(t.Context()))
+
+	// Client errors are not retried.
+	assert.Equal(t, int32(3), unavailable.attempts.Load())
+	assert.Equal(t, int32(1), rejecting.attempts.Load())
+
+	buf, err := os.ReadFile(deadLetters)
+	require.NoError(t, err)
+	assert.NotContains(t, string(buf), "s3cr3t")
+
+	entries := map[string]deadLetter{}
+	for line := range strings.SplitSeq(strings.TrimSpace(string(buf)), "\n") {
+		var entry deadLetter
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries[entry.URL] = entry
+	}
+	require.Len(t, entries, 2)
+	assert.Equal(t, 3, entries[unavailable.URL].Attempts)
+	assert.Equal(t, EventMaxIterationsReached, entries[unavailable.URL].Payload.Type)
+	assert.Equal(t, "session-1", entries[unavailable.URL].Payload.SessionID)
+	assert.Equal(t, 1, entries[rejecting.URL].Attempts)
+	assert.Equal(t, "session-2", entries[rejecting.URL].Payload.SessionID)
+	assert.Contains(t, entries[rejecting.URL].Error, "400")
+}
+
+func TestDispatcher_CloseAbandonsPendingDeliveries(t *testing.T) {
+	t.Parallel()
+
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	deadLetters := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	d, err := New([]Subscription{{URL: r.URL}}, WithRetries(5, time.Hour, time.Hour), WithDeadLetterLog(deadLetters))
+	require.NoError(t, err)
+
+	d.Notify("session-1", runtime.StreamStopped("session-1", "root"))
+	require.Eventually(t, func() bool { return r.attempts.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, d.Close(ctx), context.DeadlineExceeded)
+
+	buf, err := os.ReadFile(deadLetters)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), EventStreamStopped)
+
+	// Events are ignored once the dispatcher is closed.
+	d.Notify("session-1", runtime.StreamStopped("session-1", "root"))
+	assert.Equal(t, int32(1), r.attempts.Load())
+}
+
+func TestDispatcher_DoesntFollowRedirects(t *testing.T) {
+	t.Parallel()
+
+	other := newReceiver(t)
+	redirecting := httptest.NewServer(http.RedirectHandler(other.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirecting.Close)
+	deadLetters := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	d := newDispatcher(t, []Subscription{{URL: redirecting.URL, Secret: "s3cr3t"}}, WithDeadLetterLog(deadLetters))
+
+	d.Notify("session-1", runtime.Error("boom"))
+	require.NoError(t, d.Close(t.Context()))
+
+	assert.Equal(t, int32(0), other.attempts.Load())
+	buf, err := os.ReadFile(deadLetters)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), "307")
+}
+
+func TestDispatcher_QueueOverflow(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var attempts atomic.Int32
+	blocking := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		if attempts.Add(1) == 1 {
+			started <- struct{}{}
+		}
+		<-release
+	}))
+	t.Cleanup(blocking.Close)
+	deadLetters := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	d := newDispatcher(t, []Subscription{{URL: blocking.URL}}, WithQueue(1, 1), WithDeadLetterLog(deadLetters))
+
+	// The worker sends the first delivery, the second one waits and the third one doesn't fit.
+	d.Notify("session-1", runtime.Error("first"))
+	<-started
+	d.Notify("session-1", runtime.Error("second"))
+	d.Notify("session-1", runtime.Error("third"))
+
+	buf, err := os.ReadFile(deadLetters)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), "queue of the endpoint is full")
+	assert.Contains(t, string(buf), "third")
+
+	close(release)
+	require.NoError(t, d.Close(t.Context()))
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.Equal(t, 1, strings.Count(strings.TrimSpace(string(buf)), "\n")+1)
+}
+
+func TestSubscription_Validate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, Subscription{URL: "https://example.com/hook", Events: []string{EventError}}.Validate())
+	require.Error(t, Subscription{URL: "example.com/hook"}.Validate())
+	require.Error(t, Subscription{URL: "ftp://example.com/hook"}.Validate())
+	require.Error(t, Subscription{URL: "https://example.com/hook", Events: []string{"agent_choice"}}.Validate())
+
+	_, err := New([]Subscription{{URL: "not a url"}})
+	require.Error(t, err)
+}
+
+func TestVerify_RejectsOldDeliveries(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{}`)
+	timestamp := time.Now().Add(-time.Hour).Unix()
+	header := http.Header{}
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(SignatureHeader, Sign("s3cr3t", timestamp, body))
+	require.Error(t, Verify("s3cr3t", header, body))
+
+	header.Del(TimestampHeader)
+	require.Error(t, Verify("s3cr3t", header, body))
+}